@@ -18,6 +18,7 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -31,12 +32,14 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/console/prompt"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/trie"
 	"github.com/olekukonko/tablewriter"
 	"github.com/urfave/cli/v2"
@@ -69,6 +72,10 @@ Remove blockchain and state databases`,
 			dbExportCmd,
 			dbMetadataCmd,
 			dbCheckStateContentCmd,
+			dbPruneHistoryCmd,
+			dbVerifyCmd,
+			dbRepairCmd,
+			dbReindexLogsCmd,
 		},
 	}
 	dbInspectCmd = &cli.Command{
@@ -193,6 +200,63 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		}, utils.NetworkFlags, utils.DatabasePathFlags),
 		Description: "Shows metadata about the chain status.",
 	}
+	dbPruneHistoryCmd = &cli.Command{
+		Action:    pruneHistory,
+		Name:      "prune-history",
+		Usage:     "Prune block bodies and receipts below a cutoff block",
+		ArgsUsage: "<cutoff (optional)>",
+		Flags: flags.Merge([]cli.Flag{
+			utils.HistoryBlocksFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `This command deletes the block bodies and receipts of all blocks below the
+given cutoff block from the ancient store. Their transaction indices are retained,
+so lookups of the pruned transactions can be served by --rollup.historicalrpc.
+If no cutoff is given, it is derived from the current head and --history.blocks.
+Headers are always retained. Only frozen blocks can be pruned, so the cutoff is
+capped at the ancient store's head.`,
+	}
+	dbVerifyCmd = &cli.Command{
+		Action: verifyChain,
+		Name:   "verify",
+		Usage:  "Verify the consistency of the chain data",
+		Flags:  flags.Merge(utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `This command walks the canonical chain from the head header back to genesis and
+cross-checks the canonical hash mappings, headers, header number mappings, total
+difficulties, bodies, receipts and transaction lookup entries of every block, both
+in the key-value store and in the ancient store. All inconsistencies found are
+reported, together with whether they can be fixed by 'geth db repair'.`,
+	}
+	repairDryRunFlag = &cli.BoolFlag{
+		Name:  "dryrun",
+		Usage: "Only report the repairs needed, without modifying the database",
+	}
+	repairYesFlag = &cli.BoolFlag{
+		Name:  "yes",
+		Usage: "Rewind the chain if needed without asking for confirmation",
+	}
+	dbRepairCmd = &cli.Command{
+		Action: repairChain,
+		Name:   "repair",
+		Usage:  "Repair inconsistencies of the chain data",
+		Flags:  flags.Merge([]cli.Flag{repairDryRunFlag, repairYesFlag}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `This command verifies the chain data like 'geth db verify' and repairs the
+inconsistencies found. Derivable data, i.e. canonical hash and header number
+mappings, total difficulties and transaction lookup entries, is rebuilt. If any
+block is corrupted beyond repair, the chain is rewound to the last consistent
+block before it, after asking for confirmation unless --yes is given. If the
+state of the new head block is missing, it is regenerated by rewinding further
+on the next startup. With --dryrun, the repairs are only reported.`,
+	}
+	dbReindexLogsCmd = &cli.Command{
+		Action: reindexLogs,
+		Name:   "reindex-logs",
+		Usage:  "Rebuild the log index",
+		Flags:  flags.Merge([]cli.Flag{utils.LogIndexHistoryFlag}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `This command deletes the log index used for fast log filtering with --logindex
+and rebuilds it for all finished sections of the canonical chain, limited to the
+recent blocks given by --logindex.history. Blocks newer than the last finished
+section are indexed by the node once it is running.`,
+	}
 )
 
 func removeDB(ctx *cli.Context) error {
@@ -515,7 +579,7 @@ func dbDumpTrie(ctx *cli.Context) error {
 		}
 	}
 	id := trie.StorageTrieID(common.BytesToHash(state), common.BytesToHash(account), common.BytesToHash(storage))
-	theTrie, err := trie.New(id, trie.NewDatabase(db))
+	theTrie, err := trie.New(id, utils.MakeTrieDatabase(ctx, db, false))
 	if err != nil {
 		return err
 	}
@@ -552,8 +616,12 @@ func freezerInspect(ctx *cli.Context) error {
 	}
 	stack, _ := makeConfigNode(ctx)
 	ancient := stack.ResolveAncient("chaindata", ctx.String(utils.AncientFlag.Name))
+	cold, err := stack.AncientColdConfig()
 	stack.Close()
-	return rawdb.InspectFreezerTable(ancient, freezer, table, start, end)
+	if err != nil {
+		return err
+	}
+	return rawdb.InspectFreezerTable(ancient, freezer, table, start, end, cold)
 }
 
 func importLDBdata(ctx *cli.Context) error {
@@ -713,3 +781,182 @@ func showMetaData(ctx *cli.Context) error {
 	table.Render()
 	return nil
 }
+
+func pruneHistory(ctx *cli.Context) error {
+	if ctx.NArg() > 1 {
+		return fmt.Errorf("max 1 argument: %v", ctx.Command.ArgsUsage)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	var cutoff uint64
+	switch {
+	case ctx.NArg() == 1:
+		number, err := strconv.ParseUint(ctx.Args().Get(0), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid cutoff block: %v", err)
+		}
+		cutoff = number
+	case ctx.Uint64(utils.HistoryBlocksFlag.Name) != 0:
+		number := rawdb.ReadHeaderNumber(db, rawdb.ReadHeadBlockHash(db))
+		if number == nil {
+			return errors.New("head block not found")
+		}
+		limit := ctx.Uint64(utils.HistoryBlocksFlag.Name)
+		if *number < limit {
+			log.Info("Chain is shorter than the retention window, nothing to prune", "head", *number, "limit", limit)
+			return nil
+		}
+		cutoff = *number - limit + 1
+	default:
+		return fmt.Errorf("either the cutoff block or --%s is required", utils.HistoryBlocksFlag.Name)
+	}
+	start := time.Now()
+	tail, err := rawdb.PruneHistory(db, cutoff)
+	if err != nil {
+		return err
+	}
+	log.Info("Chain history pruned", "tail", tail, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// runChainVerification verifies the chain data, stopping on interrupts, and
+// prints the inconsistencies found.
+func runChainVerification(db ethdb.Database) (*core.ChainReport, error) {
+	var (
+		interrupt = make(chan os.Signal, 1)
+		stop      = make(chan struct{})
+		start     = time.Now()
+	)
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+	defer close(interrupt)
+	go func() {
+		if _, ok := <-interrupt; ok {
+			log.Info("Interrupted during chain verification, stopping")
+		}
+		close(stop)
+	}()
+	report, err := core.VerifyChain(db, stop)
+	if err != nil {
+		return nil, err
+	}
+	if len(report.Issues) > 0 {
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Block", "Hash", "Data", "Problem", "Fix"})
+		for _, issue := range report.Issues {
+			fix := "rebuild"
+			if !issue.Derivable {
+				fix = "rewind"
+			}
+			table.Append([]string{fmt.Sprint(issue.Number), issue.Hash.TerminalString(), issue.Kind, issue.Detail, fix})
+		}
+		table.Render()
+	}
+	log.Info("Verified chain data", "head", report.Head, "ancients", report.Frozen, "tail", report.Tail,
+		"checked", report.Checked, "issues", len(report.Issues), "elapsed", common.PrettyDuration(time.Since(start)))
+	return report, nil
+}
+
+func verifyChain(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	report, err := runChainVerification(db)
+	if err != nil {
+		return err
+	}
+	if number, corrupted := report.Corrupted(); corrupted {
+		return fmt.Errorf("chain data corrupted at block %d", number)
+	}
+	if len(report.Issues) > 0 {
+		return fmt.Errorf("chain data inconsistent, %d issues found", len(report.Issues))
+	}
+	return nil
+}
+
+func repairChain(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	dryrun := ctx.Bool(repairDryRunFlag.Name)
+	db := utils.MakeChainDatabase(ctx, stack, dryrun)
+	defer db.Close()
+
+	report, err := runChainVerification(db)
+	if err != nil {
+		return err
+	}
+	if len(report.Issues) == 0 {
+		log.Info("Chain data is consistent, nothing to repair")
+		return nil
+	}
+	number, corrupted := report.Corrupted()
+	if dryrun {
+		if corrupted && number > 0 {
+			log.Warn("Chain would be rewound to last consistent block", "from", report.Head, "to", number-1)
+		}
+		log.Info("Dry run, database left untouched", "issues", len(report.Issues))
+		return nil
+	}
+	if corrupted && number > 0 && !ctx.Bool(repairYesFlag.Name) {
+		msg := fmt.Sprintf("Rewind the chain from block %d to %d, deleting all data above it?", report.Head, number-1)
+		confirm, err := prompt.Stdin.PromptConfirm(msg)
+		if err != nil {
+			return err
+		}
+		if !confirm {
+			log.Info("Chain repair aborted")
+			return nil
+		}
+	}
+	repair, err := core.RepairChain(db, report)
+	if err != nil {
+		return err
+	}
+	if repair.Rewound {
+		log.Warn("Rewound chain to last consistent block", "from", report.Head, "to", repair.Head)
+	}
+	for _, kind := range []string{core.IssueCanonicalHash, core.IssueHeaderNumber, core.IssueTd, core.IssueTxLookup, core.IssueHeadBlock, core.IssueHeadFastBlock} {
+		if n := repair.Rebuilt[kind]; n > 0 {
+			log.Info("Rebuilt chain data", "data", kind, "blocks", n)
+		}
+	}
+	return nil
+}
+
+func reindexLogs(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	head := rawdb.ReadHeadHeader(db)
+	if head == nil {
+		return errors.New("no head header found")
+	}
+	start := time.Now()
+	rawdb.DeleteLogIndex(db)
+	log.Info("Deleted log index", "elapsed", common.PrettyDuration(time.Since(start)))
+
+	indexer := core.NewLogIndexer(db, params.LogIndexBlocks, params.LogIndexConfirms, ctx.Uint64(utils.LogIndexHistoryFlag.Name))
+	defer indexer.Close()
+
+	if err := indexer.ProcessSections(head.Number.Uint64()); err != nil {
+		return err
+	}
+	sections, _, _ := indexer.Sections()
+	var tail uint64
+	if t := rawdb.ReadLogIndexTail(db); t != nil {
+		tail = *t
+	}
+	log.Info("Rebuilt log index", "tail", tail, "head", sections*params.LogIndexBlocks, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
@@ -36,6 +36,7 @@ import (
 	"github.com/ethereum/go-ethereum/internal/debug"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/internal/flags"
+	"github.com/ethereum/go-ethereum/internal/tracing"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/node"
@@ -81,12 +82,28 @@ var (
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolPrivateLifetimeFlag,
+		utils.TxPoolPolicyFlag,
+		utils.TxPoolPolicyRegistryFlag,
+		utils.BlobPoolDataDirFlag,
+		utils.BlobPoolDataCapFlag,
+		utils.BlobPoolPriceBumpFlag,
 		utils.SyncModeFlag,
 		utils.SyncTargetFlag,
 		utils.ExitWhenSyncedFlag,
 		utils.GCModeFlag,
+		utils.StateSchemeFlag,
+		utils.StateHistoryFlag,
+		utils.StateDiffHistoryFlag,
+		utils.StateOnlinePruningFlag,
+		utils.StateOnlinePruningRetentionFlag,
+		utils.StateOnlinePruningBloomSizeFlag,
+		utils.StateOnlinePruningThrottleFlag,
 		utils.SnapshotFlag,
 		utils.TxLookupLimitFlag,
+		utils.HistoryBlocksFlag,
+		utils.LogIndexFlag,
+		utils.LogIndexHistoryFlag,
 		utils.LightServeFlag,
 		utils.LightIngressFlag,
 		utils.LightEgressFlag,
@@ -157,19 +174,33 @@ var (
 		utils.AuthListenFlag,
 		utils.AuthPortFlag,
 		utils.AuthVirtualHostsFlag,
+		utils.AuthApiFlag,
+		utils.AuthMethodsAllowFlag,
+		utils.AuthMethodsDenyFlag,
+		utils.AuthIdentitiesFlag,
 		utils.JWTSecretFlag,
 		utils.HTTPVirtualHostsFlag,
 		utils.GraphQLEnabledFlag,
 		utils.GraphQLCORSDomainFlag,
 		utils.GraphQLVirtualHostsFlag,
 		utils.HTTPApiFlag,
+		utils.HTTPMethodsAllowFlag,
+		utils.HTTPMethodsDenyFlag,
 		utils.HTTPPathPrefixFlag,
 		utils.WSEnabledFlag,
 		utils.WSListenAddrFlag,
 		utils.WSPortFlag,
 		utils.WSApiFlag,
+		utils.WSMethodsAllowFlag,
+		utils.WSMethodsDenyFlag,
 		utils.WSAllowedOriginsFlag,
 		utils.WSPathPrefixFlag,
+		utils.BinaryEnabledFlag,
+		utils.BinaryListenAddrFlag,
+		utils.BinaryPortFlag,
+		utils.BinaryApiFlag,
+		utils.BinaryMethodsAllowFlag,
+		utils.BinaryMethodsDenyFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
 		utils.InsecureUnlockAllowedFlag,
@@ -177,6 +208,11 @@ var (
 		utils.RPCGlobalEVMTimeoutFlag,
 		utils.RPCGlobalTxFeeCapFlag,
 		utils.AllowUnprotectedTxs,
+		utils.BatchRequestLimit,
+		utils.BatchResponseMaxSize,
+		utils.RPCConnRateLimitFlag,
+		utils.RPCIPRateLimitFlag,
+		utils.RPCMethodCostsFlag,
 	}
 
 	metricsFlags = []cli.Flag{
@@ -194,6 +230,10 @@ var (
 		utils.MetricsInfluxDBTokenFlag,
 		utils.MetricsInfluxDBBucketFlag,
 		utils.MetricsInfluxDBOrganizationFlag,
+		utils.TracingEnabledFlag,
+		utils.TracingEndpointFlag,
+		utils.TracingFileFlag,
+		utils.TracingSampleRatioFlag,
 	}
 )
 
@@ -234,6 +274,8 @@ func init() {
 		snapshotCommand,
 		// See verkle.go
 		verkleCommand,
+		// See txpoolcmd.go
+		txpoolCommand,
 	}
 	sort.Sort(cli.CommandsByName(app.Commands))
 
@@ -321,6 +363,9 @@ func prepare(ctx *cli.Context) {
 
 	// Start system runtime metrics collection
 	go metrics.CollectProcessMetrics(3 * time.Second)
+
+	// Start request tracing if enabled
+	utils.SetupTracing(ctx)
 }
 
 // geth is the main entry point into the system if no special subcommand is run.
@@ -332,6 +377,8 @@ func geth(ctx *cli.Context) error {
 	}
 
 	prepare(ctx)
+	defer tracing.Stop()
+
 	stack, backend := makeFullNode(ctx)
 	defer stack.Close()
 
@@ -17,10 +17,17 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"math/big"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/cmd/utils"
@@ -151,6 +158,37 @@ as the backend data source, making this command a lot faster.
 
 The argument is interpreted as block number or hash. If none is provided, the latest
 block is used.
+`,
+			},
+			{
+				Name:      "export",
+				Usage:     "Export the state snapshot of a block into a file",
+				ArgsUsage: "<filename> [<blockHash> | <blockNum>]",
+				Action:    exportSnapshot,
+				Flags:     flags.Merge(utils.NetworkFlags, utils.DatabasePathFlags),
+				Description: `
+geth snapshot export <filename> [<blockHash> | <blockNum>]
+will stream the flat state (accounts, storage and contract codes) of the given
+block, along with the block itself, into a chunked and checksummed file. If no
+block is provided, the head block is used. The state snapshot of the block must
+be available, i.e. only the head block and recent blocks still covered by the
+snapshot diff layers can be exported. If the file ends with .gz, the output will
+be gzipped.
+`,
+			},
+			{
+				Name:      "import",
+				Usage:     "Import the state snapshot of a block from a file",
+				ArgsUsage: "<filename>",
+				Action:    importSnapshot,
+				Flags:     flags.Merge(utils.NetworkFlags, utils.DatabasePathFlags),
+				Description: `
+geth snapshot import <filename>
+will load the state exported by 'geth snapshot export' into a freshly initialized
+database, regenerate the state tries, verify the state root and set the exported
+block as the chain head. The database must be initialized with the same genesis
+via 'geth init' beforehand. The blocks preceding the exported one are not part
+of the export.
 `,
 			},
 		},
@@ -166,6 +204,10 @@ func pruneState(ctx *cli.Context) error {
 	chaindb := utils.MakeChainDatabase(ctx, stack, false)
 	defer chaindb.Close()
 
+	if rawdb.ReadStateScheme(chaindb) == rawdb.PathScheme {
+		log.Error("Offline pruning is not needed with the path-based state scheme, stale state is pruned inherently")
+		return errors.New("state is stored in the path-based scheme")
+	}
 	prunerconfig := pruner.Config{
 		Datadir:   stack.ResolvePath(""),
 		Cachedir:  stack.ResolvePath(config.Eth.TrieCleanCacheJournal),
@@ -213,7 +255,7 @@ func verifyState(ctx *cli.Context) error {
 		NoBuild:    true,
 		AsyncBuild: false,
 	}
-	snaptree, err := snapshot.New(snapconfig, chaindb, trie.NewDatabase(chaindb), headBlock.Root())
+	snaptree, err := snapshot.New(snapconfig, chaindb, utils.MakeTrieDatabase(ctx, chaindb, false), headBlock.Root())
 	if err != nil {
 		log.Error("Failed to open snapshot tree", "err", err)
 		return err
@@ -279,7 +321,7 @@ func traverseState(ctx *cli.Context) error {
 		root = headBlock.Root()
 		log.Info("Start traversing the state", "root", root, "number", headBlock.NumberU64())
 	}
-	triedb := trie.NewDatabase(chaindb)
+	triedb := utils.MakeTrieDatabase(ctx, chaindb, false)
 	t, err := trie.NewStateTrie(trie.StateTrieID(root), triedb)
 	if err != nil {
 		log.Error("Failed to open trie", "root", root, "err", err)
@@ -369,7 +411,7 @@ func traverseRawState(ctx *cli.Context) error {
 		root = headBlock.Root()
 		log.Info("Start traversing the state", "root", root, "number", headBlock.NumberU64())
 	}
-	triedb := trie.NewDatabase(chaindb)
+	triedb := utils.MakeTrieDatabase(ctx, chaindb, false)
 	t, err := trie.NewStateTrie(trie.StateTrieID(root), triedb)
 	if err != nil {
 		log.Error("Failed to open trie", "root", root, "err", err)
@@ -496,7 +538,7 @@ func dumpState(ctx *cli.Context) error {
 		NoBuild:    true,
 		AsyncBuild: false,
 	}
-	snaptree, err := snapshot.New(snapConfig, db, trie.NewDatabase(db), root)
+	snaptree, err := snapshot.New(snapConfig, db, utils.MakeTrieDatabase(ctx, db, false), root)
 	if err != nil {
 		return err
 	}
@@ -589,3 +631,161 @@ func checkAccount(ctx *cli.Context) error {
 	log.Info("Checked the snapshot journalled storage", "time", common.PrettyDuration(time.Since(start)))
 	return nil
 }
+
+// snapshotMeta is the metadata carried in the state snapshot export, which is
+// needed to set up the chain head on import.
+type snapshotMeta struct {
+	Genesis common.Hash
+	Block   *types.Block
+	TD      *big.Int
+}
+
+// exportSnapshot streams the flat state of the given block into a file.
+func exportSnapshot(ctx *cli.Context) error {
+	if ctx.NArg() < 1 || ctx.NArg() > 2 {
+		return errors.New("need <filename> [<blockHash> | <blockNum>] args")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chaindb := utils.MakeChainDatabase(ctx, stack, true)
+	defer chaindb.Close()
+
+	headBlock := rawdb.ReadHeadBlock(chaindb)
+	if headBlock == nil {
+		log.Error("Failed to load head block")
+		return errors.New("no head block")
+	}
+	block := headBlock
+	if ctx.NArg() == 2 {
+		var hash common.Hash
+		if arg := ctx.Args().Get(1); hashish(arg) {
+			hash = common.HexToHash(arg)
+		} else {
+			number, err := strconv.ParseUint(arg, 10, 64)
+			if err != nil {
+				return err
+			}
+			hash = rawdb.ReadCanonicalHash(chaindb, number)
+		}
+		number := rawdb.ReadHeaderNumber(chaindb, hash)
+		if number == nil {
+			return fmt.Errorf("block %s not found", ctx.Args().Get(1))
+		}
+		if block = rawdb.ReadBlock(chaindb, hash, *number); block == nil {
+			return fmt.Errorf("block %s not found", ctx.Args().Get(1))
+		}
+	}
+	td := rawdb.ReadTd(chaindb, block.Hash(), block.NumberU64())
+	if td == nil {
+		return fmt.Errorf("total difficulty of block %d not found", block.NumberU64())
+	}
+	meta, err := rlp.EncodeToBytes(&snapshotMeta{
+		Genesis: rawdb.ReadCanonicalHash(chaindb, 0),
+		Block:   block,
+		TD:      td,
+	})
+	if err != nil {
+		return err
+	}
+	snapconfig := snapshot.Config{
+		CacheSize:  256,
+		Recovery:   false,
+		NoBuild:    true,
+		AsyncBuild: false,
+	}
+	snaptree, err := snapshot.New(snapconfig, chaindb, utils.MakeTrieDatabase(ctx, chaindb, false), headBlock.Root())
+	if err != nil {
+		log.Error("Failed to open snapshot tree", "err", err)
+		return err
+	}
+	// The tree is opened at the head, only the head block and the recent blocks
+	// still covered by its diff layers can be exported
+	if snaptree.Snapshot(block.Root()) == nil {
+		return fmt.Errorf("state snapshot of block %d (root %x) not available, only the head block %d and recent blocks can be exported", block.NumberU64(), block.Root(), headBlock.NumberU64())
+	}
+	fn := ctx.Args().First()
+	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var writer io.Writer = fh
+	if strings.HasSuffix(fn, ".gz") {
+		writer = gzip.NewWriter(writer)
+		defer writer.(*gzip.Writer).Close()
+	}
+	log.Info("Exporting state snapshot", "file", fn, "number", block.NumberU64(), "hash", block.Hash(), "root", block.Root())
+	if err := snapshot.Export(writer, snaptree, block.Root(), chaindb, meta); err != nil {
+		log.Error("Failed to export state snapshot", "err", err)
+		return err
+	}
+	return nil
+}
+
+// importSnapshot loads the exported flat state into a freshly initialized
+// database and sets the exported block as the chain head.
+func importSnapshot(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("need <filename> arg")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chaindb := utils.MakeChainDatabase(ctx, stack, false)
+	defer chaindb.Close()
+
+	genesis := rawdb.ReadCanonicalHash(chaindb, 0)
+	if genesis == (common.Hash{}) {
+		return errors.New("database not initialized, run 'geth init' first")
+	}
+	if head := rawdb.ReadHeadHeader(chaindb); head == nil || head.Number.Uint64() != 0 {
+		return errors.New("database is not freshly initialized")
+	}
+	fn := ctx.Args().First()
+	fh, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var reader io.Reader = bufio.NewReader(fh)
+	if strings.HasSuffix(fn, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return err
+		}
+	}
+	var meta snapshotMeta
+	check := func(header *snapshot.ExportHeader) error {
+		if err := rlp.DecodeBytes(header.Meta, &meta); err != nil {
+			return fmt.Errorf("invalid export metadata: %v", err)
+		}
+		if meta.Genesis != genesis {
+			return fmt.Errorf("genesis mismatch: have %x, want %x", genesis, meta.Genesis)
+		}
+		if meta.Block.Root() != header.Root {
+			return fmt.Errorf("state root mismatch: block %x, export %x", meta.Block.Root(), header.Root)
+		}
+		return nil
+	}
+	log.Info("Importing state snapshot", "file", fn)
+	if _, err := snapshot.Import(reader, chaindb, rawdb.ReadStateScheme(chaindb), check); err != nil {
+		log.Error("Failed to import state snapshot", "err", err)
+		return err
+	}
+	// The state is verified, set the exported block as the chain head
+	block := meta.Block
+	batch := chaindb.NewBatch()
+	rawdb.WriteTd(batch, block.Hash(), block.NumberU64(), meta.TD)
+	rawdb.WriteBlock(batch, block)
+	rawdb.WriteCanonicalHash(batch, block.Hash(), block.NumberU64())
+	rawdb.WriteHeadHeaderHash(batch, block.Hash())
+	rawdb.WriteHeadFastBlockHash(batch, block.Hash())
+	rawdb.WriteHeadBlockHash(batch, block.Hash())
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Imported state snapshot", "number", block.NumberU64(), "hash", block.Hash(), "root", block.Root())
+	return nil
+}
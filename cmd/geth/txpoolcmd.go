@@ -0,0 +1,184 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	txpoolCommand = &cli.Command{
+		Name:        "txpool",
+		Usage:       "A set of commands to migrate the transaction pool of a running node",
+		Description: "",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "dump",
+				Usage:     "Dump the pending and queued transactions of a running node into a file",
+				ArgsUsage: "<file> [endpoint]",
+				Action:    dumpTxPool,
+				Flags:     []cli.Flag{utils.DataDirFlag, utils.HttpHeaderFlag},
+				Description: `
+geth txpool dump <file> [endpoint]
+connects to a running node (over IPC in the data directory, unless an endpoint
+is given) and writes all its pending and queued transactions into the file, as
+an RLP stream along with their arrival times and local flags. The dump is
+retrieved page by page, so transactions arriving meanwhile may be missing. The
+endpoint must expose the txpool API.`,
+			},
+			{
+				Name:      "load",
+				Usage:     "Load a transaction pool dump into a running node",
+				ArgsUsage: "<file> [endpoint]",
+				Action:    loadTxPool,
+				Flags:     []cli.Flag{utils.DataDirFlag, utils.HttpHeaderFlag},
+				Description: `
+geth txpool load <file> [endpoint]
+connects to a running node (over IPC in the data directory, unless an endpoint
+is given) and injects the transactions of a dump created by 'geth txpool dump'
+into its pool, retaining their arrival times and local flags. The dump is sent
+in pages, so a failure may leave it partly loaded. The endpoint must expose the
+txpool API.`,
+			},
+		},
+	}
+)
+
+// dialTxPool connects to the node whose transaction pool is to be migrated,
+// defaulting to the IPC endpoint in the data directory.
+func dialTxPool(ctx *cli.Context) *rpc.Client {
+	if ctx.Args().Len() < 1 || ctx.Args().Len() > 2 {
+		utils.Fatalf("This command requires a file and an optional endpoint as arguments.")
+	}
+	endpoint := ctx.Args().Get(1)
+	if endpoint == "" {
+		cfg := defaultNodeConfig()
+		utils.SetDataDir(ctx, &cfg)
+		endpoint = cfg.IPCEndpoint()
+	}
+	client, err := utils.DialRPCWithHeaders(endpoint, ctx.StringSlice(utils.HttpHeaderFlag.Name))
+	if err != nil {
+		utils.Fatalf("Unable to attach to remote geth: %v", err)
+	}
+	return client
+}
+
+// txPoolLoadPageSize is the maximum number of bytes of transactions sent to the
+// node in a single import call, keeping the requests within the RPC size limits.
+const txPoolLoadPageSize = 1024 * 1024
+
+func dumpTxPool(ctx *cli.Context) error {
+	client := dialTxPool(ctx)
+	defer client.Close()
+
+	file := ctx.Args().First()
+	out, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		utils.Fatalf("Failed to create transaction pool dump: %v", err)
+	}
+	defer out.Close()
+
+	var (
+		cursor *eth.TxPoolDumpCursor
+		size   int
+	)
+	for {
+		var page eth.TxPoolDump
+		if err := client.CallContext(context.Background(), &page, "txpool_export", cursor); err != nil {
+			utils.Fatalf("Failed to export transaction pool: %v", err)
+		}
+		if _, err := out.Write(page.Dump); err != nil {
+			utils.Fatalf("Failed to write transaction pool dump: %v", err)
+		}
+		size += len(page.Dump)
+
+		if cursor = page.Next; cursor == nil {
+			break
+		}
+	}
+	log.Info("Dumped transaction pool", "file", file, "size", common.StorageSize(size))
+	return nil
+}
+
+func loadTxPool(ctx *cli.Context) error {
+	client := dialTxPool(ctx)
+	defer client.Close()
+
+	file := ctx.Args().First()
+	in, err := os.Open(file)
+	if err != nil {
+		utils.Fatalf("Failed to read transaction pool dump: %v", err)
+	}
+	defer in.Close()
+
+	var (
+		stream   = rlp.NewStream(in, 0)
+		page     []byte
+		imported uint
+		dropped  uint
+	)
+	// Create a method to import the gathered page and bump the counters, then
+	// use it to load the dump in pages.
+	flush := func() {
+		if len(page) == 0 {
+			return
+		}
+		var result map[string]hexutil.Uint
+		if err := client.CallContext(context.Background(), &result, "txpool_import", hexutil.Bytes(page)); err != nil {
+			// Report the transactions imported before the failure, if known
+			if de, ok := err.(rpc.DataError); ok {
+				if data, err := json.Marshal(de.ErrorData()); err == nil {
+					json.Unmarshal(data, &result)
+				}
+			}
+			log.Warn("Partially loaded transaction pool", "file", file, "imported", imported+uint(result["imported"]), "dropped", dropped+uint(result["dropped"]))
+			utils.Fatalf("Failed to import transaction pool: %v", err)
+		}
+		imported += uint(result["imported"])
+		dropped += uint(result["dropped"])
+		page = page[:0]
+	}
+	for {
+		entry, err := stream.Raw()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			flush()
+			utils.Fatalf("Failed to parse transaction pool dump: %v", err)
+		}
+		if len(page) > 0 && len(page)+len(entry) > txPoolLoadPageSize {
+			flush()
+		}
+		page = append(page, entry...)
+	}
+	flush()
+
+	log.Info("Loaded transaction pool", "file", file, "imported", imported, "dropped", dropped)
+	return nil
+}
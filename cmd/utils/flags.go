@@ -22,6 +22,7 @@ import (
 	"context"
 	"crypto/ecdsa"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
@@ -46,6 +47,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/txpool/blobpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -63,6 +65,7 @@ import (
 	"github.com/ethereum/go-ethereum/graphql"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/internal/flags"
+	"github.com/ethereum/go-ethereum/internal/tracing"
 	"github.com/ethereum/go-ethereum/les"
 	lescatalyst "github.com/ethereum/go-ethereum/les/catalyst"
 	"github.com/ethereum/go-ethereum/log"
@@ -78,6 +81,8 @@ import (
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/trie/triedb/pathdb"
 )
 
 // These are all the command line flags we support.
@@ -111,6 +116,23 @@ var (
 		Usage:    "Root directory for ancient data (default = inside chaindata)",
 		Category: flags.EthCategory,
 	}
+	AncientColdFlag = &cli.StringFlag{
+		Name:     "datadir.ancient.cold",
+		Usage:    "Cold store to move sealed ancient segments into (directory, file:// or s3://bucket/prefix?endpoint=...&region=... URL)",
+		Category: flags.EthCategory,
+	}
+	AncientColdAfterFlag = &cli.Uint64Flag{
+		Name:     "datadir.ancient.cold.after",
+		Usage:    "Number of recent ancient blocks whose segments are kept locally",
+		Value:    node.DefaultConfig.AncientColdThreshold,
+		Category: flags.EthCategory,
+	}
+	AncientColdCacheFlag = &cli.IntFlag{
+		Name:     "datadir.ancient.cold.cache",
+		Usage:    "Number of cold ancient segments cached locally for reading",
+		Value:    node.DefaultConfig.AncientColdCache,
+		Category: flags.EthCategory,
+	}
 	MinFreeDiskSpaceFlag = &flags.DirectoryFlag{
 		Name:     "datadir.minfreedisk",
 		Usage:    "Minimum free disk space in MB, once reached triggers auto shut down (default = --cache.gc converted to MB, 0 = disabled)",
@@ -236,6 +258,46 @@ var (
 		Value:    "full",
 		Category: flags.EthCategory,
 	}
+	StateSchemeFlag = &cli.StringFlag{
+		Name:     "state.scheme",
+		Usage:    "Scheme to use for storing ethereum state ('hash' or 'path'), defaults to the scheme of the existing state",
+		Category: flags.StateCategory,
+	}
+	StateHistoryFlag = &cli.Uint64Flag{
+		Name:     "history.state",
+		Usage:    "Number of recent blocks to retain state history for, path-based scheme only (default = 90,000 blocks, 0 = entire chain)",
+		Value:    ethconfig.Defaults.StateHistory,
+		Category: flags.StateCategory,
+	}
+	StateDiffHistoryFlag = &cli.Uint64Flag{
+		Name:     "history.statediff",
+		Usage:    "Number of recent blocks whose historical states are reconstructable from reverse state diffs (0 = disabled)",
+		Value:    ethconfig.Defaults.StateDiffHistory,
+		Category: flags.StateCategory,
+	}
+	StateOnlinePruningFlag = &cli.BoolFlag{
+		Name:     "state.prune.online",
+		Usage:    "Delete stale state in the background while the node is running, hash-based scheme only",
+		Category: flags.StateCategory,
+	}
+	StateOnlinePruningRetentionFlag = &cli.Uint64Flag{
+		Name:     "state.prune.retention",
+		Usage:    "Number of recent blocks whose state is retained by the online pruner (minimum = 128)",
+		Value:    ethconfig.Defaults.OnlinePruningRetention,
+		Category: flags.StateCategory,
+	}
+	StateOnlinePruningBloomSizeFlag = &cli.Uint64Flag{
+		Name:     "state.prune.bloomsize",
+		Usage:    "Megabytes of memory allocated to the bloom filter of the online pruner",
+		Value:    ethconfig.Defaults.OnlinePruningBloomSize,
+		Category: flags.StateCategory,
+	}
+	StateOnlinePruningThrottleFlag = &cli.DurationFlag{
+		Name:     "state.prune.throttle",
+		Usage:    "Pause between two batches of trie nodes processed by the online pruner",
+		Value:    ethconfig.Defaults.OnlinePruningThrottle,
+		Category: flags.StateCategory,
+	}
 	SnapshotFlag = &cli.BoolFlag{
 		Name:     "snapshot",
 		Usage:    `Enables snapshot-database mode (default = enable)`,
@@ -248,6 +310,23 @@ var (
 		Value:    ethconfig.Defaults.TxLookupLimit,
 		Category: flags.EthCategory,
 	}
+	HistoryBlocksFlag = &cli.Uint64Flag{
+		Name:     "history.blocks",
+		Usage:    "Number of recent blocks to retain bodies and receipts for, older ones are pruned from the ancient store (0 = entire chain)",
+		Value:    ethconfig.Defaults.HistoryBlocks,
+		Category: flags.EthCategory,
+	}
+	LogIndexFlag = &cli.BoolFlag{
+		Name:     "logindex",
+		Usage:    "Maintain an index of log addresses and topics for fast log filtering over long ranges",
+		Category: flags.EthCategory,
+	}
+	LogIndexHistoryFlag = &cli.Uint64Flag{
+		Name:     "logindex.history",
+		Usage:    "Number of recent blocks to maintain the log index for (0 = entire chain)",
+		Value:    ethconfig.Defaults.LogIndexHistory,
+		Category: flags.EthCategory,
+	}
 	LightKDFFlag = &cli.BoolFlag{
 		Name:     "lightkdf",
 		Usage:    "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -411,6 +490,41 @@ var (
 		Value:    ethconfig.Defaults.TxPool.Lifetime,
 		Category: flags.TxPoolCategory,
 	}
+	TxPoolPrivateLifetimeFlag = &cli.DurationFlag{
+		Name:     "txpool.privatelifetime",
+		Usage:    "Maximum amount of time private transactions are kept in the pool without inclusion",
+		Value:    ethconfig.Defaults.TxPool.PrivateLifetime,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolPolicyFlag = &cli.StringFlag{
+		Name:     "txpool.policy",
+		Usage:    "JSON file of sender, recipient and method allow/deny lists for transaction admission (reloaded on change)",
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolPolicyRegistryFlag = &cli.StringFlag{
+		Name:     "txpool.policyregistry",
+		Usage:    "Address of an on-chain deny-list contract consulted for transaction admission",
+		Category: flags.TxPoolCategory,
+	}
+	// Blob transaction pool settings
+	BlobPoolDataDirFlag = &cli.StringFlag{
+		Name:     "blobpool.datadir",
+		Usage:    "Data directory to store blob transactions in",
+		Value:    ethconfig.Defaults.BlobPool.Datadir,
+		Category: flags.BlobPoolCategory,
+	}
+	BlobPoolDataCapFlag = &cli.Uint64Flag{
+		Name:     "blobpool.datacap",
+		Usage:    "Disk space to allocate for pending blob transactions (soft limit)",
+		Value:    ethconfig.Defaults.BlobPool.Datacap,
+		Category: flags.BlobPoolCategory,
+	}
+	BlobPoolPriceBumpFlag = &cli.Uint64Flag{
+		Name:     "blobpool.pricebump",
+		Usage:    "Price bump percentage to replace an already existing blob transaction",
+		Value:    ethconfig.Defaults.BlobPool.PriceBump,
+		Category: flags.BlobPoolCategory,
+	}
 
 	// Performance tuning settings
 	CacheFlag = &cli.IntFlag{
@@ -575,6 +689,33 @@ var (
 		Value:    ethconfig.Defaults.RPCTxFeeCap,
 		Category: flags.APICategory,
 	}
+	BatchRequestLimit = &cli.IntFlag{
+		Name:     "rpc.batch-request-limit",
+		Usage:    "Maximum number of requests in a batch (0 = unlimited)",
+		Value:    node.DefaultConfig.BatchRequestLimit,
+		Category: flags.APICategory,
+	}
+	BatchResponseMaxSize = &cli.IntFlag{
+		Name:     "rpc.batch-response-max-size",
+		Usage:    "Maximum number of bytes returned from a batched call (0 = unlimited)",
+		Value:    node.DefaultConfig.BatchResponseMaxSize,
+		Category: flags.APICategory,
+	}
+	RPCConnRateLimitFlag = &cli.Float64Flag{
+		Name:     "rpc.ratelimit.conn",
+		Usage:    "Call cost budget granted per second to each RPC connection (0 = unlimited)",
+		Category: flags.APICategory,
+	}
+	RPCIPRateLimitFlag = &cli.Float64Flag{
+		Name:     "rpc.ratelimit.ip",
+		Usage:    "Call cost budget granted per second to each RPC client IP address (0 = unlimited)",
+		Category: flags.APICategory,
+	}
+	RPCMethodCostsFlag = &cli.StringFlag{
+		Name:     "rpc.ratelimit.costs",
+		Usage:    "Comma separated method=cost pairs overriding the default call costs (e.g. eth_getLogs=50,debug_*=100)",
+		Category: flags.APICategory,
+	}
 	// Authenticated RPC HTTP settings
 	AuthListenFlag = &cli.StringFlag{
 		Name:     "authrpc.addr",
@@ -594,6 +735,27 @@ var (
 		Value:    strings.Join(node.DefaultConfig.AuthVirtualHosts, ","),
 		Category: flags.APICategory,
 	}
+	AuthApiFlag = &cli.StringFlag{
+		Name:     "authrpc.api",
+		Usage:    "API's offered over the authenticated RPC interface",
+		Value:    strings.Join(node.DefaultConfig.AuthModules, ","),
+		Category: flags.APICategory,
+	}
+	AuthMethodsAllowFlag = &cli.StringFlag{
+		Name:     "authrpc.methods.allow",
+		Usage:    "Comma separated list of methods callable over the authenticated RPC interface (e.g. 'engine_*,eth_chainId'), all if empty",
+		Category: flags.APICategory,
+	}
+	AuthMethodsDenyFlag = &cli.StringFlag{
+		Name:     "authrpc.methods.deny",
+		Usage:    "Comma separated list of methods not callable over the authenticated RPC interface",
+		Category: flags.APICategory,
+	}
+	AuthIdentitiesFlag = &cli.StringFlag{
+		Name:     "authrpc.identities",
+		Usage:    `Path to a JSON file restricting the authenticated RPC methods per JWT id claim (e.g. {"op-node": {"allow": ["engine_*"]}}), "*" applying to all other tokens`,
+		Category: flags.APICategory,
+	}
 	JWTSecretFlag = &flags.DirectoryFlag{
 		Name:     "authrpc.jwtsecret",
 		Usage:    "Path to a JWT secret to use for authenticated RPC endpoints",
@@ -666,6 +828,16 @@ var (
 		Value:    "",
 		Category: flags.APICategory,
 	}
+	HTTPMethodsAllowFlag = &cli.StringFlag{
+		Name:     "http.methods.allow",
+		Usage:    "Comma separated list of methods callable over the HTTP-RPC interface (e.g. 'eth_*,debug_trace*'), all if empty",
+		Category: flags.APICategory,
+	}
+	HTTPMethodsDenyFlag = &cli.StringFlag{
+		Name:     "http.methods.deny",
+		Usage:    "Comma separated list of methods not callable over the HTTP-RPC interface",
+		Category: flags.APICategory,
+	}
 	HTTPPathPrefixFlag = &cli.StringFlag{
 		Name:     "http.rpcprefix",
 		Usage:    "HTTP path path prefix on which JSON-RPC is served. Use '/' to serve on all paths.",
@@ -712,6 +884,16 @@ var (
 		Value:    "",
 		Category: flags.APICategory,
 	}
+	WSMethodsAllowFlag = &cli.StringFlag{
+		Name:     "ws.methods.allow",
+		Usage:    "Comma separated list of methods callable over the WS-RPC interface, all if empty",
+		Category: flags.APICategory,
+	}
+	WSMethodsDenyFlag = &cli.StringFlag{
+		Name:     "ws.methods.deny",
+		Usage:    "Comma separated list of methods not callable over the WS-RPC interface",
+		Category: flags.APICategory,
+	}
 	WSAllowedOriginsFlag = &cli.StringFlag{
 		Name:     "ws.origins",
 		Usage:    "Origins from which to accept websockets requests",
@@ -724,6 +906,39 @@ var (
 		Value:    "",
 		Category: flags.APICategory,
 	}
+	BinaryEnabledFlag = &cli.BoolFlag{
+		Name:     "binrpc",
+		Usage:    "Enable the binary RPC server",
+		Category: flags.APICategory,
+	}
+	BinaryListenAddrFlag = &cli.StringFlag{
+		Name:     "binrpc.addr",
+		Usage:    "Binary RPC server listening interface",
+		Value:    node.DefaultBinaryHost,
+		Category: flags.APICategory,
+	}
+	BinaryPortFlag = &cli.IntFlag{
+		Name:     "binrpc.port",
+		Usage:    "Binary RPC server listening port",
+		Value:    node.DefaultBinaryPort,
+		Category: flags.APICategory,
+	}
+	BinaryApiFlag = &cli.StringFlag{
+		Name:     "binrpc.api",
+		Usage:    "API's offered over the binary RPC interface",
+		Value:    "",
+		Category: flags.APICategory,
+	}
+	BinaryMethodsAllowFlag = &cli.StringFlag{
+		Name:     "binrpc.methods.allow",
+		Usage:    "Comma separated list of methods callable over the binary RPC interface, all if empty",
+		Category: flags.APICategory,
+	}
+	BinaryMethodsDenyFlag = &cli.StringFlag{
+		Name:     "binrpc.methods.deny",
+		Usage:    "Comma separated list of methods not callable over the binary RPC interface",
+		Category: flags.APICategory,
+	}
 	ExecFlag = &cli.StringFlag{
 		Name:     "exec",
 		Usage:    "Execute JavaScript statement",
@@ -978,6 +1193,30 @@ Please note that --` + MetricsHTTPFlag.Name + ` must be set to start the server.
 		Value:    metrics.DefaultConfig.InfluxDBOrganization,
 		Category: flags.MetricsCategory,
 	}
+
+	// Request tracing settings
+	TracingEnabledFlag = &cli.BoolFlag{
+		Name:     "tracing",
+		Usage:    "Enable span based tracing of RPC requests",
+		Category: flags.MetricsCategory,
+	}
+	TracingEndpointFlag = &cli.StringFlag{
+		Name:     "tracing.endpoint",
+		Usage:    "OTLP/HTTP endpoint of the trace collector",
+		Value:    tracing.DefaultConfig.Endpoint,
+		Category: flags.MetricsCategory,
+	}
+	TracingFileFlag = &cli.StringFlag{
+		Name:     "tracing.file",
+		Usage:    "Write traces to the given file instead of sending them to a collector",
+		Category: flags.MetricsCategory,
+	}
+	TracingSampleRatioFlag = &cli.Float64Flag{
+		Name:     "tracing.sample",
+		Usage:    "Fraction of the requests to trace, unless the caller decided sampling",
+		Value:    tracing.DefaultConfig.SampleRatio,
+		Category: flags.MetricsCategory,
+	}
 )
 
 var (
@@ -994,6 +1233,9 @@ var (
 	DatabasePathFlags = []cli.Flag{
 		DataDirFlag,
 		AncientFlag,
+		AncientColdFlag,
+		AncientColdAfterFlag,
+		AncientColdCacheFlag,
 		RemoteDBFlag,
 		HttpHeaderFlag,
 	}
@@ -1175,6 +1417,19 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 		cfg.AuthVirtualHosts = SplitAndTrim(ctx.String(AuthVirtualHostsFlag.Name))
 	}
 
+	if ctx.IsSet(AuthApiFlag.Name) {
+		cfg.AuthModules = SplitAndTrim(ctx.String(AuthApiFlag.Name))
+	}
+	setMethodPolicy(ctx, &cfg.AuthMethods, AuthMethodsAllowFlag, AuthMethodsDenyFlag)
+
+	if ctx.IsSet(AuthIdentitiesFlag.Name) {
+		identities, err := readAuthIdentities(ctx.String(AuthIdentitiesFlag.Name))
+		if err != nil {
+			Fatalf("Invalid --%s: %v", AuthIdentitiesFlag.Name, err)
+		}
+		cfg.AuthIdentities = identities
+	}
+
 	if ctx.IsSet(HTTPCORSDomainFlag.Name) {
 		cfg.HTTPCors = SplitAndTrim(ctx.String(HTTPCORSDomainFlag.Name))
 	}
@@ -1182,6 +1437,7 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.IsSet(HTTPApiFlag.Name) {
 		cfg.HTTPModules = SplitAndTrim(ctx.String(HTTPApiFlag.Name))
 	}
+	setMethodPolicy(ctx, &cfg.HTTPMethods, HTTPMethodsAllowFlag, HTTPMethodsDenyFlag)
 
 	if ctx.IsSet(HTTPVirtualHostsFlag.Name) {
 		cfg.HTTPVirtualHosts = SplitAndTrim(ctx.String(HTTPVirtualHostsFlag.Name))
@@ -1195,6 +1451,78 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// setMethodPolicy applies the method allow and deny lists of an RPC endpoint from
+// the command line flags.
+func setMethodPolicy(ctx *cli.Context, policy *rpc.MethodPolicy, allow, deny *cli.StringFlag) {
+	if ctx.IsSet(allow.Name) {
+		policy.Allow = SplitAndTrim(ctx.String(allow.Name))
+	}
+	if ctx.IsSet(deny.Name) {
+		policy.Deny = SplitAndTrim(ctx.String(deny.Name))
+	}
+	if err := policy.Validate(); err != nil {
+		Fatalf("Invalid --%s or --%s: %v", allow.Name, deny.Name, err)
+	}
+}
+
+// readAuthIdentities loads the per-identity method policies of the authenticated
+// RPC interface from the given JSON file, mapping JWT id claims to the methods
+// allowed and denied for them.
+func readAuthIdentities(file string) (map[string]rpc.MethodPolicy, error) {
+	blob, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var identities map[string]struct {
+		Allow []string `json:"allow"`
+		Deny  []string `json:"deny"`
+	}
+	if err := json.Unmarshal(blob, &identities); err != nil {
+		return nil, err
+	}
+	policies := make(map[string]rpc.MethodPolicy, len(identities))
+	for id, identity := range identities {
+		policy := rpc.MethodPolicy{Allow: identity.Allow, Deny: identity.Deny}
+		if err := policy.Validate(); err != nil {
+			return nil, fmt.Errorf("identity %q: %v", id, err)
+		}
+		policies[id] = policy
+	}
+	return policies, nil
+}
+
+// setRPCLimits applies the RPC request limits from the command line flags.
+func setRPCLimits(ctx *cli.Context, cfg *node.Config) {
+	if ctx.IsSet(BatchRequestLimit.Name) {
+		cfg.BatchRequestLimit = ctx.Int(BatchRequestLimit.Name)
+	}
+	if ctx.IsSet(BatchResponseMaxSize.Name) {
+		cfg.BatchResponseMaxSize = ctx.Int(BatchResponseMaxSize.Name)
+	}
+	if ctx.IsSet(RPCConnRateLimitFlag.Name) {
+		cfg.RPCCostLimit.ConnRate = ctx.Float64(RPCConnRateLimitFlag.Name)
+	}
+	if ctx.IsSet(RPCIPRateLimitFlag.Name) {
+		cfg.RPCCostLimit.IPRate = ctx.Float64(RPCIPRateLimitFlag.Name)
+	}
+	if ctx.IsSet(RPCMethodCostsFlag.Name) {
+		if cfg.RPCCostLimit.Costs == nil {
+			cfg.RPCCostLimit.Costs = make(map[string]float64)
+		}
+		for _, entry := range SplitAndTrim(ctx.String(RPCMethodCostsFlag.Name)) {
+			method, value, ok := strings.Cut(entry, "=")
+			if !ok {
+				Fatalf("Invalid --%s entry %q, want method=cost", RPCMethodCostsFlag.Name, entry)
+			}
+			cost, err := strconv.ParseFloat(value, 64)
+			if err != nil || cost < 0 {
+				Fatalf("Invalid --%s cost for %s: %q", RPCMethodCostsFlag.Name, method, value)
+			}
+			cfg.RPCCostLimit.Costs[method] = cost
+		}
+	}
+}
+
 // setGraphQL creates the GraphQL listener interface string from the set
 // command line flags, returning empty if the GraphQL endpoint is disabled.
 func setGraphQL(ctx *cli.Context, cfg *node.Config) {
@@ -1226,12 +1554,31 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	if ctx.IsSet(WSApiFlag.Name) {
 		cfg.WSModules = SplitAndTrim(ctx.String(WSApiFlag.Name))
 	}
+	setMethodPolicy(ctx, &cfg.WSMethods, WSMethodsAllowFlag, WSMethodsDenyFlag)
 
 	if ctx.IsSet(WSPathPrefixFlag.Name) {
 		cfg.WSPathPrefix = ctx.String(WSPathPrefixFlag.Name)
 	}
 }
 
+// setBinary creates the binary RPC listener interface string from the set
+// command line flags, returning empty if the binary endpoint is disabled.
+func setBinary(ctx *cli.Context, cfg *node.Config) {
+	if ctx.Bool(BinaryEnabledFlag.Name) && cfg.BinaryHost == "" {
+		cfg.BinaryHost = "127.0.0.1"
+		if ctx.IsSet(BinaryListenAddrFlag.Name) {
+			cfg.BinaryHost = ctx.String(BinaryListenAddrFlag.Name)
+		}
+	}
+	if ctx.IsSet(BinaryPortFlag.Name) {
+		cfg.BinaryPort = ctx.Int(BinaryPortFlag.Name)
+	}
+	if ctx.IsSet(BinaryApiFlag.Name) {
+		cfg.BinaryModules = SplitAndTrim(ctx.String(BinaryApiFlag.Name))
+	}
+	setMethodPolicy(ctx, &cfg.BinaryMethods, BinaryMethodsAllowFlag, BinaryMethodsDenyFlag)
+}
+
 // setIPC creates an IPC path configuration from the set command line flags,
 // returning an empty string if IPC was explicitly disabled, or the set path.
 func setIPC(ctx *cli.Context, cfg *node.Config) {
@@ -1446,6 +1793,8 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setHTTP(ctx, cfg)
 	setGraphQL(ctx, cfg)
 	setWS(ctx, cfg)
+	setBinary(ctx, cfg)
+	setRPCLimits(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 	SetDataDir(ctx, cfg)
 	setSmartCard(ctx, cfg)
@@ -1488,6 +1837,15 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 		log.Info(fmt.Sprintf("Using %s as db engine", dbEngine))
 		cfg.DBEngine = dbEngine
 	}
+	if ctx.IsSet(AncientColdFlag.Name) {
+		cfg.AncientColdStore = ctx.String(AncientColdFlag.Name)
+	}
+	if ctx.IsSet(AncientColdAfterFlag.Name) {
+		cfg.AncientColdThreshold = ctx.Uint64(AncientColdAfterFlag.Name)
+	}
+	if ctx.IsSet(AncientColdCacheFlag.Name) {
+		cfg.AncientColdCache = ctx.Int(AncientColdCacheFlag.Name)
+	}
 }
 
 func setSmartCard(ctx *cli.Context, cfg *node.Config) {
@@ -1589,6 +1947,31 @@ func setTxPool(ctx *cli.Context, cfg *txpool.Config) {
 	if ctx.IsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.Duration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.IsSet(TxPoolPrivateLifetimeFlag.Name) {
+		cfg.PrivateLifetime = ctx.Duration(TxPoolPrivateLifetimeFlag.Name)
+	}
+	if ctx.IsSet(TxPoolPolicyFlag.Name) {
+		cfg.Policy = ctx.String(TxPoolPolicyFlag.Name)
+	}
+	if ctx.IsSet(TxPoolPolicyRegistryFlag.Name) {
+		registry := ctx.String(TxPoolPolicyRegistryFlag.Name)
+		if !common.IsHexAddress(registry) {
+			Fatalf("Option %q: invalid address %q", TxPoolPolicyRegistryFlag.Name, registry)
+		}
+		cfg.PolicyRegistry = common.HexToAddress(registry)
+	}
+}
+
+func setBlobPool(ctx *cli.Context, cfg *blobpool.Config) {
+	if ctx.IsSet(BlobPoolDataDirFlag.Name) {
+		cfg.Datadir = ctx.String(BlobPoolDataDirFlag.Name)
+	}
+	if ctx.IsSet(BlobPoolDataCapFlag.Name) {
+		cfg.Datacap = ctx.Uint64(BlobPoolDataCapFlag.Name)
+	}
+	if ctx.IsSet(BlobPoolPriceBumpFlag.Name) {
+		cfg.PriceBump = ctx.Uint64(BlobPoolPriceBumpFlag.Name)
+	}
 }
 
 func setMiner(ctx *cli.Context, cfg *miner.Config) {
@@ -1697,6 +2080,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	setEtherbase(ctx, cfg)
 	setGPO(ctx, &cfg.GPO, ctx.String(SyncModeFlag.Name) == "light")
 	setTxPool(ctx, &cfg.TxPool)
+	setBlobPool(ctx, &cfg.BlobPool)
 	setMiner(ctx, &cfg.Miner)
 	setRequiredBlocks(ctx, cfg)
 	setLes(ctx, cfg)
@@ -1744,15 +2128,58 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(CacheNoPrefetchFlag.Name) {
 		cfg.NoPrefetch = ctx.Bool(CacheNoPrefetchFlag.Name)
 	}
+	cfg.StateScheme = stateSchemeFlag(ctx)
+	if cfg.NoPruning && cfg.StateScheme == rawdb.PathScheme {
+		Fatalf("--%s=archive is not supported by the path-based state scheme", GCModeFlag.Name)
+	}
+	if ctx.IsSet(StateHistoryFlag.Name) {
+		cfg.StateHistory = ctx.Uint64(StateHistoryFlag.Name)
+	}
+	if ctx.IsSet(StateDiffHistoryFlag.Name) {
+		cfg.StateDiffHistory = ctx.Uint64(StateDiffHistoryFlag.Name)
+	}
+	if ctx.IsSet(StateOnlinePruningFlag.Name) {
+		cfg.OnlinePruning = ctx.Bool(StateOnlinePruningFlag.Name)
+	}
+	if cfg.OnlinePruning {
+		if cfg.NoPruning {
+			Fatalf("--%s is not supported with --%s=archive", StateOnlinePruningFlag.Name, GCModeFlag.Name)
+		}
+		if cfg.StateScheme == rawdb.PathScheme {
+			Fatalf("--%s is not supported by the path-based state scheme", StateOnlinePruningFlag.Name)
+		}
+	}
+	if ctx.IsSet(StateOnlinePruningRetentionFlag.Name) {
+		cfg.OnlinePruningRetention = ctx.Uint64(StateOnlinePruningRetentionFlag.Name)
+	}
+	if ctx.IsSet(StateOnlinePruningBloomSizeFlag.Name) {
+		cfg.OnlinePruningBloomSize = ctx.Uint64(StateOnlinePruningBloomSizeFlag.Name)
+	}
+	if ctx.IsSet(StateOnlinePruningThrottleFlag.Name) {
+		cfg.OnlinePruningThrottle = ctx.Duration(StateOnlinePruningThrottleFlag.Name)
+	}
 	// Read the value from the flag no matter if it's set or not.
 	cfg.Preimages = ctx.Bool(CachePreimagesFlag.Name)
 	if cfg.NoPruning && !cfg.Preimages {
 		cfg.Preimages = true
 		log.Info("Enabling recording of key preimages since archive mode is used")
 	}
+	if cfg.StateDiffHistory > 0 && !cfg.Preimages {
+		cfg.Preimages = true
+		log.Info("Enabling recording of key preimages since state diff history is used")
+	}
 	if ctx.IsSet(TxLookupLimitFlag.Name) {
 		cfg.TxLookupLimit = ctx.Uint64(TxLookupLimitFlag.Name)
 	}
+	if ctx.IsSet(HistoryBlocksFlag.Name) {
+		cfg.HistoryBlocks = ctx.Uint64(HistoryBlocksFlag.Name)
+	}
+	if ctx.IsSet(LogIndexFlag.Name) {
+		cfg.LogIndex = ctx.Bool(LogIndexFlag.Name)
+	}
+	if ctx.IsSet(LogIndexHistoryFlag.Name) {
+		cfg.LogIndexHistory = ctx.Uint64(LogIndexHistoryFlag.Name)
+	}
 	if ctx.IsSet(CacheFlag.Name) || ctx.IsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.Int(CacheFlag.Name) * ctx.Int(CacheTrieFlag.Name) / 100
 	}
@@ -2097,6 +2524,26 @@ func SetupMetrics(ctx *cli.Context) {
 	}
 }
 
+// SetupTracing starts exporting request traces if enabled.
+func SetupTracing(ctx *cli.Context) {
+	if !ctx.Bool(TracingEnabledFlag.Name) {
+		return
+	}
+	config := tracing.DefaultConfig
+	config.Endpoint = ctx.String(TracingEndpointFlag.Name)
+	config.File = ctx.String(TracingFileFlag.Name)
+	config.SampleRatio = ctx.Float64(TracingSampleRatioFlag.Name)
+
+	if err := tracing.Start(config); err != nil {
+		Fatalf("Failed to start request tracing: %v", err)
+	}
+	if config.File != "" {
+		log.Info("Enabling request tracing", "file", config.File, "sample", config.SampleRatio)
+	} else {
+		log.Info("Enabling request tracing", "endpoint", config.Endpoint, "sample", config.SampleRatio)
+	}
+}
+
 func SplitTagsFlag(tagsFlag string) map[string]string {
 	tags := strings.Split(tagsFlag, ",")
 	tagsMap := map[string]string{}
@@ -2218,6 +2665,8 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockCh
 		TrieTimeLimit:       ethconfig.Defaults.TrieTimeout,
 		SnapshotLimit:       ethconfig.Defaults.SnapshotCache,
 		Preimages:           ctx.Bool(CachePreimagesFlag.Name),
+		StateScheme:         ParseStateScheme(ctx, chainDb),
+		StateHistory:        ctx.Uint64(StateHistoryFlag.Name),
 	}
 	if cache.TrieDirtyDisabled && !cache.Preimages {
 		cache.Preimages = true
@@ -2247,6 +2696,46 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockCh
 	return chain, chainDb
 }
 
+// stateSchemeFlag maps the value of the state scheme flag to the identifier of
+// the scheme used by the database, or returns empty if it's not set.
+func stateSchemeFlag(ctx *cli.Context) string {
+	if !ctx.IsSet(StateSchemeFlag.Name) {
+		return ""
+	}
+	switch scheme := ctx.String(StateSchemeFlag.Name); scheme {
+	case "hash":
+		return rawdb.HashScheme
+	case "path":
+		return rawdb.PathScheme
+	default:
+		Fatalf("--%s must be either 'hash' or 'path'", StateSchemeFlag.Name)
+	}
+	return ""
+}
+
+// ParseStateScheme resolves the scheme to use for the state in the database,
+// checking the one requested on the command line against the stored state.
+func ParseStateScheme(ctx *cli.Context, disk ethdb.Database) string {
+	scheme, err := rawdb.ParseStateScheme(stateSchemeFlag(ctx), disk)
+	if err != nil {
+		Fatalf("%v", err)
+	}
+	return scheme
+}
+
+// MakeTrieDatabase constructs a trie database based on the configured scheme.
+func MakeTrieDatabase(ctx *cli.Context, disk ethdb.Database, preimage bool) *trie.Database {
+	config := &trie.Config{
+		Preimages: preimage,
+	}
+	if ParseStateScheme(ctx, disk) == rawdb.PathScheme {
+		config.PathDB = &pathdb.Config{
+			StateHistory: ctx.Uint64(StateHistoryFlag.Name),
+		}
+	}
+	return trie.NewDatabaseWithConfig(disk, config)
+}
+
 // MakeConsolePreloads retrieves the absolute paths for the console JavaScript
 // scripts to preload before starting.
 func MakeConsolePreloads(ctx *cli.Context) []string {
@@ -18,8 +18,12 @@
 package utils
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 func Test_SplitTagsFlag(t *testing.T) {
@@ -62,3 +66,49 @@ func Test_SplitTagsFlag(t *testing.T) {
 		})
 	}
 }
+
+func TestReadAuthIdentities(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want map[string]rpc.MethodPolicy
+		fail bool
+	}{
+		{
+			"policies",
+			`{"op-node": {"allow": ["engine_*", "eth_chainId"]}, "*": {"deny": ["admin_*"]}}`,
+			map[string]rpc.MethodPolicy{
+				"op-node": {Allow: []string{"engine_*", "eth_chainId"}},
+				"*":       {Deny: []string{"admin_*"}},
+			},
+			false,
+		},
+		{
+			"invalid pattern",
+			`{"op-node": {"allow": ["engine_["]}}`,
+			nil,
+			true,
+		},
+		{
+			"garbage",
+			`["op-node"]`,
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "identities.json")
+			if err := os.WriteFile(file, []byte(tt.file), 0600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readAuthIdentities(file)
+			if (err != nil) != tt.fail {
+				t.Fatalf("readAuthIdentities() error = %v, want failure %v", err, tt.fail)
+			}
+			if !tt.fail && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readAuthIdentities() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
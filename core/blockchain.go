@@ -36,6 +36,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
@@ -48,6 +49,7 @@ import (
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/trie/triedb/pathdb"
 )
 
 var (
@@ -90,13 +92,15 @@ var (
 )
 
 const (
-	bodyCacheLimit      = 256
-	blockCacheLimit     = 256
-	receiptsCacheLimit  = 32
-	txLookupCacheLimit  = 1024
-	maxFutureBlocks     = 256
-	maxTimeFutureBlocks = 30
-	TriesInMemory       = 128
+	bodyCacheLimit       = 256
+	blockCacheLimit      = 256
+	receiptsCacheLimit   = 32
+	txLookupCacheLimit   = 1024
+	stateDiffCacheLimit  = 128
+	historicalStateLimit = 16
+	maxFutureBlocks      = 256
+	maxTimeFutureBlocks  = 30
+	TriesInMemory        = 128
 
 	// BlockChainVersion ensures that an incompatible database forces a resync from scratch.
 	//
@@ -136,11 +140,32 @@ type CacheConfig struct {
 	TrieTimeLimit       time.Duration // Time limit after which to flush the current in-memory trie to disk
 	SnapshotLimit       int           // Memory allowance (MB) to use for caching snapshot entries in memory
 	Preimages           bool          // Whether to store preimage of trie key to the disk
+	StateHistory        uint64        // Number of blocks from head whose state histories are reserved.
+	StateScheme         string        // Scheme used to store ethereum states and merkle tree nodes on top
+	StateDiffHistory    uint64        // Number of recent blocks whose reverse state diffs are retained, 0 disables it
+	HistoryBlocks       uint64        // Number of recent blocks whose bodies and receipts are retained, 0 keeps all
+
+	OnlinePruning *pruner.OnlineConfig // Configurations of the background state pruning, nil disables it
 
 	SnapshotNoBuild bool // Whether the background generation is allowed
 	SnapshotWait    bool // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
 }
 
+// triedbConfig derives the configures for trie database.
+func (c *CacheConfig) triedbConfig() *trie.Config {
+	config := &trie.Config{
+		Cache:     c.TrieCleanLimit,
+		Journal:   c.TrieCleanJournal,
+		Preimages: c.Preimages,
+	}
+	if c.StateScheme == rawdb.PathScheme {
+		config.PathDB = &pathdb.Config{
+			StateHistory: c.StateHistory,
+		}
+	}
+	return config
+}
+
 // defaultCacheConfig are the default caching values if none are specified by the
 // user (also used during testing).
 var defaultCacheConfig = &CacheConfig{
@@ -176,6 +201,7 @@ type BlockChain struct {
 	lastWrite     uint64                           // Last block when the state was flushed
 	flushInterval atomic.Int64                     // Time interval (processing time) after which to flush a state
 	triedb        *trie.Database                   // The database handler for maintaining trie nodes.
+	pruner        *pruner.OnlinePruner             // Background pruner of stale trie nodes, nil if disabled
 	stateCache    state.Database                   // State database to reuse between imports (contains state cache)
 
 	// txLookupLimit is the maximum number of blocks from head whose tx indices
@@ -192,7 +218,9 @@ type BlockChain struct {
 	chainHeadFeed event.Feed
 	logsFeed      event.Feed
 	blockProcFeed event.Feed
+	stateDiffFeed event.Feed
 	scope         event.SubscriptionScope
+	diffScope     event.SubscriptionScope // State diff subscriptions, the state changes are only tracked if any
 	genesisBlock  *types.Block
 
 	// This mutex synchronizes chain write operations.
@@ -210,6 +238,12 @@ type BlockChain struct {
 	blockCache    *lru.Cache[common.Hash, *types.Block]
 	txLookupCache *lru.Cache[common.Hash, *rawdb.LegacyTxLookupEntry]
 
+	// stateDiffCache holds the state changes of the recently processed blocks
+	stateDiffCache *lru.Cache[common.Hash, state.StateDiff]
+
+	// historicalStates holds the recently reconstructed historical states
+	historicalStates *lru.Cache[common.Hash, *historicalState]
+
 	// future blocks are blocks added for later processing
 	futureBlocks *lru.Cache[common.Hash, *types.Block]
 
@@ -233,12 +267,13 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 	if cacheConfig == nil {
 		cacheConfig = defaultCacheConfig
 	}
+	// The path-based scheme only retains a single version of the state on
+	// disk, it can't be used to run an archive node.
+	if cacheConfig.StateScheme == rawdb.PathScheme && cacheConfig.TrieDirtyDisabled {
+		return nil, errors.New("archive mode is not supported by the path-based state scheme")
+	}
 	// Open trie database with provided config
-	triedb := trie.NewDatabaseWithConfig(db, &trie.Config{
-		Cache:     cacheConfig.TrieCleanLimit,
-		Journal:   cacheConfig.TrieCleanJournal,
-		Preimages: cacheConfig.Preimages,
-	})
+	triedb := trie.NewDatabaseWithConfig(db, cacheConfig.triedbConfig())
 	// Setup the genesis block, commit the provided genesis specification
 	// to database if the genesis block is not present yet, or load the
 	// stored one from database.
@@ -272,8 +307,11 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 		blockCache:    lru.NewCache[common.Hash, *types.Block](blockCacheLimit),
 		txLookupCache: lru.NewCache[common.Hash, *rawdb.LegacyTxLookupEntry](txLookupCacheLimit),
 		futureBlocks:  lru.NewCache[common.Hash, *types.Block](maxFutureBlocks),
-		engine:        engine,
-		vmConfig:      vmConfig,
+
+		stateDiffCache:   lru.NewCache[common.Hash, state.StateDiff](stateDiffCacheLimit),
+		historicalStates: lru.NewCache[common.Hash, *historicalState](historicalStateLimit),
+		engine:           engine,
+		vmConfig:         vmConfig,
 	}
 	bc.flushInterval.Store(int64(cacheConfig.TrieTimeLimit))
 	bc.forker = NewForkChoice(bc, shouldPreserve)
@@ -287,6 +325,14 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 	if err != nil {
 		return nil, err
 	}
+	if cacheConfig.OnlinePruning != nil {
+		if cacheConfig.TrieDirtyDisabled {
+			return nil, errors.New("online pruning is not supported in archive mode")
+		}
+		if bc.pruner, err = pruner.NewOnlinePruner(bc.db, bc.triedb, *cacheConfig.OnlinePruning); err != nil {
+			return nil, err
+		}
+	}
 	bc.genesisBlock = bc.GetBlockByNumber(0)
 	if bc.genesisBlock == nil {
 		return nil, ErrNoGenesis
@@ -620,6 +666,11 @@ func (bc *BlockChain) setHeadBeyondRoot(head uint64, time uint64, root common.Ha
 	}
 	defer bc.chainmu.Unlock()
 
+	// Rewinding might make states out of the pruning retention live again,
+	// interrupt the background pruning before they get deleted.
+	if bc.pruner != nil {
+		bc.pruner.Abort()
+	}
 	// Track the block number of the requested root hash
 	var rootNumber uint64 // (no root == always 0)
 
@@ -648,7 +699,7 @@ func (bc *BlockChain) setHeadBeyondRoot(head uint64, time uint64, root common.Ha
 					if root != (common.Hash{}) && !beyondRoot && newHeadBlock.Root() == root {
 						beyondRoot, rootNumber = true, newHeadBlock.NumberU64()
 					}
-					if !bc.HasState(newHeadBlock.Root()) {
+					if !bc.HasState(newHeadBlock.Root()) && !bc.stateRecoverable(newHeadBlock.Root()) {
 						log.Trace("Block state missing, rewinding further", "number", newHeadBlock.NumberU64(), "hash", newHeadBlock.Hash())
 						if pivot == nil || newHeadBlock.NumberU64() > *pivot {
 							parent := bc.GetBlock(newHeadBlock.ParentHash(), newHeadBlock.NumberU64()-1)
@@ -677,6 +728,14 @@ func (bc *BlockChain) setHeadBeyondRoot(head uint64, time uint64, root common.Ha
 								log.Debug("Recommitted genesis state to disk")
 							}
 						}
+						// If the state is gone from the layers but can be restored
+						// from the state histories, roll the database back to it.
+						if !bc.HasState(newHeadBlock.Root()) && bc.stateRecoverable(newHeadBlock.Root()) {
+							if err := bc.triedb.Recover(newHeadBlock.Root()); err != nil {
+								log.Crit("Failed to rollback state", "err", err)
+							}
+							log.Debug("Rewound to block with recovered state", "number", newHeadBlock.NumberU64(), "hash", newHeadBlock.Hash())
+						}
 						log.Debug("Rewound to block with state", "number", newHeadBlock.NumberU64(), "hash", newHeadBlock.Hash())
 						break
 					}
@@ -741,6 +800,7 @@ func (bc *BlockChain) setHeadBeyondRoot(head uint64, time uint64, root common.Ha
 			rawdb.DeleteBody(db, hash, num)
 			rawdb.DeleteReceipts(db, hash, num)
 		}
+		rawdb.DeleteStateDiff(db, num, hash)
 		// Todo(rjl493456442) txlookup, bloombits, etc
 	}
 	// If SetHead was only called as a chain reparation method, try to skip
@@ -766,6 +826,7 @@ func (bc *BlockChain) setHeadBeyondRoot(head uint64, time uint64, root common.Ha
 	bc.receiptsCache.Purge()
 	bc.blockCache.Purge()
 	bc.txLookupCache.Purge()
+	bc.stateDiffCache.Purge()
 	bc.futureBlocks.Purge()
 
 	// Clear safe block, finalized block if needed
@@ -789,6 +850,14 @@ func (bc *BlockChain) SnapSyncCommitHead(hash common.Hash) error {
 		return fmt.Errorf("non existent block [%x..]", hash[:4])
 	}
 	root := block.Root()
+	if bc.pruner != nil {
+		bc.pruner.Abort()
+	}
+	if bc.triedb.Scheme() == rawdb.PathScheme {
+		if err := bc.triedb.Enable(root); err != nil {
+			return err
+		}
+	}
 	if !bc.HasState(root) {
 		return fmt.Errorf("non existent state [%x..]", root[:4])
 	}
@@ -925,6 +994,7 @@ func (bc *BlockChain) stopWithoutSaving() {
 
 	// Unsubscribe all subscriptions registered from blockchain.
 	bc.scope.Close()
+	bc.diffScope.Close()
 
 	// Signal shutdown to all goroutines.
 	close(bc.quit)
@@ -945,6 +1015,11 @@ func (bc *BlockChain) stopWithoutSaving() {
 func (bc *BlockChain) Stop() {
 	bc.stopWithoutSaving()
 
+	// Interrupt the background pruning, it's restarted with the next run.
+	if bc.pruner != nil {
+		bc.pruner.Stop()
+	}
+
 	// Ensure that the entirety of the state snapshot is journalled to disk.
 	var snapBase common.Hash
 	if bc.snaps != nil {
@@ -954,12 +1029,20 @@ func (bc *BlockChain) Stop() {
 		}
 	}
 
-	// Ensure the state of a recent block is also stored to disk before exiting.
-	// We're writing three different states to catch different restart scenarios:
-	//  - HEAD:     So we don't need to reprocess any blocks in the general case
-	//  - HEAD-1:   So we don't do large reorgs if our HEAD becomes an uncle
-	//  - HEAD-127: So we have a hard limit on the number of blocks reexecuted
-	if !bc.cacheConfig.TrieDirtyDisabled {
+	if bc.triedb.Scheme() == rawdb.PathScheme {
+		// Ensure the state of the head block is stored to disk before exiting,
+		// the older states can be recovered from the state histories.
+		head := bc.CurrentBlock()
+		log.Info("Writing cached state to disk", "block", head.Number, "hash", head.Hash(), "root", head.Root)
+		if err := bc.triedb.Commit(head.Root, true); err != nil {
+			log.Error("Failed to commit recent state trie", "err", err)
+		}
+	} else if !bc.cacheConfig.TrieDirtyDisabled {
+		// Ensure the state of a recent block is also stored to disk before exiting.
+		// We're writing three different states to catch different restart scenarios:
+		//  - HEAD:     So we don't need to reprocess any blocks in the general case
+		//  - HEAD-1:   So we don't do large reorgs if our HEAD becomes an uncle
+		//  - HEAD-127: So we have a hard limit on the number of blocks reexecuted
 		triedb := bc.triedb
 
 		for _, offset := range []uint64{0, 1, TriesInMemory - 1} {
@@ -1357,15 +1440,29 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	if err := blockBatch.Write(); err != nil {
 		log.Crit("Failed to write block into disk", "err", err)
 	}
+	// Stash the state changes of the block before they're flushed, the mutations
+	// are already hashed into the tries during validation.
+	if diff := state.StateDiff(); diff != nil {
+		bc.stateDiffCache.Add(block.Hash(), diff)
+	}
+
 	// Commit all cached state changes into underlying memory database.
 	root, err := state.Commit(bc.chainConfig.IsEIP158(block.Number()))
 	if err != nil {
 		return err
 	}
+	if bc.cacheConfig.StateDiffHistory > 0 {
+		bc.writeStateDiff(block, state)
+	}
 	// If we're running an archive node, always flush
 	if bc.cacheConfig.TrieDirtyDisabled {
 		return bc.triedb.Commit(root, false)
 	}
+	// The path-based database maintains the in-memory layers by itself, the
+	// old ones are flattened into the disk as new states are added.
+	if bc.triedb.Scheme() == rawdb.PathScheme {
+		return nil
+	}
 	// Full but not archive node, do proper garbage collection
 	bc.triedb.Reference(root, common.Hash{}) // metadata reference to keep trie alive
 	bc.triegc.Push(root, -int64(block.NumberU64()))
@@ -1403,6 +1500,10 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 			bc.triedb.Commit(header.Root, true)
 			bc.lastWrite = chosen
 			bc.gcproc = 0
+
+			if bc.pruner != nil {
+				bc.pruner.Committed(chosen, header.Root, current)
+			}
 		}
 	}
 	// Garbage collect anything below our required write retention
@@ -1417,6 +1518,39 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	return nil
 }
 
+// TrackStateOrigins enables tracking the changes made to the given state if they
+// are needed, i.e. if reverse state diffs are retained or state diffs are
+// subscribed to. It must be called on the states blocks are processed on before
+// they're written via WriteBlockAndSetHead.
+func (bc *BlockChain) TrackStateOrigins(statedb *state.StateDB) {
+	if bc.cacheConfig.StateDiffHistory > 0 || bc.diffScope.Count() > 0 {
+		statedb.TrackOrigins()
+	}
+}
+
+// writeStateDiff stores the reverse state diff of the given block which has just
+// been committed, and deletes the diffs falling out of the retention window.
+// Failures are not fatal, the historical states across the block are simply
+// not reconstructable.
+func (bc *BlockChain) writeStateDiff(block *types.Block, state *state.StateDB) {
+	diff, err := state.ReverseDiff()
+	if err == nil {
+		var blob []byte
+		if blob, err = rlp.EncodeToBytes(diff); err == nil {
+			rawdb.WriteStateDiff(bc.db, block.NumberU64(), block.Hash(), blob)
+		}
+	}
+	if err != nil {
+		log.Warn("Failed to write state diff", "number", block.NumberU64(), "hash", block.Hash(), "err", err)
+	}
+	if number := block.NumberU64(); number > bc.cacheConfig.StateDiffHistory {
+		stale := number - bc.cacheConfig.StateDiffHistory
+		for _, hash := range rawdb.ReadAllHashes(bc.db, stale) {
+			rawdb.DeleteStateDiff(bc.db, stale, hash)
+		}
+	}
+}
+
 // WriteBlockAndSetHead writes the given block and all associated state to the database,
 // and applies the block as the new chain head.
 func (bc *BlockChain) WriteBlockAndSetHead(block *types.Block, receipts []*types.Receipt, logs []*types.Log, state *state.StateDB, emitHeadEvent bool) (status WriteStatus, err error) {
@@ -1461,6 +1595,8 @@ func (bc *BlockChain) writeBlockAndSetHead(block *types.Block, receipts []*types
 		if len(logs) > 0 {
 			bc.logsFeed.Send(logs)
 		}
+		bc.sendStateDiff(block)
+
 		// In theory, we should fire a ChainHeadEvent when we inject
 		// a canonical block, but sometimes we can insert a batch of
 		// canonical blocks. Avoid firing too many ChainHeadEvents,
@@ -1475,6 +1611,14 @@ func (bc *BlockChain) writeBlockAndSetHead(block *types.Block, receipts []*types
 	return status, nil
 }
 
+// sendStateDiff emits the state changes of the given canonical block if they
+// are still cached.
+func (bc *BlockChain) sendStateDiff(block *types.Block) {
+	if diff, ok := bc.stateDiffCache.Get(block.Hash()); ok {
+		bc.stateDiffFeed.Send(StateDiffEvent{Block: block, Hash: block.Hash(), Diff: diff})
+	}
+}
+
 // addFutureBlock checks if the block is within the max allowed window to get
 // accepted for future processing, and returns an error if the block is too far
 // ahead and was not added.
@@ -1728,6 +1872,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, setHead bool) (int, error)
 		if err != nil {
 			return it.index, err
 		}
+		bc.TrackStateOrigins(statedb)
 
 		// Enable prefetching to pull in trie node paths while processing transactions
 		statedb.StartPrefetcher("chain")
@@ -2229,6 +2374,10 @@ func (bc *BlockChain) reorg(oldHead *types.Header, newHead *types.Block) error {
 	if len(rebirthLogs) > 0 {
 		bc.logsFeed.Send(rebirthLogs)
 	}
+	// New state diffs, the one of the new head is sent by the caller:
+	for i := len(newChain) - 1; i >= 1; i-- {
+		bc.sendStateDiff(newChain[i])
+	}
 	return nil
 }
 
@@ -2278,6 +2427,7 @@ func (bc *BlockChain) SetCanonical(head *types.Block) (common.Hash, error) {
 	if len(logs) > 0 {
 		bc.logsFeed.Send(logs)
 	}
+	bc.sendStateDiff(head)
 	bc.chainHeadFeed.Send(ChainHeadEvent{Block: head})
 
 	context := []interface{}{
@@ -2352,6 +2502,9 @@ func (bc *BlockChain) skipBlock(err error, it *insertIterator) bool {
 func (bc *BlockChain) indexBlocks(tail *uint64, head uint64, done chan struct{}) {
 	defer func() { close(done) }()
 
+	// Blocks below the history cutoff have no bodies to index anymore
+	cutoff := bc.HistoryPruningCutoff()
+
 	// The tail flag is not existent, it means the node is just initialized
 	// and all blocks(may from ancient store) are not indexed yet.
 	if tail == nil {
@@ -2359,12 +2512,15 @@ func (bc *BlockChain) indexBlocks(tail *uint64, head uint64, done chan struct{})
 		if bc.txLookupLimit != 0 && head >= bc.txLookupLimit {
 			from = head - bc.txLookupLimit + 1
 		}
+		if from < cutoff {
+			from = cutoff
+		}
 		rawdb.IndexTransactions(bc.db, from, head+1, bc.quit)
 		return
 	}
 	// The tail flag is existent, but the whole chain is required to be indexed.
 	if bc.txLookupLimit == 0 || head < bc.txLookupLimit {
-		if *tail > 0 {
+		if *tail > cutoff {
 			// It can happen when chain is rewound to a historical point which
 			// is even lower than the indexes tail, recap the indexing target
 			// to new head to avoid reading non-existent block bodies.
@@ -2372,17 +2528,27 @@ func (bc *BlockChain) indexBlocks(tail *uint64, head uint64, done chan struct{})
 			if end > head+1 {
 				end = head + 1
 			}
-			rawdb.IndexTransactions(bc.db, 0, end, bc.quit)
+			rawdb.IndexTransactions(bc.db, cutoff, end, bc.quit)
 		}
 		return
 	}
 	// Update the transaction index to the new chain state
 	if head-bc.txLookupLimit+1 < *tail {
 		// Reindex a part of missing indices and rewind index tail to HEAD-limit
-		rawdb.IndexTransactions(bc.db, head-bc.txLookupLimit+1, *tail, bc.quit)
+		from := head - bc.txLookupLimit + 1
+		if from < cutoff {
+			from = cutoff
+		}
+		rawdb.IndexTransactions(bc.db, from, *tail, bc.quit)
 	} else {
-		// Unindex a part of stale indices and forward index tail to HEAD-limit
-		rawdb.UnindexTransactions(bc.db, *tail, head-bc.txLookupLimit+1, bc.quit)
+		// Unindex a part of stale indices and forward index tail to HEAD-limit.
+		// The indices of pruned blocks can't be located without their bodies,
+		// they are retained.
+		from := *tail
+		if from < cutoff {
+			from = cutoff
+		}
+		rawdb.UnindexTransactions(bc.db, from, head-bc.txLookupLimit+1, bc.quit)
 	}
 }
 
@@ -2415,7 +2581,10 @@ func (bc *BlockChain) maintainTxIndex() {
 		case head := <-headCh:
 			if done == nil {
 				done = make(chan struct{})
-				go bc.indexBlocks(rawdb.ReadTxIndexTail(bc.db), head.Block.NumberU64(), done)
+				go func(head uint64) {
+					bc.expireHistory(head)
+					bc.indexBlocks(rawdb.ReadTxIndexTail(bc.db), head, done)
+				}(head.Block.NumberU64())
 			}
 		case <-done:
 			done = nil
@@ -2429,6 +2598,21 @@ func (bc *BlockChain) maintainTxIndex() {
 	}
 }
 
+// expireHistory prunes the block bodies and receipts which fall out of the
+// configured history retention window.
+func (bc *BlockChain) expireHistory(head uint64) {
+	limit := bc.cacheConfig.HistoryBlocks
+	if limit == 0 || head < limit {
+		return
+	}
+	if _, err := bc.db.Tail(); err != nil {
+		return // No ancient store to prune
+	}
+	if _, err := rawdb.PruneHistory(bc.db, head-limit+1); err != nil {
+		log.Error("Failed to expire chain history", "err", err)
+	}
+}
+
 // reportBlock logs a bad block error.
 func (bc *BlockChain) reportBlock(block *types.Block, receipts types.Receipts, err error) {
 	rawdb.WriteBadBlock(bc.db, block)
@@ -17,12 +17,15 @@
 package core
 
 import (
+	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
@@ -154,6 +157,16 @@ func (bc *BlockChain) HasFastBlock(hash common.Hash, number uint64) bool {
 	return rawdb.HasReceipts(bc.db, hash, number)
 }
 
+// HistoryPruningCutoff returns the first block whose body and receipts are
+// still available, all earlier ones have been expired from the database.
+func (bc *BlockChain) HistoryPruningCutoff() uint64 {
+	tail, err := bc.db.Tail()
+	if err != nil {
+		return 0
+	}
+	return tail
+}
+
 // GetBlock retrieves a block from the database by hash and number,
 // caching it if found.
 func (bc *BlockChain) GetBlock(hash common.Hash, number uint64) *types.Block {
@@ -208,6 +221,13 @@ func (bc *BlockChain) GetBlocksFromHash(hash common.Hash, n int) (blocks []*type
 	return
 }
 
+// GetStateDiff retrieves the state changes made by the given block if it was
+// processed recently, nil is returned otherwise.
+func (bc *BlockChain) GetStateDiff(hash common.Hash) state.StateDiff {
+	diff, _ := bc.stateDiffCache.Get(hash)
+	return diff
+}
+
 // GetReceiptsByHash retrieves the receipts for all transactions in a given block.
 func (bc *BlockChain) GetReceiptsByHash(hash common.Hash) types.Receipts {
 	if receipts, ok := bc.receiptsCache.Get(hash); ok {
@@ -282,6 +302,17 @@ func (bc *BlockChain) HasState(hash common.Hash) bool {
 	return err == nil
 }
 
+// stateRecoverable checks if the specified state is recoverable.
+// Note, this function assumes the state is not present, because
+// state is not treated as recoverable if it's available, thus
+// false will be returned in this case.
+func (bc *BlockChain) stateRecoverable(root common.Hash) bool {
+	if bc.triedb.Scheme() == rawdb.HashScheme {
+		return false
+	}
+	return bc.triedb.Recoverable(root)
+}
+
 // HasBlockAndState checks if a block and associated state trie is fully present
 // in the database or not, caching it if present.
 func (bc *BlockChain) HasBlockAndState(hash common.Hash, number uint64) bool {
@@ -321,6 +352,97 @@ func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
 	return state.New(root, bc.stateCache, bc.snaps)
 }
 
+// historicalState is a state reconstructed from reverse state diffs, cached
+// together with the root of the available state it was rolled back from. The
+// unmodified parts of the state are still resolved from the base.
+type historicalState struct {
+	state *state.StateDB
+	base  common.Hash
+}
+
+// HistoricalState returns a state database for the given canonical header. If
+// the state is not available, it's reconstructed by rolling back the reverse
+// state diffs from the closest available state above it, or from a recently
+// reconstructed one. Only the states within the configured diff retention
+// window are reconstructable.
+func (bc *BlockChain) HistoricalState(header *types.Header) (*state.StateDB, error) {
+	statedb, err := bc.StateAt(header.Root)
+	if err == nil || bc.cacheConfig.StateDiffHistory == 0 {
+		return statedb, err
+	}
+	number := header.Number.Uint64()
+	if bc.GetCanonicalHash(number) != header.Hash() {
+		return nil, fmt.Errorf("block #%d [%x] is not canonical", number, header.Hash())
+	}
+	head := bc.CurrentBlock().Number.Uint64()
+	if number+bc.cacheConfig.StateDiffHistory < head {
+		return nil, fmt.Errorf("block #%d is beyond the state diff window (%d blocks)", number, bc.cacheConfig.StateDiffHistory)
+	}
+	if cached := bc.cachedHistoricalState(header.Hash()); cached != nil {
+		return cached.state.Copy(), nil
+	}
+	// Collect the reverse diffs until a block with available or cached state
+	// is found
+	var (
+		diffs  []*state.ReverseDiff
+		base   common.Hash
+		cached *historicalState
+	)
+	for n := number + 1; ; n++ {
+		current := bc.GetHeaderByNumber(n)
+		if current == nil {
+			return nil, fmt.Errorf("no available state above block #%d", number)
+		}
+		blob := rawdb.ReadStateDiff(bc.db, n, current.Hash())
+		if len(blob) == 0 {
+			return nil, fmt.Errorf("missing state diff of block #%d", n)
+		}
+		diff := new(state.ReverseDiff)
+		if err := rlp.DecodeBytes(blob, diff); err != nil {
+			return nil, fmt.Errorf("invalid state diff of block #%d: %v", n, err)
+		}
+		diffs = append(diffs, diff)
+		if cached = bc.cachedHistoricalState(current.Hash()); cached != nil {
+			base = cached.base
+			break
+		}
+		if bc.HasState(current.Root) {
+			base = current.Root
+			break
+		}
+	}
+	if cached != nil {
+		statedb = cached.state.Copy()
+	} else if statedb, err = bc.StateAt(base); err != nil {
+		return nil, err
+	}
+	for i := len(diffs) - 1; i >= 0; i-- {
+		if err := statedb.ApplyReverseDiff(diffs[i]); err != nil {
+			return nil, err
+		}
+		if root := statedb.IntermediateRoot(false); root != diffs[i].Parent {
+			return nil, fmt.Errorf("state root mismatch after rollback: have %x, want %x", root, diffs[i].Parent)
+		}
+	}
+	// Cache a copy, the returned state may be mutated by the caller
+	bc.historicalStates.Add(header.Hash(), &historicalState{state: statedb.Copy(), base: base})
+	return statedb, nil
+}
+
+// cachedHistoricalState returns the reconstructed state of the given block if
+// it's cached and its base state is still available.
+func (bc *BlockChain) cachedHistoricalState(hash common.Hash) *historicalState {
+	cached, ok := bc.historicalStates.Get(hash)
+	if !ok {
+		return nil
+	}
+	if !bc.HasState(cached.base) {
+		bc.historicalStates.Remove(hash)
+		return nil
+	}
+	return cached
+}
+
 // Config retrieves the chain's fork configuration.
 func (bc *BlockChain) Config() *params.ChainConfig { return bc.chainConfig }
 
@@ -379,6 +501,14 @@ func (bc *BlockChain) TrieDB() *trie.Database {
 	return bc.triedb
 }
 
+// PruneStatus returns the progress of the background state pruning.
+func (bc *BlockChain) PruneStatus() (*pruner.OnlineStatus, error) {
+	if bc.pruner == nil {
+		return nil, errors.New("online pruning is not enabled")
+	}
+	return bc.pruner.Status(), nil
+}
+
 // SubscribeRemovedLogsEvent registers a subscription of RemovedLogsEvent.
 func (bc *BlockChain) SubscribeRemovedLogsEvent(ch chan<- RemovedLogsEvent) event.Subscription {
 	return bc.scope.Track(bc.rmLogsFeed.Subscribe(ch))
@@ -404,6 +534,11 @@ func (bc *BlockChain) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscript
 	return bc.scope.Track(bc.logsFeed.Subscribe(ch))
 }
 
+// SubscribeStateDiffEvent registers a subscription of StateDiffEvent.
+func (bc *BlockChain) SubscribeStateDiffEvent(ch chan<- StateDiffEvent) event.Subscription {
+	return bc.diffScope.Track(bc.stateDiffFeed.Subscribe(ch))
+}
+
 // SubscribeBlockProcessingEvent registers a subscription of bool where true means
 // block processing has started while false means it has stopped.
 func (bc *BlockChain) SubscribeBlockProcessingEvent(ch chan<- bool) event.Subscription {
@@ -22,6 +22,7 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
@@ -33,6 +34,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -4338,3 +4340,445 @@ func TestEIP3651(t *testing.T) {
 		t.Fatalf("sender balance incorrect: expected %d, got %d", expected, actual)
 	}
 }
+
+// Tests that a chain using the path-based state scheme retains the recent
+// states in memory, persists the head on shutdown and can rewind beyond the
+// in-memory layers by applying the state histories.
+func TestPathSchemeRewind(t *testing.T) {
+	var (
+		engine = ethash.NewFaker()
+		gspec  = &Genesis{Config: params.TestChainConfig, BaseFee: big.NewInt(params.InitialBaseFee)}
+		db     = rawdb.NewMemoryDatabase()
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 2*TriesInMemory, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{byte(i)})
+	})
+	config := *defaultCacheConfig
+	config.StateScheme = rawdb.PathScheme
+	config.SnapshotLimit = 0
+
+	chain, err := NewBlockChain(db, &config, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	if scheme := rawdb.ReadStateScheme(db); scheme != rawdb.PathScheme {
+		t.Fatalf("state scheme mismatch: have %s, want %s", scheme, rawdb.PathScheme)
+	}
+	// The states of the recent blocks are available, the older ones recoverable
+	for i, block := range blocks {
+		recent := i >= len(blocks)-TriesInMemory-1
+		if have := chain.HasState(block.Root()); have != recent {
+			t.Fatalf("block %d: state availability mismatch: have %v, want %v", block.NumberU64(), have, recent)
+		}
+		if have := chain.stateRecoverable(block.Root()); have == recent {
+			t.Fatalf("block %d: state recoverability mismatch: have %v, want %v", block.NumberU64(), have, !recent)
+		}
+	}
+	// Rewind the chain beyond the in-memory layers
+	target := blocks[TriesInMemory/2-1]
+	if err := chain.SetHead(target.NumberU64()); err != nil {
+		t.Fatalf("failed to rewind chain: %v", err)
+	}
+	if head := chain.CurrentBlock(); head.Hash() != target.Hash() {
+		t.Fatalf("head block mismatch: have #%d, want #%d", head.Number, target.NumberU64())
+	}
+	if !chain.HasState(target.Root()) {
+		t.Fatal("rewound state not available")
+	}
+	// Ensure the head state survives a restart and the chain can progress
+	chain.Stop()
+
+	chain, err = NewBlockChain(db, &config, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to recreate chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if head := chain.CurrentBlock(); head.Hash() != target.Hash() {
+		t.Fatalf("reopened head block mismatch: have #%d, want #%d", head.Number, target.NumberU64())
+	}
+	if n, err := chain.InsertChain(blocks[target.NumberU64():]); err != nil {
+		t.Fatalf("block %d: failed to reinsert into chain: %v", n, err)
+	}
+	if !chain.HasState(blocks[len(blocks)-1].Root()) {
+		t.Fatal("head state not available after reimport")
+	}
+}
+
+// Tests that the online pruner deletes the stale state in the background, while
+// retaining all the states within the retention window intact.
+func TestOnlinePruning(t *testing.T) {
+	var (
+		engine  = ethash.NewFaker()
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		funds   = big.NewInt(1000000000000000000)
+		gspec   = &Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   GenesisAlloc{address: {Balance: funds}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		signer = types.LatestSigner(gspec.Config)
+		db     = rawdb.NewMemoryDatabase()
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 5*TriesInMemory, func(i int, b *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(b.TxNonce(address), common.Address{byte(i >> 8), byte(i)}, big.NewInt(1000), params.TxGas, b.header.BaseFee, nil), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign tx: %v", err)
+		}
+		b.AddTx(tx)
+	})
+	config := *defaultCacheConfig
+	config.TrieTimeLimit = time.Nanosecond // Flush a state for every block
+	config.SnapshotLimit = 0
+	config.OnlinePruning = &pruner.OnlineConfig{Retention: pruner.MinRetention, BloomSize: 1}
+
+	chain, err := NewBlockChain(db, &config, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	// Import the blocks one by one, waiting for the background tasks of the
+	// pruner to finish so that the cycle is deterministic.
+	wait := func() *pruner.OnlineStatus {
+		for {
+			status, _ := chain.PruneStatus()
+			if status.Phase != "marking" && status.Phase != "sweeping" {
+				return status
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	var status *pruner.OnlineStatus
+	for i, block := range blocks {
+		if _, err := chain.InsertChain(types.Blocks{block}); err != nil {
+			t.Fatalf("block %d: failed to insert into chain: %v", i, err)
+		}
+		if status = wait(); status.Cycles > 0 {
+			break
+		}
+	}
+	if status.Cycles != 1 {
+		t.Fatalf("pruning cycle not finished: %+v", status)
+	}
+	if status.Swept == 0 {
+		t.Fatalf("no stale state pruned: %+v", status)
+	}
+	if status.Error != "" {
+		t.Fatalf("pruning failed: %v", status.Error)
+	}
+	// The states prior to the pruning target are gone, the later ones are intact
+	head := chain.CurrentBlock().Number.Uint64()
+	if rawdb.HasLegacyTrieNode(db, blocks[0].Root()) {
+		t.Fatal("stale state not pruned")
+	}
+	for number := head - pruner.MinRetention; number <= head; number++ {
+		// Check the flushed states directly in the disk, bypassing the caches
+		root := chain.GetHeaderByNumber(number).Root
+		if !rawdb.HasLegacyTrieNode(db, root) {
+			continue
+		}
+		tr, err := trie.NewStateTrie(trie.StateTrieID(root), trie.NewDatabase(db))
+		if err != nil {
+			t.Fatalf("block %d: failed to open state: %v", number, err)
+		}
+		it := tr.NodeIterator(nil)
+		for it.Next(true) {
+		}
+		if it.Error() != nil {
+			t.Fatalf("block %d: state corrupted: %v", number, it.Error())
+		}
+	}
+	// Ensure the chain can still progress on top of the pruned database
+	if _, err := chain.InsertChain(blocks[head:]); err != nil {
+		t.Fatalf("failed to insert remaining blocks: %v", err)
+	}
+}
+
+// Tests that the historical states which are no longer available can be
+// reconstructed from the reverse state diffs within the retention window.
+func TestStateDiffHistory(t *testing.T) {
+	var (
+		engine  = ethash.NewFaker()
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		funds   = big.NewInt(1000000000000000000)
+		writer  = common.HexToAddress("0xaaaa")
+		killer  = common.HexToAddress("0xbbbb")
+		gspec   = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: GenesisAlloc{
+				address: {Balance: funds},
+				// Stores the block number into slot 0 and slot NUMBER
+				writer: {
+					Code:    []byte{byte(vm.NUMBER), byte(vm.PUSH1), 0x00, byte(vm.SSTORE), byte(vm.NUMBER), byte(vm.NUMBER), byte(vm.SSTORE), byte(vm.STOP)},
+					Balance: big.NewInt(0),
+				},
+				// Self-destructs along with its storage
+				killer: {
+					Code:    []byte{byte(vm.PUSH1), 0x00, byte(vm.SELFDESTRUCT)},
+					Storage: map[common.Hash]common.Hash{{0x01}: {0x01}, {0x02}: {0x02}},
+					Balance: big.NewInt(1),
+				},
+			},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		signer = types.LatestSigner(gspec.Config)
+		window = uint64(2 * TriesInMemory)
+		killAt = 40
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, int(window)+32, func(i int, b *BlockGen) {
+		txs := []*types.Transaction{
+			types.NewTransaction(b.TxNonce(address), common.Address{byte(i >> 8), byte(i)}, big.NewInt(1000), params.TxGas, b.header.BaseFee, nil),
+			types.NewTransaction(b.TxNonce(address)+1, writer, nil, 100000, b.header.BaseFee, nil),
+		}
+		if i == killAt {
+			txs = append(txs, types.NewTransaction(b.TxNonce(address)+2, killer, nil, 100000, b.header.BaseFee, nil))
+		}
+		for _, tx := range txs {
+			signed, err := types.SignTx(tx, signer, key)
+			if err != nil {
+				t.Fatalf("failed to sign tx: %v", err)
+			}
+			b.AddTx(signed)
+		}
+	})
+	config := *defaultCacheConfig
+	config.SnapshotLimit = 0
+	config.Preimages = true
+	config.StateDiffHistory = window
+
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), &config, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	var (
+		head          = chain.CurrentBlock().Number.Uint64()
+		reconstructed int
+	)
+	for number := uint64(1); number <= head; number++ {
+		header := chain.GetHeaderByNumber(number)
+		if chain.HasState(header.Root) {
+			continue
+		}
+		statedb, err := chain.HistoricalState(header)
+		if number+window < head {
+			if err == nil {
+				t.Fatalf("block %d: state beyond the window reconstructed", number)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("block %d: failed to reconstruct state: %v", number, err)
+		}
+		if root := statedb.IntermediateRoot(false); root != header.Root {
+			t.Fatalf("block %d: state root mismatch: have %x, want %x", number, root, header.Root)
+		}
+		if have := statedb.GetState(writer, common.Hash{}); have != common.BigToHash(header.Number) {
+			t.Fatalf("block %d: storage mismatch: have %x, want %x", number, have, header.Number)
+		}
+		if have, want := statedb.Exist(killer), number <= uint64(killAt); have != want {
+			t.Fatalf("block %d: destructed account existence mismatch: have %v, want %v", number, have, want)
+		}
+		reconstructed++
+	}
+	if reconstructed == 0 {
+		t.Fatal("no historical state reconstructed")
+	}
+	// Reconstructed states are cached, unaffected by mutating the returned ones
+	header := chain.GetHeaderByNumber(head - window + 1)
+	statedb, err := chain.HistoricalState(header)
+	if err != nil {
+		t.Fatalf("failed to reconstruct state: %v", err)
+	}
+	statedb.SetState(writer, common.Hash{}, common.Hash{0xff})
+	statedb.IntermediateRoot(false)
+
+	if statedb, err = chain.HistoricalState(header); err != nil {
+		t.Fatalf("failed to retrieve cached state: %v", err)
+	}
+	if root := statedb.IntermediateRoot(false); root != header.Root {
+		t.Fatalf("cached state root mismatch: have %x, want %x", root, header.Root)
+	}
+	// The parent is rolled back from the cached state, the diffs above aren't needed
+	for n := header.Number.Uint64() + 1; n <= head; n++ {
+		rawdb.DeleteStateDiff(chain.db, n, chain.GetCanonicalHash(n))
+	}
+	parent := chain.GetHeaderByNumber(head - window)
+	if statedb, err = chain.HistoricalState(parent); err != nil {
+		t.Fatalf("failed to reconstruct parent state: %v", err)
+	}
+	if root := statedb.IntermediateRoot(false); root != parent.Root {
+		t.Fatalf("parent state root mismatch: have %x, want %x", root, parent.Root)
+	}
+	// Ensure the diffs out of the window are pruned
+	stale := head - window
+	if rawdb.HasStateDiff(chain.db, stale, chain.GetCanonicalHash(stale)) {
+		t.Fatal("stale state diff not pruned")
+	}
+}
+
+// Tests that the state changes of the processed blocks are recorded and emitted
+// once the blocks become canonical.
+func TestStateDiff(t *testing.T) {
+	var (
+		engine    = ethash.NewFaker()
+		key, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address   = crypto.PubkeyToAddress(key.PublicKey)
+		funds     = big.NewInt(1000000000000000000)
+		recipient = common.HexToAddress("0xdead")
+		writer    = common.HexToAddress("0xaaaa")
+		gspec     = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: GenesisAlloc{
+				address: {Balance: funds},
+				// Stores the block number into slot 0
+				writer: {
+					Code:    []byte{byte(vm.NUMBER), byte(vm.PUSH1), 0x00, byte(vm.SSTORE), byte(vm.STOP)},
+					Balance: big.NewInt(0),
+				},
+			},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 1, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{1})
+		tx, _ := types.SignTx(types.NewTransaction(0, recipient, big.NewInt(1000), params.TxGas, b.header.BaseFee, nil), signer, key)
+		b.AddTx(tx)
+		tx, _ = types.SignTx(types.NewTransaction(1, writer, nil, 50000, b.header.BaseFee, nil), signer, key)
+		b.AddTx(tx)
+	})
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	events := make(chan StateDiffEvent, 1)
+	sub := chain.SubscribeStateDiffEvent(events)
+	defer sub.Unsubscribe()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	var ev StateDiffEvent
+	select {
+	case ev = <-events:
+	case <-time.After(time.Second):
+		t.Fatal("state diff event not emitted")
+	}
+	if ev.Hash != blocks[0].Hash() {
+		t.Fatalf("state diff event block mismatch: have %x, want %x", ev.Hash, blocks[0].Hash())
+	}
+	diff := chain.GetStateDiff(blocks[0].Hash())
+	if diff == nil {
+		t.Fatal("state diff not cached")
+	}
+	if sender := diff[address]; sender == nil || sender.Pre.Nonce != 0 || sender.Post.Nonce != 2 {
+		t.Fatalf("sender diff mismatch: %+v", sender)
+	}
+	if recv := diff[recipient]; recv == nil || recv.Pre != nil || recv.Post.Balance.ToInt().Int64() != 1000 {
+		t.Fatalf("recipient diff mismatch: %+v", recv)
+	}
+	want := state.StateDiffSlot{Pre: common.Hash{}, Post: common.BigToHash(big.NewInt(1))}
+	if w := diff[writer]; w == nil || len(w.Storage) != 1 || w.Storage[common.Hash{}] != want {
+		t.Fatalf("writer diff mismatch: %+v", w)
+	}
+	// Ensure the same changes are obtained by re-executing the block
+	statedb, _ := chain.StateAt(gspec.ToBlock().Root())
+	statedb.TrackOrigins()
+	if _, _, _, err := chain.Processor().Process(blocks[0], statedb, vm.Config{}); err != nil {
+		t.Fatalf("failed to re-execute block: %v", err)
+	}
+	statedb.IntermediateRoot(true)
+	if have := statedb.StateDiff(); !reflect.DeepEqual(have, diff) {
+		t.Fatalf("re-executed state diff mismatch: have %+v, want %+v", have, diff)
+	}
+	// Without subscribers or reverse diffs, the state changes aren't tracked
+	plain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer plain.Stop()
+
+	if _, err := plain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if diff := plain.GetStateDiff(blocks[0].Hash()); diff != nil {
+		t.Fatalf("untracked state diff recorded: %+v", diff)
+	}
+}
+
+// Tests that the state changes of the blocks becoming canonical through a reorg
+// are emitted, in chain order.
+func TestStateDiffReorg(t *testing.T) {
+	var (
+		engine  = ethash.NewFaker()
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   GenesisAlloc{address: {Balance: big.NewInt(1000000000000000000)}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	genDb, blocks, _ := GenerateChainWithGenesis(gspec, engine, 1, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{1})
+	})
+	fork, _ := GenerateChain(gspec.Config, gspec.ToBlock(), engine, genDb, 3, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{2})
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), common.HexToAddress("0xdead"), big.NewInt(1000), params.TxGas, b.header.BaseFee, nil), signer, key)
+		b.AddTx(tx)
+	})
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	events := make(chan StateDiffEvent, 8)
+	sub := chain.SubscribeStateDiffEvent(events)
+	defer sub.Unsubscribe()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	for _, block := range fork {
+		if err := chain.InsertBlockWithoutSetHead(block); err != nil {
+			t.Fatalf("failed to insert fork block: %v", err)
+		}
+	}
+	if _, err := chain.SetCanonical(fork[len(fork)-1]); err != nil {
+		t.Fatalf("failed to set canonical head: %v", err)
+	}
+	want := []common.Hash{blocks[0].Hash(), fork[0].Hash(), fork[1].Hash(), fork[2].Hash()}
+	for i, hash := range want {
+		select {
+		case ev := <-events:
+			if ev.Hash != hash {
+				t.Fatalf("event %d: block mismatch: have %x, want %x", i, ev.Hash, hash)
+			}
+			if sender := ev.Diff[address]; i > 0 && (sender == nil || uint64(sender.Post.Nonce) != uint64(i)) {
+				t.Fatalf("event %d: sender diff mismatch: %+v", i, sender)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("event %d: state diff not emitted", i)
+		}
+	}
+	select {
+	case ev := <-events:
+		t.Fatalf("unexpected state diff event: %x", ev.Hash)
+	default:
+	}
+}
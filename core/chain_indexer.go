@@ -381,6 +381,41 @@ func (c *ChainIndexer) updateLoop() {
 	}
 }
 
+// ProcessSections synchronously processes all sections of the canonical chain
+// completed at the given head, without waiting for confirmations. It is meant
+// for rebuilding an index offline and must not be used with a started indexer.
+func (c *ChainIndexer) ProcessSections(head uint64) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var (
+		sections = (head + 1) / c.sectionSize
+		logged   = time.Now()
+	)
+	for c.storedSections < sections {
+		section := c.storedSections
+		var lastHead common.Hash
+		if section > 0 {
+			lastHead = c.SectionHead(section - 1)
+		}
+		newHead, err := c.processSection(section, lastHead)
+		if err != nil {
+			return err
+		}
+		c.setSectionHead(section, newHead)
+		c.setValidSections(section + 1)
+
+		if time.Since(logged) > 8*time.Second {
+			c.log.Info("Processing chain index", "section", section, "sections", sections)
+			logged = time.Now()
+		}
+	}
+	if c.knownSections < sections {
+		c.knownSections = sections
+	}
+	return nil
+}
+
 // processSection processes an entire section by calling backend functions while
 // ensuring the continuity of the passed headers. Since the chain mutex is not
 // held while processing, the continuity can be broken by a long reorg, in which
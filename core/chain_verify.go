@@ -0,0 +1,384 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Kinds of inconsistencies reported by VerifyChain.
+const (
+	IssueCanonicalHash = "canonical hash"   // Canonical number->hash mapping missing or wrong
+	IssueHeaderNumber  = "header number"    // Header hash->number mapping missing or wrong
+	IssueHeader        = "header"           // Header missing or corrupted
+	IssueTd            = "total difficulty" // Total difficulty missing
+	IssueBody          = "body"             // Body missing or not matching the header
+	IssueReceipts      = "receipts"         // Receipts missing or not matching the header
+	IssueTxLookup      = "tx lookup"        // Transaction lookup entry missing or wrong
+	IssueHeadBlock     = "head block"       // Head block pointer not on the canonical chain
+	IssueHeadFastBlock = "head fast block"  // Head fast block pointer not on the canonical chain
+)
+
+// ChainIssue is a single inconsistency found in the chain data.
+type ChainIssue struct {
+	Number    uint64      // Block number the issue was found at
+	Hash      common.Hash // Canonical hash of the block, derived from its child if possible
+	Kind      string      // Kind of data affected
+	Detail    string      // Human readable description of the problem
+	Derivable bool        // Whether the data can be rebuilt from other chain data
+}
+
+// ChainReport is the result of a chain consistency check.
+type ChainReport struct {
+	Head        uint64  // Number of the head header the check started from
+	BodyHead    uint64  // Highest block whose body and receipts are expected, the head (fast) block
+	Frozen      uint64  // Number of blocks in the ancient store
+	Tail        uint64  // First block whose body and receipts are retained
+	TxIndexTail *uint64 // First block whose transactions are indexed, nil if none
+	Checked     uint64  // Number of blocks checked
+	Issues      []ChainIssue
+}
+
+// Corrupted returns the lowest block number with data that cannot be rebuilt.
+// The chain needs to be rewound below it to be consistent again.
+func (r *ChainReport) Corrupted() (uint64, bool) {
+	var (
+		lowest uint64
+		found  bool
+	)
+	for _, issue := range r.Issues {
+		if !issue.Derivable && (!found || issue.Number < lowest) {
+			lowest, found = issue.Number, true
+		}
+	}
+	return lowest, found
+}
+
+// ChainRepair is the result of a chain repair.
+type ChainRepair struct {
+	Rewound bool           // Whether the chain was rewound
+	Head    uint64         // Head header number after the repair
+	Rebuilt map[string]int // Number of rebuilt entries per issue kind
+}
+
+// VerifyChain cross-checks the canonical chain data in the database, walking
+// from the head header back to genesis. Every block is checked for its canonical
+// hash and header number mappings, header, total difficulty, body and receipts
+// (unless pruned) and transaction lookup entries (within the indexed range),
+// both in the key-value store and in the ancient store. Headers ahead of the
+// head (fast) block, e.g. during sync, are not expected to have bodies and
+// receipts yet.
+func VerifyChain(db ethdb.Database, interrupt chan struct{}) (*ChainReport, error) {
+	headHash := rawdb.ReadHeadHeaderHash(db)
+	headNumber := rawdb.ReadHeaderNumber(db, headHash)
+	if headNumber == nil {
+		return nil, errors.New("head header not found")
+	}
+	frozen, err := db.Ancients()
+	if err != nil {
+		return nil, err
+	}
+	tail, err := db.Tail()
+	if err != nil {
+		return nil, err
+	}
+	var bodyHead uint64
+	for _, hash := range []common.Hash{rawdb.ReadHeadBlockHash(db), rawdb.ReadHeadFastBlockHash(db)} {
+		if number := rawdb.ReadHeaderNumber(db, hash); number != nil && *number > bodyHead {
+			bodyHead = *number
+		}
+	}
+	if bodyHead > *headNumber {
+		bodyHead = *headNumber
+	}
+	report := &ChainReport{
+		Head:        *headNumber,
+		BodyHead:    bodyHead,
+		Frozen:      frozen,
+		Tail:        tail,
+		TxIndexTail: rawdb.ReadTxIndexTail(db),
+	}
+	var (
+		start  = time.Now()
+		logged = time.Now()
+		hash   = headHash
+	)
+	for number := *headNumber; ; number-- {
+		select {
+		case <-interrupt:
+			return report, errors.New("chain verification interrupted")
+		default:
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Verifying chain", "number", number, "issues", len(report.Issues), "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+		hash = verifyBlock(db, report, number, hash)
+		report.Checked++
+		if number == 0 {
+			break
+		}
+	}
+	// Ensure the head pointers reference canonical blocks
+	for _, marker := range []struct {
+		kind string
+		hash common.Hash
+	}{
+		{IssueHeadBlock, rawdb.ReadHeadBlockHash(db)},
+		{IssueHeadFastBlock, rawdb.ReadHeadFastBlockHash(db)},
+	} {
+		if marker.hash == (common.Hash{}) {
+			continue
+		}
+		number := rawdb.ReadHeaderNumber(db, marker.hash)
+		if number != nil && *number <= *headNumber && rawdb.ReadCanonicalHash(db, *number) == marker.hash {
+			continue
+		}
+		if number == nil || *number > *headNumber {
+			number = headNumber
+		}
+		// The pointer can be reset to the canonical block at the same height
+		canon := rawdb.ReadCanonicalHash(db, *number)
+		report.Issues = append(report.Issues, ChainIssue{
+			Number:    *number,
+			Hash:      canon,
+			Kind:      marker.kind,
+			Detail:    fmt.Sprintf("pointer %x is not canonical", marker.hash),
+			Derivable: canon != (common.Hash{}),
+		})
+	}
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		return report.Issues[i].Number < report.Issues[j].Number
+	})
+	return report, nil
+}
+
+// verifyBlock checks the data of a single canonical block, expected to have the
+// given hash as referenced by its child. The returned hash is the expected hash
+// of the parent, or the empty hash if it cannot be determined.
+func verifyBlock(db ethdb.Database, report *ChainReport, number uint64, hash common.Hash) common.Hash {
+	issue := func(kind string, derivable bool, format string, args ...interface{}) {
+		report.Issues = append(report.Issues, ChainIssue{
+			Number:    number,
+			Hash:      hash,
+			Kind:      kind,
+			Detail:    fmt.Sprintf(format, args...),
+			Derivable: derivable,
+		})
+	}
+	// Check the canonical mapping, which can only be rewritten if the block
+	// is not frozen yet.
+	canon := rawdb.ReadCanonicalHash(db, number)
+	if hash == (common.Hash{}) {
+		// The child is missing, fall back to the canonical mapping
+		if canon == (common.Hash{}) {
+			issue(IssueCanonicalHash, false, "canonical hash missing and not derivable")
+			return common.Hash{}
+		}
+		hash = canon
+	} else if canon != hash {
+		if canon == (common.Hash{}) {
+			issue(IssueCanonicalHash, number >= report.Frozen, "canonical hash missing")
+		} else {
+			issue(IssueCanonicalHash, number >= report.Frozen, "canonical hash %x mismatch", canon)
+		}
+	}
+	if stored := rawdb.ReadHeaderNumber(db, hash); stored == nil {
+		issue(IssueHeaderNumber, true, "header number missing")
+	} else if *stored != number {
+		issue(IssueHeaderNumber, true, "header number %d mismatch", *stored)
+	}
+	header := rawdb.ReadHeader(db, hash, number)
+	if header == nil {
+		issue(IssueHeader, false, "header missing or corrupted")
+		return common.Hash{}
+	}
+	if have := header.Hash(); have != hash {
+		issue(IssueHeader, false, "header hash %x mismatch", have)
+		return common.Hash{}
+	}
+	if rawdb.ReadTd(db, hash, number) == nil {
+		issue(IssueTd, number > 0 && number >= report.Frozen, "total difficulty missing")
+	}
+	// Bodies and receipts are only retained above the history cutoff, and are
+	// only present up to the head (fast) block
+	if number < report.Tail || number > report.BodyHead {
+		return header.ParentHash
+	}
+	body := rawdb.ReadBody(db, hash, number)
+	if body == nil {
+		issue(IssueBody, false, "body missing or corrupted")
+		return header.ParentHash
+	}
+	txs := types.Transactions(body.Transactions)
+	if root := types.DeriveSha(txs, trie.NewStackTrie(nil)); root != header.TxHash {
+		issue(IssueBody, false, "transaction root %x mismatch", root)
+		return header.ParentHash
+	}
+	if uncles := types.CalcUncleHash(body.Uncles); uncles != header.UncleHash {
+		issue(IssueBody, false, "uncle hash %x mismatch", uncles)
+	}
+	receipts := rawdb.ReadRawReceipts(db, hash, number)
+	switch {
+	case receipts == nil:
+		issue(IssueReceipts, false, "receipts missing or corrupted")
+	case len(receipts) != len(txs):
+		issue(IssueReceipts, false, "receipt count %d mismatch, have %d transactions", len(receipts), len(txs))
+	default:
+		if root := types.DeriveSha(receipts, trie.NewStackTrie(nil)); root != header.ReceiptHash {
+			issue(IssueReceipts, false, "receipt root %x mismatch", root)
+		}
+	}
+	// Transactions are only indexed above the index tail
+	if report.TxIndexTail != nil && number >= *report.TxIndexTail {
+		for _, tx := range txs {
+			if stored := rawdb.ReadTxLookupEntry(db, tx.Hash()); stored == nil {
+				issue(IssueTxLookup, true, "lookup entry of transaction %x missing", tx.Hash())
+			} else if *stored != number {
+				issue(IssueTxLookup, true, "lookup entry of transaction %x points to block %d", tx.Hash(), *stored)
+			}
+		}
+	}
+	return header.ParentHash
+}
+
+// RepairChain fixes the inconsistencies of a chain report. If any block data is
+// corrupted beyond repair, the chain is rewound to the block preceding it first.
+// All derivable data below the new head is rebuilt afterwards.
+func RepairChain(db ethdb.Database, report *ChainReport) (*ChainRepair, error) {
+	repair := &ChainRepair{
+		Head:    report.Head,
+		Rebuilt: make(map[string]int),
+	}
+	if number, corrupted := report.Corrupted(); corrupted {
+		if number == 0 {
+			return nil, errors.New("genesis block corrupted, resync required")
+		}
+		if err := rewindChain(db, report, number-1); err != nil {
+			return nil, err
+		}
+		repair.Rewound, repair.Head = true, number-1
+	}
+	// Rebuild the derivable data in ascending order, so that the total
+	// difficulties can be recalculated from the parents.
+	var (
+		batch   = db.NewBatch()
+		indexed = make(map[uint64]bool)
+	)
+	for _, issue := range report.Issues {
+		if !issue.Derivable || issue.Number > repair.Head {
+			continue
+		}
+		switch issue.Kind {
+		case IssueCanonicalHash:
+			rawdb.WriteCanonicalHash(batch, issue.Hash, issue.Number)
+		case IssueHeaderNumber:
+			rawdb.WriteHeaderNumber(batch, issue.Hash, issue.Number)
+		case IssueTd:
+			header := rawdb.ReadHeader(db, issue.Hash, issue.Number)
+			ptd := rawdb.ReadTd(db, header.ParentHash, issue.Number-1)
+			if ptd == nil {
+				return nil, fmt.Errorf("total difficulty of block %d missing", issue.Number-1)
+			}
+			// Written directly, the child might depend on it
+			rawdb.WriteTd(db, issue.Hash, issue.Number, new(big.Int).Add(ptd, header.Difficulty))
+		case IssueTxLookup:
+			// All missing entries of a block are reported separately, but
+			// rebuilt at once.
+			if indexed[issue.Number] {
+				continue
+			}
+			indexed[issue.Number] = true
+			rawdb.WriteTxLookupEntriesByBlock(batch, rawdb.ReadBlock(db, issue.Hash, issue.Number))
+		case IssueHeadBlock:
+			rawdb.WriteHeadBlockHash(batch, issue.Hash)
+		case IssueHeadFastBlock:
+			rawdb.WriteHeadFastBlockHash(batch, issue.Hash)
+		default:
+			continue
+		}
+		repair.Rebuilt[issue.Kind]++
+
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return nil, err
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return nil, err
+	}
+	return repair, nil
+}
+
+// rewindChain rewinds the chain to the given block, deleting all canonical data
+// above it and resetting the head pointers. The state of the new head might be
+// missing, which is repaired by the blockchain on the next startup.
+func rewindChain(db ethdb.Database, report *ChainReport, head uint64) error {
+	hash := rawdb.ReadCanonicalHash(db, head)
+	if hash == (common.Hash{}) {
+		return fmt.Errorf("canonical hash of rewind target %d missing", head)
+	}
+	batch := db.NewBatch()
+	for number := report.Head; number > head; number-- {
+		canon := rawdb.ReadCanonicalHash(db, number)
+		if canon == (common.Hash{}) {
+			continue
+		}
+		if body := rawdb.ReadBody(db, canon, number); body != nil {
+			hashes := make([]common.Hash, len(body.Transactions))
+			for i, tx := range body.Transactions {
+				hashes[i] = tx.Hash()
+			}
+			rawdb.DeleteTxLookupEntries(batch, hashes)
+		}
+		if number >= report.Frozen {
+			rawdb.DeleteBlock(batch, canon, number)
+			rawdb.DeleteCanonicalHash(batch, number)
+		}
+	}
+	// Reset all head pointers above the target
+	if number := rawdb.ReadHeaderNumber(db, rawdb.ReadHeadBlockHash(db)); number == nil || *number > head {
+		rawdb.WriteHeadBlockHash(batch, hash)
+	}
+	if number := rawdb.ReadHeaderNumber(db, rawdb.ReadHeadFastBlockHash(db)); number == nil || *number > head {
+		rawdb.WriteHeadFastBlockHash(batch, hash)
+	}
+	rawdb.WriteHeadHeaderHash(batch, hash)
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	if report.Frozen > head+1 {
+		if err := db.TruncateHead(head + 1); err != nil {
+			return err
+		}
+	}
+	log.Info("Rewound chain", "number", head, "hash", hash)
+	return nil
+}
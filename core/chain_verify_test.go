@@ -0,0 +1,201 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestVerifyAndRepairChain(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		funds   = big.NewInt(100000000000000000)
+		gspec   = &Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   GenesisAlloc{address: {Balance: funds}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, receipts := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 16, func(i int, block *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0x00}, big.NewInt(1000), params.TxGas, block.header.BaseFee, nil), signer, key)
+		if err != nil {
+			panic(err)
+		}
+		block.AddTx(tx)
+	})
+	db, _ := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	defer db.Close()
+
+	// Import the chain in snap sync fashion, freezing the first blocks
+	chain, err := NewBlockChain(db, nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	headers := make([]*types.Header, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header()
+	}
+	if n, err := chain.InsertHeaderChain(headers); err != nil {
+		t.Fatalf("failed to insert header %d: %v", n, err)
+	}
+	if n, err := chain.InsertReceiptChain(blocks, receipts, 8); err != nil {
+		t.Fatalf("failed to insert receipt %d: %v", n, err)
+	}
+	chain.Stop()
+
+	report, err := VerifyChain(db, nil)
+	if err != nil {
+		t.Fatalf("failed to verify chain: %v", err)
+	}
+	if len(report.Issues) != 0 {
+		t.Fatalf("consistent chain reported issues: %v", report.Issues)
+	}
+	if report.Head != 16 || report.Frozen != 9 || report.Checked != 17 {
+		t.Fatalf("report mismatch: head %d, frozen %d, checked %d", report.Head, report.Frozen, report.Checked)
+	}
+	// Damage derivable data, both for frozen and live blocks, and lose a body
+	rawdb.DeleteHeaderNumber(db, blocks[2].Hash())
+	rawdb.DeleteCanonicalHash(db, 10)
+	rawdb.DeleteTxLookupEntry(db, blocks[10].Transactions()[0].Hash())
+	rawdb.DeleteTd(db, blocks[11].Hash(), 12)
+	rawdb.DeleteBody(db, blocks[13].Hash(), 14)
+
+	if report, err = VerifyChain(db, nil); err != nil {
+		t.Fatalf("failed to verify chain: %v", err)
+	}
+	want := []struct {
+		number    uint64
+		kind      string
+		derivable bool
+	}{
+		{3, IssueHeaderNumber, true},
+		{10, IssueCanonicalHash, true},
+		{11, IssueTxLookup, true},
+		{12, IssueTd, true},
+		{14, IssueBody, false},
+	}
+	if len(report.Issues) != len(want) {
+		t.Fatalf("issue count mismatch: have %d, want %d: %v", len(report.Issues), len(want), report.Issues)
+	}
+	for i, issue := range report.Issues {
+		if issue.Number != want[i].number || issue.Kind != want[i].kind || issue.Derivable != want[i].derivable {
+			t.Errorf("issue %d mismatch: have %d/%s/%v, want %d/%s/%v", i, issue.Number, issue.Kind, issue.Derivable, want[i].number, want[i].kind, want[i].derivable)
+		}
+		if issue.Hash != blocks[issue.Number-1].Hash() {
+			t.Errorf("issue %d hash mismatch: have %x, want %x", i, issue.Hash, blocks[issue.Number-1].Hash())
+		}
+	}
+	if number, corrupted := report.Corrupted(); !corrupted || number != 14 {
+		t.Fatalf("corrupted block mismatch: have %d/%v, want 14", number, corrupted)
+	}
+	// Repair the chain, it should be rewound to the block before the lost body
+	repair, err := RepairChain(db, report)
+	if err != nil {
+		t.Fatalf("failed to repair chain: %v", err)
+	}
+	if !repair.Rewound || repair.Head != 13 {
+		t.Fatalf("repair mismatch: rewound %v, head %d", repair.Rewound, repair.Head)
+	}
+	for _, kind := range []string{IssueHeaderNumber, IssueCanonicalHash, IssueTxLookup, IssueTd} {
+		if repair.Rebuilt[kind] != 1 {
+			t.Errorf("rebuilt %s count mismatch: have %d, want 1", kind, repair.Rebuilt[kind])
+		}
+	}
+	if report, err = VerifyChain(db, nil); err != nil {
+		t.Fatalf("failed to verify chain: %v", err)
+	}
+	if len(report.Issues) != 0 {
+		t.Fatalf("repaired chain reported issues: %v", report.Issues)
+	}
+	if report.Head != 13 {
+		t.Fatalf("head mismatch: have %d, want 13", report.Head)
+	}
+	if hash := rawdb.ReadHeadFastBlockHash(db); hash != blocks[12].Hash() {
+		t.Fatalf("head fast block mismatch: have %x, want %x", hash, blocks[12].Hash())
+	}
+}
+
+// Tests that headers synced ahead of the head (fast) block are not reported as
+// missing their bodies and receipts, so a healthy syncing node is not rewound.
+func TestVerifyChainAheadOfBodies(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		funds   = big.NewInt(100000000000000000)
+		gspec   = &Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   GenesisAlloc{address: {Balance: funds}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, receipts := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 16, func(i int, block *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0x00}, big.NewInt(1000), params.TxGas, block.header.BaseFee, nil), signer, key)
+		if err != nil {
+			panic(err)
+		}
+		block.AddTx(tx)
+	})
+	db, _ := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	defer db.Close()
+
+	// Import all the headers, but only the first half of the bodies and receipts
+	chain, err := NewBlockChain(db, nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	headers := make([]*types.Header, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header()
+	}
+	if n, err := chain.InsertHeaderChain(headers); err != nil {
+		t.Fatalf("failed to insert header %d: %v", n, err)
+	}
+	if n, err := chain.InsertReceiptChain(blocks[:8], receipts[:8], 4); err != nil {
+		t.Fatalf("failed to insert receipt %d: %v", n, err)
+	}
+	chain.Stop()
+
+	report, err := VerifyChain(db, nil)
+	if err != nil {
+		t.Fatalf("failed to verify chain: %v", err)
+	}
+	if len(report.Issues) != 0 {
+		t.Fatalf("syncing chain reported issues: %v", report.Issues)
+	}
+	if report.Head != 16 || report.BodyHead != 8 {
+		t.Fatalf("report mismatch: head %d, body head %d", report.Head, report.BodyHead)
+	}
+	repair, err := RepairChain(db, report)
+	if err != nil {
+		t.Fatalf("failed to repair chain: %v", err)
+	}
+	if repair.Rewound {
+		t.Fatalf("healthy syncing chain rewound to %d", repair.Head)
+	}
+}
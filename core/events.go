@@ -18,12 +18,47 @@ package core
 
 import (
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 )
 
 // NewTxsEvent is posted when a batch of transactions enter the transaction pool.
 type NewTxsEvent struct{ Txs []*types.Transaction }
 
+// TxLifecycle is the kind of state change a transaction went through in the
+// transaction pool.
+type TxLifecycle string
+
+const (
+	TxAdded    TxLifecycle = "added"    // Accepted into the pool as non-executable
+	TxPromoted TxLifecycle = "promoted" // Moved into the executable set
+	TxReplaced TxLifecycle = "replaced" // Superseded by another transaction with the same nonce
+	TxDemoted  TxLifecycle = "demoted"  // Moved back into the non-executable set
+	TxEvicted  TxLifecycle = "evicted"  // Dropped from the pool without being included
+	TxIncluded TxLifecycle = "included" // Removed from the pool after being included in a block
+)
+
+// TxEvictReason is the reason for a transaction being evicted from the pool.
+type TxEvictReason string
+
+const (
+	TxEvictUnderpriced TxEvictReason = "underpriced" // Outbid by better paying transactions or below the price limit
+	TxEvictCapacity    TxEvictReason = "capacity"    // Exceeding the per-account or global slot limits
+	TxEvictLifetime    TxEvictReason = "lifetime"    // Stayed in the pool for longer than permitted
+	TxEvictUnpayable   TxEvictReason = "unpayable"   // Sender can no longer pay for it or it exceeds the block gas limit
+	TxEvictStale       TxEvictReason = "stale"       // Nonce used up by a transaction not known to the pool
+)
+
+// TxPoolEvent is a single lifecycle change of a transaction in the pool.
+type TxPoolEvent struct {
+	Type        TxLifecycle
+	Tx          *types.Transaction
+	Replacement common.Hash   // Hash of the replacing transaction for TxReplaced, empty if private
+	Reason      TxEvictReason // Reason of the eviction for TxEvicted
+	BlockHash   common.Hash   // Hash of the including block for TxIncluded
+	BlockNumber uint64        // Number of the including block for TxIncluded
+}
+
 // NewMinedBlockEvent is posted when a block has been imported.
 type NewMinedBlockEvent struct{ Block *types.Block }
 
@@ -41,3 +76,11 @@ type ChainSideEvent struct {
 }
 
 type ChainHeadEvent struct{ Block *types.Block }
+
+// StateDiffEvent is posted when a block becomes canonical, carrying the state
+// changes made by the block.
+type StateDiffEvent struct {
+	Block *types.Block
+	Hash  common.Hash
+	Diff  state.StateDiff
+}
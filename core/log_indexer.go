@@ -0,0 +1,281 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// logIndexThrottling is the time to wait between processing two consecutive
+	// log index sections.
+	logIndexThrottling = 10 * time.Millisecond
+
+	// logPositionSize is the size of an encoded log position: the offset of the
+	// block within the section and the index of the log within the block, both
+	// as uint32 big endian.
+	logPositionSize = 8
+)
+
+// LogIndexer implements a core.ChainIndexer, building up an index of the logs
+// of the canonical chain by address and topic. For every section and address or
+// topic it stores the positions of the logs containing it, so log filters can
+// look up matching logs directly instead of checking every block.
+//
+// Reorgs are handled by the chain indexer, which reprocesses any reorged section,
+// replacing all its entries. Sections older than the history window are pruned.
+type LogIndexer struct {
+	db      ethdb.Database    // database instance to write index data and metadata into
+	size    uint64            // section size to generate the log index for
+	history uint64            // number of recent blocks to index, 0 for the entire chain
+	section uint64            // section number being processed currently
+	skip    bool              // whether the current section is outside the indexed range
+	entries map[string][]byte // log positions of the current section by kind and value
+}
+
+// NewLogIndexer returns a chain indexer that generates the log index for the
+// canonical chain, covering the given number of recent blocks or the entire
+// chain if history is zero.
+func NewLogIndexer(db ethdb.Database, size, confirms, history uint64) *ChainIndexer {
+	backend := &LogIndexer{
+		db:      db,
+		size:    size,
+		history: history,
+	}
+	table := rawdb.NewTable(db, string(rawdb.LogIndexTablePrefix))
+
+	return NewChainIndexer(db, table, backend, size, confirms, logIndexThrottling, "logindex")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new log index section.
+func (b *LogIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	b.section, b.entries = section, make(map[string][]byte)
+	b.skip = !b.indexable(section)
+	return nil
+}
+
+// indexable reports whether the section lies within the history window and all
+// its receipts are still available.
+func (b *LogIndexer) indexable(section uint64) bool {
+	first := section * b.size
+	if tail, err := b.db.Tail(); err == nil && first < tail {
+		return false
+	}
+	if b.history == 0 {
+		return true
+	}
+	head := rawdb.ReadHeaderNumber(b.db, rawdb.ReadHeadHeaderHash(b.db))
+	return head == nil || first+b.size+b.history > *head+1
+}
+
+// Process implements core.ChainIndexerBackend, adding the logs of a new header
+// into the index.
+func (b *LogIndexer) Process(ctx context.Context, header *types.Header) error {
+	if b.skip || header.Bloom == (types.Bloom{}) {
+		return nil
+	}
+	number := header.Number.Uint64()
+	receipts := rawdb.ReadRawReceipts(b.db, header.Hash(), number)
+	if receipts == nil {
+		return fmt.Errorf("receipts of block #%d missing", number)
+	}
+	var (
+		pos   [logPositionSize]byte
+		index uint32
+	)
+	binary.BigEndian.PutUint32(pos[:4], uint32(number-b.section*b.size))
+	for _, receipt := range receipts {
+		for _, l := range receipt.Logs {
+			binary.BigEndian.PutUint32(pos[4:], index)
+			b.add(rawdb.LogIndexAddress, l.Address.Bytes(), pos[:])
+			for i, topic := range l.Topics {
+				if i < rawdb.LogIndexTopics {
+					b.add(byte(i), topic.Bytes(), pos[:])
+				}
+			}
+			index++
+		}
+	}
+	return nil
+}
+
+// add appends a log position to the entry of the given address or topic.
+func (b *LogIndexer) add(kind byte, value []byte, pos []byte) {
+	key := string(append([]byte{kind}, value...))
+	b.entries[key] = append(b.entries[key], pos...)
+}
+
+// Commit implements core.ChainIndexerBackend, finalizing the log index section
+// and writing it out into the database.
+func (b *LogIndexer) Commit() error {
+	if b.skip {
+		return nil
+	}
+	// Drop any entries of a previously indexed, reorged version of the section
+	rawdb.DeleteLogIndexSections(b.db, b.section, b.section+1)
+
+	batch := b.db.NewBatch()
+	for key, positions := range b.entries {
+		rawdb.WriteLogIndexEntry(batch, b.section, key[0], []byte(key[1:]), positions)
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	first := b.section * b.size
+	if tail := rawdb.ReadLogIndexTail(b.db); tail == nil || *tail > first {
+		rawdb.WriteLogIndexTail(batch, first)
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	// Prune the sections which left the history window
+	if last := first + b.size - 1; b.history > 0 && last+1 > b.history {
+		return b.Prune(last + 1 - b.history)
+	}
+	return nil
+}
+
+// Prune implements core.ChainIndexerBackend, deleting all sections of the log
+// index older than the given threshold.
+func (b *LogIndexer) Prune(threshold uint64) error {
+	tail := rawdb.ReadLogIndexTail(b.db)
+	if tail == nil {
+		return nil
+	}
+	from, to := *tail/b.size, threshold/b.size
+	if from >= to {
+		return nil
+	}
+	rawdb.DeleteLogIndexSections(b.db, from, to)
+	rawdb.WriteLogIndexTail(b.db, to*b.size)
+	log.Debug("Pruned log index", "from", from*b.size, "to", to*b.size)
+	return nil
+}
+
+// LogIndexMatch is the position of a log found in the log index.
+type LogIndexMatch struct {
+	Number uint64 // Number of the block containing the log
+	Index  uint   // Index of the log within the block
+}
+
+// MatchLogIndex looks up the logs of a log index section matching the filter
+// criteria, returning their positions ordered by block and log index. At least
+// one address or topic must be given, wildcard queries can't use the index.
+func MatchLogIndex(db ethdb.KeyValueReader, size, section uint64, addresses []common.Address, topics [][]common.Hash) []LogIndexMatch {
+	var (
+		positions []uint64
+		first     = true
+	)
+	intersect := func(set []uint64) bool {
+		if first {
+			positions, first = set, false
+		} else {
+			positions = intersectLogPositions(positions, set)
+		}
+		return len(positions) > 0
+	}
+	if len(addresses) > 0 {
+		var set []uint64
+		for _, addr := range addresses {
+			set = unionLogPositions(set, decodeLogPositions(rawdb.ReadLogIndexEntry(db, section, rawdb.LogIndexAddress, addr.Bytes())))
+		}
+		if !intersect(set) {
+			return nil
+		}
+	}
+	for i, sub := range topics {
+		if len(sub) == 0 {
+			continue // wildcard
+		}
+		if i >= rawdb.LogIndexTopics {
+			return nil // logs have at most four topics
+		}
+		var set []uint64
+		for _, topic := range sub {
+			set = unionLogPositions(set, decodeLogPositions(rawdb.ReadLogIndexEntry(db, section, byte(i), topic.Bytes())))
+		}
+		if !intersect(set) {
+			return nil
+		}
+	}
+	matches := make([]LogIndexMatch, len(positions))
+	for i, pos := range positions {
+		matches[i] = LogIndexMatch{
+			Number: section*size + pos>>32,
+			Index:  uint(uint32(pos)),
+		}
+	}
+	return matches
+}
+
+// decodeLogPositions decodes the positions stored in a log index entry. As the
+// block offset precedes the log index, positions compare like the logs.
+func decodeLogPositions(data []byte) []uint64 {
+	positions := make([]uint64, 0, len(data)/logPositionSize)
+	for ; len(data) >= logPositionSize; data = data[logPositionSize:] {
+		positions = append(positions, binary.BigEndian.Uint64(data))
+	}
+	return positions
+}
+
+// unionLogPositions merges two ordered position lists.
+func unionLogPositions(a, b []uint64) []uint64 {
+	if len(a) == 0 {
+		return b
+	}
+	union := make([]uint64, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		switch {
+		case a[0] < b[0]:
+			union, a = append(union, a[0]), a[1:]
+		case a[0] > b[0]:
+			union, b = append(union, b[0]), b[1:]
+		default:
+			union, a, b = append(union, a[0]), a[1:], b[1:]
+		}
+	}
+	union = append(union, a...)
+	return append(union, b...)
+}
+
+// intersectLogPositions returns the positions contained in both ordered lists.
+func intersectLogPositions(a, b []uint64) []uint64 {
+	var intersection []uint64
+	for len(a) > 0 && len(b) > 0 {
+		switch {
+		case a[0] < b[0]:
+			a = a[1:]
+		case a[0] > b[0]:
+			b = b[1:]
+		default:
+			intersection, a, b = append(intersection, a[0]), a[1:], b[1:]
+		}
+	}
+	return intersection
+}
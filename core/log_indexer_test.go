@@ -0,0 +1,118 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// writeLogIndexBlock stores a block header with a single receipt holding the
+// given logs and returns the header.
+func writeLogIndexBlock(db ethdb.Database, number uint64, extra byte, logs []*types.Log) *types.Header {
+	receipts := types.Receipts{{Status: types.ReceiptStatusSuccessful, Logs: logs}}
+	header := &types.Header{Number: new(big.Int).SetUint64(number), Extra: []byte{extra}, Bloom: types.CreateBloom(receipts)}
+	rawdb.WriteHeader(db, header)
+	rawdb.WriteReceipts(db, header.Hash(), number, receipts)
+	return header
+}
+
+// indexLogSection runs the log indexer over a section of the given headers.
+func indexLogSection(t *testing.T, b *LogIndexer, section uint64, headers []*types.Header) {
+	if err := b.Reset(context.Background(), section, common.Hash{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, header := range headers {
+		if err := b.Process(context.Background(), header); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLogIndexer(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		indexer = &LogIndexer{db: db, size: 4}
+
+		addr1, addr2   = common.HexToAddress("0x1"), common.HexToAddress("0x2")
+		topic1, topic2 = common.HexToHash("0x01"), common.HexToHash("0x02")
+	)
+	indexLogSection(t, indexer, 0, []*types.Header{
+		writeLogIndexBlock(db, 0, 0, nil),
+		writeLogIndexBlock(db, 1, 0, []*types.Log{{Address: addr1, Topics: []common.Hash{topic1}}, {Address: addr2, Topics: []common.Hash{topic2, topic1}}}),
+		writeLogIndexBlock(db, 2, 0, nil),
+		writeLogIndexBlock(db, 3, 0, []*types.Log{{Address: addr2, Topics: []common.Hash{topic1}}}),
+	})
+	for i, tt := range []struct {
+		addresses []common.Address
+		topics    [][]common.Hash
+		want      []LogIndexMatch
+	}{
+		{[]common.Address{addr1}, nil, []LogIndexMatch{{1, 0}}},
+		{[]common.Address{addr1, addr2}, nil, []LogIndexMatch{{1, 0}, {1, 1}, {3, 0}}},
+		{nil, [][]common.Hash{{topic1}}, []LogIndexMatch{{1, 0}, {3, 0}}},
+		{nil, [][]common.Hash{nil, {topic1}}, []LogIndexMatch{{1, 1}}},
+		{[]common.Address{addr2}, [][]common.Hash{{topic1, topic2}}, []LogIndexMatch{{1, 1}, {3, 0}}},
+		{[]common.Address{addr1}, [][]common.Hash{{topic2}}, nil},
+	} {
+		if have := MatchLogIndex(db, 4, 0, tt.addresses, tt.topics); !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: matches mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+	// Reindexing a reorged section replaces all its entries
+	indexLogSection(t, indexer, 0, []*types.Header{
+		writeLogIndexBlock(db, 0, 1, nil),
+		writeLogIndexBlock(db, 1, 1, nil),
+		writeLogIndexBlock(db, 2, 1, []*types.Log{{Address: addr2, Topics: []common.Hash{topic2}}}),
+		writeLogIndexBlock(db, 3, 1, nil),
+	})
+	if have := MatchLogIndex(db, 4, 0, []common.Address{addr1}, nil); len(have) != 0 {
+		t.Errorf("stale entries after reorg: %v", have)
+	}
+	if have := MatchLogIndex(db, 4, 0, []common.Address{addr2}, nil); !reflect.DeepEqual(have, []LogIndexMatch{{2, 0}}) {
+		t.Errorf("reorged entries mismatch: %v", have)
+	}
+	// Sections leaving the history window are pruned
+	indexer.history = 4
+	indexLogSection(t, indexer, 1, []*types.Header{
+		writeLogIndexBlock(db, 4, 0, nil),
+		writeLogIndexBlock(db, 5, 0, []*types.Log{{Address: addr2}}),
+		writeLogIndexBlock(db, 6, 0, nil),
+		writeLogIndexBlock(db, 7, 0, nil),
+	})
+	if tail := rawdb.ReadLogIndexTail(db); tail == nil {
+		t.Fatal("log index tail missing")
+	} else if *tail != 4 {
+		t.Fatalf("log index tail mismatch: have %d, want 4", *tail)
+	}
+	if have := MatchLogIndex(db, 4, 0, []common.Address{addr2}, nil); len(have) != 0 {
+		t.Errorf("pruned section still indexed: %v", have)
+	}
+	if have := MatchLogIndex(db, 4, 1, []common.Address{addr2}, nil); !reflect.DeepEqual(have, []LogIndexMatch{{5, 0}}) {
+		t.Errorf("section 1 entries mismatch: %v", have)
+	}
+}
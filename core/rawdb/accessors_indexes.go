@@ -18,6 +18,7 @@ package rawdb
 
 import (
 	"bytes"
+	"encoding/binary"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -159,6 +160,92 @@ func WriteBloomBits(db ethdb.KeyValueWriter, bit uint, section uint64, head comm
 	}
 }
 
+// Kinds of log index entries. Topics are indexed by their position within the
+// log, the kind of a topic entry is its position.
+const (
+	LogIndexTopics  = 4    // Number of indexed topic positions
+	LogIndexAddress = 0xff // Kind of the entries indexing log addresses
+)
+
+// ReadLogIndexEntry retrieves the positions of the logs in the given log index
+// section which contain the value, an address or topic depending on the kind.
+func ReadLogIndexEntry(db ethdb.KeyValueReader, section uint64, kind byte, value []byte) []byte {
+	data, _ := db.Get(logIndexKey(section, kind, value))
+	return data
+}
+
+// WriteLogIndexEntry stores the positions of the logs in the given log index
+// section which contain the value.
+func WriteLogIndexEntry(db ethdb.KeyValueWriter, section uint64, kind byte, value []byte, positions []byte) {
+	if err := db.Put(logIndexKey(section, kind, value), positions); err != nil {
+		log.Crit("Failed to store log index entry", "err", err)
+	}
+}
+
+// DeleteLogIndexSections removes all log index entries of the sections in the
+// range [from, to).
+func DeleteLogIndexSections(db ethdb.KeyValueStore, from uint64, to uint64) {
+	it := db.NewIterator(logIndexPrefix, encodeBlockNumber(from))
+	defer it.Release()
+
+	end := logIndexSectionKey(to)
+	for it.Next() {
+		if bytes.Compare(it.Key(), end) >= 0 {
+			break
+		}
+		if err := db.Delete(it.Key()); err != nil {
+			log.Crit("Failed to delete log index entry", "err", err)
+		}
+	}
+	if it.Error() != nil {
+		log.Crit("Failed to delete log index", "err", it.Error())
+	}
+}
+
+// DeleteLogIndex removes the entire log index, including the progress of the
+// chain indexer building it.
+func DeleteLogIndex(db ethdb.KeyValueStore) {
+	for _, prefix := range [][]byte{logIndexPrefix, LogIndexTablePrefix} {
+		it := db.NewIterator(prefix, nil)
+		for it.Next() {
+			if err := db.Delete(it.Key()); err != nil {
+				log.Crit("Failed to delete log index entry", "err", err)
+			}
+		}
+		if it.Error() != nil {
+			log.Crit("Failed to delete log index", "err", it.Error())
+		}
+		it.Release()
+	}
+	DeleteLogIndexTail(db)
+}
+
+// ReadLogIndexTail retrieves the number of the oldest block whose logs have been
+// indexed.
+func ReadLogIndexTail(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(logIndexTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteLogIndexTail stores the number of the oldest block whose logs have been
+// indexed.
+func WriteLogIndexTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(logIndexTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the log index tail", "err", err)
+	}
+}
+
+// DeleteLogIndexTail removes the log index tail marker.
+func DeleteLogIndexTail(db ethdb.KeyValueWriter) {
+	if err := db.Delete(logIndexTailKey); err != nil {
+		log.Crit("Failed to delete the log index tail", "err", err)
+	}
+}
+
 // DeleteBloombits removes all compressed bloom bits vector belonging to the
 // given section range and bit index.
 func DeleteBloombits(db ethdb.Database, bit uint, from uint64, to uint64) {
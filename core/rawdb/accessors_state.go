@@ -17,6 +17,8 @@
 package rawdb
 
 import (
+	"encoding/binary"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
@@ -92,3 +94,99 @@ func DeleteCode(db ethdb.KeyValueWriter, hash common.Hash) {
 		log.Crit("Failed to delete contract code", "err", err)
 	}
 }
+
+// ReadStateID retrieves the state id with the provided state root.
+func ReadStateID(db ethdb.KeyValueReader, root common.Hash) *uint64 {
+	data, err := db.Get(stateIDKey(root))
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteStateID writes the provided state lookup to database.
+func WriteStateID(db ethdb.KeyValueWriter, root common.Hash, id uint64) {
+	var buff [8]byte
+	binary.BigEndian.PutUint64(buff[:], id)
+	if err := db.Put(stateIDKey(root), buff[:]); err != nil {
+		log.Crit("Failed to store state ID", "err", err)
+	}
+}
+
+// DeleteStateID deletes the specified state lookup from the database.
+func DeleteStateID(db ethdb.KeyValueWriter, root common.Hash) {
+	if err := db.Delete(stateIDKey(root)); err != nil {
+		log.Crit("Failed to delete state ID", "err", err)
+	}
+}
+
+// ReadPersistentStateID retrieves the id of the persistent state from the database.
+func ReadPersistentStateID(db ethdb.KeyValueReader) uint64 {
+	data, _ := db.Get(persistentStateIDKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// WritePersistentStateID stores the id of the persistent state into database.
+func WritePersistentStateID(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(persistentStateIDKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the persistent state ID", "err", err)
+	}
+}
+
+// ReadTrieHistory retrieves the reverse trie diff which reverts the state with
+// the given id to its parent.
+func ReadTrieHistory(db ethdb.KeyValueReader, id uint64) []byte {
+	data, _ := db.Get(trieHistoryKey(id))
+	return data
+}
+
+// HasTrieHistory checks if the reverse trie diff with the given id is present.
+func HasTrieHistory(db ethdb.KeyValueReader, id uint64) bool {
+	ok, _ := db.Has(trieHistoryKey(id))
+	return ok
+}
+
+// WriteTrieHistory stores the reverse trie diff of the state with the given id.
+func WriteTrieHistory(db ethdb.KeyValueWriter, id uint64, blob []byte) {
+	if err := db.Put(trieHistoryKey(id), blob); err != nil {
+		log.Crit("Failed to store trie history", "err", err)
+	}
+}
+
+// DeleteTrieHistory deletes the reverse trie diff with the given id.
+func DeleteTrieHistory(db ethdb.KeyValueWriter, id uint64) {
+	if err := db.Delete(trieHistoryKey(id)); err != nil {
+		log.Crit("Failed to delete trie history", "err", err)
+	}
+}
+
+// ReadStateDiff retrieves the reverse state diff which reverts the state of the
+// given block to its parent.
+func ReadStateDiff(db ethdb.KeyValueReader, number uint64, hash common.Hash) []byte {
+	data, _ := db.Get(stateDiffKey(number, hash))
+	return data
+}
+
+// HasStateDiff checks if the reverse state diff of the given block is present.
+func HasStateDiff(db ethdb.KeyValueReader, number uint64, hash common.Hash) bool {
+	ok, _ := db.Has(stateDiffKey(number, hash))
+	return ok
+}
+
+// WriteStateDiff stores the reverse state diff of the given block.
+func WriteStateDiff(db ethdb.KeyValueWriter, number uint64, hash common.Hash, blob []byte) {
+	if err := db.Put(stateDiffKey(number, hash), blob); err != nil {
+		log.Crit("Failed to store state diff", "err", err)
+	}
+}
+
+// DeleteStateDiff deletes the reverse state diff of the given block.
+func DeleteStateDiff(db ethdb.KeyValueWriter, number uint64, hash common.Hash) {
+	if err := db.Delete(stateDiffKey(number, hash)); err != nil {
+		log.Crit("Failed to delete state diff", "err", err)
+	}
+}
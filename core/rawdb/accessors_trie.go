@@ -21,6 +21,7 @@ import (
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
@@ -261,3 +262,51 @@ func DeleteTrieNode(db ethdb.KeyValueWriter, owner common.Hash, path []byte, has
 		panic(fmt.Sprintf("Unknown scheme %v", scheme))
 	}
 }
+
+// ReadStateScheme reads the state scheme of the persistent state, or none if
+// the state is not present in the database yet.
+func ReadStateScheme(db ethdb.Reader) string {
+	// Check if the state in path-based scheme is present. The account trie root
+	// is always persisted, unless the entire state is empty, in which case the
+	// state lookups are checked as well.
+	if blob, _ := ReadAccountTrieNode(db, nil); len(blob) != 0 {
+		return PathScheme
+	}
+	if ReadPersistentStateID(db) != 0 || ReadStateID(db, types.EmptyRootHash) != nil {
+		return PathScheme
+	}
+	// In the hash-based scheme, the genesis state is always stored on disk, so
+	// it suffices to check the scheme of the genesis state.
+	header := ReadHeader(db, ReadCanonicalHash(db, 0), 0)
+	if header == nil {
+		return "" // empty datadir
+	}
+	if !HasLegacyTrieNode(db, header.Root) {
+		return "" // no state on disk
+	}
+	return HashScheme
+}
+
+// ParseStateScheme checks the requested state scheme against the one the
+// persistent state is already stored with, and returns the scheme to use. An
+// empty request selects the stored scheme, or the hash-based one for a fresh
+// database. Requesting a scheme different from the stored one is an error, as
+// there is no migration between the two.
+func ParseStateScheme(provided string, disk ethdb.Database) (string, error) {
+	if provided != "" && provided != HashScheme && provided != PathScheme {
+		return "", fmt.Errorf("unknown state scheme %q", provided)
+	}
+	stored := ReadStateScheme(disk)
+	if provided == "" {
+		if stored == "" {
+			log.Info("State scheme set to default", "scheme", HashScheme)
+			return HashScheme, nil
+		}
+		log.Info("State scheme set to already existing", "scheme", stored)
+		return stored, nil
+	}
+	if stored == "" || stored == provided {
+		return provided, nil
+	}
+	return "", fmt.Errorf("incompatible state scheme, stored: %s, provided: %s", stored, provided)
+}
@@ -18,9 +18,11 @@ package rawdb
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/metrics"
 )
 
 type tableSize struct {
@@ -91,9 +93,10 @@ func inspectFreezers(db ethdb.Database) ([]freezerInfo, error) {
 
 // InspectFreezerTable dumps out the index of a specific freezer table. The passed
 // ancient indicates the path of root ancient directory where the chain freezer can
-// be opened. Start and end specify the range for dumping out indexes.
+// be opened. Start and end specify the range for dumping out indexes. The cold
+// config needs to be passed if data files of the freezer have been offloaded.
 // Note this function can only be used for debugging purposes.
-func InspectFreezerTable(ancient string, freezerName string, tableName string, start, end int64) error {
+func InspectFreezerTable(ancient string, freezerName string, tableName string, start, end int64, coldConfig *ColdConfig) error {
 	var (
 		path   string
 		tables map[string]bool
@@ -112,7 +115,23 @@ func InspectFreezerTable(ancient string, freezerName string, tableName string, s
 		}
 		return fmt.Errorf("unknown table, supported ones: %v", names)
 	}
-	table, err := newFreezerTable(path, tableName, noSnappy, true)
+	// Data files offloaded into the cold tier can only be detected through the
+	// cold store. Cache them in a private directory to leave the one of a node
+	// running on the same freezer alone.
+	var cold *coldTier
+	if coldConfig != nil {
+		dir, err := os.MkdirTemp("", "coldcache")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+
+		if cold, err = newColdTier(dir, coldConfig); err != nil {
+			return err
+		}
+		defer cold.close()
+	}
+	table, err := openTable(path, tableName, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, freezerTableSize, noSnappy, true, cold)
 	if err != nil {
 		return err
 	}
@@ -51,9 +51,10 @@ type chainFreezer struct {
 	trigger chan chan struct{} // Manual blocking freeze trigger, test determinism
 }
 
-// newChainFreezer initializes the freezer for ancient chain data.
-func newChainFreezer(datadir string, namespace string, readonly bool) (*chainFreezer, error) {
-	freezer, err := NewChainFreezer(datadir, namespace, readonly)
+// newChainFreezer initializes the freezer for ancient chain data, optionally
+// with a cold tier for the sealed data files.
+func newChainFreezer(datadir string, namespace string, readonly bool, cold *ColdConfig) (*chainFreezer, error) {
+	freezer, err := newFreezer(datadir, namespace, readonly, freezerTableSize, chainFreezerNoSnappy, chainFreezerPrunable, cold)
 	if err != nil {
 		return nil, err
 	}
@@ -182,6 +183,11 @@ func (f *chainFreezer) freeze(db ethdb.KeyValueStore) {
 		}
 		batch.Reset()
 
+		// Move the sealed segments out of the local ancient store if configured
+		if err := f.offload(); err != nil {
+			log.Error("Failed to offload frozen segments", "err", err)
+		}
+
 		// Wipe out side chains also and track dangling side chains
 		var dangling []common.Hash
 		frozen = f.frozen.Load() // Needs reload after during freezeRange
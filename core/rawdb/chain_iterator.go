@@ -353,3 +353,31 @@ func UnindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt ch
 func unindexTransactionsForTesting(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool) {
 	unindexTransactions(db, from, to, interrupt, hook)
 }
+
+// PruneHistory expires the block bodies and receipts below the given cutoff
+// block by advancing the tail of the ancient store. Only frozen blocks can be
+// pruned, so the cutoff is capped at the number of ancient items. The tx
+// indices of the pruned blocks are retained, so that lookups of expired
+// transactions can still be attributed to the pruned history. The resulting
+// history tail is returned.
+func PruneHistory(db ethdb.Database, cutoff uint64) (uint64, error) {
+	frozen, err := db.Ancients()
+	if err != nil {
+		return 0, err
+	}
+	tail, err := db.Tail()
+	if err != nil {
+		return 0, err
+	}
+	if cutoff > frozen {
+		cutoff = frozen
+	}
+	if cutoff <= tail {
+		return tail, nil
+	}
+	if err := db.TruncateTail(cutoff); err != nil {
+		return tail, err
+	}
+	log.Info("Pruned chain history", "tail", cutoff, "pruned", cutoff-tail)
+	return cutoff, nil
+}
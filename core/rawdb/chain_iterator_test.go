@@ -206,3 +206,87 @@ func TestIndexTransactions(t *testing.T) {
 	verify(8, 11, true, 8)
 	verify(0, 8, false, 8)
 }
+
+func TestPruneHistory(t *testing.T) {
+	ancient := t.TempDir()
+	db, err := NewDatabaseWithFreezer(NewMemoryDatabase(), ancient, "", false)
+	if err != nil {
+		t.Fatalf("failed to create database with ancient backend: %v", err)
+	}
+
+	// Freeze a chain of ten blocks with a transaction each
+	var (
+		to       = common.BytesToAddress([]byte{0x11})
+		blocks   = []*types.Block{types.NewBlock(&types.Header{Number: big.NewInt(0)}, nil, nil, nil, newHasher())}
+		receipts = []types.Receipts{nil}
+		txs      = []*types.Transaction{nil}
+	)
+	for i := uint64(1); i <= 10; i++ {
+		tx := types.NewTx(&types.LegacyTx{Nonce: i, GasPrice: big.NewInt(11111), Gas: 1111, To: &to})
+		header := &types.Header{Number: big.NewInt(int64(i)), ParentHash: blocks[i-1].Hash()}
+		blocks = append(blocks, types.NewBlock(header, []*types.Transaction{tx}, nil, nil, newHasher()))
+		receipts = append(receipts, types.Receipts{{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{}}})
+		txs = append(txs, tx)
+	}
+	if _, err := WriteAncientBlocks(db, blocks, receipts, big.NewInt(1)); err != nil {
+		t.Fatalf("failed to write ancient blocks: %v", err)
+	}
+	IndexTransactions(db, 0, 11, nil)
+
+	// Prune the first five blocks and ensure only bodies and receipts are gone
+	tail, err := PruneHistory(db, 5)
+	if err != nil {
+		t.Fatalf("failed to prune history: %v", err)
+	}
+	if tail != 5 {
+		t.Fatalf("history tail mismatch: have %d, want 5", tail)
+	}
+	for i, block := range blocks {
+		number, hash := uint64(i), block.Hash()
+		if ReadHeader(db, hash, number) == nil {
+			t.Errorf("block %d: header missing", number)
+		}
+		if ReadCanonicalHash(db, number) != hash {
+			t.Errorf("block %d: canonical hash missing", number)
+		}
+		pruned := number < 5
+		if have := ReadBodyRLP(db, hash, number) == nil; have != pruned {
+			t.Errorf("block %d: body pruned mismatch: have %v, want %v", number, have, pruned)
+		}
+		if have := ReadReceiptsRLP(db, hash, number) == nil; have != pruned {
+			t.Errorf("block %d: receipts pruned mismatch: have %v, want %v", number, have, pruned)
+		}
+		if number > 0 {
+			if entry := ReadTxLookupEntry(db, txs[i].Hash()); entry == nil || *entry != number {
+				t.Errorf("block %d: tx index mismatch: have %v, want %d", number, entry, number)
+			}
+		}
+	}
+	if itail := ReadTxIndexTail(db); itail == nil || *itail != 0 {
+		t.Fatalf("tx index tail mismatch: have %v, want 0", itail)
+	}
+	// The cutoff is capped at the frozen blocks
+	if tail, err = PruneHistory(db, 100); err != nil {
+		t.Fatalf("failed to prune history: %v", err)
+	}
+	if tail != 11 {
+		t.Fatalf("history tail mismatch: have %d, want 11", tail)
+	}
+	if ReadHeaderRLP(db, blocks[10].Hash(), 10) == nil {
+		t.Fatal("head header pruned")
+	}
+	db.Close()
+
+	// Reopen the freezer, the unpruned tables must not be truncated by the repair
+	f, err := NewChainFreezer(resolveChainFreezerDir(ancient), "", false)
+	if err != nil {
+		t.Fatalf("failed to reopen freezer: %v", err)
+	}
+	defer f.Close()
+	if tail, _ := f.Tail(); tail != 11 {
+		t.Fatalf("freezer tail mismatch: have %d, want 11", tail)
+	}
+	if hash, err := f.Ancient(ChainFreezerHashTable, 0); err != nil || common.BytesToHash(hash) != blocks[0].Hash() {
+		t.Fatalf("genesis hash lost: %x, %v", hash, err)
+	}
+}
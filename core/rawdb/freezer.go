@@ -72,6 +72,8 @@ type Freezer struct {
 
 	readonly     bool
 	tables       map[string]*freezerTable // Data tables for storing everything
+	prunable     map[string]bool          // Tables affected by tail truncation, nil means all
+	cold         *coldTier                // Cold tier for offloading sealed data files, nil if disabled
 	instanceLock *flock.Flock             // File-system lock to prevent double opens
 	closeOnce    sync.Once
 }
@@ -79,7 +81,7 @@ type Freezer struct {
 // NewChainFreezer is a small utility method around NewFreezer that sets the
 // default parameters for the chain storage.
 func NewChainFreezer(datadir string, namespace string, readonly bool) (*Freezer, error) {
-	return NewFreezer(datadir, namespace, readonly, freezerTableSize, chainFreezerNoSnappy)
+	return newFreezer(datadir, namespace, readonly, freezerTableSize, chainFreezerNoSnappy, chainFreezerPrunable, nil)
 }
 
 // NewFreezer creates a freezer instance for maintaining immutable ordered
@@ -88,6 +90,13 @@ func NewChainFreezer(datadir string, namespace string, readonly bool) (*Freezer,
 // The 'tables' argument defines the data tables. If the value of a map
 // entry is true, snappy compression is disabled for the table.
 func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]bool) (*Freezer, error) {
+	return newFreezer(datadir, namespace, readonly, maxTableSize, tables, nil, nil)
+}
+
+// newFreezer creates a freezer instance like NewFreezer, additionally moving
+// the sealed data files into the cold store if one is configured. If prunable
+// is non-nil, only the listed tables are affected by tail truncations.
+func newFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]bool, prunable map[string]bool, coldConfig *ColdConfig) (*Freezer, error) {
 	// Create the initial freezer object
 	var (
 		readMeter  = metrics.NewRegisteredMeter(namespace+"ancient/read", nil)
@@ -117,12 +126,20 @@ func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize ui
 	freezer := &Freezer{
 		readonly:     readonly,
 		tables:       make(map[string]*freezerTable),
+		prunable:     prunable,
 		instanceLock: lock,
 	}
-
+	if coldConfig != nil {
+		cold, err := newColdTier(filepath.Join(datadir, "coldcache"), coldConfig)
+		if err != nil {
+			lock.Unlock()
+			return nil, err
+		}
+		freezer.cold = cold
+	}
 	// Create the tables.
 	for name, disableSnappy := range tables {
-		table, err := newTable(datadir, name, readMeter, writeMeter, sizeGauge, maxTableSize, disableSnappy, readonly)
+		table, err := openTable(datadir, name, readMeter, writeMeter, sizeGauge, maxTableSize, disableSnappy, readonly, freezer.cold)
 		if err != nil {
 			for _, table := range freezer.tables {
 				table.Close()
@@ -168,6 +185,9 @@ func (f *Freezer) Close() error {
 				errs = append(errs, err)
 			}
 		}
+		if f.cold != nil {
+			f.cold.close()
+		}
 		if err := f.instanceLock.Unlock(); err != nil {
 			errs = append(errs, err)
 		}
@@ -304,7 +324,10 @@ func (f *Freezer) TruncateTail(tail uint64) error {
 	if f.tail.Load() >= tail {
 		return nil
 	}
-	for _, table := range f.tables {
+	for kind, table := range f.tables {
+		if !f.isPrunable(kind) {
+			continue
+		}
 		if err := table.truncateTail(tail); err != nil {
 			return err
 		}
@@ -327,6 +350,29 @@ func (f *Freezer) Sync() error {
 	return nil
 }
 
+// offload moves the sealed data files of all tables into the cold tier, apart
+// from the ones holding the most recent items within the configured threshold.
+func (f *Freezer) offload() error {
+	if f.cold == nil || f.readonly {
+		return nil
+	}
+	frozen := f.frozen.Load()
+	if frozen <= f.cold.threshold {
+		return nil
+	}
+	for _, table := range f.tables {
+		if err := table.offload(frozen - f.cold.threshold); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isPrunable reports whether the given table is affected by tail truncations.
+func (f *Freezer) isPrunable(kind string) bool {
+	return f.prunable == nil || f.prunable[kind]
+}
+
 // validate checks that every table has the same boundary.
 // Used instead of `repair` in readonly mode.
 func (f *Freezer) validate() error {
@@ -334,24 +380,31 @@ func (f *Freezer) validate() error {
 		return nil
 	}
 	var (
-		head uint64
-		tail uint64
-		name string
+		head     uint64
+		tail     uint64
+		name     string
+		tailName string
 	)
 	// Hack to get boundary of any table
 	for kind, table := range f.tables {
 		head = table.items.Load()
-		tail = table.itemHidden.Load()
 		name = kind
 		break
 	}
+	for kind, table := range f.tables {
+		if f.isPrunable(kind) {
+			tail = table.itemHidden.Load()
+			tailName = kind
+			break
+		}
+	}
 	// Now check every table against those boundaries.
 	for kind, table := range f.tables {
 		if head != table.items.Load() {
 			return fmt.Errorf("freezer tables %s and %s have differing head: %d != %d", kind, name, table.items.Load(), head)
 		}
-		if tail != table.itemHidden.Load() {
-			return fmt.Errorf("freezer tables %s and %s have differing tail: %d != %d", kind, name, table.itemHidden.Load(), tail)
+		if f.isPrunable(kind) && tail != table.itemHidden.Load() {
+			return fmt.Errorf("freezer tables %s and %s have differing tail: %d != %d", kind, tailName, table.itemHidden.Load(), tail)
 		}
 	}
 	f.frozen.Store(head)
@@ -365,20 +418,23 @@ func (f *Freezer) repair() error {
 		head = uint64(math.MaxUint64)
 		tail = uint64(0)
 	)
-	for _, table := range f.tables {
+	for kind, table := range f.tables {
 		items := table.items.Load()
 		if head > items {
 			head = items
 		}
 		hidden := table.itemHidden.Load()
-		if hidden > tail {
+		if f.isPrunable(kind) && hidden > tail {
 			tail = hidden
 		}
 	}
-	for _, table := range f.tables {
+	for kind, table := range f.tables {
 		if err := table.truncateHead(head); err != nil {
 			return err
 		}
+		if !f.isPrunable(kind) {
+			continue
+		}
 		if err := table.truncateTail(tail); err != nil {
 			return err
 		}
@@ -0,0 +1,517 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/log"
+	"golang.org/x/sync/singleflight"
+)
+
+// errColdNotFound is returned by cold stores if the requested segment is unknown.
+var errColdNotFound = errors.New("cold segment not found")
+
+// defaultColdCacheSegments is the number of cold segments kept locally if the
+// configuration doesn't specify it.
+const defaultColdCacheSegments = 2
+
+// ColdStore is a secondary storage for sealed freezer data files. Segments are
+// written once and never modified, so the store only has to support whole
+// object uploads and downloads.
+type ColdStore interface {
+	// Put uploads the segment with the given name and size.
+	Put(name string, r io.Reader, size int64) error
+
+	// Get opens the segment with the given name for reading.
+	Get(name string) (io.ReadCloser, error)
+
+	// Has reports whether the segment with the given name is stored.
+	Has(name string) (bool, error)
+
+	// Delete removes the segment with the given name. Deleting a missing
+	// segment is not an error.
+	Delete(name string) error
+}
+
+// ColdConfig contains the settings of the cold tier of the chain freezer.
+type ColdConfig struct {
+	Store     ColdStore // Secondary store to move sealed segments into
+	Threshold uint64    // Number of recent frozen items whose segments are kept locally
+	Cache     int       // Number of cold segments cached locally for reading
+}
+
+// OpenColdStore opens a cold store identified by the given location. Supported
+// are plain directories (optionally with the file:// scheme) and S3-compatible
+// buckets in the form of s3://bucket/prefix. The S3 endpoint and region can be
+// overridden via the endpoint and region query parameters, the credentials are
+// read from the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY variables.
+func OpenColdStore(location string) (ColdStore, error) {
+	if !strings.Contains(location, "://") {
+		return NewDirColdStore(location)
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "file":
+		return NewDirColdStore(u.Path)
+	case "s3":
+		if u.Host == "" {
+			return nil, errors.New("missing bucket in cold store location")
+		}
+		var (
+			query    = u.Query()
+			region   = query.Get("region")
+			endpoint = query.Get("endpoint")
+		)
+		if region == "" {
+			region = "us-east-1"
+		}
+		if endpoint == "" {
+			endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+		}
+		return NewS3ColdStore(endpoint, region, u.Host, strings.Trim(u.Path, "/"), os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"))
+	default:
+		return nil, fmt.Errorf("unsupported cold store scheme %q", u.Scheme)
+	}
+}
+
+// dirColdStore is a cold store keeping the segments in a plain directory, e.g.
+// on a network mount or a cheaper disk.
+type dirColdStore struct {
+	dir string
+}
+
+// NewDirColdStore creates a cold store backed by the given directory.
+func NewDirColdStore(dir string) (ColdStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &dirColdStore{dir: dir}, nil
+}
+
+// Put implements ColdStore, writing the segment to a temporary file first so
+// that partial uploads are never visible.
+func (s *dirColdStore) Put(name string, r io.Reader, size int64) error {
+	tmp, err := os.CreateTemp(s.dir, name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if n, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	} else if n != size {
+		tmp.Close()
+		return fmt.Errorf("segment %s size mismatch: have %d, want %d", name, n, size)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(s.dir, name))
+}
+
+// Get implements ColdStore.
+func (s *dirColdStore) Get(name string) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(s.dir, name))
+	if os.IsNotExist(err) {
+		return nil, errColdNotFound
+	}
+	return f, err
+}
+
+// Has implements ColdStore.
+func (s *dirColdStore) Has(name string) (bool, error) {
+	_, err := os.Stat(filepath.Join(s.dir, name))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Delete implements ColdStore.
+func (s *dirColdStore) Delete(name string) error {
+	err := os.Remove(filepath.Join(s.dir, name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// coldTier wraps a cold store with a local cache of the recently read segments.
+// It's shared by all tables of a freezer.
+type coldTier struct {
+	store     ColdStore
+	threshold uint64
+	dir       string // Directory of the locally cached segments
+	limit     int    // Maximum number of cached segments
+
+	files   lru.BasicLRU[string, *coldFile]
+	seq     uint64     // Counter to name the cached segment files uniquely
+	lock    sync.Mutex // Protects the cache, reads run outside of it
+	fetches singleflight.Group
+}
+
+// coldFile is a locally cached cold segment. Files are reference counted, so
+// that segments evicted from the cache are only closed after the running reads.
+type coldFile struct {
+	file    *os.File
+	refs    int  // Number of running reads
+	evicted bool // Whether the file was dropped from the cache
+}
+
+// newColdTier creates the cold tier of a freezer, caching the segments in the
+// given directory. The local cache is wiped since it's populated on demand anyway.
+func newColdTier(dir string, config *ColdConfig) (*coldTier, error) {
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	limit := config.Cache
+	if limit <= 0 {
+		limit = defaultColdCacheSegments
+	}
+	return &coldTier{
+		store:     config.Store,
+		threshold: config.Threshold,
+		dir:       dir,
+		limit:     limit,
+		files:     lru.NewBasicLRU[string, *coldFile](limit),
+	}, nil
+}
+
+// has reports whether the given segment is stored in the cold store.
+func (c *coldTier) has(name string) (bool, error) {
+	return c.store.Has(name)
+}
+
+// readAt reads len(buf) bytes from the given cold segment at the given offset,
+// downloading the segment into the local cache if needed. Concurrent misses of
+// the same segment share a single download.
+func (c *coldTier) readAt(name string, buf []byte, offset int64) error {
+	f, err := c.acquire(name)
+	if err != nil {
+		return err
+	}
+	defer c.release(f)
+
+	_, err = f.file.ReadAt(buf, offset)
+	return err
+}
+
+// acquire retrieves the given segment from the local cache, downloading it if
+// it's missing, and marks it as being read.
+func (c *coldTier) acquire(name string) (*coldFile, error) {
+	for {
+		c.lock.Lock()
+		if f, ok := c.files.Get(name); ok {
+			f.refs++
+			c.lock.Unlock()
+			return f, nil
+		}
+		c.lock.Unlock()
+
+		res, err, _ := c.fetches.Do(name, func() (interface{}, error) {
+			return c.fetch(name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		// The downloaded file might have been evicted and closed already if
+		// the cache is thrashing, retry in that case
+		f := res.(*coldFile)
+
+		c.lock.Lock()
+		if f.file != nil {
+			f.refs++
+			c.lock.Unlock()
+			return f, nil
+		}
+		c.lock.Unlock()
+	}
+}
+
+// fetch downloads the given segment into the local cache.
+func (c *coldTier) fetch(name string) (*coldFile, error) {
+	c.lock.Lock()
+	if f, ok := c.files.Peek(name); ok {
+		c.lock.Unlock()
+		return f, nil
+	}
+	c.seq++
+	path := filepath.Join(c.dir, fmt.Sprintf("%s.%d", name, c.seq))
+	c.lock.Unlock()
+
+	if err := c.download(name, path); err != nil {
+		return nil, err
+	}
+	file, err := openFreezerFileForReadOnly(path)
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	f := &coldFile{file: file}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.files.Len() >= c.limit {
+		_, old, _ := c.files.RemoveOldest()
+		c.drop(old)
+	}
+	c.files.Add(name, f)
+	return f, nil
+}
+
+// release marks a read of the given cached segment as finished, deleting the
+// segment if it was evicted in the meantime.
+func (c *coldTier) release(f *coldFile) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if f.refs--; f.refs == 0 && f.evicted {
+		c.closeFile(f)
+	}
+}
+
+// drop marks the given segment as evicted from the cache, deleting it right
+// away if it's not being read. The lock must be held.
+func (c *coldTier) drop(f *coldFile) {
+	f.evicted = true
+	if f.refs == 0 {
+		c.closeFile(f)
+	}
+}
+
+// closeFile closes and deletes the given cached segment. The lock must be held.
+func (c *coldTier) closeFile(f *coldFile) {
+	if f.file == nil {
+		return
+	}
+	f.file.Close()
+	os.Remove(f.file.Name())
+	f.file = nil
+}
+
+// upload copies the given local segment into the cold store.
+func (c *coldTier) upload(name string, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return c.store.Put(name, f, stat.Size())
+}
+
+// download fetches the given cold segment into the given local path.
+func (c *coldTier) download(name string, path string) error {
+	r, err := c.store.Get(name)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve cold segment %s: %w", name, err)
+	}
+	defer r.Close()
+
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to retrieve cold segment %s: %w", name, err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// restore moves the given cold segment back into the given local path, used
+// if a cold segment needs to be modified again.
+func (c *coldTier) restore(name string, path string) error {
+	c.evict(name)
+	if err := c.download(name, path); err != nil {
+		return err
+	}
+	return c.store.Delete(name)
+}
+
+// remove deletes the given segment from the cold store and the local cache.
+func (c *coldTier) remove(name string) {
+	c.evict(name)
+	if err := c.store.Delete(name); err != nil {
+		log.Warn("Failed to delete cold segment", "name", name, "err", err)
+	}
+}
+
+// evict drops the given segment from the local cache.
+func (c *coldTier) evict(name string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if f, ok := c.files.Peek(name); ok {
+		c.files.Remove(name)
+		c.drop(f)
+	}
+}
+
+// close releases all cached segments.
+func (c *coldTier) close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, name := range c.files.Keys() {
+		f, _ := c.files.Peek(name)
+		c.closeFile(f)
+	}
+	c.files.Purge()
+}
+
+// isCold reports whether the given data file of the table has been offloaded
+// into the cold tier, i.e. it's missing locally but present in the cold store.
+func (t *freezerTable) isCold(num uint32) (bool, error) {
+	if t.cold == nil {
+		return false, nil
+	}
+	if _, err := os.Stat(filepath.Join(t.path, t.fileName(num))); !os.IsNotExist(err) {
+		return false, err
+	}
+	return t.cold.has(t.fileName(num))
+}
+
+// thawFile moves the given data file back from the cold tier if it has been
+// offloaded, used before the file becomes the writable head again. The caller
+// must hold the write lock.
+func (t *freezerTable) thawFile(num uint32) error {
+	if _, ok := t.files[num]; ok {
+		return nil
+	}
+	cold, err := t.isCold(num)
+	if err != nil || !cold {
+		return err
+	}
+	t.logger.Info("Restoring cold freezer file", "file", num)
+	return t.cold.restore(t.fileName(num), filepath.Join(t.path, t.fileName(num)))
+}
+
+// removeColdFiles deletes the offloaded data files in the range [from, to)
+// from the cold tier. The caller must hold the write lock.
+func (t *freezerTable) removeColdFiles(from, to uint32) {
+	if t.cold == nil {
+		return
+	}
+	for num := from; num < to; num++ {
+		if _, ok := t.files[num]; !ok {
+			t.cold.remove(t.fileName(num))
+		}
+	}
+}
+
+// offload moves the sealed data files containing only items below the given
+// limit into the cold tier and deletes the local copies. The files are uploaded
+// without holding the lock, as sealed files are never modified.
+func (t *freezerTable) offload(limit uint64) error {
+	if t.cold == nil || t.readonly {
+		return nil
+	}
+	// Collect the local data files entirely below the limit
+	t.lock.RLock()
+	if t.index == nil {
+		t.lock.RUnlock()
+		return errClosed
+	}
+	bound := t.headId
+	if limit < t.items.Load() {
+		if limit <= t.itemOffset.Load() {
+			t.lock.RUnlock()
+			return nil
+		}
+		buffer := make([]byte, indexEntrySize)
+		if _, err := t.index.ReadAt(buffer, int64((limit-t.itemOffset.Load()+1)*indexEntrySize)); err != nil {
+			t.lock.RUnlock()
+			return err
+		}
+		var entry indexEntry
+		entry.unmarshalBinary(buffer)
+		if entry.filenum < bound {
+			bound = entry.filenum
+		}
+	}
+	var sealed []uint32
+	for num := t.tailId; num < bound; num++ {
+		if _, ok := t.files[num]; ok {
+			sealed = append(sealed, num)
+		}
+	}
+	t.lock.RUnlock()
+
+	// Upload the files one by one, only dropping the local copy after success
+	for _, num := range sealed {
+		var (
+			name = t.fileName(num)
+			path = filepath.Join(t.path, name)
+		)
+		if err := t.cold.upload(name, path); err != nil {
+			return fmt.Errorf("failed to offload freezer file %s: %w", name, err)
+		}
+		t.lock.Lock()
+		if _, ok := t.files[num]; !ok || num < t.tailId || num >= t.headId {
+			// The file was truncated away in the meantime, drop the upload too
+			t.lock.Unlock()
+			t.cold.remove(name)
+			continue
+		}
+		t.releaseFile(num)
+		err := os.Remove(path)
+		t.lock.Unlock()
+
+		if err != nil {
+			return err
+		}
+		t.logger.Debug("Offloaded freezer file to cold tier", "file", num)
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// s3UnsignedPayload is the payload hash used for streamed uploads, which the
+// S3 API accepts over TLS without hashing the whole segment upfront.
+const s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+const (
+	// s3RequestTimeout is the maximum time a request may take including the
+	// transfer of the body, generous enough for the largest segments.
+	s3RequestTimeout = 30 * time.Minute
+
+	// s3HeaderTimeout is the maximum time to wait for the response headers
+	// after the request has been sent.
+	s3HeaderTimeout = 30 * time.Second
+
+	// s3DialTimeout is the maximum time to establish a connection, including
+	// the TLS handshake.
+	s3DialTimeout = 10 * time.Second
+)
+
+// s3ColdStore is a cold store backed by a bucket of an S3-compatible service,
+// addressed in path-style so that self-hosted services work too.
+type s3ColdStore struct {
+	endpoint *url.URL
+	region   string
+	bucket   string
+	prefix   string
+	key      string // Access key id, anonymous requests are sent if empty
+	secret   string // Secret access key
+	client   *http.Client
+}
+
+// NewS3ColdStore creates a cold store keeping the segments in the given bucket
+// of an S3-compatible service. The segment names are prefixed with the given
+// prefix, which allows multiple nodes to share a bucket.
+func NewS3ColdStore(endpoint, region, bucket, prefix, key, secret string) (ColdStore, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", endpoint)
+	}
+	return &s3ColdStore{
+		endpoint: u,
+		region:   region,
+		bucket:   bucket,
+		prefix:   prefix,
+		key:      key,
+		secret:   secret,
+		client: &http.Client{
+			Timeout: s3RequestTimeout,
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				DialContext:           (&net.Dialer{Timeout: s3DialTimeout}).DialContext,
+				TLSHandshakeTimeout:   s3DialTimeout,
+				ResponseHeaderTimeout: s3HeaderTimeout,
+				MaxIdleConnsPerHost:   16,
+				IdleConnTimeout:       90 * time.Second,
+			},
+		},
+	}, nil
+}
+
+// objectURL returns the location of the given segment.
+func (s *s3ColdStore) objectURL(name string) *url.URL {
+	segments := []string{s.bucket}
+	if s.prefix != "" {
+		segments = append(segments, strings.Split(s.prefix, "/")...)
+	}
+	segments = append(segments, name)
+
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + strings.Join(segments, "/")
+	return &u
+}
+
+// do sends a signed request for the given segment.
+func (s *s3ColdStore) do(method string, name string, body io.Reader, size int64) (*http.Response, error) {
+	req, err := http.NewRequest(method, s.objectURL(name).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	if s.key != "" {
+		signS3Request(req, s.region, s.key, s.secret, s3UnsignedPayload, time.Now())
+	}
+	return s.client.Do(req)
+}
+
+// Put implements ColdStore.
+func (s *s3ColdStore) Put(name string, r io.Reader, size int64) error {
+	res, err := s.do(http.MethodPut, name, io.NopCloser(r), size)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return s3Error(res)
+	}
+	return nil
+}
+
+// Get implements ColdStore.
+func (s *s3ColdStore) Get(name string) (io.ReadCloser, error) {
+	res, err := s.do(http.MethodGet, name, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	switch res.StatusCode {
+	case http.StatusOK:
+		return res.Body, nil
+	case http.StatusNotFound:
+		res.Body.Close()
+		return nil, errColdNotFound
+	default:
+		defer res.Body.Close()
+		return nil, s3Error(res)
+	}
+}
+
+// Has implements ColdStore.
+func (s *s3ColdStore) Has(name string) (bool, error) {
+	res, err := s.do(http.MethodHead, name, nil, 0)
+	if err != nil {
+		return false, err
+	}
+	res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, s3Error(res)
+	}
+}
+
+// Delete implements ColdStore.
+func (s *s3ColdStore) Delete(name string) error {
+	res, err := s.do(http.MethodDelete, name, nil, 0)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNotFound {
+		return s3Error(res)
+	}
+	return nil
+}
+
+// s3Error converts a failed S3 response into an error.
+func s3Error(res *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+	return fmt.Errorf("s3 request failed: %s: %s", res.Status, strings.TrimSpace(string(body)))
+}
+
+// signS3Request signs the request with the AWS signature version 4 scheme. All
+// headers set on the request at this point are included in the signature.
+func signS3Request(req *http.Request, region, key, secret, payloadHash string, now time.Time) {
+	var (
+		stamp = now.UTC().Format("20060102T150405Z")
+		day   = stamp[:8]
+		scope = day + "/" + region + "/s3/aws4_request"
+	)
+	req.Header.Set("X-Amz-Date", stamp)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	// Assemble the canonical form of the headers, including the host
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	digest := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		stamp,
+		scope,
+		hex.EncodeToString(digest[:]),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+secret), day)
+	signingKey = hmacSHA256(signingKey, region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", key, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -111,6 +111,8 @@ type freezerTable struct {
 	headId uint32              // number of the currently active head file
 	tailId uint32              // number of the earliest file
 
+	cold *coldTier // Cold tier holding the offloaded data files, nil if disabled
+
 	headBytes  int64         // Number of bytes written to the head file
 	readMeter  metrics.Meter // Meter for measuring the effective amount of data read
 	writeMeter metrics.Meter // Meter for measuring the effective amount of data written
@@ -129,6 +131,12 @@ func newFreezerTable(path, name string, disableSnappy, readonly bool) (*freezerT
 // non-existent. Both files are truncated to the shortest common length to ensure
 // they don't go out of sync.
 func newTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, noCompression, readonly bool) (*freezerTable, error) {
+	return openTable(path, name, readMeter, writeMeter, sizeGauge, maxFilesize, noCompression, readonly, nil)
+}
+
+// openTable opens a freezer table like newTable, but with the sealed data files
+// possibly residing in the given cold tier.
+func openTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, noCompression, readonly bool, cold *coldTier) (*freezerTable, error) {
 	// Ensure the containing directory exists and open the indexEntry file
 	if err := os.MkdirAll(path, 0755); err != nil {
 		return nil, err
@@ -178,6 +186,7 @@ func newTable(path string, name string, readMeter metrics.Meter, writeMeter metr
 		noCompression: noCompression,
 		readonly:      readonly,
 		maxFileSize:   maxFilesize,
+		cold:          cold,
 	}
 	if err := tab.repair(); err != nil {
 		tab.Close()
@@ -257,6 +266,9 @@ func (t *freezerTable) repair() error {
 	if t.readonly {
 		t.head, err = t.openFile(lastIndex.filenum, openFreezerFileForReadOnly)
 	} else {
+		if err := t.thawFile(lastIndex.filenum); err != nil {
+			return err
+		}
 		t.head, err = t.openFile(lastIndex.filenum, openFreezerFileForAppend)
 	}
 	if err != nil {
@@ -300,6 +312,9 @@ func (t *freezerTable) repair() error {
 			if newLastIndex.filenum != lastIndex.filenum {
 				// Release earlier opened file
 				t.releaseFile(lastIndex.filenum)
+				if err := t.thawFile(newLastIndex.filenum); err != nil {
+					return err
+				}
 				if t.head, err = t.openFile(newLastIndex.filenum, openFreezerFileForAppend); err != nil {
 					return err
 				}
@@ -358,8 +373,16 @@ func (t *freezerTable) preopen() (err error) {
 	// The repair might have already opened (some) files
 	t.releaseFilesAfter(0, false)
 
-	// Open all except head in RDONLY
+	// Open all except head in RDONLY, the offloaded ones are read from the
+	// cold tier instead
 	for i := t.tailId; i < t.headId; i++ {
+		cold, err := t.isCold(i)
+		if err != nil {
+			return err
+		}
+		if cold {
+			continue
+		}
 		if _, err = t.openFile(i, openFreezerFileForReadOnly); err != nil {
 			return err
 		}
@@ -419,6 +442,10 @@ func (t *freezerTable) truncateHead(items uint64) error {
 	if expected.filenum != t.headId {
 		// If already open for reading, force-reopen for writing
 		t.releaseFile(expected.filenum)
+		if err := t.thawFile(expected.filenum); err != nil {
+			return err
+		}
+		t.removeColdFiles(expected.filenum+1, t.headId)
 		newHead, err := t.openFile(expected.filenum, openFreezerFileForAppend)
 		if err != nil {
 			return err
@@ -537,6 +564,7 @@ func (t *freezerTable) truncateTail(items uint64) error {
 		return err
 	}
 	// Release any files before the current tail
+	t.removeColdFiles(t.tailId, newTailId)
 	t.tailId = newTailId
 	t.itemOffset.Store(newDeleted)
 	t.releaseFilesBefore(t.tailId, true)
@@ -589,17 +617,19 @@ func (t *freezerTable) Close() error {
 	return nil
 }
 
+// fileName returns the name of the data file with the given number.
+func (t *freezerTable) fileName(num uint32) string {
+	if t.noCompression {
+		return fmt.Sprintf("%s.%04d.rdat", t.name, num)
+	}
+	return fmt.Sprintf("%s.%04d.cdat", t.name, num)
+}
+
 // openFile assumes that the write-lock is held by the caller
 func (t *freezerTable) openFile(num uint32, opener func(string) (*os.File, error)) (f *os.File, err error) {
 	var exist bool
 	if f, exist = t.files[num]; !exist {
-		var name string
-		if t.noCompression {
-			name = fmt.Sprintf("%s.%04d.rdat", t.name, num)
-		} else {
-			name = fmt.Sprintf("%s.%04d.cdat", t.name, num)
-		}
-		f, err = opener(filepath.Join(t.path, name))
+		f, err = opener(filepath.Join(t.path, t.fileName(num)))
 		if err != nil {
 			return nil, err
 		}
@@ -729,16 +759,43 @@ func (t *freezerTable) RetrieveItems(start, count, maxBytes uint64) ([][]byte, e
 	return output, nil
 }
 
+// coldRead is a read from an offloaded data file, deferred until the table lock
+// is released since it may need to download the file first.
+type coldRead struct {
+	name   string // Name of the offloaded data file
+	offset int64  // Offset to read from in the data file
+	pos    int    // Position in the output buffer to read into
+	length int    // Number of bytes to read
+}
+
 // retrieveItems reads up to 'count' items from the table. It reads at least
 // one item, but otherwise avoids reading more than maxBytes bytes.
 // It returns the (potentially compressed) data, and the sizes.
 func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []int, error) {
+	output, sizes, colds, err := t.retrieveLocalItems(start, count, maxBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Sealed data files are never modified, so the offloaded ones can be read
+	// without holding the lock. They might be deleted by a truncation in the
+	// meantime, in which case the read fails as if it happened afterwards.
+	for _, read := range colds {
+		if err := t.cold.readAt(read.name, output[read.pos:read.pos+read.length], read.offset); err != nil {
+			return nil, nil, err
+		}
+	}
+	return output, sizes, nil
+}
+
+// retrieveLocalItems is the locked part of retrieveItems, reading the items from
+// the local data files and collecting the reads from the offloaded ones.
+func (t *freezerTable) retrieveLocalItems(start, count, maxBytes uint64) ([]byte, []int, []coldRead, error) {
 	t.lock.RLock()
 	defer t.lock.RUnlock()
 
 	// Ensure the table and the item are accessible
 	if t.index == nil || t.head == nil || t.meta == nil {
-		return nil, nil, errClosed
+		return nil, nil, nil, errClosed
 	}
 	var (
 		items  = t.items.Load()      // the total items(head + 1)
@@ -747,7 +804,7 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 	// Ensure the start is written, not deleted from the tail, and that the
 	// caller actually wants something
 	if items <= start || hidden > start || count == 0 {
-		return nil, nil, errOutOfBounds
+		return nil, nil, nil, errOutOfBounds
 	}
 	if start+count > items {
 		count = items - start
@@ -755,6 +812,7 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 	var (
 		output     = make([]byte, maxBytes) // Buffer to read data into
 		outputSize int                      // Used size of that buffer
+		colds      []coldRead               // Reads deferred to after the lock
 	)
 	// readData is a helper method to read a single data item from disk.
 	readData := func(fileId, start uint32, length int) error {
@@ -765,9 +823,12 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 		}
 		dataFile, exist := t.files[fileId]
 		if !exist {
-			return fmt.Errorf("missing data file %d", fileId)
-		}
-		if _, err := dataFile.ReadAt(output[outputSize:outputSize+length], int64(start)); err != nil {
+			// Data files not opened locally have been offloaded into the cold tier
+			if t.cold == nil {
+				return fmt.Errorf("missing data file %d", fileId)
+			}
+			colds = append(colds, coldRead{name: t.fileName(fileId), offset: int64(start), pos: outputSize, length: length})
+		} else if _, err := dataFile.ReadAt(output[outputSize:outputSize+length], int64(start)); err != nil {
 			return err
 		}
 		outputSize += length
@@ -776,7 +837,7 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 	// Read all the indexes in one go
 	indices, err := t.getIndices(start, count)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	var (
 		sizes      []int               // The sizes for each element
@@ -795,7 +856,7 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 			// If we have unread data in the first file, we need to do that read now.
 			if unreadSize > 0 {
 				if err := readData(firstIndex.filenum, readStart, unreadSize); err != nil {
-					return nil, nil, err
+					return nil, nil, nil, err
 				}
 				unreadSize = 0
 			}
@@ -806,7 +867,7 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 			// read this last item, but we need to do the deferred reads now.
 			if unreadSize > 0 {
 				if err := readData(secondIndex.filenum, readStart, unreadSize); err != nil {
-					return nil, nil, err
+					return nil, nil, nil, err
 				}
 			}
 			break
@@ -818,7 +879,7 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 		if i == len(indices)-2 || uint64(totalSize) > maxBytes {
 			// Last item, need to do the read now
 			if err := readData(secondIndex.filenum, readStart, unreadSize); err != nil {
-				return nil, nil, err
+				return nil, nil, nil, err
 			}
 			break
 		}
@@ -826,7 +887,7 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 
 	// Update metrics.
 	t.readMeter.Mark(int64(totalSize))
-	return output[:outputSize], sizes, nil
+	return output[:outputSize], sizes, colds, nil
 }
 
 // has returns an indicator whether the specified number data is still accessible
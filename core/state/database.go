@@ -100,6 +100,10 @@ type Trie interface {
 	// DeleteAccount abstracts an account deletion from the trie.
 	DeleteAccount(address common.Address) error
 
+	// Witness returns the set of encoded trie nodes resolved from the database
+	// since the trie was opened or last committed.
+	Witness() map[string]struct{}
+
 	// Hash returns the root hash of the trie. It does not write to the database and
 	// can be used even if the trie doesn't have one.
 	Hash() common.Hash
@@ -0,0 +1,480 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+	onlineMarkedMeter     = metrics.NewRegisteredMeter("state/pruner/online/marked", nil)
+	onlineSweptNodesMeter = metrics.NewRegisteredMeter("state/pruner/online/swept/nodes", nil)
+	onlineSweptBytesMeter = metrics.NewRegisteredMeter("state/pruner/online/swept/bytes", nil)
+	onlineProgressGauge   = metrics.NewRegisteredGauge("state/pruner/online/progress", nil)
+	onlineCyclesCounter   = metrics.NewRegisteredCounter("state/pruner/online/cycles", nil)
+)
+
+// MinRetention is the minimal number of recent blocks whose state must be
+// retained by the online pruner. It matches the number of tries kept in the
+// memory by a full node, so that the states referenced by the in-memory layers
+// (e.g. the base of the state snapshot) are never deleted.
+const MinRetention = 128
+
+// errPruningAborted is returned if the pruning cycle is interrupted.
+var errPruningAborted = errors.New("pruning aborted")
+
+// OnlineConfig includes all the configurations for online pruning.
+type OnlineConfig struct {
+	Retention uint64        // Number of recent blocks whose persisted state is retained
+	BloomSize uint64        // Megabytes of memory allocated to the bloom filter of live nodes
+	Throttle  time.Duration // Pause between two batches of processed trie nodes
+}
+
+// DefaultOnlineConfig contains the default settings for online pruning.
+var DefaultOnlineConfig = OnlineConfig{
+	Retention: 3600,
+	BloomSize: 1024,
+	Throttle:  10 * time.Millisecond,
+}
+
+// onlinePhase is the stage of a pruning cycle.
+type onlinePhase string
+
+const (
+	phaseIdle     onlinePhase = "idle"     // No pruning cycle in progress
+	phaseTracking onlinePhase = "tracking" // Committed nodes tracked, waiting for the target state
+	phaseMarking  onlinePhase = "marking"  // Target state being traversed
+	phaseWaiting  onlinePhase = "waiting"  // Target state marked, waiting for it to leave the retention window
+	phaseSweeping onlinePhase = "sweeping" // Unmarked trie nodes being deleted
+)
+
+// markBatch is the number of trie nodes marked between two throttling pauses.
+const markBatch = 10000
+
+// OnlineStatus is the progress report of the online pruner.
+type OnlineStatus struct {
+	Phase        string             `json:"phase"`
+	Cycles       uint64             `json:"cycles"`
+	Target       common.Hash        `json:"target"`
+	TargetNumber uint64             `json:"targetNumber"`
+	Marked       uint64             `json:"marked"`
+	Swept        uint64             `json:"swept"`
+	SweptSize    common.StorageSize `json:"sweptSize"`
+	Progress     float64            `json:"progress"`
+	Elapsed      string             `json:"elapsed"`
+	Error        string             `json:"error,omitempty"`
+}
+
+// OnlinePruner deletes the stale trie nodes of the hash-based state scheme in
+// the background, without stopping the node. It runs in cycles of mark and
+// sweep, all the nodes which don't belong to the states of the retained recent
+// blocks are deleted at the end of each cycle.
+//
+// A cycle begins with tracking all the trie nodes committed into the database.
+// The next state flushed to disk which was created after the tracking began
+// is picked as the pruning target and traversed in its entirety. Every node of
+// a more recent state is either part of the target, or was committed after it
+// and thus tracked. Once the target falls out of the retention window, all the
+// trie nodes on disk which are neither part of the target nor tracked are
+// deleted incrementally.
+//
+// Similar to the offline pruner, the live nodes are recorded in a bloom filter
+// so a small fraction of stale nodes might be left behind.
+type OnlinePruner struct {
+	config OnlineConfig
+	db     ethdb.Database
+	triedb *trie.Database
+
+	phase   onlinePhase
+	bloom   *stateBloom   // Live trie nodes of the current cycle
+	begin   uint64        // Head block number when the tracking began
+	target  common.Hash   // State root picked as the pruning target
+	number  uint64        // Block number of the pruning target
+	started time.Time     // Time when the current cycle began
+	abort   chan struct{} // Channel to interrupt the background task of the cycle
+	running sync.WaitGroup
+
+	cycles    uint64
+	marked    uint64
+	swept     uint64
+	sweptSize common.StorageSize
+	progress  float64
+	lastErr   error
+
+	lock sync.Mutex
+}
+
+// NewOnlinePruner creates an online pruner on top of the given hash-based trie
+// database. The pruner is driven by the state commits reported through the
+// Committed method.
+func NewOnlinePruner(db ethdb.Database, triedb *trie.Database, config OnlineConfig) (*OnlinePruner, error) {
+	if triedb.Scheme() != rawdb.HashScheme {
+		return nil, errors.New("online pruning is only supported by the hash-based state scheme")
+	}
+	if config.Retention < MinRetention {
+		log.Warn("Sanitizing pruning retention", "provided", config.Retention, "updated", MinRetention)
+		config.Retention = MinRetention
+	}
+	if config.BloomSize == 0 {
+		config.BloomSize = DefaultOnlineConfig.BloomSize
+	}
+	p := &OnlinePruner{
+		config: config,
+		db:     db,
+		triedb: triedb,
+		phase:  phaseIdle,
+	}
+	// The hook is installed for the entire lifetime of the pruner, the nodes
+	// are only recorded while a cycle is in progress. It's invoked with the
+	// trie database lock held, so it can't be toggled with the pruner lock.
+	if err := triedb.SetInsertHook(p.track); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Committed notifies the pruner that the state of the given block has been
+// flushed to disk in its entirety, while the head block is at the given height.
+// It's expected to be called sequentially by the chain and never blocks.
+func (p *OnlinePruner) Committed(number uint64, root common.Hash, head uint64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	switch p.phase {
+	case phaseIdle:
+		bloom, err := newStateBloomWithSize(p.config.BloomSize)
+		if err != nil {
+			p.lastErr = err
+			return
+		}
+		p.bloom, p.begin, p.started = bloom, head, time.Now()
+		p.marked, p.swept, p.sweptSize, p.progress, p.lastErr = 0, 0, 0, 0, nil
+		p.phase = phaseTracking
+		log.Info("Started online state pruning", "head", head)
+
+	case phaseTracking:
+		// The target must be created after the tracking began, all the nodes
+		// of the states in between might not be contained otherwise.
+		if number <= p.begin {
+			return
+		}
+		p.target, p.number = root, number
+		p.phase = phaseMarking
+		p.spawn(p.mark)
+
+	case phaseWaiting:
+		if number < p.number+p.config.Retention {
+			return
+		}
+		p.phase = phaseSweeping
+		p.spawn(p.sweep)
+	}
+}
+
+// track is the hook invoked by the trie database for every committed node.
+func (p *OnlinePruner) track(hash common.Hash) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.bloom != nil {
+		p.bloom.Put(hash.Bytes(), nil)
+	}
+}
+
+// spawn runs the given task of the cycle in the background. The lock is
+// assumed to be held.
+func (p *OnlinePruner) spawn(task func(abort chan struct{}) error) {
+	abort := make(chan struct{})
+	p.abort = abort
+
+	p.running.Add(1)
+	go func() {
+		defer p.running.Done()
+
+		err := task(abort)
+
+		p.lock.Lock()
+		defer p.lock.Unlock()
+
+		if p.abort != abort {
+			return // Cycle was reset in the meantime
+		}
+		p.abort = nil
+		switch {
+		case err != nil:
+			if err != errPruningAborted {
+				log.Error("Online state pruning failed", "phase", p.phase, "err", err)
+				p.lastErr = err
+			}
+			p.reset()
+		case p.phase == phaseMarking:
+			p.phase = phaseWaiting
+			log.Info("Marked pruning target", "number", p.number, "root", p.target, "nodes", p.marked)
+		case p.phase == phaseSweeping:
+			p.cycles++
+			onlineCyclesCounter.Inc(1)
+			log.Info("Finished online state pruning", "nodes", p.swept, "size", p.sweptSize, "elapsed", common.PrettyDuration(time.Since(p.started)))
+			p.reset()
+		}
+	}()
+}
+
+// reset drops the current cycle. The lock is assumed to be held.
+func (p *OnlinePruner) reset() {
+	p.bloom = nil
+	p.phase = phaseIdle
+}
+
+// mark traverses the pruning target and the genesis state, recording all
+// their trie nodes and contract codes as live.
+func (p *OnlinePruner) mark(abort chan struct{}) error {
+	p.lock.Lock()
+	root, bloom := p.target, p.bloom
+	p.lock.Unlock()
+
+	genesis := rawdb.ReadHeader(p.db, rawdb.ReadCanonicalHash(p.db, 0), 0)
+	if genesis == nil {
+		return errors.New("missing genesis header")
+	}
+	for _, root := range []common.Hash{genesis.Root, root} {
+		if err := p.markState(root, bloom, abort); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// markState records all the trie nodes and codes of the given state in the bloom.
+func (p *OnlinePruner) markState(root common.Hash, bloom *stateBloom, abort chan struct{}) error {
+	var count int
+	mark := func(key []byte) error {
+		bloom.Put(key, nil)
+		onlineMarkedMeter.Mark(1)
+
+		p.lock.Lock()
+		p.marked++
+		p.lock.Unlock()
+
+		if count++; count%markBatch == 0 {
+			select {
+			case <-abort:
+				return errPruningAborted
+			case <-time.After(p.config.Throttle):
+			}
+		}
+		return nil
+	}
+	t, err := trie.NewStateTrie(trie.StateTrieID(root), p.triedb)
+	if err != nil {
+		return err
+	}
+	accIter := t.NodeIterator(nil)
+	for accIter.Next(true) {
+		// Embedded nodes don't have hash.
+		if hash := accIter.Hash(); hash != (common.Hash{}) {
+			if err := mark(hash.Bytes()); err != nil {
+				return err
+			}
+		}
+		if !accIter.Leaf() {
+			continue
+		}
+		var acc types.StateAccount
+		if err := rlp.DecodeBytes(accIter.LeafBlob(), &acc); err != nil {
+			return err
+		}
+		if acc.Root != types.EmptyRootHash {
+			id := trie.StorageTrieID(root, common.BytesToHash(accIter.LeafKey()), acc.Root)
+			storageTrie, err := trie.NewStateTrie(id, p.triedb)
+			if err != nil {
+				return err
+			}
+			storageIter := storageTrie.NodeIterator(nil)
+			for storageIter.Next(true) {
+				if hash := storageIter.Hash(); hash != (common.Hash{}) {
+					if err := mark(hash.Bytes()); err != nil {
+						return err
+					}
+				}
+			}
+			if storageIter.Error() != nil {
+				return storageIter.Error()
+			}
+		}
+		if !bytes.Equal(acc.CodeHash, types.EmptyCodeHash.Bytes()) {
+			if err := mark(acc.CodeHash); err != nil {
+				return err
+			}
+		}
+	}
+	return accIter.Error()
+}
+
+// sweep iterates over the entire database and deletes all the legacy trie
+// nodes (and codes) which are not recorded as live.
+func (p *OnlinePruner) sweep(abort chan struct{}) error {
+	p.lock.Lock()
+	bloom := p.bloom
+	p.lock.Unlock()
+
+	var (
+		pending [][]byte
+		size    common.StorageSize
+		iter    = p.db.NewIterator(nil, nil)
+	)
+	defer func() { iter.Release() }()
+
+	// flush deletes the accumulated stale entries. The nodes might have been
+	// committed again since they were collected, so they're checked again with
+	// the lock held, which blocks any concurrent tracking until the deletion is
+	// persisted. Nodes committed afterwards are rewritten on their next flush.
+	flush := func() error {
+		p.lock.Lock()
+		defer p.lock.Unlock()
+
+		var (
+			batch = p.db.NewBatch()
+			count int
+		)
+		for _, key := range pending {
+			if bloom.Contain(key) {
+				continue
+			}
+			batch.Delete(key)
+			count++
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		p.swept += uint64(count)
+		p.sweptSize += size
+		onlineSweptNodesMeter.Mark(int64(count))
+		onlineSweptBytesMeter.Mark(int64(size))
+
+		pending, size = pending[:0], 0
+		return nil
+	}
+	for iter.Next() {
+		key := iter.Key()
+
+		// Only legacy trie nodes and codes are keyed by their bare hash, the
+		// new-scheme codes are never deleted as they're not tracked.
+		if len(key) != common.HashLength || bloom.Contain(key) {
+			continue
+		}
+		pending = append(pending, common.CopyBytes(key))
+		size += common.StorageSize(len(key) + len(iter.Value()))
+
+		if size >= ethdb.IdealBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+			progress := float64(binary.BigEndian.Uint64(key[:8])) / math.MaxUint64
+			p.lock.Lock()
+			p.progress = progress
+			p.lock.Unlock()
+			onlineProgressGauge.Update(int64(progress * 100))
+
+			select {
+			case <-abort:
+				return errPruningAborted
+			case <-time.After(p.config.Throttle):
+			}
+			// Recreate the iterator after every batch commit in order
+			// to allow the underlying compactor to delete the entries.
+			iter.Release()
+			iter = p.db.NewIterator(nil, key)
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	p.lock.Lock()
+	p.progress = 1
+	p.lock.Unlock()
+	onlineProgressGauge.Update(100)
+	return nil
+}
+
+// Abort interrupts the pruning cycle in progress and waits for the background
+// task to terminate. It must be invoked before operations which might make
+// states out of the retention window live again, e.g. rewinding the chain.
+// A new cycle is started with the next state commit.
+func (p *OnlinePruner) Abort() {
+	p.lock.Lock()
+	if p.phase == phaseIdle {
+		p.lock.Unlock()
+		return
+	}
+	if p.abort != nil {
+		close(p.abort)
+		p.abort = nil
+	}
+	log.Info("Aborted online state pruning", "phase", p.phase)
+	p.reset()
+	p.lock.Unlock()
+
+	p.running.Wait()
+}
+
+// Stop terminates the pruner, interrupting any pruning cycle in progress.
+func (p *OnlinePruner) Stop() {
+	p.Abort()
+	p.triedb.SetInsertHook(nil)
+}
+
+// Status returns the progress report of the pruner.
+func (p *OnlinePruner) Status() *OnlineStatus {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	status := &OnlineStatus{
+		Phase:     string(p.phase),
+		Cycles:    p.cycles,
+		Marked:    p.marked,
+		Swept:     p.swept,
+		SweptSize: p.sweptSize,
+		Progress:  p.progress,
+	}
+	if p.phase != phaseIdle {
+		status.Elapsed = common.PrettyDuration(time.Since(p.started)).String()
+	}
+	if p.phase == phaseMarking || p.phase == phaseWaiting || p.phase == phaseSweeping {
+		status.Target, status.TargetNumber = p.target, p.number
+	}
+	if p.lastErr != nil {
+		status.Error = p.lastErr.Error()
+	}
+	return status
+}
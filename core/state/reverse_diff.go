@@ -0,0 +1,181 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// errNoCommit is returned if the reverse diff is requested from a state which
+// has never been committed with its origins tracked.
+var errNoCommit = errors.New("state not committed with tracked origins")
+
+// reverseDiffSource is the set of original values of the states mutated by the
+// last commit, stashed for constructing the reverse diff.
+type reverseDiffSource struct {
+	parent    common.Hash                                    // State root before the commit
+	root      common.Hash                                    // State root after the commit
+	accounts  map[common.Address]*types.StateAccount         // Original accounts, nil if not existent
+	storages  map[common.Address]map[common.Hash]common.Hash // Original slots of the accounts
+	destructs map[common.Address]struct{}                    // Accounts destructed by the commit
+}
+
+// ReverseDiffSlot is the original value of a storage slot.
+type ReverseDiffSlot struct {
+	Key   common.Hash
+	Value common.Hash // Zero value means the slot didn't exist
+}
+
+// ReverseDiffAccount is the original content of an account mutated in a block.
+type ReverseDiffAccount struct {
+	Address    common.Address
+	Account    *types.StateAccount `rlp:"nil"` // Nil means the account didn't exist
+	Destructed bool                // Flag whether the storage is wiped in the block
+	Storage    []ReverseDiffSlot   // Full storage if destructed, mutated slots otherwise
+}
+
+// ReverseDiff contains the original values of all the states mutated by a block,
+// applying it on top of the post-state results in the pre-state.
+type ReverseDiff struct {
+	Parent   common.Hash // State root before the block
+	Root     common.Hash // State root after the block
+	Accounts []ReverseDiffAccount
+}
+
+// ReverseDiff constructs the reverse diff of the last commit, which can be used
+// to roll the committed state back to its parent. The origins must be tracked,
+// see TrackOrigins.
+//
+// The full storage of the destructed accounts is resolved from the parent state,
+// which requires the preimages of the slot keys to be available.
+func (s *StateDB) ReverseDiff() (*ReverseDiff, error) {
+	src := s.lastCommit
+	if src == nil {
+		return nil, errNoCommit
+	}
+	diff := &ReverseDiff{
+		Parent: src.parent,
+		Root:   src.root,
+	}
+	for addr, origin := range src.accounts {
+		account := ReverseDiffAccount{
+			Address: addr,
+			Account: origin,
+		}
+		if _, ok := src.destructs[addr]; ok {
+			account.Destructed = true
+			if origin != nil && origin.Root != types.EmptyRootHash {
+				slots, err := s.originStorage(src.parent, addr, origin.Root)
+				if err != nil {
+					return nil, err
+				}
+				account.Storage = slots
+			}
+		} else {
+			for key, value := range src.storages[addr] {
+				account.Storage = append(account.Storage, ReverseDiffSlot{Key: key, Value: value})
+			}
+		}
+		sort.Slice(account.Storage, func(i, j int) bool {
+			return bytes.Compare(account.Storage[i].Key[:], account.Storage[j].Key[:]) < 0
+		})
+		diff.Accounts = append(diff.Accounts, account)
+	}
+	sort.Slice(diff.Accounts, func(i, j int) bool {
+		return bytes.Compare(diff.Accounts[i].Address[:], diff.Accounts[j].Address[:]) < 0
+	})
+	return diff, nil
+}
+
+// originStorage resolves the full storage of the given account in the parent
+// state, keyed by the preimages of the slot hashes.
+func (s *StateDB) originStorage(parent common.Hash, addr common.Address, root common.Hash) ([]ReverseDiffSlot, error) {
+	tr, err := s.db.OpenStorageTrie(parent, crypto.Keccak256Hash(addr.Bytes()), root)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		slots []ReverseDiffSlot
+		it    = trie.NewIterator(tr.NodeIterator(nil))
+	)
+	for it.Next() {
+		key := tr.GetKey(it.Key)
+		if key == nil {
+			return nil, fmt.Errorf("missing preimage of slot %x in %x", it.Key, addr)
+		}
+		_, content, _, err := rlp.Split(it.Value)
+		if err != nil {
+			return nil, err
+		}
+		slots = append(slots, ReverseDiffSlot{Key: common.BytesToHash(key), Value: common.BytesToHash(content)})
+	}
+	if it.Err != nil {
+		return nil, it.Err
+	}
+	return slots, nil
+}
+
+// ApplyReverseDiff rolls the state back by the given reverse diff. The state is
+// expected to be the post-state of the diff, the pre-state can be verified by
+// comparing the intermediate root with the diff's parent.
+func (s *StateDB) ApplyReverseDiff(diff *ReverseDiff) error {
+	// Wipe out the accounts which didn't exist or whose storage was destructed
+	// in the block, they are recreated with the original content below.
+	for _, account := range diff.Accounts {
+		if account.Account == nil || account.Destructed {
+			s.Suicide(account.Address)
+		}
+	}
+	s.Finalise(false)
+
+	for _, account := range diff.Accounts {
+		if account.Account == nil {
+			continue
+		}
+		if account.Destructed {
+			s.CreateAccount(account.Address)
+		}
+		s.SetNonce(account.Address, account.Account.Nonce)
+		s.SetBalance(account.Address, account.Account.Balance)
+
+		if codeHash := common.BytesToHash(account.Account.CodeHash); codeHash != s.GetCodeHash(account.Address) {
+			var code []byte
+			if codeHash != types.EmptyCodeHash {
+				blob, err := s.db.ContractCode(crypto.Keccak256Hash(account.Address.Bytes()), codeHash)
+				if err != nil {
+					return fmt.Errorf("missing code %x of %x: %v", codeHash, account.Address, err)
+				}
+				code = blob
+			}
+			s.SetCode(account.Address, code)
+		}
+		for _, slot := range account.Storage {
+			s.SetState(account.Address, slot.Key, slot.Value)
+		}
+	}
+	s.Finalise(false)
+	return s.dbErr
+}
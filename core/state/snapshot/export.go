@@ -0,0 +1,410 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// The export format is a magic prefix followed by a sequence of chunks, each
+// framed as:
+//
+//	kind (1 byte) | payload length (4 bytes BE) | RLP payload | CRC32-C (4 bytes BE)
+//
+// The first chunk is the header and the last one is the footer, in between the
+// accounts are streamed in hash order, each followed by its storage chunks and
+// the contract codes not yet exported.
+const (
+	exportVersion   = 1
+	exportChunkSize = 1024 * 1024 // Soft limit of the chunk payload size
+	exportMaxChunk  = 64 * 1024 * 1024
+
+	chunkHeader   = 0x00
+	chunkAccounts = 0x01
+	chunkStorage  = 0x02
+	chunkCodes    = 0x03
+	chunkFooter   = 0x04
+)
+
+var (
+	exportMagic = []byte("GSNP")
+	crcTable    = crc32.MakeTable(crc32.Castagnoli)
+
+	errExportTruncated = errors.New("snapshot export truncated")
+)
+
+// ExportHeader is the leading chunk of an export, identifying the state.
+type ExportHeader struct {
+	Version uint64
+	Root    common.Hash
+	Meta    []byte // Opaque metadata of the exporter, e.g. the head block
+}
+
+type exportAccount struct {
+	Hash    common.Hash
+	Account []byte // Slim RLP encoded account
+}
+
+type exportStorage struct {
+	Account common.Hash
+	Hashes  []common.Hash
+	Slots   [][]byte
+}
+
+type exportFooter struct {
+	Accounts uint64
+	Slots    uint64
+	Codes    uint64
+}
+
+// exportWriter frames and checksums the chunks written into the stream.
+type exportWriter struct {
+	w   io.Writer
+	buf bytes.Buffer
+
+	accounts []exportAccount
+	size     int
+}
+
+func (ew *exportWriter) writeChunk(kind byte, payload interface{}) error {
+	blob, err := rlp.EncodeToBytes(payload)
+	if err != nil {
+		return err
+	}
+	ew.buf.Reset()
+	ew.buf.WriteByte(kind)
+	binary.Write(&ew.buf, binary.BigEndian, uint32(len(blob)))
+	ew.buf.Write(blob)
+
+	crc := crc32.Checksum(ew.buf.Bytes(), crcTable)
+	binary.Write(&ew.buf, binary.BigEndian, crc)
+	_, err = ew.w.Write(ew.buf.Bytes())
+	return err
+}
+
+// flushAccounts writes out the pending accounts, if any.
+func (ew *exportWriter) flushAccounts() error {
+	if len(ew.accounts) == 0 {
+		return nil
+	}
+	if err := ew.writeChunk(chunkAccounts, ew.accounts); err != nil {
+		return err
+	}
+	ew.accounts, ew.size = nil, 0
+	return nil
+}
+
+// Export streams the flat state of the given root along with the referenced
+// contract codes into the writer. The meta is carried in the export header
+// untouched.
+func Export(w io.Writer, snaptree *Tree, root common.Hash, codedb ethdb.KeyValueReader, meta []byte) error {
+	accIt, err := snaptree.AccountIterator(root, common.Hash{})
+	if err != nil {
+		return err
+	}
+	defer accIt.Release()
+
+	ew := &exportWriter{w: w}
+	if _, err := w.Write(exportMagic); err != nil {
+		return err
+	}
+	if err := ew.writeChunk(chunkHeader, &ExportHeader{Version: exportVersion, Root: root, Meta: meta}); err != nil {
+		return err
+	}
+	var (
+		footer exportFooter
+		codes  = make(map[common.Hash]struct{})
+		start  = time.Now()
+		logged = time.Now()
+	)
+	for accIt.Next() {
+		account, err := FullAccount(accIt.Account())
+		if err != nil {
+			return err
+		}
+		ew.accounts = append(ew.accounts, exportAccount{Hash: accIt.Hash(), Account: common.CopyBytes(accIt.Account())})
+		ew.size += common.HashLength + len(accIt.Account())
+		footer.Accounts++
+
+		// Stream the storage of the account, chunked by the size limit
+		if !bytes.Equal(account.Root, types.EmptyRootHash.Bytes()) {
+			if err := ew.flushAccounts(); err != nil {
+				return err
+			}
+			stIt, err := snaptree.StorageIterator(root, accIt.Hash(), common.Hash{})
+			if err != nil {
+				return err
+			}
+			var (
+				chunk = exportStorage{Account: accIt.Hash()}
+				size  int
+			)
+			for stIt.Next() {
+				chunk.Hashes = append(chunk.Hashes, stIt.Hash())
+				chunk.Slots = append(chunk.Slots, common.CopyBytes(stIt.Slot()))
+				size += common.HashLength + len(stIt.Slot())
+				footer.Slots++
+
+				if size >= exportChunkSize {
+					if err := ew.writeChunk(chunkStorage, &chunk); err != nil {
+						stIt.Release()
+						return err
+					}
+					chunk.Hashes, chunk.Slots, size = nil, nil, 0
+				}
+			}
+			err = stIt.Error()
+			stIt.Release()
+			if err != nil {
+				return err
+			}
+			if len(chunk.Hashes) > 0 {
+				if err := ew.writeChunk(chunkStorage, &chunk); err != nil {
+					return err
+				}
+			}
+		}
+		// Export the contract code once, no matter how many accounts share it
+		if codeHash := common.BytesToHash(account.CodeHash); codeHash != types.EmptyCodeHash {
+			if _, ok := codes[codeHash]; !ok {
+				code := rawdb.ReadCode(codedb, codeHash)
+				if len(code) == 0 {
+					return fmt.Errorf("missing code %x", codeHash)
+				}
+				if err := ew.flushAccounts(); err != nil {
+					return err
+				}
+				if err := ew.writeChunk(chunkCodes, [][]byte{code}); err != nil {
+					return err
+				}
+				codes[codeHash] = struct{}{}
+				footer.Codes++
+			}
+		}
+		if ew.size >= exportChunkSize {
+			if err := ew.flushAccounts(); err != nil {
+				return err
+			}
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Exporting state snapshot", "at", accIt.Hash(), "accounts", footer.Accounts, "slots", footer.Slots,
+				"codes", footer.Codes, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := accIt.Error(); err != nil {
+		return err
+	}
+	if err := ew.flushAccounts(); err != nil {
+		return err
+	}
+	if err := ew.writeChunk(chunkFooter, &footer); err != nil {
+		return err
+	}
+	log.Info("Exported state snapshot", "root", root, "accounts", footer.Accounts, "slots", footer.Slots,
+		"codes", footer.Codes, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// readChunk reads the next chunk from the stream and verifies its checksum.
+func readChunk(r io.Reader) (byte, []byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return 0, nil, errExportTruncated
+		}
+		return 0, nil, err
+	}
+	size := binary.BigEndian.Uint32(prefix[1:])
+	if size > exportMaxChunk {
+		return 0, nil, fmt.Errorf("oversized chunk: %d bytes", size)
+	}
+	blob := make([]byte, int(size)+4)
+	if _, err := io.ReadFull(r, blob); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return 0, nil, errExportTruncated
+		}
+		return 0, nil, err
+	}
+	payload := blob[:size]
+	crc := crc32.Update(crc32.Checksum(prefix[:], crcTable), crcTable, payload)
+	if want := binary.BigEndian.Uint32(blob[size:]); crc != want {
+		return 0, nil, fmt.Errorf("chunk checksum mismatch: have %x, want %x", crc, want)
+	}
+	return prefix[0], payload, nil
+}
+
+// Import reads an export from the stream into the flat state of the database,
+// then regenerates the merkle tries with the given scheme and verifies the
+// state root. The header is passed to the check callback before anything is
+// written, giving the caller a chance to reject the export.
+//
+// On success the imported flat state is marked as a complete snapshot.
+func Import(r io.Reader, db ethdb.Database, scheme string, check func(*ExportHeader) error) (*ExportHeader, error) {
+	magic := make([]byte, len(exportMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || !bytes.Equal(magic, exportMagic) {
+		return nil, errors.New("not a snapshot export")
+	}
+	kind, payload, err := readChunk(r)
+	if err != nil {
+		return nil, err
+	}
+	if kind != chunkHeader {
+		return nil, fmt.Errorf("unexpected chunk %d, want header", kind)
+	}
+	header := new(ExportHeader)
+	if err := rlp.DecodeBytes(payload, header); err != nil {
+		return nil, fmt.Errorf("invalid export header: %v", err)
+	}
+	if header.Version != exportVersion {
+		return nil, fmt.Errorf("unsupported export version %d", header.Version)
+	}
+	if check != nil {
+		if err := check(header); err != nil {
+			return nil, err
+		}
+	}
+	if rawdb.ReadSnapshotRoot(db) != (common.Hash{}) {
+		return nil, errors.New("database already contains a state snapshot")
+	}
+	var (
+		batch  = db.NewBatch()
+		have   exportFooter
+		footer *exportFooter
+		last   common.Hash
+		start  = time.Now()
+		logged = time.Now()
+	)
+	flush := func(force bool) error {
+		if batch.ValueSize() > ethdb.IdealBatchSize || (force && batch.ValueSize() > 0) {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		return nil
+	}
+	for footer == nil {
+		kind, payload, err := readChunk(r)
+		if err != nil {
+			return nil, err
+		}
+		switch kind {
+		case chunkAccounts:
+			var accounts []exportAccount
+			if err := rlp.DecodeBytes(payload, &accounts); err != nil {
+				return nil, fmt.Errorf("invalid account chunk: %v", err)
+			}
+			for _, account := range accounts {
+				if have.Accounts > 0 && bytes.Compare(account.Hash[:], last[:]) <= 0 {
+					return nil, fmt.Errorf("account %x out of order", account.Hash)
+				}
+				if _, err := FullAccount(account.Account); err != nil {
+					return nil, fmt.Errorf("invalid account %x: %v", account.Hash, err)
+				}
+				rawdb.WriteAccountSnapshot(batch, account.Hash, account.Account)
+				last = account.Hash
+				have.Accounts++
+			}
+		case chunkStorage:
+			var storage exportStorage
+			if err := rlp.DecodeBytes(payload, &storage); err != nil {
+				return nil, fmt.Errorf("invalid storage chunk: %v", err)
+			}
+			if len(storage.Hashes) != len(storage.Slots) {
+				return nil, fmt.Errorf("storage chunk of %x malformed", storage.Account)
+			}
+			for i, hash := range storage.Hashes {
+				rawdb.WriteStorageSnapshot(batch, storage.Account, hash, storage.Slots[i])
+			}
+			have.Slots += uint64(len(storage.Hashes))
+		case chunkCodes:
+			var codes [][]byte
+			if err := rlp.DecodeBytes(payload, &codes); err != nil {
+				return nil, fmt.Errorf("invalid code chunk: %v", err)
+			}
+			for _, code := range codes {
+				rawdb.WriteCode(batch, crypto.Keccak256Hash(code), code)
+			}
+			have.Codes += uint64(len(codes))
+		case chunkFooter:
+			footer = new(exportFooter)
+			if err := rlp.DecodeBytes(payload, footer); err != nil {
+				return nil, fmt.Errorf("invalid export footer: %v", err)
+			}
+		default:
+			return nil, fmt.Errorf("unknown chunk %d", kind)
+		}
+		if err := flush(false); err != nil {
+			return nil, err
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Importing state snapshot", "accounts", have.Accounts, "slots", have.Slots, "codes", have.Codes,
+				"elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if have != *footer {
+		return nil, fmt.Errorf("export content mismatch: have %+v, want %+v", have, *footer)
+	}
+	if err := flush(true); err != nil {
+		return nil, err
+	}
+	log.Info("Imported flat state, regenerating tries", "accounts", have.Accounts, "slots", have.Slots,
+		"codes", have.Codes, "elapsed", common.PrettyDuration(time.Since(start)))
+
+	// Regenerate the tries from the flat state and verify the root
+	base := &diskLayer{diskdb: db, root: header.Root}
+	accIt := base.AccountIterator(common.Hash{})
+	defer accIt.Release()
+
+	root, err := generateTrieRoot(db, scheme, accIt, common.Hash{}, stackTrieGenerate, func(dst ethdb.KeyValueWriter, accountHash, codeHash common.Hash, stat *generateStats) (common.Hash, error) {
+		if codeHash != types.EmptyCodeHash && !rawdb.HasCode(db, codeHash) {
+			return common.Hash{}, fmt.Errorf("missing code %x", codeHash)
+		}
+		stIt, _ := base.StorageIterator(accountHash, common.Hash{})
+		defer stIt.Release()
+
+		return generateTrieRoot(dst, scheme, stIt, accountHash, stackTrieGenerate, nil, stat, false)
+	}, newGenerateStats(), true)
+	if err != nil {
+		return nil, err
+	}
+	if root != header.Root {
+		return nil, fmt.Errorf("state root mismatch: have %x, want %x", root, header.Root)
+	}
+	// Mark the imported flat state as a complete snapshot
+	rawdb.WriteSnapshotRoot(db, root)
+	journalProgress(db, nil, nil)
+
+	log.Info("Imported state snapshot", "root", root, "elapsed", common.PrettyDuration(time.Since(start)))
+	return header, nil
+}
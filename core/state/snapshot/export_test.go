@@ -0,0 +1,112 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/VictoriaMetrics/fastcache"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// makeExport creates a small state with storage and code, and exports it.
+func makeExport(t *testing.T) (common.Hash, []byte) {
+	var (
+		helper   = newHelper()
+		code     = []byte{0x60, 0x00, 0xff}
+		codeHash = crypto.Keccak256(code)
+		keys     = []string{"key-1", "key-2", "key-3"}
+		vals     = []string{"val-1", "val-2", "val-3"}
+	)
+	rawdb.WriteCode(helper.diskdb, common.BytesToHash(codeHash), code)
+
+	stRoot := helper.makeStorageTrie(hashData([]byte("acc-1")), keys, vals, true)
+	helper.addAccount("acc-1", &Account{Balance: big.NewInt(1), Root: stRoot, CodeHash: codeHash})
+	helper.addSnapStorage("acc-1", keys, vals)
+
+	helper.addAccount("acc-2", &Account{Balance: big.NewInt(2), Root: types.EmptyRootHash.Bytes(), CodeHash: types.EmptyCodeHash.Bytes()})
+
+	stRoot = helper.makeStorageTrie(hashData([]byte("acc-3")), keys, vals, true)
+	helper.addAccount("acc-3", &Account{Balance: big.NewInt(3), Root: stRoot, CodeHash: codeHash})
+	helper.addSnapStorage("acc-3", keys, vals)
+
+	root := helper.Commit()
+	snaps := &Tree{
+		layers: map[common.Hash]snapshot{
+			root: &diskLayer{diskdb: helper.diskdb, cache: fastcache.New(500 * 1024), root: root},
+		},
+	}
+	var buf bytes.Buffer
+	if err := Export(&buf, snaps, root, helper.diskdb, []byte("meta")); err != nil {
+		t.Fatalf("failed to export snapshot: %v", err)
+	}
+	return root, buf.Bytes()
+}
+
+// Tests that an exported state can be imported into an empty database, with
+// the tries regenerated and the snapshot marked complete.
+func TestExportImport(t *testing.T) {
+	root, blob := makeExport(t)
+
+	for _, scheme := range []string{rawdb.HashScheme, rawdb.PathScheme} {
+		db := rawdb.NewMemoryDatabase()
+		header, err := Import(bytes.NewReader(blob), db, scheme, nil)
+		if err != nil {
+			t.Fatalf("%s: failed to import snapshot: %v", scheme, err)
+		}
+		if header.Root != root || string(header.Meta) != "meta" {
+			t.Fatalf("%s: header mismatch: %+v", scheme, header)
+		}
+		if have := rawdb.ReadSnapshotRoot(db); have != root {
+			t.Fatalf("%s: snapshot root mismatch: have %x, want %x", scheme, have, root)
+		}
+		if scheme == rawdb.HashScheme {
+			tr, err := trie.NewStateTrie(trie.StateTrieID(root), trie.NewDatabase(db))
+			if err != nil {
+				t.Fatalf("failed to open imported state: %v", err)
+			}
+			it := tr.NodeIterator(nil)
+			for it.Next(true) {
+			}
+			if it.Error() != nil {
+				t.Fatalf("imported state incomplete: %v", it.Error())
+			}
+		} else if _, hash := rawdb.ReadAccountTrieNode(db, nil); hash != root {
+			t.Fatalf("imported state root node mismatch: have %x, want %x", hash, root)
+		}
+	}
+}
+
+// Tests that corrupted or truncated exports are rejected.
+func TestImportCorrupted(t *testing.T) {
+	_, blob := makeExport(t)
+
+	corrupted := common.CopyBytes(blob)
+	corrupted[len(corrupted)/2] ^= 0xff
+	if _, err := Import(bytes.NewReader(corrupted), rawdb.NewMemoryDatabase(), rawdb.HashScheme, nil); err == nil {
+		t.Fatal("corrupted export imported")
+	}
+	if _, err := Import(bytes.NewReader(blob[:len(blob)-10]), rawdb.NewMemoryDatabase(), rawdb.HashScheme, nil); err != errExportTruncated {
+		t.Fatalf("truncated export error mismatch: have %v, want %v", err, errExportTruncated)
+	}
+}
@@ -64,6 +64,7 @@ type stateObject struct {
 	address  common.Address
 	addrHash common.Hash // hash of ethereum address of the account
 	data     types.StateAccount
+	origin   *types.StateAccount // Account data at the beginning of the block, nil if it didn't exist
 	db       *StateDB
 
 	// Write caches.
@@ -276,6 +277,7 @@ func (s *stateObject) updateTrie(db Database) (Trie, error) {
 	// The snapshot storage map for the object
 	var (
 		storage map[common.Hash][]byte
+		origin  map[common.Hash]common.Hash
 		hasher  = s.db.hasher
 	)
 	tr, err := s.getTrie(db)
@@ -290,6 +292,19 @@ func (s *stateObject) updateTrie(db Database) (Trie, error) {
 		if value == s.originStorage[key] {
 			continue
 		}
+		// Track the original value of the slot in the block, the trie might
+		// be updated several times within the same block.
+		if s.db.storagesOrigin != nil {
+			if origin == nil {
+				if origin = s.db.storagesOrigin[s.address]; origin == nil {
+					origin = make(map[common.Hash]common.Hash)
+					s.db.storagesOrigin[s.address] = origin
+				}
+			}
+			if _, ok := origin[key]; !ok {
+				origin[key] = s.originStorage[key]
+			}
+		}
 		s.originStorage[key] = value
 
 		var v []byte
@@ -405,6 +420,7 @@ func (s *stateObject) setBalance(amount *big.Int) {
 
 func (s *stateObject) deepCopy(db *StateDB) *stateObject {
 	stateObject := newObject(db, s.address, s.data)
+	stateObject.origin = s.origin
 	if s.trie != nil {
 		stateObject.trie = db.db.CopyTrie(s.trie)
 	}
@@ -453,6 +469,10 @@ func (s *stateObject) CodeSize(db Database) int {
 	if bytes.Equal(s.CodeHash(), types.EmptyCodeHash.Bytes()) {
 		return 0
 	}
+	// The full code is required by the witness to prove the size
+	if s.db.witness != nil {
+		return len(s.Code(db))
+	}
 	size, err := db.ContractCodeSize(s.addrHash, common.BytesToHash(s.CodeHash()))
 	if err != nil {
 		s.db.setError(fmt.Errorf("can't load code size %x: %v", s.CodeHash(), err))
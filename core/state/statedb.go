@@ -79,6 +79,16 @@ type StateDB struct {
 	stateObjectsDirty    map[common.Address]struct{} // State objects modified in the current execution
 	stateObjectsDestruct map[common.Address]struct{} // State objects destructed in the block
 
+	// The original values of the state mutated in the block, used to construct
+	// the state diff and reverse diff of the block. Both maps are nil unless the
+	// tracking is enabled by TrackOrigins.
+	accountsOrigin map[common.Address]*types.StateAccount         // nil if the account didn't exist
+	storagesOrigin map[common.Address]map[common.Hash]common.Hash // Original slot values of the accounts
+	lastCommit     *reverseDiffSource                             // Original values of the last committed block
+
+	// The trie nodes and codes accessed, nil if witness collection is disabled
+	witness *Witness
+
 	// DB error.
 	// State objects are used by the consensus core and VM which are
 	// unable to deal with database-level errors. Any error that occurs
@@ -516,6 +526,13 @@ func (s *StateDB) updateStateObject(obj *stateObject) {
 	if err := s.trie.UpdateAccount(addr, &obj.data); err != nil {
 		s.setError(fmt.Errorf("updateStateObject (%x) error: %v", addr[:], err))
 	}
+	// Track the original value of the account, it might be updated several
+	// times within the same block.
+	if s.accountsOrigin != nil {
+		if _, ok := s.accountsOrigin[addr]; !ok {
+			s.accountsOrigin[addr] = obj.origin
+		}
+	}
 
 	// If state snapshotting is active, cache the data til commit. Note, this
 	// update mechanism is not symmetric to the deletion, because whereas it is
@@ -537,6 +554,11 @@ func (s *StateDB) deleteStateObject(obj *stateObject) {
 	if err := s.trie.DeleteAccount(addr); err != nil {
 		s.setError(fmt.Errorf("deleteStateObject (%x) error: %v", addr[:], err))
 	}
+	if s.accountsOrigin != nil {
+		if _, ok := s.accountsOrigin[addr]; !ok {
+			s.accountsOrigin[addr] = obj.origin
+		}
+	}
 }
 
 // getStateObject retrieves a state object given by the address, returning nil if
@@ -602,6 +624,10 @@ func (s *StateDB) getDeletedStateObject(addr common.Address) *stateObject {
 	}
 	// Insert into the live set
 	obj := newObject(s, addr, *data)
+	if s.accountsOrigin != nil {
+		origin := *data
+		obj.origin = &origin
+	}
 	s.setStateObject(obj)
 	return obj
 }
@@ -632,6 +658,12 @@ func (s *StateDB) createObject(addr common.Address) (newobj, prev *stateObject)
 		}
 	}
 	newobj = newObject(s, addr, types.StateAccount{})
+	if prev != nil {
+		newobj.origin = prev.origin
+		if s.witness != nil {
+			s.witness.addObject(prev)
+		}
+	}
 	if prev == nil {
 		s.journal.append(createObjectChange{account: &addr})
 	} else {
@@ -706,6 +738,7 @@ func (s *StateDB) Copy() *StateDB {
 		stateObjectsPending:  make(map[common.Address]struct{}, len(s.stateObjectsPending)),
 		stateObjectsDirty:    make(map[common.Address]struct{}, len(s.journal.dirties)),
 		stateObjectsDestruct: make(map[common.Address]struct{}, len(s.stateObjectsDestruct)),
+		lastCommit:           s.lastCommit,
 		refund:               s.refund,
 		logs:                 make(map[common.Hash][]*types.Log, len(s.logs)),
 		logSize:              s.logSize,
@@ -749,6 +782,20 @@ func (s *StateDB) Copy() *StateDB {
 	for addr := range s.stateObjectsDestruct {
 		state.stateObjectsDestruct[addr] = struct{}{}
 	}
+	// Deep copy the original values tracked for the reverse diff.
+	if s.accountsOrigin != nil {
+		state.TrackOrigins()
+	}
+	for addr, origin := range s.accountsOrigin {
+		state.accountsOrigin[addr] = origin
+	}
+	for addr, slots := range s.storagesOrigin {
+		cpy := make(map[common.Hash]common.Hash, len(slots))
+		for key, value := range slots {
+			cpy[key] = value
+		}
+		state.storagesOrigin[addr] = cpy
+	}
 	for hash, logs := range s.logs {
 		cpy := make([]*types.Log, len(logs))
 		for i, l := range logs {
@@ -1060,6 +1107,7 @@ func (s *StateDB) Commit(deleteEmptyObjects bool) (common.Hash, error) {
 		}
 		s.snap, s.snapAccounts, s.snapStorage = nil, nil, nil
 	}
+	destructs := s.stateObjectsDestruct
 	if len(s.stateObjectsDestruct) > 0 {
 		s.stateObjectsDestruct = make(map[common.Address]struct{})
 	}
@@ -1070,6 +1118,27 @@ func (s *StateDB) Commit(deleteEmptyObjects bool) (common.Hash, error) {
 	if origin == (common.Hash{}) {
 		origin = types.EmptyRootHash
 	}
+	// Stash the original values of the mutated states for constructing the
+	// reverse diff, and rebase the tracked origins onto the committed state.
+	if s.accountsOrigin != nil {
+		s.lastCommit = &reverseDiffSource{
+			parent:    origin,
+			root:      root,
+			accounts:  s.accountsOrigin,
+			storages:  s.storagesOrigin,
+			destructs: destructs,
+		}
+		for addr := range s.accountsOrigin {
+			if obj := s.stateObjects[addr]; obj != nil && !obj.deleted {
+				data := obj.data
+				obj.origin = &data
+			} else if obj != nil {
+				obj.origin = nil
+			}
+		}
+		s.accountsOrigin = make(map[common.Address]*types.StateAccount)
+		s.storagesOrigin = make(map[common.Address]map[common.Hash]common.Hash)
+	}
 	if root != origin {
 		start := time.Now()
 		if err := s.db.TrieDB().Update(root, origin, nodes); err != nil {
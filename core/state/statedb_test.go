@@ -32,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
 // Tests that updating a state trie does not leak any database writes prior to
@@ -998,3 +999,68 @@ func TestStateDBTransientStorage(t *testing.T) {
 		t.Fatalf("transient storage mismatch: have %x, want %x", got, value)
 	}
 }
+
+// Tests that the witness collected while mutating a state is sufficient to
+// replay the same mutations on a database containing nothing but the witness.
+func TestWitness(t *testing.T) {
+	var (
+		db   = rawdb.NewMemoryDatabase()
+		sdb  = NewDatabase(db)
+		code = []byte{0x60, 0x00, 0xff}
+	)
+	state, _ := New(types.EmptyRootHash, sdb, nil)
+	for i := byte(0); i < 64; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		state.SetBalance(addr, big.NewInt(int64(i)+1))
+		state.SetNonce(addr, uint64(i))
+		if i%4 == 0 {
+			state.SetCode(addr, code)
+			for j := byte(0); j < 16; j++ {
+				state.SetState(addr, common.Hash{j}, common.Hash{i, j})
+			}
+		}
+	}
+	root, _ := state.Commit(false)
+	if err := sdb.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	mutate := func(s *StateDB) {
+		s.GetBalance(common.BytesToAddress([]byte{1}))
+		s.GetState(common.BytesToAddress([]byte{4}), common.Hash{3})
+		s.GetCode(common.BytesToAddress([]byte{8}))
+		s.GetCodeSize(common.BytesToAddress([]byte{12}))
+		s.SetState(common.BytesToAddress([]byte{16}), common.Hash{1}, common.Hash{0xff})
+		s.SetState(common.BytesToAddress([]byte{16}), common.Hash{2}, common.Hash{}) // deletion
+		s.AddBalance(common.BytesToAddress([]byte{200}), big.NewInt(1))              // creation
+		s.Suicide(common.BytesToAddress([]byte{20}))
+		s.Finalise(true)
+	}
+	state, _ = New(root, NewDatabase(db), nil)
+	state.StartWitness()
+	mutate(state)
+	want := state.IntermediateRoot(true)
+	witness := state.Witness()
+
+	// Replay the mutations on a database populated with the witness only
+	wdb := rawdb.NewMemoryDatabase()
+	for node := range witness.Nodes {
+		rawdb.WriteLegacyTrieNode(wdb, crypto.Keccak256Hash([]byte(node)), []byte(node))
+	}
+	for hash, blob := range witness.Codes {
+		rawdb.WriteCode(wdb, hash, blob)
+	}
+	replay, err := New(root, NewDatabase(wdb), nil)
+	if err != nil {
+		t.Fatalf("failed to open witness state: %v", err)
+	}
+	mutate(replay)
+	if have := replay.IntermediateRoot(true); have != want {
+		t.Fatalf("root mismatch: have %x, want %x", have, want)
+	}
+	if err := replay.Error(); err != nil {
+		t.Fatalf("witness incomplete: %v", err)
+	}
+	if len(witness.Codes) != 1 {
+		t.Fatalf("code count mismatch: have %d, want 1", len(witness.Codes))
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// StateDiffAccount is the content of an account on either side of a diff.
+type StateDiffAccount struct {
+	Balance  *hexutil.Big   `json:"balance"`
+	Nonce    hexutil.Uint64 `json:"nonce"`
+	CodeHash common.Hash    `json:"codeHash"`
+}
+
+// StateDiffSlot is the value of a storage slot before and after a block.
+type StateDiffSlot struct {
+	Pre  common.Hash `json:"pre"`
+	Post common.Hash `json:"post"`
+}
+
+// AccountDiff is the change of an account made by a block. Pre or post is nil
+// if the account didn't exist before or after the block.
+//
+// If the account was destructed, its storage was wiped out entirely and only the
+// slots written afterwards are contained.
+type AccountDiff struct {
+	Pre        *StateDiffAccount             `json:"pre"`
+	Post       *StateDiffAccount             `json:"post"`
+	Destructed bool                          `json:"destructed,omitempty"`
+	Storage    map[common.Hash]StateDiffSlot `json:"storage,omitempty"`
+}
+
+// StateDiff is the set of accounts changed by a block.
+type StateDiff map[common.Address]*AccountDiff
+
+func newStateDiffAccount(account *types.StateAccount) *StateDiffAccount {
+	if account == nil {
+		return nil
+	}
+	return &StateDiffAccount{
+		Balance:  (*hexutil.Big)(account.Balance),
+		Nonce:    hexutil.Uint64(account.Nonce),
+		CodeHash: common.BytesToHash(account.CodeHash),
+	}
+}
+
+// TrackOrigins enables tracking the original values of the states mutated from
+// now on, which StateDiff and ReverseDiff are constructed from. The current state
+// is taken as the origin, so it must be enabled before the block is processed.
+func (s *StateDB) TrackOrigins() {
+	if s.accountsOrigin != nil {
+		return
+	}
+	s.accountsOrigin = make(map[common.Address]*types.StateAccount)
+	s.storagesOrigin = make(map[common.Address]map[common.Hash]common.Hash)
+
+	// The accounts already loaded are the origins of the upcoming mutations
+	for _, obj := range s.stateObjects {
+		if !obj.deleted {
+			data := obj.data
+			obj.origin = &data
+		}
+	}
+}
+
+// StateDiff returns the accounts and storage slots changed since the last commit,
+// or nil if the origins aren't tracked. The mutations must be flushed into the
+// tries via IntermediateRoot beforehand.
+func (s *StateDB) StateDiff() StateDiff {
+	if s.accountsOrigin == nil {
+		return nil
+	}
+	diff := make(StateDiff)
+	for addr, origin := range s.accountsOrigin {
+		var (
+			account = &AccountDiff{Pre: newStateDiffAccount(origin)}
+			obj     = s.stateObjects[addr]
+		)
+		if obj != nil && !obj.deleted {
+			account.Post = newStateDiffAccount(&obj.data)
+		}
+		if _, ok := s.stateObjectsDestruct[addr]; ok && origin != nil {
+			account.Destructed = true
+		}
+		for key, pre := range s.storagesOrigin[addr] {
+			var post common.Hash
+			if obj != nil && !obj.deleted {
+				post = obj.originStorage[key]
+			}
+			if pre == post {
+				continue
+			}
+			if account.Storage == nil {
+				account.Storage = make(map[common.Hash]StateDiffSlot)
+			}
+			account.Storage[key] = StateDiffSlot{Pre: pre, Post: post}
+		}
+		// Skip the accounts which are only touched but not changed
+		if !account.Destructed && len(account.Storage) == 0 && sameAccount(origin, account.Post) {
+			continue
+		}
+		diff[addr] = account
+	}
+	return diff
+}
+
+// sameAccount reports whether the original account equals to the new one.
+func sameAccount(origin *types.StateAccount, post *StateDiffAccount) bool {
+	if origin == nil || post == nil {
+		return origin == nil && post == nil
+	}
+	return origin.Nonce == uint64(post.Nonce) && origin.Balance.Cmp(post.Balance.ToInt()) == 0 && bytes.Equal(origin.CodeHash, post.CodeHash.Bytes())
+}
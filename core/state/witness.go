@@ -0,0 +1,97 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Witness contains the trie nodes and contract codes accessed while executing
+// state transitions, which is sufficient to re-execute them on top of the
+// original state root without access to the full state.
+type Witness struct {
+	Nodes map[string]struct{}    // Set of encoded trie nodes
+	Codes map[common.Hash][]byte // Contract codes keyed by code hash
+}
+
+// add merges the given set of trie nodes into the witness.
+func (w *Witness) add(nodes map[string]struct{}) {
+	for node := range nodes {
+		w.Nodes[node] = struct{}{}
+	}
+}
+
+// addObject merges the storage trie nodes and the code accessed through the
+// given state object into the witness.
+func (w *Witness) addObject(obj *stateObject) {
+	if obj.trie != nil {
+		w.add(obj.trie.Witness())
+	}
+	// Codes deployed in the block are not part of the pre-state
+	if obj.code != nil && !obj.dirtyCode {
+		w.Codes[crypto.Keccak256Hash(obj.code)] = obj.code
+	}
+}
+
+// StartWitness enables the collection of the trie nodes and codes accessed
+// from now on. The snapshot is detached as reading it bypasses the tries, the
+// state must not be committed afterwards.
+func (s *StateDB) StartWitness() {
+	s.snap = nil
+	s.witness = &Witness{
+		Nodes: make(map[string]struct{}),
+		Codes: make(map[common.Hash][]byte),
+	}
+}
+
+// Witness returns the trie nodes and codes accessed since the witness collection
+// was started, nil if it's disabled. The trie nodes resolved for the deletions
+// are only included after the state mutations are hashed into the tries.
+func (s *StateDB) Witness() *Witness {
+	if s.witness == nil {
+		return nil
+	}
+	witness := &Witness{
+		Nodes: make(map[string]struct{}, len(s.witness.Nodes)),
+		Codes: make(map[common.Hash][]byte, len(s.witness.Codes)),
+	}
+	witness.add(s.witness.Nodes)
+	for hash, code := range s.witness.Codes {
+		witness.Codes[hash] = code
+	}
+	witness.add(s.trie.Witness())
+	for _, obj := range s.stateObjects {
+		witness.addObject(obj)
+	}
+	return witness
+}
+
+// NewWitnessDatabase creates a state database holding nothing but the trie nodes
+// and codes of the given witness, which is sufficient to replay the transitions
+// the witness was collected for.
+func NewWitnessDatabase(witness *Witness) Database {
+	db := rawdb.NewMemoryDatabase()
+	for node := range witness.Nodes {
+		rawdb.WriteLegacyTrieNode(db, crypto.Keccak256Hash([]byte(node)), []byte(node))
+	}
+	for hash, code := range witness.Codes {
+		rawdb.WriteCode(db, hash, code)
+	}
+	return NewDatabase(db)
+}
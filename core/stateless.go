@@ -0,0 +1,161 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// ExecutionWitness is the data required to execute a block statelessly on top
+// of its parent state root.
+type ExecutionWitness struct {
+	Headers []*types.Header `json:"headers"` // Parent and the ancestors accessed via BLOCKHASH
+	State   []hexutil.Bytes `json:"state"`   // Encoded trie nodes accessed during execution
+	Codes   []hexutil.Bytes `json:"codes"`   // Contract codes accessed during execution
+}
+
+// NewExecutionWitness assembles the execution witness from the ancestor headers
+// and the state accessed during the block execution. The headers are expected
+// in descending order, starting with the parent.
+func NewExecutionWitness(headers []*types.Header, witness *state.Witness) *ExecutionWitness {
+	result := &ExecutionWitness{
+		Headers: headers,
+		State:   make([]hexutil.Bytes, 0, len(witness.Nodes)),
+		Codes:   make([]hexutil.Bytes, 0, len(witness.Codes)),
+	}
+	for node := range witness.Nodes {
+		result.State = append(result.State, hexutil.Bytes(node))
+	}
+	for _, code := range witness.Codes {
+		result.Codes = append(result.Codes, code)
+	}
+	sort.Slice(result.State, func(i, j int) bool { return bytes.Compare(result.State[i], result.State[j]) < 0 })
+	sort.Slice(result.Codes, func(i, j int) bool { return bytes.Compare(result.Codes[i], result.Codes[j]) < 0 })
+	return result
+}
+
+// stateWitness converts the execution witness into the state witness.
+func (w *ExecutionWitness) stateWitness() *state.Witness {
+	witness := &state.Witness{
+		Nodes: make(map[string]struct{}, len(w.State)),
+		Codes: make(map[common.Hash][]byte, len(w.Codes)),
+	}
+	for _, node := range w.State {
+		witness.Nodes[string(node)] = struct{}{}
+	}
+	for _, code := range w.Codes {
+		witness.Codes[crypto.Keccak256Hash(code)] = code
+	}
+	return witness
+}
+
+// witnessChain is the chain context backed by the headers of an execution
+// witness, serving the ancestors for the BLOCKHASH opcode and the engine.
+type witnessChain struct {
+	config  *params.ChainConfig
+	engine  consensus.Engine
+	parent  *types.Header
+	headers map[common.Hash]*types.Header
+}
+
+func newWitnessChain(config *params.ChainConfig, engine consensus.Engine, headers []*types.Header) *witnessChain {
+	chain := &witnessChain{
+		config:  config,
+		engine:  engine,
+		parent:  headers[0],
+		headers: make(map[common.Hash]*types.Header, len(headers)),
+	}
+	for _, header := range headers {
+		chain.headers[header.Hash()] = header
+	}
+	return chain
+}
+
+func (c *witnessChain) Config() *params.ChainConfig  { return c.config }
+func (c *witnessChain) Engine() consensus.Engine     { return c.engine }
+func (c *witnessChain) CurrentHeader() *types.Header { return c.parent }
+
+func (c *witnessChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.headers[hash]; header != nil && header.Number.Uint64() == number {
+		return header
+	}
+	return nil
+}
+
+func (c *witnessChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	return c.headers[hash]
+}
+
+func (c *witnessChain) GetHeaderByNumber(number uint64) *types.Header {
+	for header := c.parent; header != nil; header = c.headers[header.ParentHash] {
+		if header.Number.Uint64() == number {
+			return header
+		}
+	}
+	return nil
+}
+
+func (c *witnessChain) GetTd(hash common.Hash, number uint64) *big.Int { return nil }
+
+// ExecuteStateless executes the block on top of the parent state contained in
+// the witness without access to any database, and verifies the resulting state
+// root, receipts root, bloom and gas used against the block header.
+func ExecuteStateless(config *params.ChainConfig, engine consensus.Engine, block *types.Block, witness *ExecutionWitness, cfg vm.Config) (types.Receipts, error) {
+	if len(witness.Headers) == 0 {
+		return nil, errors.New("parent header missing from witness")
+	}
+	parent := witness.Headers[0]
+	if parent.Hash() != block.ParentHash() || parent.Number.Uint64()+1 != block.NumberU64() {
+		return nil, fmt.Errorf("parent mismatch (have %x, want %x)", parent.Hash(), block.ParentHash())
+	}
+	statedb, err := state.New(parent.Root, state.NewWitnessDatabase(witness.stateWitness()), nil)
+	if err != nil {
+		return nil, err
+	}
+	chain := newWitnessChain(config, engine, witness.Headers)
+	receipts, _, usedGas, err := applyBlock(config, chain, engine, block, statedb, cfg)
+	if err != nil {
+		// A transaction failing on missing witness data is reported as such
+		if dbErr := statedb.Error(); dbErr != nil {
+			err = fmt.Errorf("incomplete witness: %w", dbErr)
+		}
+		return nil, err
+	}
+	// Missing witness data surfaces as a database error, report it explicitly
+	// instead of a root mismatch.
+	statedb.IntermediateRoot(config.IsEIP158(block.Number()))
+	if err := statedb.Error(); err != nil {
+		return nil, fmt.Errorf("incomplete witness: %w", err)
+	}
+	if err := NewBlockValidator(config, nil, engine).ValidateState(block, statedb, receipts, usedGas); err != nil {
+		return nil, err
+	}
+	return receipts, nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that a block can be executed with nothing but the witness collected
+// while processing it, and that incomplete witnesses are rejected.
+func TestExecuteStateless(t *testing.T) {
+	var (
+		engine  = ethash.NewFaker()
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		counter = common.HexToAddress("0xaaaa")
+		gspec   = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: GenesisAlloc{
+				address: {Balance: big.NewInt(1000000000000000000)},
+				// Increments slot 0 and stores the block number into slot NUMBER
+				counter: {
+					Code: []byte{
+						byte(vm.PUSH1), 0x00, byte(vm.SLOAD), byte(vm.PUSH1), 0x01, byte(vm.ADD), byte(vm.PUSH1), 0x00, byte(vm.SSTORE),
+						byte(vm.NUMBER), byte(vm.NUMBER), byte(vm.SSTORE), byte(vm.STOP),
+					},
+					Balance: big.NewInt(0),
+				},
+			},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 4, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{1})
+		tx, _ := types.SignTx(types.NewTransaction(uint64(2*i), common.Address{0x02}, big.NewInt(1000), params.TxGas, b.header.BaseFee, nil), signer, key)
+		b.AddTx(tx)
+		tx, _ = types.SignTx(types.NewTransaction(uint64(2*i+1), counter, nil, 100000, b.header.BaseFee, nil), signer, key)
+		b.AddTx(tx)
+	})
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// Collect the witness of the last block by re-executing it
+	block := blocks[len(blocks)-1]
+	parent := chain.GetHeaderByHash(block.ParentHash())
+
+	statedb, err := state.New(parent.Root, chain.StateCache(), nil)
+	if err != nil {
+		t.Fatalf("failed to open parent state: %v", err)
+	}
+	statedb.StartWitness()
+	if _, _, _, err := chain.Processor().Process(block, statedb, vm.Config{}); err != nil {
+		t.Fatalf("failed to process block: %v", err)
+	}
+	statedb.IntermediateRoot(true)
+	witness := NewExecutionWitness([]*types.Header{parent}, statedb.Witness())
+
+	// Round-trip the witness through JSON as external tools would
+	blob, err := json.Marshal(witness)
+	if err != nil {
+		t.Fatalf("failed to encode witness: %v", err)
+	}
+	var decoded ExecutionWitness
+	if err := json.Unmarshal(blob, &decoded); err != nil {
+		t.Fatalf("failed to decode witness: %v", err)
+	}
+	receipts, err := ExecuteStateless(gspec.Config, engine, block, &decoded, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to execute statelessly: %v", err)
+	}
+	if len(receipts) != len(block.Transactions()) {
+		t.Fatalf("receipt count mismatch: have %d, want %d", len(receipts), len(block.Transactions()))
+	}
+	// Drop each trie node in turn, the execution must fail
+	for i := range witness.State {
+		incomplete := *witness
+		incomplete.State = append(witness.State[:i:i], witness.State[i+1:]...)
+		if _, err := ExecuteStateless(gspec.Config, engine, block, &incomplete, vm.Config{}); err == nil {
+			t.Fatalf("node %d: incomplete witness accepted", i)
+		}
+	}
+	// The witness of another parent must be rejected
+	wrong := *witness
+	wrong.Headers = []*types.Header{chain.GetHeaderByHash(parent.ParentHash)}
+	if _, err := ExecuteStateless(gspec.Config, engine, block, &wrong, vm.Config{}); err == nil {
+		t.Fatal("witness of wrong parent accepted")
+	}
+}
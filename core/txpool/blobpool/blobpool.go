@@ -0,0 +1,909 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package blobpool implements the EIP-4844 blob transaction pool.
+package blobpool
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/leveldb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	// maxBlobsPerTransaction is the maximum number of blobs a single transaction
+	// is allowed to contain. Whilst the spec states it's unlimited, the block
+	// data gas limit is what caps it in practice.
+	maxBlobsPerTransaction = params.MaxDataGasPerBlock / params.BlobTxDataGasPerBlob
+
+	// maxTxsPerAccount is the maximum number of blob transactions admitted from
+	// a single account. The limit is enforced to minimize the DoS potential of
+	// a private tx cancelling publicly propagated blobs.
+	maxTxsPerAccount = 16
+
+	// txMaxSize is the maximum size a single transaction can have, including
+	// its blob sidecar. This limit mostly guards against garbage transactions
+	// as the blobs themselves are already capped.
+	txMaxSize = 1024 * 1024
+)
+
+var (
+	// errMissingSidecar is returned if a blob transaction arrives without its
+	// blobs, commitments and proofs attached.
+	errMissingSidecar = errors.New("missing blob sidecar")
+
+	// errBlobless is returned if a blob transaction does not reference any blobs.
+	errBlobless = errors.New("blobless blob transaction")
+
+	// errTooManyBlobs is returned if a blob transaction references more blobs
+	// than a single block could include.
+	errTooManyBlobs = errors.New("too many blobs in transaction")
+
+	// errBlobCreate is returned if a blob transaction attempts to deploy a contract.
+	errBlobCreate = errors.New("blob transaction of type create")
+
+	// errAccountLimit is returned if an account already has the maximum number
+	// of blob transactions tracked by the pool.
+	errAccountLimit = errors.New("account blob transaction limit exceeded")
+)
+
+var (
+	datacapGauge  = metrics.NewRegisteredGauge("blobpool/datacap", nil)
+	datausedGauge = metrics.NewRegisteredGauge("blobpool/dataused", nil)
+	txsGauge      = metrics.NewRegisteredGauge("blobpool/txs", nil)
+
+	addedMeter       = metrics.NewRegisteredMeter("blobpool/add/valid", nil)
+	invalidMeter     = metrics.NewRegisteredMeter("blobpool/add/invalid", nil)
+	replacedMeter    = metrics.NewRegisteredMeter("blobpool/add/replaced", nil)
+	includedMeter    = metrics.NewRegisteredMeter("blobpool/drop/included", nil)
+	nofundsMeter     = metrics.NewRegisteredMeter("blobpool/drop/nofunds", nil)
+	gappedMeter      = metrics.NewRegisteredMeter("blobpool/drop/gapped", nil)
+	overflowMeter    = metrics.NewRegisteredMeter("blobpool/drop/overflow", nil)
+	corruptedMeter   = metrics.NewRegisteredMeter("blobpool/drop/corrupted", nil)
+	underpricedMeter = metrics.NewRegisteredMeter("blobpool/drop/underpriced", nil)
+)
+
+// blockChain defines the minimal set of methods needed to back a blob pool with
+// a chain. Exists to allow mocking the live chain out of tests.
+type blockChain interface {
+	// Config retrieves the chain's fork configuration.
+	Config() *params.ChainConfig
+
+	// CurrentBlock returns the current head of the chain.
+	CurrentBlock() *types.Header
+
+	// StateAt returns a state database for a given root hash (generally the head).
+	StateAt(root common.Hash) (*state.StateDB, error)
+}
+
+// blobTxMeta is the minimal subset of a blob transaction kept in memory. The
+// blob sidecar itself only lives on disk and is only loaded when requested.
+type blobTxMeta struct {
+	tx    *types.Transaction // Transaction stripped of its blob sidecar
+	size  uint64             // Storage size of the transaction, including the sidecar
+	cost  *big.Int           // Maximum cost the transaction may incur, L1 fees included
+	local bool               // Whether the transaction is exempt from the tip threshold
+}
+
+// BlobPool is the transaction pool dedicated to EIP-4844 blob transactions.
+//
+// Blob transactions are special snowflakes that are designed for a very specific
+// purpose (rollups) and are expected to adhere to that specific use case. These
+// behavioural expectations allow us to design a transaction pool that is more
+// robust (i.e. resending issues) and more resilient to DoS attacks (e.g. replace
+// -flush attacks) than the generic tx pool. These improvements are also mandatory
+// however, since the entire disk content of the pool is multiple orders of
+// magnitude larger than the legacy pool's.
+//
+//   - Blobs are large, so they are kept on disk and only transaction metadata is
+//     tracked in memory. The sidecar is only loaded when a peer requests it.
+//   - Nonce gaps are not allowed: every account's transactions are contiguous
+//     from the current state nonce, so everything tracked is executable.
+//   - Replacements must bump the execution tip, the execution fee cap and the
+//     blob fee cap all by the configured percentage, making fee-bumping attacks
+//     expensive.
+//   - When the disk cap is exceeded, the account whose worst transaction is the
+//     furthest away from the current base and blob fees gets its highest nonce
+//     evicted, repeated until the pool fits into its cap.
+//   - Reorged transactions are not reinjected, since blocks don't carry the blob
+//     sidecars that would be needed to propagate them again.
+type BlobPool struct {
+	config Config       // Pool configuration
+	chain  blockChain   // Chain object to access the state through
+	signer types.Signer // Transaction signer to use for sender recovery
+
+	store   ethdb.KeyValueStore    // Persistent data store for the full transactions
+	reserve txpool.AddressReserver // Address reserver to ensure exclusivity across subpools
+
+	head     *types.Header    // Current head of the chain
+	state    *state.StateDB   // Current state at the head of the chain
+	l1CostFn types.L1CostFunc // Rollup cost function at the head of the chain
+	gasTip   *big.Int         // Currently accepted minimum gas tip
+	basefee  *big.Int         // Base fee of the next block, nil before London
+	blobfee  *big.Int         // Blob fee of the next block
+
+	index  map[common.Address][]*blobTxMeta // Blob transactions grouped by accounts, sorted by nonce
+	spent  map[common.Address]*big.Int      // Expenditure tracking for individual accounts
+	lookup map[common.Hash]common.Address   // Lookup table mapping transactions to their senders
+	stored uint64                           // Useful data size of all transactions on disk
+
+	txFeed event.Feed              // Event feed to send out new tx events on pool inclusion
+	scope  event.SubscriptionScope // Event scope to track and mass unsubscribe on termination
+
+	lock sync.RWMutex // Mutex protecting the pool during reorg handling
+}
+
+// New creates a new blob transaction pool to gather, sort and filter inbound
+// blob transactions from the network.
+func New(config Config, chain blockChain) *BlobPool {
+	// Sanitize the input to ensure no vulnerable gas prices are set
+	config = (&config).sanitize()
+
+	// Create the transaction pool with its initial settings
+	return &BlobPool{
+		config: config,
+		chain:  chain,
+		signer: types.LatestSigner(chain.Config()),
+		index:  make(map[common.Address][]*blobTxMeta),
+		spent:  make(map[common.Address]*big.Int),
+		lookup: make(map[common.Hash]common.Address),
+	}
+}
+
+// Filter returns whether the given transaction can be consumed by the blob pool.
+func (p *BlobPool) Filter(tx *types.Transaction) bool {
+	return tx.Type() == types.BlobTxType
+}
+
+// Init sets the gas price needed to keep a transaction in the pool and the chain
+// head to allow balance / nonce checks. The transaction journal will be loaded
+// from disk and filtered based on the provided starting settings.
+func (p *BlobPool) Init(gasTip *big.Int, head *types.Header, reserve txpool.AddressReserver) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.reserve = reserve
+
+	// Open the persistent store holding the full blob transactions
+	if p.config.Datadir == "" {
+		p.store = memorydb.New()
+	} else {
+		if err := os.MkdirAll(p.config.Datadir, 0700); err != nil {
+			return err
+		}
+		store, err := leveldb.New(p.config.Datadir, 16, 16, "eth/db/blobpool/", false)
+		if err != nil {
+			return err
+		}
+		p.store = store
+	}
+	// If the head state is not available (e.g. snap sync in progress), start
+	// with an empty one. Nothing can be validated yet, so dropping the stale
+	// transactions is the only meaningful thing to do anyway.
+	statedb, err := p.chain.StateAt(head.Root)
+	if err != nil {
+		log.Warn("Blob pool head state unavailable", "number", head.Number, "root", head.Root, "err", err)
+		statedb, _ = state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	}
+	p.gasTip = gasTip
+	p.setHead(head, statedb)
+
+	// Index all transactions on disk, dropping anything unparsable
+	var corrupted [][]byte
+
+	it := p.store.NewIterator(nil, nil)
+	for it.Next() {
+		if err := p.parseTransaction(it.Key(), it.Value()); err != nil {
+			log.Warn("Dropping corrupted blob transaction", "key", common.Bytes2Hex(it.Key()), "err", err)
+			corrupted = append(corrupted, common.CopyBytes(it.Key()))
+		}
+	}
+	it.Release()
+
+	for _, key := range corrupted {
+		if err := p.store.Delete(key); err != nil {
+			log.Error("Failed to delete corrupted blob transaction", "err", err)
+		}
+	}
+	corruptedMeter.Mark(int64(len(corrupted)))
+
+	// Sort the loaded transactions and drop anything not executable anymore
+	for addr, txs := range p.index {
+		sort.Slice(txs, func(i, j int) bool {
+			return txs[i].tx.Nonce() < txs[j].tx.Nonce()
+		})
+		p.recheck(addr)
+	}
+	p.evict()
+	p.updateMetrics()
+
+	log.Info("Blob pool initialized", "txs", len(p.lookup), "size", common.StorageSize(p.stored), "datacap", common.StorageSize(p.config.Datacap))
+	return nil
+}
+
+// parseTransaction is a callback method on pool creation that gets called for
+// each transaction on disk to create the in-memory metadata index.
+func (p *BlobPool) parseTransaction(key []byte, blob []byte) error {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(blob); err != nil {
+		return err
+	}
+	if tx.BlobTxSidecar() == nil {
+		return errMissingSidecar
+	}
+	if hash := tx.Hash(); common.BytesToHash(key) != hash {
+		return fmt.Errorf("hash mismatch: have %x, want %x", hash, key)
+	}
+	sender, err := types.Sender(p.signer, tx)
+	if err != nil {
+		return err
+	}
+	if _, ok := p.index[sender]; !ok {
+		if err := p.reserve(sender, true); err != nil {
+			return err
+		}
+		p.index[sender] = nil
+		p.spent[sender] = new(big.Int)
+	}
+	p.index[sender] = append(p.index[sender], &blobTxMeta{
+		tx:   tx.WithoutBlobTxSidecar(),
+		size: uint64(len(blob)),
+		cost: p.txCost(tx),
+	})
+	p.lookup[tx.Hash()] = sender
+	p.stored += uint64(len(blob))
+	return nil
+}
+
+// Close closes down the underlying persistent store.
+func (p *BlobPool) Close() error {
+	p.scope.Close()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.store == nil {
+		return nil
+	}
+	return p.store.Close()
+}
+
+// setHead updates the chain head related fields of the pool: the state to run
+// the validations against and the fees the next block will require.
+func (p *BlobPool) setHead(head *types.Header, statedb *state.StateDB) {
+	p.head, p.state = head, statedb
+
+	p.l1CostFn = types.NewL1CostFunc(p.chain.Config(), statedb)
+	p.basefee = nil
+	if p.chain.Config().IsLondon(new(big.Int).Add(head.Number, common.Big1)) {
+		p.basefee = misc.CalcBaseFee(p.chain.Config(), head)
+	}
+	// The excess data gas of the next block can't be known without its parent's
+	// data gas usage, so approximate the blob fee with that of the head.
+	p.blobfee = misc.CalcBlobFee(head.ExcessDataGas)
+}
+
+// txCost returns the maximum cost a transaction may incur when executed on top
+// of the current head, including any rollup data fees.
+func (p *BlobPool) txCost(tx *types.Transaction) *big.Int {
+	cost := tx.Cost()
+	if l1Cost := p.l1CostFn(p.head.Number.Uint64(), p.head.Time, tx.RollupDataGas(), tx.IsDepositTx()); l1Cost != nil {
+		cost = cost.Add(cost, l1Cost)
+	}
+	return cost
+}
+
+// recheck verifies the pool's content for a specific account and drops anything
+// that does not fit anymore: transactions included in the chain, those made
+// non-executable by a nonce gap and those the account can't pay for anymore.
+//
+// The pool lock must be held.
+func (p *BlobPool) recheck(addr common.Address) {
+	var (
+		txs     = p.index[addr]
+		nonce   = p.state.GetNonce(addr)
+		balance = p.state.GetBalance(addr)
+		spent   = new(big.Int)
+	)
+	// Drop all the transactions already included in the chain
+	var included int
+	for included < len(txs) && txs[included].tx.Nonce() < nonce {
+		p.drop(addr, txs[included])
+		included++
+	}
+	includedMeter.Mark(int64(included))
+	txs = txs[included:]
+
+	// Keep the executable prefix of the remainder and drop anything after a gap
+	// or the first transaction the account cannot afford
+	var keep int
+	for ; keep < len(txs); keep++ {
+		if txs[keep].tx.Nonce() != nonce+uint64(keep) {
+			gappedMeter.Mark(int64(len(txs) - keep))
+			break
+		}
+		if spent.Add(spent, txs[keep].cost).Cmp(balance) > 0 {
+			spent.Sub(spent, txs[keep].cost)
+			nofundsMeter.Mark(int64(len(txs) - keep))
+			break
+		}
+	}
+	for _, meta := range txs[keep:] {
+		p.drop(addr, meta)
+	}
+	txs = txs[:keep]
+
+	if len(txs) == 0 {
+		p.release(addr)
+		return
+	}
+	p.index[addr] = txs
+	p.spent[addr] = spent
+}
+
+// drop removes a single transaction from the persistent store and the lookup
+// indices, leaving the account index for the caller to update.
+//
+// The pool lock must be held.
+func (p *BlobPool) drop(addr common.Address, meta *blobTxMeta) {
+	hash := meta.tx.Hash()
+	if err := p.store.Delete(hash[:]); err != nil {
+		log.Error("Failed to delete blob transaction", "hash", hash, "err", err)
+	}
+	delete(p.lookup, hash)
+	p.stored -= meta.size
+}
+
+// release removes an account that does not have any transactions in the pool
+// anymore, handing its reservation back to the main pool.
+//
+// The pool lock must be held.
+func (p *BlobPool) release(addr common.Address) {
+	delete(p.index, addr)
+	delete(p.spent, addr)
+	if err := p.reserve(addr, false); err != nil {
+		log.Error("Failed to release blob pool account", "address", addr, "err", err)
+	}
+}
+
+// Reset implements txpool.SubPool, allowing the blob pool's internal state to be
+// kept in sync with the main transaction pool's internal state.
+func (p *BlobPool) Reset(oldHead, newHead *types.Header) {
+	statedb, err := p.chain.StateAt(newHead.Root)
+	if err != nil {
+		log.Error("Failed to reset blobpool state", "err", err)
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.setHead(newHead, statedb)
+	for addr := range p.index {
+		p.recheck(addr)
+	}
+	p.evict()
+	p.updateMetrics()
+}
+
+// SetGasTip implements txpool.SubPool, allowing the blob pool's gas requirements
+// to be kept in sync with the main transaction pool's gas requirements. If the
+// tip was raised, all non-local transactions below it are dropped, along with
+// the higher nonces of their accounts to avoid leaving nonce gaps behind.
+//
+// Transactions loaded back from disk after a restart are not considered local.
+func (p *BlobPool) SetGasTip(tip *big.Int) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	old := p.gasTip
+	p.gasTip = tip
+
+	if old != nil && tip.Cmp(old) <= 0 {
+		return
+	}
+	for addr, txs := range p.index {
+		for i, meta := range txs {
+			if meta.local || meta.tx.GasTipCapIntCmp(tip) >= 0 {
+				continue
+			}
+			for _, drop := range txs[i:] {
+				p.drop(addr, drop)
+			}
+			underpricedMeter.Mark(int64(len(txs) - i))
+
+			if txs = txs[:i]; len(txs) == 0 {
+				p.release(addr)
+				break
+			}
+			p.index[addr] = txs
+
+			spent := new(big.Int)
+			for _, meta := range txs {
+				spent.Add(spent, meta.cost)
+			}
+			p.spent[addr] = spent
+			break
+		}
+	}
+	p.updateMetrics()
+}
+
+// validateTx checks whether a transaction is valid according to the consensus
+// rules and adheres to some heuristic limits of the local node (price and size).
+// It returns the transaction it would replace, if any.
+//
+// The pool lock must be held.
+func (p *BlobPool) validateTx(tx *types.Transaction, local bool) (*blobTxMeta, error) {
+	// Ensure the transaction type is allowed at all at the current head
+	if !p.chain.Config().IsCancun(p.head.Number, p.head.Time) {
+		return nil, core.ErrTxTypeNotSupported
+	}
+	// Ensure the blob sidecar is present and matches the transaction
+	sidecar := tx.BlobTxSidecar()
+	if sidecar == nil {
+		return nil, errMissingSidecar
+	}
+	hashes := tx.BlobHashes()
+	if len(hashes) == 0 {
+		return nil, errBlobless
+	}
+	if len(hashes) > maxBlobsPerTransaction {
+		return nil, fmt.Errorf("%w: have %d, max %d", errTooManyBlobs, len(hashes), maxBlobsPerTransaction)
+	}
+	if len(sidecar.Blobs) != len(hashes) {
+		return nil, fmt.Errorf("invalid number of %d blobs compared to %d blob hashes", len(sidecar.Blobs), len(hashes))
+	}
+	if len(sidecar.Commitments) != len(hashes) {
+		return nil, fmt.Errorf("invalid number of %d blob commitments compared to %d blob hashes", len(sidecar.Commitments), len(hashes))
+	}
+	if len(sidecar.Proofs) != len(hashes) {
+		return nil, fmt.Errorf("invalid number of %d blob proofs compared to %d blob hashes", len(sidecar.Proofs), len(hashes))
+	}
+	for i, vhash := range sidecar.BlobHashes() {
+		if vhash != hashes[i] {
+			return nil, fmt.Errorf("blob %d: computed hash %#x mismatches transaction one %#x", i, vhash, hashes[i])
+		}
+	}
+	for i := range sidecar.Blobs {
+		if err := kzg4844.VerifyBlobProof(sidecar.Blobs[i], sidecar.Commitments[i], sidecar.Proofs[i]); err != nil {
+			return nil, fmt.Errorf("invalid blob %d: %v", i, err)
+		}
+	}
+	// Run the stateless sanity checks shared with the legacy pool
+	if tx.Size() > txMaxSize {
+		return nil, txpool.ErrOversizedData
+	}
+	if tx.To() == nil {
+		return nil, errBlobCreate
+	}
+	if tx.Value().Sign() < 0 {
+		return nil, txpool.ErrNegativeValue
+	}
+	if p.head.GasLimit < tx.Gas() {
+		return nil, txpool.ErrGasLimit
+	}
+	if tx.GasFeeCap().BitLen() > 256 {
+		return nil, core.ErrFeeCapVeryHigh
+	}
+	if tx.GasTipCap().BitLen() > 256 {
+		return nil, core.ErrTipVeryHigh
+	}
+	if tx.GasFeeCapIntCmp(tx.GasTipCap()) < 0 {
+		return nil, core.ErrTipAboveFeeCap
+	}
+	from, err := types.Sender(p.signer, tx)
+	if err != nil {
+		return nil, txpool.ErrInvalidSender
+	}
+	if !local && tx.GasTipCapIntCmp(p.gasTip) < 0 {
+		return nil, txpool.ErrUnderpriced
+	}
+	if tx.BlobGasFeeCapIntCmp(big.NewInt(params.BlobTxMinDataGasprice)) < 0 {
+		return nil, txpool.ErrUnderpriced
+	}
+	intrGas, err := core.IntrinsicGas(tx.Data(), tx.AccessList(), false, true, true, true)
+	if err != nil {
+		return nil, err
+	}
+	if tx.Gas() < intrGas {
+		return nil, core.ErrIntrinsicGas
+	}
+	// Ensure the transaction fits into the account's contiguous nonce range
+	var (
+		txs   = p.index[from]
+		nonce = p.state.GetNonce(from)
+		next  = nonce + uint64(len(txs))
+	)
+	if tx.Nonce() < nonce {
+		return nil, core.ErrNonceTooLow
+	}
+	if tx.Nonce() > next {
+		return nil, core.ErrNonceTooHigh
+	}
+	var prev *blobTxMeta
+	if tx.Nonce() < next {
+		// Replacements need to bump all the fee caps by the configured percentage
+		prev = txs[tx.Nonce()-nonce]
+		if !p.bumped(prev.tx.GasTipCap(), tx.GasTipCap()) ||
+			!p.bumped(prev.tx.GasFeeCap(), tx.GasFeeCap()) ||
+			!p.bumped(prev.tx.BlobGasFeeCap(), tx.BlobGasFeeCap()) {
+			return nil, txpool.ErrReplaceUnderpriced
+		}
+	} else if len(txs) >= maxTxsPerAccount {
+		return nil, errAccountLimit
+	}
+	// Ensure the account can pay for all its transactions, including this one
+	spent := new(big.Int).Add(p.txCost(tx), p.spentBy(from))
+	if prev != nil {
+		spent.Sub(spent, prev.cost)
+	}
+	if p.state.GetBalance(from).Cmp(spent) < 0 {
+		if len(txs) == 0 {
+			return nil, core.ErrInsufficientFunds
+		}
+		return nil, txpool.ErrOverdraft
+	}
+	return prev, nil
+}
+
+// bumped returns whether the new price exceeds the old one by at least the
+// configured price bump percentage.
+func (p *BlobPool) bumped(oldPrice, newPrice *big.Int) bool {
+	threshold := new(big.Int).Mul(oldPrice, big.NewInt(int64(100+p.config.PriceBump)))
+	threshold.Div(threshold, big.NewInt(100))
+	return newPrice.Cmp(threshold) >= 0
+}
+
+// spentBy returns the cumulative cost of all the transactions pooled from the
+// given account.
+func (p *BlobPool) spentBy(addr common.Address) *big.Int {
+	if spent := p.spent[addr]; spent != nil {
+		return spent
+	}
+	return common.Big0
+}
+
+// Has returns an indicator whether subpool has a transaction cached with the
+// given hash.
+func (p *BlobPool) Has(hash common.Hash) bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	_, ok := p.lookup[hash]
+	return ok
+}
+
+// Get returns a transaction if it is contained in the pool, or nil otherwise.
+// The transaction is loaded from disk, complete with its blob sidecar.
+func (p *BlobPool) Get(hash common.Hash) *types.Transaction {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if _, ok := p.lookup[hash]; !ok {
+		return nil
+	}
+	blob, err := p.store.Get(hash[:])
+	if err != nil {
+		log.Error("Tracked blob transaction missing from store", "hash", hash, "err", err)
+		return nil
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(blob); err != nil {
+		log.Error("Blobs corrupted for tracked transaction", "hash", hash, "err", err)
+		return nil
+	}
+	return tx
+}
+
+// Add inserts a set of blob transactions into the pool if they pass validation
+// (both consensus validity and pool restrictions).
+//
+// Blob transactions are always added synchronously, the sync flag is ignored.
+func (p *BlobPool) Add(txs []*types.Transaction, local bool, sync bool) []error {
+	var (
+		errs  = make([]error, len(txs))
+		added = make([]*types.Transaction, 0, len(txs))
+	)
+	p.lock.Lock()
+	for i, tx := range txs {
+		if errs[i] = p.add(tx, local); errs[i] == nil {
+			added = append(added, tx.WithoutBlobTxSidecar())
+		}
+	}
+	p.updateMetrics()
+	p.lock.Unlock()
+
+	if len(added) > 0 {
+		p.txFeed.Send(core.NewTxsEvent{Txs: added})
+	}
+	return errs
+}
+
+// add inserts a single blob transaction into the pool if it passes validation.
+//
+// The pool lock must be held.
+func (p *BlobPool) add(tx *types.Transaction, local bool) error {
+	hash := tx.Hash()
+	if _, ok := p.lookup[hash]; ok {
+		return txpool.ErrAlreadyKnown
+	}
+	prev, err := p.validateTx(tx, local)
+	if err != nil {
+		log.Trace("Transaction validation failed", "hash", hash, "err", err)
+		invalidMeter.Mark(1)
+		return err
+	}
+	blob, err := tx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	// Accounts new to the blob pool need to be reserved from the other pools
+	from, _ := types.Sender(p.signer, tx) // already validated above
+	if _, ok := p.index[from]; !ok {
+		if err := p.reserve(from, true); err != nil {
+			return err
+		}
+		p.spent[from] = new(big.Int)
+	}
+	if err := p.store.Put(hash[:], blob); err != nil {
+		if len(p.index[from]) == 0 {
+			p.release(from)
+		}
+		return err
+	}
+	meta := &blobTxMeta{
+		tx:    tx.WithoutBlobTxSidecar(),
+		size:  uint64(len(blob)),
+		cost:  p.txCost(tx),
+		local: local,
+	}
+	// Insert the transaction into the account index, replacing any previous one
+	// in place, but keep the replaced one on disk until the new one is known to
+	// fit into the pool.
+	idx := int(tx.Nonce() - p.state.GetNonce(from))
+	if prev != nil {
+		p.index[from][idx] = meta
+		p.stored -= prev.size
+	} else {
+		p.index[from] = append(p.index[from], meta)
+	}
+	p.stored += meta.size
+
+	// If the pool outgrew its storage cap, room needs to be made. The new
+	// transaction might be the cheapest of all though, in which case it's
+	// rejected and the pool is left as it was.
+	if drops := p.evictions(); len(p.index[from])-drops[from] <= idx {
+		p.stored -= meta.size
+		if prev != nil {
+			p.index[from][idx] = prev
+			p.stored += prev.size
+		} else if p.index[from] = p.index[from][:idx]; idx == 0 {
+			p.release(from)
+		}
+		if err := p.store.Delete(hash[:]); err != nil {
+			log.Error("Failed to delete blob transaction", "hash", hash, "err", err)
+		}
+		return txpool.ErrUnderpriced
+	}
+	spent := p.spent[from]
+	if prev != nil {
+		// The replaced transaction is out of the index and the storage size
+		// already, only the disk and lookup entries are left to delete
+		prevHash := prev.tx.Hash()
+		if err := p.store.Delete(prevHash[:]); err != nil {
+			log.Error("Failed to delete blob transaction", "hash", prevHash, "err", err)
+		}
+		delete(p.lookup, prevHash)
+		spent.Sub(spent, prev.cost)
+
+		replacedMeter.Mark(1)
+	}
+	spent.Add(spent, meta.cost)
+
+	p.lookup[hash] = from
+	addedMeter.Mark(1)
+
+	p.evict()
+	return nil
+}
+
+// evictions returns the number of transactions to drop from the end of every
+// account's index for the pool to fit into its storage cap, always removing the
+// highest nonce of the account with the worst priority.
+//
+// The pool lock must be held.
+func (p *BlobPool) evictions() map[common.Address]int {
+	var (
+		drops  = make(map[common.Address]int)
+		stored = p.stored
+	)
+	for stored > p.config.Datacap {
+		var (
+			worst    common.Address
+			priority float64
+			found    bool
+		)
+		for addr, txs := range p.index {
+			txs = txs[:len(txs)-drops[addr]]
+			if len(txs) == 0 {
+				continue
+			}
+			prio := p.accountPriority(txs)
+			if !found || prio < priority || (prio == priority && bytes.Compare(addr[:], worst[:]) < 0) {
+				worst, priority, found = addr, prio, true
+			}
+		}
+		if !found {
+			break
+		}
+		txs := p.index[worst]
+		stored -= txs[len(txs)-1-drops[worst]].size
+		drops[worst]++
+	}
+	return drops
+}
+
+// evict drops transactions from the pool until it fits into its storage cap.
+//
+// The pool lock must be held.
+func (p *BlobPool) evict() {
+	for addr, drops := range p.evictions() {
+		txs := p.index[addr]
+		for _, meta := range txs[len(txs)-drops:] {
+			p.drop(addr, meta)
+		}
+		overflowMeter.Mark(int64(drops))
+
+		if txs = txs[:len(txs)-drops]; len(txs) == 0 {
+			p.release(addr)
+			continue
+		}
+		p.index[addr] = txs
+
+		spent := new(big.Int)
+		for _, meta := range txs {
+			spent.Add(spent, meta.cost)
+		}
+		p.spent[addr] = spent
+	}
+}
+
+// Pending retrieves all currently processable transactions, grouped by origin
+// account and sorted by nonce. The transactions are returned without their blob
+// sidecars, which are never included in blocks.
+func (p *BlobPool) Pending(enforceTips bool) map[common.Address]types.Transactions {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	pending := make(map[common.Address]types.Transactions)
+	for addr, txs := range p.index {
+		var lazies types.Transactions
+		for _, meta := range txs {
+			// Stop at the first transaction that cannot be included into the
+			// next block, everything after it is stuck until it becomes valid
+			if p.basefee != nil && meta.tx.GasFeeCapIntCmp(p.basefee) < 0 {
+				break
+			}
+			if meta.tx.BlobGasFeeCapIntCmp(p.blobfee) < 0 {
+				break
+			}
+			if enforceTips && meta.tx.EffectiveGasTipIntCmp(p.gasTip, p.basefee) < 0 {
+				break
+			}
+			lazies = append(lazies, meta.tx)
+		}
+		if len(lazies) > 0 {
+			pending[addr] = lazies
+		}
+	}
+	return pending
+}
+
+// SubscribeNewTxsEvent registers a subscription of NewTxsEvent and starts sending
+// events to the given channel.
+func (p *BlobPool) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
+	return p.scope.Track(p.txFeed.Subscribe(ch))
+}
+
+// Nonce returns the next nonce of an account, with all transactions executable
+// by the pool already applied on top.
+func (p *BlobPool) Nonce(addr common.Address) uint64 {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.state.GetNonce(addr) + uint64(len(p.index[addr]))
+}
+
+// Stats retrieves the current pool stats, namely the number of pending and the
+// number of queued (non-executable) transactions. Since the blob pool does not
+// accept nonce gaps, all tracked transactions are pending.
+func (p *BlobPool) Stats() (int, int) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return len(p.lookup), 0
+}
+
+// Content retrieves the data content of the transaction pool, returning all the
+// pending as well as queued transactions, grouped by account and sorted by nonce.
+// The transactions are returned without their blob sidecars.
+func (p *BlobPool) Content() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	pending := make(map[common.Address]types.Transactions, len(p.index))
+	for addr, txs := range p.index {
+		pending[addr] = p.flatten(txs)
+	}
+	return pending, make(map[common.Address]types.Transactions)
+}
+
+// ContentFrom retrieves the data content of the transaction pool, returning the
+// pending as well as queued transactions of this address, grouped by nonce.
+// The transactions are returned without their blob sidecars.
+func (p *BlobPool) ContentFrom(addr common.Address) (types.Transactions, types.Transactions) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.flatten(p.index[addr]), types.Transactions{}
+}
+
+// flatten returns the sidecar-less transactions of an account's index.
+func (p *BlobPool) flatten(txs []*blobTxMeta) types.Transactions {
+	flat := make(types.Transactions, len(txs))
+	for i, meta := range txs {
+		flat[i] = meta.tx
+	}
+	return flat
+}
+
+// Status returns the known status (unknown/pending/queued) of a transaction
+// identified by its hash.
+func (p *BlobPool) Status(hash common.Hash) txpool.TxStatus {
+	if p.Has(hash) {
+		return txpool.TxStatusPending
+	}
+	return txpool.TxStatusUnknown
+}
+
+// updateMetrics retrieves a bunch of stats from the pool and reports them.
+//
+// The pool lock must be held.
+func (p *BlobPool) updateMetrics() {
+	datacapGauge.Update(int64(p.config.Datacap))
+	datausedGauge.Update(int64(p.stored))
+	txsGauge.Update(int64(len(p.lookup)))
+}
@@ -0,0 +1,469 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package blobpool
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+var (
+	emptyBlob          = kzg4844.Blob{}
+	emptyBlobCommit, _ = kzg4844.BlobToCommitment(emptyBlob)
+	emptyBlobProof, _  = kzg4844.ComputeBlobProof(emptyBlob, emptyBlobCommit)
+)
+
+// testChainConfig is a chain config with all the forks up to Cancun enabled.
+var testChainConfig = func() *params.ChainConfig {
+	config := *params.AllEthashProtocolChanges
+	config.ShanghaiTime = new(uint64)
+	config.CancunTime = new(uint64)
+	return &config
+}()
+
+// testBlockChain is a mock of the live chain for testing the pool.
+type testBlockChain struct {
+	config  *params.ChainConfig
+	statedb *state.StateDB
+}
+
+func (bc *testBlockChain) Config() *params.ChainConfig {
+	return bc.config
+}
+
+func (bc *testBlockChain) CurrentBlock() *types.Header {
+	return &types.Header{
+		Number:   big.NewInt(1),
+		GasLimit: 30_000_000,
+		GasUsed:  15_000_000,
+		BaseFee:  big.NewInt(1),
+	}
+}
+
+func (bc *testBlockChain) StateAt(common.Hash) (*state.StateDB, error) {
+	return bc.statedb, nil
+}
+
+// testReserver is a mock address reserver tracking the accounts of the pool.
+type testReserver map[common.Address]bool
+
+func (r testReserver) reserve(addr common.Address, reserve bool) error {
+	if reserve {
+		r[addr] = true
+	} else {
+		delete(r, addr)
+	}
+	return nil
+}
+
+// makeTx creates a signed blob transaction with a single valid blob attached.
+func makeTx(nonce uint64, tip uint64, feeCap uint64, blobFeeCap uint64, key *ecdsa.PrivateKey) *types.Transaction {
+	return makeMultiBlobTx(nonce, tip, feeCap, blobFeeCap, 1, key)
+}
+
+// makeMultiBlobTx creates a signed blob transaction with the given number of
+// valid blobs attached.
+func makeMultiBlobTx(nonce uint64, tip uint64, feeCap uint64, blobFeeCap uint64, blobs int, key *ecdsa.PrivateKey) *types.Transaction {
+	sidecar := new(types.BlobTxSidecar)
+	for i := 0; i < blobs; i++ {
+		sidecar.Blobs = append(sidecar.Blobs, emptyBlob)
+		sidecar.Commitments = append(sidecar.Commitments, emptyBlobCommit)
+		sidecar.Proofs = append(sidecar.Proofs, emptyBlobProof)
+	}
+	to := common.Address{0x01}
+	return types.MustSignNewTx(key, types.LatestSigner(testChainConfig), &types.BlobTx{
+		ChainID:    uint256.MustFromBig(testChainConfig.ChainID),
+		Nonce:      nonce,
+		GasTipCap:  uint256.NewInt(tip),
+		GasFeeCap:  uint256.NewInt(feeCap),
+		Gas:        21000,
+		To:         &to,
+		Value:      uint256.NewInt(100),
+		BlobFeeCap: uint256.NewInt(blobFeeCap),
+		BlobHashes: sidecar.BlobHashes(),
+		Sidecar:    sidecar,
+	})
+}
+
+// newTestPool creates a blob pool backed by an in-memory chain state in which
+// the given accounts are funded.
+func newTestPool(t *testing.T, config Config, keys ...*ecdsa.PrivateKey) (*BlobPool, *state.StateDB, testReserver) {
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	for _, key := range keys {
+		statedb.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(params.Ether))
+	}
+	chain := &testBlockChain{config: testChainConfig, statedb: statedb}
+	reserver := make(testReserver)
+
+	pool := New(config, chain)
+	if err := pool.Init(big.NewInt(1), chain.CurrentBlock(), reserver.reserve); err != nil {
+		t.Fatalf("failed to initialize pool: %v", err)
+	}
+	return pool, statedb, reserver
+}
+
+// Tests that blob transactions are validated before being accepted, and that
+// the pool serves them with sidecars to the network but without to the miner.
+func TestAdd(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	pool, _, reserver := newTestPool(t, Config{PriceBump: 100, Datacap: 1 << 30}, key)
+	defer pool.Close()
+
+	// Transactions without sidecars, with nonce gaps or mismatching blobs must
+	// be rejected
+	if err := pool.Add([]*types.Transaction{makeTx(0, 1, 1000, 100, key).WithoutBlobTxSidecar()}, false, true)[0]; !errors.Is(err, errMissingSidecar) {
+		t.Errorf("sidecar-less transaction error mismatch: have %v, want %v", err, errMissingSidecar)
+	}
+	if err := pool.Add([]*types.Transaction{makeTx(1, 1, 1000, 100, key)}, false, true)[0]; !errors.Is(err, core.ErrNonceTooHigh) {
+		t.Errorf("gapped transaction error mismatch: have %v, want %v", err, core.ErrNonceTooHigh)
+	}
+	bad := makeTx(0, 1, 1000, 100, key)
+	bad.BlobTxSidecar().Commitments[0][0] ^= 0xff
+	if err := pool.Add([]*types.Transaction{bad}, false, true)[0]; err == nil {
+		t.Errorf("transaction with mismatching commitment accepted")
+	}
+	if len(reserver) != 0 {
+		t.Fatalf("rejected transactions reserved accounts: %v", reserver)
+	}
+	// Valid transactions should be accepted and served
+	tx := makeTx(0, 1, 1000, 100, key)
+	if err := pool.Add([]*types.Transaction{tx}, false, true)[0]; err != nil {
+		t.Fatalf("failed to add valid transaction: %v", err)
+	}
+	if !reserver[addr] {
+		t.Errorf("account not reserved")
+	}
+	if err := pool.Add([]*types.Transaction{tx}, false, true)[0]; !errors.Is(err, txpool.ErrAlreadyKnown) {
+		t.Errorf("duplicate transaction error mismatch: have %v, want %v", err, txpool.ErrAlreadyKnown)
+	}
+	if have := pool.Get(tx.Hash()); have == nil || have.BlobTxSidecar() == nil {
+		t.Errorf("pooled transaction not served with sidecar")
+	}
+	pending := pool.Pending(true)
+	if len(pending[addr]) != 1 || pending[addr][0].Hash() != tx.Hash() {
+		t.Fatalf("pending transactions mismatch: have %v", pending)
+	}
+	if pending[addr][0].BlobTxSidecar() != nil {
+		t.Errorf("pending transaction served with sidecar")
+	}
+	if nonce := pool.Nonce(addr); nonce != 1 {
+		t.Errorf("pending nonce mismatch: have %d, want %d", nonce, 1)
+	}
+	if status := pool.Status(tx.Hash()); status != txpool.TxStatusPending {
+		t.Errorf("transaction status mismatch: have %v, want %v", status, txpool.TxStatusPending)
+	}
+}
+
+// Tests that replacing a pooled transaction requires bumping all of its fee caps.
+func TestReplacement(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+
+	pool, _, _ := newTestPool(t, Config{PriceBump: 100, Datacap: 1 << 30}, key)
+	defer pool.Close()
+
+	tx := makeTx(0, 10, 1000, 100, key)
+	if err := pool.Add([]*types.Transaction{tx}, false, true)[0]; err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	// Bumping only some of the fees must be rejected
+	for i, repl := range []*types.Transaction{
+		makeTx(0, 20, 2000, 199, key),
+		makeTx(0, 20, 1999, 200, key),
+		makeTx(0, 19, 2000, 200, key),
+	} {
+		if err := pool.Add([]*types.Transaction{repl}, false, true)[0]; !errors.Is(err, txpool.ErrReplaceUnderpriced) {
+			t.Errorf("replacement %d: error mismatch: have %v, want %v", i, err, txpool.ErrReplaceUnderpriced)
+		}
+	}
+	// Bumping everything must succeed and drop the original
+	repl := makeTx(0, 20, 2000, 200, key)
+	if err := pool.Add([]*types.Transaction{repl}, false, true)[0]; err != nil {
+		t.Fatalf("failed to replace transaction: %v", err)
+	}
+	if pool.Has(tx.Hash()) {
+		t.Errorf("replaced transaction still pooled")
+	}
+	if !pool.Has(repl.Hash()) {
+		t.Errorf("replacement transaction not pooled")
+	}
+	if pending, _ := pool.Stats(); pending != 1 {
+		t.Errorf("pending transaction count mismatch: have %d, want %d", pending, 1)
+	}
+}
+
+// Tests that when the pool overflows, the transactions least likely to get
+// included based on their blob fee caps get evicted first.
+func TestEviction(t *testing.T) {
+	var keys []*ecdsa.PrivateKey
+	for i := 0; i < 4; i++ {
+		key, _ := crypto.GenerateKey()
+		keys = append(keys, key)
+	}
+	// Measure the size of a transaction to limit the pool to three of them, with
+	// some leeway as signature lengths might differ slightly
+	blob, _ := makeTx(0, 1, 1000, 100, keys[0]).MarshalBinary()
+	size := uint64(len(blob))
+
+	pool, _, reserver := newTestPool(t, Config{PriceBump: 100, Datacap: 3*size + size/2}, keys...)
+	defer pool.Close()
+
+	var txs []*types.Transaction
+	for i, blobFeeCap := range []uint64{100, 10, 1000} {
+		tx := makeTx(0, 1, 1000, blobFeeCap, keys[i])
+		if err := pool.Add([]*types.Transaction{tx}, false, true)[0]; err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+		txs = append(txs, tx)
+	}
+	// Adding a better paying transaction should evict the worst blob payer
+	tx := makeTx(0, 1, 1000, 50, keys[3])
+	if err := pool.Add([]*types.Transaction{tx}, false, true)[0]; err != nil {
+		t.Fatalf("failed to add overflowing transaction: %v", err)
+	}
+	if pool.Has(txs[1].Hash()) {
+		t.Errorf("cheapest transaction not evicted")
+	}
+	if reserver[crypto.PubkeyToAddress(keys[1].PublicKey)] {
+		t.Errorf("evicted account still reserved")
+	}
+	for _, tx := range []*types.Transaction{txs[0], txs[2], tx} {
+		if !pool.Has(tx.Hash()) {
+			t.Errorf("transaction %x evicted", tx.Hash())
+		}
+	}
+	// Adding a transaction worse than everything pooled should be rejected
+	if err := pool.Add([]*types.Transaction{makeTx(0, 1, 1000, 5, keys[1])}, false, true)[0]; !errors.Is(err, txpool.ErrUnderpriced) {
+		t.Errorf("underpriced transaction error mismatch: have %v, want %v", err, txpool.ErrUnderpriced)
+	}
+	if pending, _ := pool.Stats(); pending != 3 {
+		t.Errorf("pending transaction count mismatch: have %d, want %d", pending, 3)
+	}
+}
+
+// Tests that a replacement overflowing the pool is rejected if it would be the
+// one evicted, keeping the replaced transaction, and evicts others otherwise.
+func TestReplacementEviction(t *testing.T) {
+	var keys []*ecdsa.PrivateKey
+	for i := 0; i < 3; i++ {
+		key, _ := crypto.GenerateKey()
+		keys = append(keys, key)
+	}
+	blob, _ := makeTx(0, 1, 1000, 100, keys[0]).MarshalBinary()
+	size := uint64(len(blob))
+
+	pool, _, reserver := newTestPool(t, Config{PriceBump: 100, Datacap: 3*size + size/2}, keys...)
+	defer pool.Close()
+
+	var txs []*types.Transaction
+	for i, blobFeeCap := range []uint64{100, 10, 1000} {
+		tx := makeTx(0, 1, 1000, blobFeeCap, keys[i])
+		if err := pool.Add([]*types.Transaction{tx}, false, true)[0]; err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+		txs = append(txs, tx)
+	}
+	stored := pool.stored
+
+	// A larger replacement of the worst transaction, still the worst, must be
+	// rejected without losing the original
+	repl := makeMultiBlobTx(0, 2, 2000, 20, 2, keys[1])
+	if err := pool.Add([]*types.Transaction{repl}, false, true)[0]; !errors.Is(err, txpool.ErrUnderpriced) {
+		t.Fatalf("overflowing replacement error mismatch: have %v, want %v", err, txpool.ErrUnderpriced)
+	}
+	if pool.Has(repl.Hash()) || pool.Get(repl.Hash()) != nil {
+		t.Errorf("rejected replacement pooled")
+	}
+	for i, tx := range txs {
+		if !pool.Has(tx.Hash()) || pool.Get(tx.Hash()) == nil {
+			t.Errorf("transaction %d dropped", i)
+		}
+	}
+	if pool.stored != stored {
+		t.Errorf("stored size mismatch: have %d, want %d", pool.stored, stored)
+	}
+	if !reserver[crypto.PubkeyToAddress(keys[1].PublicKey)] {
+		t.Errorf("account of rejected replacement released")
+	}
+	// A larger replacement paying better than others should evict the worst
+	repl = makeMultiBlobTx(0, 2, 2000, 2000, 2, keys[1])
+	if err := pool.Add([]*types.Transaction{repl}, false, true)[0]; err != nil {
+		t.Fatalf("failed to replace transaction: %v", err)
+	}
+	if pool.Has(txs[0].Hash()) || pool.Has(txs[1].Hash()) {
+		t.Errorf("evicted or replaced transaction still pooled")
+	}
+	if !pool.Has(repl.Hash()) || !pool.Has(txs[2].Hash()) {
+		t.Errorf("surviving transactions dropped")
+	}
+	if pool.stored > pool.config.Datacap {
+		t.Errorf("pool exceeds its cap: %d > %d", pool.stored, pool.config.Datacap)
+	}
+}
+
+// Tests that the pool drops included and unpayable transactions on reset.
+func TestReset(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	pool, statedb, reserver := newTestPool(t, Config{PriceBump: 100, Datacap: 1 << 30}, key)
+	defer pool.Close()
+
+	var txs []*types.Transaction
+	for nonce := uint64(0); nonce < 3; nonce++ {
+		tx := makeTx(nonce, 1, 1000, 100, key)
+		if err := pool.Add([]*types.Transaction{tx}, false, true)[0]; err != nil {
+			t.Fatalf("failed to add transaction %d: %v", nonce, err)
+		}
+		txs = append(txs, tx)
+	}
+	// Include the first transaction and drain the account enough to only be
+	// able to pay for the second one
+	statedb.SetNonce(addr, 1)
+	statedb.SetBalance(addr, txs[1].Cost())
+
+	head := pool.chain.CurrentBlock()
+	pool.Reset(head, head)
+
+	if pool.Has(txs[0].Hash()) {
+		t.Errorf("included transaction not dropped")
+	}
+	if !pool.Has(txs[1].Hash()) {
+		t.Errorf("payable transaction dropped")
+	}
+	if pool.Has(txs[2].Hash()) {
+		t.Errorf("unpayable transaction not dropped")
+	}
+	// Including everything should release the account
+	statedb.SetNonce(addr, 3)
+	pool.Reset(head, head)
+
+	if pending, _ := pool.Stats(); pending != 0 {
+		t.Errorf("pending transaction count mismatch: have %d, want %d", pending, 0)
+	}
+	if reserver[addr] {
+		t.Errorf("emptied account still reserved")
+	}
+}
+
+// Tests that raising the gas tip drops the underpriced remote transactions along
+// with their higher nonces, but keeps the local ones.
+func TestSetGasTip(t *testing.T) {
+	var (
+		key1, _ = crypto.GenerateKey()
+		key2, _ = crypto.GenerateKey()
+		key3, _ = crypto.GenerateKey()
+		addr3   = crypto.PubkeyToAddress(key3.PublicKey)
+	)
+	pool, _, reserver := newTestPool(t, Config{PriceBump: 100, Datacap: 1 << 30}, key1, key2, key3)
+	defer pool.Close()
+
+	var (
+		pricey = makeTx(0, 10, 1000, 100, key1)
+		cheap  = makeTx(1, 1, 1000, 100, key1)
+		after  = makeTx(2, 10, 1000, 100, key1)
+		local  = makeTx(0, 1, 1000, 100, key2)
+		remote = makeTx(0, 1, 1000, 100, key3)
+	)
+	for _, tx := range []*types.Transaction{pricey, cheap, after, remote} {
+		if err := pool.Add([]*types.Transaction{tx}, false, true)[0]; err != nil {
+			t.Fatalf("failed to add remote transaction: %v", err)
+		}
+	}
+	if err := pool.Add([]*types.Transaction{local}, true, true)[0]; err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	stored := pool.stored
+
+	// Lowering the tip should not drop anything
+	pool.SetGasTip(big.NewInt(1))
+	if pending, _ := pool.Stats(); pending != 5 {
+		t.Fatalf("pending transaction count mismatch: have %d, want %d", pending, 5)
+	}
+	// Raising it should drop the cheap remotes, gapped followers included
+	pool.SetGasTip(big.NewInt(5))
+
+	for _, tx := range []*types.Transaction{pricey, local} {
+		if !pool.Has(tx.Hash()) {
+			t.Errorf("transaction %#x dropped", tx.Hash())
+		}
+	}
+	for _, tx := range []*types.Transaction{cheap, after, remote} {
+		blob, _ := tx.MarshalBinary()
+		stored -= uint64(len(blob))
+
+		if pool.Has(tx.Hash()) {
+			t.Errorf("transaction %#x not dropped", tx.Hash())
+		}
+		if blob, _ := pool.store.Get(tx.Hash().Bytes()); blob != nil {
+			t.Errorf("transaction %#x not deleted from disk", tx.Hash())
+		}
+	}
+	if pending, _ := pool.Stats(); pending != 2 {
+		t.Errorf("pending transaction count mismatch: have %d, want %d", pending, 2)
+	}
+	if pool.stored != stored {
+		t.Errorf("stored size mismatch: have %d, want %d", pool.stored, stored)
+	}
+	if reserver[addr3] {
+		t.Errorf("emptied account still reserved")
+	}
+}
+
+// Tests that pooled transactions survive a restart.
+func TestPersistence(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	config := Config{Datadir: t.TempDir(), PriceBump: 100, Datacap: 1 << 30}
+
+	pool, statedb, _ := newTestPool(t, config, key)
+	tx := makeTx(0, 1, 1000, 100, key)
+	if err := pool.Add([]*types.Transaction{tx}, false, true)[0]; err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if err := pool.Close(); err != nil {
+		t.Fatalf("failed to close pool: %v", err)
+	}
+	chain := &testBlockChain{config: testChainConfig, statedb: statedb}
+	reserver := make(testReserver)
+
+	pool = New(config, chain)
+	if err := pool.Init(big.NewInt(1), chain.CurrentBlock(), reserver.reserve); err != nil {
+		t.Fatalf("failed to reinitialize pool: %v", err)
+	}
+	defer pool.Close()
+
+	if have := pool.Get(tx.Hash()); have == nil || have.BlobTxSidecar() == nil {
+		t.Fatalf("transaction not reloaded with its sidecar")
+	}
+	if !reserver[crypto.PubkeyToAddress(key.PublicKey)] {
+		t.Errorf("reloaded account not reserved")
+	}
+}
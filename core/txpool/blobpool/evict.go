@@ -0,0 +1,58 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package blobpool
+
+import (
+	"math"
+	"math/big"
+)
+
+// log1_125 is used in the eviction priority calculation.
+var log1_125 = math.Log(1.125)
+
+// accountPriority calculates the eviction priority of an account based on its
+// pooled transactions. An account is only as good as its worst transaction,
+// since everything queued up behind it cannot be included before it is.
+//
+// The pool lock must be held.
+func (p *BlobPool) accountPriority(txs []*blobTxMeta) float64 {
+	priority := math.Inf(1)
+	for _, meta := range txs {
+		// The priority of a transaction is the number of fee jumps its caps are
+		// away from the network fees, the blob fee and execution fee weighing
+		// equally. Whichever is closer to (or deeper under) the network fee
+		// decides how long the transaction is likely to stay stuck.
+		prio := feeJumps(meta.tx.BlobGasFeeCap()) - feeJumps(p.blobfee)
+		if p.basefee != nil {
+			prio = math.Min(prio, feeJumps(meta.tx.GasFeeCap())-feeJumps(p.basefee))
+		}
+		priority = math.Min(priority, prio)
+	}
+	return priority
+}
+
+// feeJumps calculates the log1.125(fee), namely the number of fee jumps needed
+// to reach the requested one. Since both the base fee and the blob fee can move
+// by at most 12.5% per block, this approximates the number of blocks it takes
+// for the network fee to reach a transaction's cap.
+func feeJumps(fee *big.Int) float64 {
+	if fee.Sign() <= 0 {
+		return 0
+	}
+	f, _ := new(big.Float).SetInt(fee).Float64()
+	return math.Log(f) / log1_125
+}
@@ -0,0 +1,208 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// dumpEntry is a single transaction in a dump of the pool content, along with
+// the metadata needed to restore it into another pool as it was.
+type dumpEntry struct {
+	Tx    *types.Transaction
+	Time  uint64 // Arrival time of the transaction, in unix nanoseconds
+	Local bool   // Whether the transaction was tracked as local
+}
+
+// DumpCursor is the position of a paged export, identifying the last exported
+// transaction by its sender and nonce.
+type DumpCursor struct {
+	Account common.Address
+	Nonce   uint64
+}
+
+// dumpItem is a dump entry along with its position in the export order.
+type dumpItem struct {
+	from  common.Address
+	entry *dumpEntry
+}
+
+// Export writes all the pending and queued transactions of the pool into the
+// given writer as a stream of RLP encoded entries, ordered by nonce for every
+// account. Private transactions are left out. Transactions of the subpools are
+// exported along with their blob sidecars, but they aren't tracked as local.
+func (pool *TxPool) Export(w io.Writer) (int, error) {
+	n, _, err := pool.ExportPage(w, nil, 0)
+	return n, err
+}
+
+// ExportPage is like Export, but only writes the transactions positioned after
+// the given cursor (nil meaning from the start), ordered by sender and nonce, and
+// stops before exceeding the given number of bytes (0 meaning unlimited). At
+// least one transaction is always written if any is left. The returned cursor
+// is the position to continue from, nil if the export is complete.
+//
+// Since the pool keeps changing in between pages, transactions added before the
+// cursor after the export started will be missing from the dump.
+func (pool *TxPool) ExportPage(w io.Writer, after *DumpCursor, limit int) (int, *DumpCursor, error) {
+	var items []*dumpItem
+
+	pool.mu.RLock()
+	for _, set := range []map[common.Address]*list{pool.pending, pool.queue} {
+		for addr, list := range set {
+			local := pool.locals.contains(addr)
+			for _, tx := range list.Flatten() {
+				if pool.all.IsPrivate(tx.Hash()) {
+					continue
+				}
+				items = append(items, &dumpItem{from: addr, entry: &dumpEntry{
+					Tx:    tx,
+					Time:  uint64(tx.Time().UnixNano()),
+					Local: local,
+				}})
+			}
+		}
+	}
+	pool.mu.RUnlock()
+
+	// The subpools only hand out their transactions without the sidecars, so
+	// retrieve the complete ones one by one. Anything gone in the meantime is
+	// simply skipped.
+	for _, sp := range pool.subpools {
+		pending, queued := sp.Content()
+		for _, set := range []map[common.Address]types.Transactions{pending, queued} {
+			for addr, txs := range set {
+				for _, tx := range txs {
+					if after != nil && !dumpAfter(addr, tx.Nonce(), after) {
+						continue
+					}
+					full := sp.Get(tx.Hash())
+					if full == nil {
+						continue
+					}
+					items = append(items, &dumpItem{from: addr, entry: &dumpEntry{
+						Tx:   full,
+						Time: uint64(tx.Time().UnixNano()),
+					}})
+				}
+			}
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if c := bytes.Compare(items[i].from[:], items[j].from[:]); c != 0 {
+			return c < 0
+		}
+		return items[i].entry.Tx.Nonce() < items[j].entry.Tx.Nonce()
+	})
+	if after != nil {
+		items = items[sort.Search(len(items), func(i int) bool {
+			return dumpAfter(items[i].from, items[i].entry.Tx.Nonce(), after)
+		}):]
+	}
+	var size int
+	for i, item := range items {
+		blob, err := rlp.EncodeToBytes(item.entry)
+		if err != nil {
+			return i, nil, err
+		}
+		if limit > 0 && i > 0 && size+len(blob) > limit {
+			last := items[i-1]
+			return i, &DumpCursor{Account: last.from, Nonce: last.entry.Tx.Nonce()}, nil
+		}
+		if _, err := w.Write(blob); err != nil {
+			return i, nil, err
+		}
+		size += len(blob)
+	}
+	return len(items), nil, nil
+}
+
+// dumpAfter returns whether a transaction is positioned after the cursor in the
+// export order.
+func dumpAfter(from common.Address, nonce uint64, cursor *DumpCursor) bool {
+	if c := bytes.Compare(from[:], cursor.Account[:]); c != 0 {
+		return c > 0
+	}
+	return nonce > cursor.Nonce
+}
+
+// Import reads a transaction stream produced by Export and injects the contents
+// into the pool, retaining the original arrival times and local flags. Invalid
+// transactions are skipped, their number being returned along with the count of
+// the imported ones.
+func (pool *TxPool) Import(r io.Reader) (int, int, error) {
+	var (
+		stream  = rlp.NewStream(r, 0)
+		total   int
+		dropped int
+		failure error
+
+		locals  types.Transactions
+		remotes types.Transactions
+	)
+	// Create a method to inject a limited batch of transactions and bump the
+	// dropped counter, then use it to inject the dump in small-ish batches.
+	flush := func() {
+		for _, batch := range []struct {
+			txs   types.Transactions
+			local bool
+		}{{locals, !pool.config.NoLocals}, {remotes, false}} {
+			if len(batch.txs) == 0 {
+				continue
+			}
+			for _, err := range pool.addTxs(batch.txs, batch.local, true) {
+				if err != nil {
+					log.Debug("Failed to import transaction", "err", err)
+					dropped++
+				}
+			}
+		}
+		locals, remotes = locals[:0], remotes[:0]
+	}
+	for {
+		entry := new(dumpEntry)
+		if err := stream.Decode(entry); err != nil {
+			if err != io.EOF {
+				failure = err
+			}
+			break
+		}
+		entry.Tx.SetTime(time.Unix(0, int64(entry.Time)))
+		total++
+
+		if entry.Local {
+			locals = append(locals, entry.Tx)
+		} else {
+			remotes = append(remotes, entry.Tx)
+		}
+		if len(locals)+len(remotes) > 1024 {
+			flush()
+		}
+	}
+	flush()
+
+	log.Info("Imported transaction pool dump", "transactions", total, "dropped", dropped)
+	return total - dropped, dropped, failure
+}
@@ -0,0 +1,255 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the content of a pool can be exported and imported into another
+// one, retaining the arrival times and the local flags of the transactions.
+func TestExportImport(t *testing.T) {
+	t.Parallel()
+
+	newPool := func() *TxPool {
+		statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
+		return NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
+	}
+	source, sink := newPool(), newPool()
+	defer source.Stop()
+	defer sink.Stop()
+
+	local, _ := crypto.GenerateKey()
+	remote, _ := crypto.GenerateKey()
+	for _, pool := range []*TxPool{source, sink} {
+		testAddBalance(pool, crypto.PubkeyToAddress(local.PublicKey), big.NewInt(1000000000))
+		testAddBalance(pool, crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(1000000000))
+	}
+	// Fill the source pool with pending and queued transactions
+	var (
+		arrival = time.Unix(1600000000, 123456789)
+		locals  = []*types.Transaction{transaction(0, 100000, local), transaction(1, 100000, local), transaction(3, 100000, local)}
+		remotes = []*types.Transaction{transaction(0, 100000, remote), transaction(2, 100000, remote)}
+	)
+	for i, tx := range append(append([]*types.Transaction{}, locals...), remotes...) {
+		tx.SetTime(arrival.Add(time.Duration(i) * time.Second))
+	}
+	for i, err := range source.AddLocals(locals) {
+		if err != nil {
+			t.Fatalf("failed to add local transaction %d: %v", i, err)
+		}
+	}
+	for i, err := range source.AddRemotesSync(remotes) {
+		if err != nil {
+			t.Fatalf("failed to add remote transaction %d: %v", i, err)
+		}
+	}
+	// Private transactions should not be exported
+	private, _ := crypto.GenerateKey()
+	testAddBalance(source, crypto.PubkeyToAddress(private.PublicKey), big.NewInt(1000000000))
+	if err := source.AddPrivate(transaction(0, 100000, private)); err != nil {
+		t.Fatalf("failed to add private transaction: %v", err)
+	}
+	// Export the source pool and import it into the sink
+	var dump bytes.Buffer
+	if n, err := source.Export(&dump); err != nil || n != len(locals)+len(remotes) {
+		t.Fatalf("export mismatch: have %d/%v, want %d/nil", n, err, len(locals)+len(remotes))
+	}
+	imported, dropped, err := sink.Import(&dump)
+	if err != nil {
+		t.Fatalf("failed to import dump: %v", err)
+	}
+	if imported != len(locals)+len(remotes) || dropped != 0 {
+		t.Fatalf("import mismatch: have %d/%d, want %d/%d", imported, dropped, len(locals)+len(remotes), 0)
+	}
+	if pending, queued := sink.Stats(); pending != 3 || queued != 2 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want %d/%d", pending, queued, 3, 2)
+	}
+	for _, tx := range append(append([]*types.Transaction{}, locals...), remotes...) {
+		have := sink.Get(tx.Hash())
+		if have == nil {
+			t.Fatalf("transaction %x missing from sink", tx.Hash())
+		}
+		if !have.Time().Equal(tx.Time()) {
+			t.Errorf("transaction %x time mismatch: have %v, want %v", tx.Hash(), have.Time(), tx.Time())
+		}
+	}
+	if locals := sink.Locals(); len(locals) != 1 || locals[0] != crypto.PubkeyToAddress(local.PublicKey) {
+		t.Errorf("local accounts mismatch: have %v, want [%v]", locals, crypto.PubkeyToAddress(local.PublicKey))
+	}
+	if err := validatePoolInternals(sink); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	// Importing the same dump again should drop everything as known
+	dump.Reset()
+	source.Export(&dump)
+	if imported, dropped, _ := sink.Import(&dump); imported != 0 || dropped != len(locals)+len(remotes) {
+		t.Fatalf("reimport mismatch: have %d/%d, want %d/%d", imported, dropped, 0, len(locals)+len(remotes))
+	}
+}
+
+// Tests that the transactions of the subpools are exported and imported back
+// into them.
+func TestExportImportSubPool(t *testing.T) {
+	t.Parallel()
+
+	newPool := func() (*TxPool, *testSubPool) {
+		statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
+		sub := &testSubPool{signer: types.LatestSigner(params.TestChainConfig)}
+		return NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain, sub), sub
+	}
+	source, _ := newPool()
+	sink, sinkSub := newPool()
+	defer source.Stop()
+	defer sink.Stop()
+
+	key, _ := crypto.GenerateKey()
+	special := types.MustSignNewTx(key, types.LatestSigner(params.TestChainConfig), &types.AccessListTx{
+		ChainID:  params.TestChainConfig.ChainID,
+		Gas:      100000,
+		GasPrice: big.NewInt(1),
+	})
+	if err := source.AddRemotesSync([]*types.Transaction{special})[0]; err != nil {
+		t.Fatalf("failed to add subpool transaction: %v", err)
+	}
+	var dump bytes.Buffer
+	if n, err := source.Export(&dump); err != nil || n != 1 {
+		t.Fatalf("export mismatch: have %d/%v, want %d/nil", n, err, 1)
+	}
+	if imported, dropped, err := sink.Import(&dump); err != nil || imported != 1 || dropped != 0 {
+		t.Fatalf("import mismatch: have %d/%d/%v, want %d/%d/nil", imported, dropped, err, 1, 0)
+	}
+	if !sinkSub.Has(special.Hash()) {
+		t.Fatalf("subpool transaction not imported into the subpool")
+	}
+}
+
+// Tests that exporting a pool page by page produces the same dump as exporting
+// it at once, every page holding at least one transaction.
+func TestExportPaged(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
+	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	var txs []*types.Transaction
+	for i := 0; i < 3; i++ {
+		key, _ := crypto.GenerateKey()
+		testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+		for nonce := uint64(0); nonce < 3; nonce++ {
+			txs = append(txs, transaction(nonce, 100000, key))
+		}
+	}
+	for i, err := range pool.AddRemotesSync(txs) {
+		if err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+	}
+	var full bytes.Buffer
+	if _, err := pool.Export(&full); err != nil {
+		t.Fatalf("failed to export pool: %v", err)
+	}
+	// Export with a limit of just above two transactions, and once with a limit
+	// below a single one, which should still make progress
+	size := full.Len() / len(txs)
+	for _, tt := range []struct {
+		limit int
+		pages int
+	}{{2*size + size/2, 5}, {1, len(txs)}} {
+		limit := tt.limit
+		var (
+			paged  bytes.Buffer
+			cursor *DumpCursor
+			pages  int
+			total  int
+		)
+		for {
+			var page bytes.Buffer
+			n, next, err := pool.ExportPage(&page, cursor, limit)
+			if err != nil {
+				t.Fatalf("limit %d: failed to export page %d: %v", limit, pages, err)
+			}
+			if n == 0 {
+				t.Fatalf("limit %d: page %d empty", limit, pages)
+			}
+			paged.Write(page.Bytes())
+			total += n
+			pages++
+
+			if cursor = next; cursor == nil {
+				break
+			}
+		}
+		if total != len(txs) {
+			t.Errorf("limit %d: exported transaction count mismatch: have %d, want %d", limit, total, len(txs))
+		}
+		if pages != tt.pages {
+			t.Errorf("limit %d: page count mismatch: have %d, want %d", limit, pages, tt.pages)
+		}
+		if !bytes.Equal(paged.Bytes(), full.Bytes()) {
+			t.Errorf("limit %d: paged dump mismatch", limit)
+		}
+	}
+}
+
+// Tests that importing a corrupted dump reports the transactions imported before
+// the corruption along with the error.
+func TestImportCorrupted(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
+	source := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
+	defer source.Stop()
+
+	key, _ := crypto.GenerateKey()
+	testAddBalance(source, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+	if err := source.addRemoteSync(transaction(0, 100000, key)); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	var dump bytes.Buffer
+	source.Export(&dump)
+	dump.Write([]byte{0xc5, 0x01})
+
+	statedb, _ = state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain = newTestBlockChain(1000000, statedb, new(event.Feed))
+	sink := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
+	defer sink.Stop()
+	testAddBalance(sink, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	imported, dropped, err := sink.Import(&dump)
+	if err == nil {
+		t.Fatalf("corrupted dump imported without error")
+	}
+	if imported != 1 || dropped != 0 {
+		t.Fatalf("import mismatch: have %d/%d, want %d/%d", imported, dropped, 1, 0)
+	}
+}
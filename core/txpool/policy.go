@@ -0,0 +1,333 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// ErrAdmissionDenied is returned if a transaction is rejected by the configured
+// admission policy of the pool, independently of its validity. The returned error
+// wraps this one with the reason of the rejection.
+var ErrAdmissionDenied = errors.New("transaction denied by admission policy")
+
+var (
+	// policyReloadInterval is the time interval to check the policy file for changes.
+	policyReloadInterval = 5 * time.Second
+)
+
+var (
+	// Metrics for the transactions rejected by the admission policy
+	policySenderMeter    = metrics.NewRegisteredMeter("txpool/policy/sender", nil)
+	policyRecipientMeter = metrics.NewRegisteredMeter("txpool/policy/recipient", nil)
+	policyMethodMeter    = metrics.NewRegisteredMeter("txpool/policy/method", nil)
+	policyCreateMeter    = metrics.NewRegisteredMeter("txpool/policy/create", nil)
+	policyRegistryMeter  = metrics.NewRegisteredMeter("txpool/policy/registry", nil)
+)
+
+// admissionPolicy is a custom filter deciding whether a transaction is allowed
+// into the pool, consulted before any validation takes place.
+type admissionPolicy interface {
+	// admit returns an error wrapping ErrAdmissionDenied if the transaction sent
+	// by the given account should be rejected.
+	admit(tx *types.Transaction, from common.Address) error
+
+	// close terminates any background processing of the policy.
+	close()
+}
+
+// addressList is a pair of allow- and deny-lists of accounts in the policy file.
+type addressList struct {
+	Allow []common.Address `json:"allow"`
+	Deny  []common.Address `json:"deny"`
+}
+
+// methodList is a pair of allow- and deny-lists of method selectors in the
+// policy file.
+type methodList struct {
+	Allow []hexutil.Bytes `json:"allow"`
+	Deny  []hexutil.Bytes `json:"deny"`
+}
+
+// policyFile is the on-disk JSON format of the static admission lists.
+type policyFile struct {
+	Senders              addressList `json:"senders"`
+	Recipients           addressList `json:"recipients"`
+	Methods              methodList  `json:"methods"`
+	DenyContractCreation bool        `json:"denyContractCreation"`
+}
+
+// policyRules is the parsed form of a policy file. Deny-lists always win, while
+// non-empty allow-lists only let through the entries explicitly listed.
+type policyRules struct {
+	senderAllow    map[common.Address]struct{}
+	senderDeny     map[common.Address]struct{}
+	recipientAllow map[common.Address]struct{}
+	recipientDeny  map[common.Address]struct{}
+	methodAllow    map[[4]byte]struct{}
+	methodDeny     map[[4]byte]struct{}
+	denyCreate     bool
+	failure        error // Reason for denying everything if the policy could not be loaded
+}
+
+// parsePolicy parses the JSON content of a policy file into a set of rules.
+func parsePolicy(blob []byte) (*policyRules, error) {
+	var file policyFile
+	if err := json.Unmarshal(blob, &file); err != nil {
+		return nil, err
+	}
+	rules := &policyRules{
+		senderAllow:    addressSet(file.Senders.Allow),
+		senderDeny:     addressSet(file.Senders.Deny),
+		recipientAllow: addressSet(file.Recipients.Allow),
+		recipientDeny:  addressSet(file.Recipients.Deny),
+		methodAllow:    make(map[[4]byte]struct{}),
+		methodDeny:     make(map[[4]byte]struct{}),
+		denyCreate:     file.DenyContractCreation,
+	}
+	for _, list := range []struct {
+		selectors []hexutil.Bytes
+		set       map[[4]byte]struct{}
+	}{{file.Methods.Allow, rules.methodAllow}, {file.Methods.Deny, rules.methodDeny}} {
+		for _, selector := range list.selectors {
+			if len(selector) != 4 {
+				return nil, fmt.Errorf("invalid method selector %v: want 4 bytes, have %d", selector, len(selector))
+			}
+			var sel [4]byte
+			copy(sel[:], selector)
+			list.set[sel] = struct{}{}
+		}
+	}
+	return rules, nil
+}
+
+// addressSet converts a list of addresses into a set.
+func addressSet(addrs []common.Address) map[common.Address]struct{} {
+	set := make(map[common.Address]struct{}, len(addrs))
+	for _, addr := range addrs {
+		set[addr] = struct{}{}
+	}
+	return set
+}
+
+// check runs a transaction through the static rules.
+func (r *policyRules) check(tx *types.Transaction, from common.Address) error {
+	if r.failure != nil {
+		return fmt.Errorf("%w: policy unavailable: %v", ErrAdmissionDenied, r.failure)
+	}
+	if _, ok := r.senderDeny[from]; ok {
+		policySenderMeter.Mark(1)
+		return fmt.Errorf("%w: sender %v is denied", ErrAdmissionDenied, from)
+	}
+	if _, ok := r.senderAllow[from]; !ok && len(r.senderAllow) > 0 {
+		policySenderMeter.Mark(1)
+		return fmt.Errorf("%w: sender %v is not allowed", ErrAdmissionDenied, from)
+	}
+	to := tx.To()
+	if to == nil {
+		if r.denyCreate {
+			policyCreateMeter.Mark(1)
+			return fmt.Errorf("%w: contract creation is denied", ErrAdmissionDenied)
+		}
+		return nil
+	}
+	if _, ok := r.recipientDeny[*to]; ok {
+		policyRecipientMeter.Mark(1)
+		return fmt.Errorf("%w: recipient %v is denied", ErrAdmissionDenied, *to)
+	}
+	if _, ok := r.recipientAllow[*to]; !ok && len(r.recipientAllow) > 0 {
+		policyRecipientMeter.Mark(1)
+		return fmt.Errorf("%w: recipient %v is not allowed", ErrAdmissionDenied, *to)
+	}
+	// Method selectors only apply to calls carrying at least a selector, plain
+	// transfers are governed by the recipient lists alone
+	if data := tx.Data(); len(data) >= 4 {
+		var selector [4]byte
+		copy(selector[:], data)
+		if _, ok := r.methodDeny[selector]; ok {
+			policyMethodMeter.Mark(1)
+			return fmt.Errorf("%w: method %#x is denied", ErrAdmissionDenied, selector)
+		}
+		if _, ok := r.methodAllow[selector]; !ok && len(r.methodAllow) > 0 {
+			policyMethodMeter.Mark(1)
+			return fmt.Errorf("%w: method %#x is not allowed", ErrAdmissionDenied, selector)
+		}
+	}
+	return nil
+}
+
+// filePolicy is an admission policy backed by a JSON file of static allow- and
+// deny-lists, which is reloaded whenever the file changes on disk.
+type filePolicy struct {
+	path    string                      // Filesystem path of the policy file
+	rules   atomic.Pointer[policyRules] // Currently active rules
+	modtime time.Time                   // Modification time of the active rules' file
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newFilePolicy loads the policy file from the given path and starts watching
+// it for changes. Running without the configured controls is not an option, so
+// if the file cannot be loaded, everything is denied until it gets fixed.
+func newFilePolicy(path string) *filePolicy {
+	p := &filePolicy{
+		path: path,
+		quit: make(chan struct{}),
+	}
+	if err := p.reload(); err != nil {
+		log.Error("Failed to load txpool admission policy", "err", err)
+		p.rules.Store(&policyRules{failure: err})
+	}
+	p.wg.Add(1)
+	go p.loop()
+	return p
+}
+
+// reload parses the policy file if it changed since it was last loaded.
+func (p *filePolicy) reload() error {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(p.modtime) {
+		return nil
+	}
+	blob, err := os.ReadFile(p.path)
+	if err != nil {
+		return err
+	}
+	rules, err := parsePolicy(blob)
+	if err != nil {
+		return fmt.Errorf("invalid policy file %s: %v", p.path, err)
+	}
+	p.rules.Store(rules)
+	p.modtime = info.ModTime()
+
+	log.Info("Loaded txpool admission policy", "path", p.path,
+		"senders", len(rules.senderAllow)+len(rules.senderDeny),
+		"recipients", len(rules.recipientAllow)+len(rules.recipientDeny),
+		"methods", len(rules.methodAllow)+len(rules.methodDeny))
+	return nil
+}
+
+// loop periodically checks the policy file for changes. Broken updates are
+// reported, but the previously loaded rules stay in effect until fixed.
+func (p *filePolicy) loop() {
+	defer p.wg.Done()
+
+	reload := time.NewTicker(policyReloadInterval)
+	defer reload.Stop()
+
+	for {
+		select {
+		case <-reload.C:
+			if err := p.reload(); err != nil {
+				log.Error("Failed to reload txpool admission policy", "err", err)
+			}
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+func (p *filePolicy) admit(tx *types.Transaction, from common.Address) error {
+	return p.rules.Load().check(tx, from)
+}
+
+func (p *filePolicy) close() {
+	close(p.quit)
+	p.wg.Wait()
+}
+
+// registryPolicy is an admission policy backed by a deny-list contract in the
+// chain state. The contract is expected to hold a mapping(address => uint256)
+// in its first storage slot, with any non-zero value denying the account both
+// as sender and as recipient.
+type registryPolicy struct {
+	chain    blockChain     // Chain to read the registry state from
+	registry common.Address // Address of the registry contract
+
+	root  common.Hash    // State root the registry was last read at
+	state *state.StateDB // State the registry was last read from
+	lock  sync.Mutex     // Lock protecting the cached state
+}
+
+// newRegistryPolicy creates an admission policy reading the deny-list from the
+// given contract.
+func newRegistryPolicy(chain blockChain, registry common.Address) *registryPolicy {
+	return &registryPolicy{
+		chain:    chain,
+		registry: registry,
+	}
+}
+
+// denied returns whether the registry has the given account denied at the head
+// state of the chain.
+func (p *registryPolicy) denied(addrs ...common.Address) (common.Address, bool, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if head := p.chain.CurrentBlock(); p.state == nil || head.Root != p.root {
+		statedb, err := p.chain.StateAt(head.Root)
+		if err != nil {
+			return common.Address{}, false, err
+		}
+		p.root, p.state = head.Root, statedb
+	}
+	for _, addr := range addrs {
+		slot := crypto.Keccak256Hash(common.LeftPadBytes(addr[:], 32), common.Hash{}.Bytes())
+		if p.state.GetState(p.registry, slot) != (common.Hash{}) {
+			return addr, true, nil
+		}
+	}
+	return common.Address{}, false, nil
+}
+
+func (p *registryPolicy) admit(tx *types.Transaction, from common.Address) error {
+	addrs := []common.Address{from}
+	if to := tx.To(); to != nil {
+		addrs = append(addrs, *to)
+	}
+	addr, denied, err := p.denied(addrs...)
+	if err != nil {
+		// The registry can't be consulted, err on the side of compliance
+		policyRegistryMeter.Mark(1)
+		return fmt.Errorf("%w: registry unavailable: %v", ErrAdmissionDenied, err)
+	}
+	if denied {
+		policyRegistryMeter.Mark(1)
+		return fmt.Errorf("%w: account %v is denied by registry %v", ErrAdmissionDenied, addr, p.registry)
+	}
+	return nil
+}
+
+func (p *registryPolicy) close() {}
@@ -0,0 +1,248 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the static policy rules admit and deny transactions as configured.
+func TestPolicyRules(t *testing.T) {
+	t.Parallel()
+
+	var (
+		alice   = common.HexToAddress("0x000000000000000000000000000000000000a11c")
+		bob     = common.HexToAddress("0x0000000000000000000000000000000000000b0b")
+		carol   = common.HexToAddress("0x00000000000000000000000000000000000ca501")
+		token   = common.HexToAddress("0x0000000000000000000000000000000000070ce2")
+		mixer   = common.HexToAddress("0x0000000000000000000000000000000000003123")
+		approve = common.FromHex("0x095ea7b3")
+	)
+	call := func(to *common.Address, data []byte) *types.Transaction {
+		return types.NewTx(&types.LegacyTx{To: to, Data: data, Gas: 21000, GasPrice: big.NewInt(1)})
+	}
+	tests := []struct {
+		policy string
+		tx     *types.Transaction
+		from   common.Address
+		denied bool
+	}{
+		// Empty policies allow everything
+		{`{}`, call(&token, approve), alice, false},
+		{`{}`, call(nil, nil), alice, false},
+
+		// Sender lists
+		{`{"senders":{"deny":["0x0000000000000000000000000000000000000b0b"]}}`, call(&token, nil), alice, false},
+		{`{"senders":{"deny":["0x0000000000000000000000000000000000000b0b"]}}`, call(&token, nil), bob, true},
+		{`{"senders":{"allow":["0x000000000000000000000000000000000000a11c"]}}`, call(&token, nil), alice, false},
+		{`{"senders":{"allow":["0x000000000000000000000000000000000000a11c"]}}`, call(&token, nil), carol, true},
+		{`{"senders":{"allow":["0x0000000000000000000000000000000000000b0b"],"deny":["0x0000000000000000000000000000000000000b0b"]}}`, call(&token, nil), bob, true},
+
+		// Recipient lists
+		{`{"recipients":{"deny":["0x0000000000000000000000000000000000003123"]}}`, call(&mixer, nil), alice, true},
+		{`{"recipients":{"deny":["0x0000000000000000000000000000000000003123"]}}`, call(&token, nil), alice, false},
+		{`{"recipients":{"allow":["0x0000000000000000000000000000000000070ce2"]}}`, call(&token, nil), alice, false},
+		{`{"recipients":{"allow":["0x0000000000000000000000000000000000070ce2"]}}`, call(&mixer, nil), alice, true},
+		{`{"recipients":{"allow":["0x0000000000000000000000000000000000070ce2"]}}`, call(nil, nil), alice, false},
+
+		// Method selector lists
+		{`{"methods":{"deny":["0x095ea7b3"]}}`, call(&token, append(approve, 1, 2, 3)), alice, true},
+		{`{"methods":{"deny":["0x095ea7b3"]}}`, call(&token, []byte{0x09, 0x5e}), alice, false},
+		{`{"methods":{"allow":["0xa9059cbb"]}}`, call(&token, approve), alice, true},
+		{`{"methods":{"allow":["0xa9059cbb"]}}`, call(&token, nil), alice, false},
+
+		// Contract creations
+		{`{"denyContractCreation":true}`, call(nil, nil), alice, true},
+		{`{"denyContractCreation":true}`, call(&token, nil), alice, false},
+	}
+	for i, tt := range tests {
+		rules, err := parsePolicy([]byte(tt.policy))
+		if err != nil {
+			t.Fatalf("test %d: failed to parse policy: %v", i, err)
+		}
+		err = rules.check(tt.tx, tt.from)
+		if tt.denied && !errors.Is(err, ErrAdmissionDenied) {
+			t.Errorf("test %d: transaction admitted, want denial", i)
+		}
+		if !tt.denied && err != nil {
+			t.Errorf("test %d: transaction denied: %v", i, err)
+		}
+	}
+	// Malformed policies should be rejected
+	for i, policy := range []string{
+		`{"senders":`,
+		`{"senders":{"deny":["0xzz"]}}`,
+		`{"methods":{"deny":["0x095ea7"]}}`,
+	} {
+		if _, err := parsePolicy([]byte(policy)); err == nil {
+			t.Errorf("malformed policy %d: parsed successfully", i)
+		}
+	}
+}
+
+// Tests that the policy file is picked up again after it changes on disk, and
+// that broken updates retain the previous rules.
+func TestPolicyFileReload(t *testing.T) {
+	t.Parallel()
+
+	var (
+		path  = filepath.Join(t.TempDir(), "policy.json")
+		alice = common.HexToAddress("0x000000000000000000000000000000000000a11c")
+		bob   = common.HexToAddress("0x0000000000000000000000000000000000000b0b")
+		tx    = types.NewTx(&types.LegacyTx{To: &common.Address{}, Gas: 21000, GasPrice: big.NewInt(1)})
+		now   = time.Now()
+	)
+	update := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write policy file: %v", err)
+		}
+		// Filesystem timestamps might be coarse, force a change
+		now = now.Add(time.Second)
+		if err := os.Chtimes(path, now, now); err != nil {
+			t.Fatalf("failed to update policy file time: %v", err)
+		}
+	}
+	// A missing policy file should deny everything
+	policy := newFilePolicy(path)
+	defer policy.close()
+
+	if err := policy.admit(tx, alice); !errors.Is(err, ErrAdmissionDenied) {
+		t.Fatalf("missing policy admitted transaction: %v", err)
+	}
+	// Create the file and ensure it's loaded
+	update(`{"senders":{"deny":["0x0000000000000000000000000000000000000b0b"]}}`)
+	if err := policy.reload(); err != nil {
+		t.Fatalf("failed to reload policy: %v", err)
+	}
+	if err := policy.admit(tx, alice); err != nil {
+		t.Fatalf("allowed sender denied: %v", err)
+	}
+	if err := policy.admit(tx, bob); !errors.Is(err, ErrAdmissionDenied) {
+		t.Fatalf("denied sender admitted: %v", err)
+	}
+	// Change the rules and ensure they are swapped out
+	update(`{"senders":{"deny":["0x000000000000000000000000000000000000a11c"]}}`)
+	if err := policy.reload(); err != nil {
+		t.Fatalf("failed to reload policy: %v", err)
+	}
+	if err := policy.admit(tx, alice); !errors.Is(err, ErrAdmissionDenied) {
+		t.Fatalf("denied sender admitted: %v", err)
+	}
+	if err := policy.admit(tx, bob); err != nil {
+		t.Fatalf("allowed sender denied: %v", err)
+	}
+	// Break the rules and ensure the old ones are retained
+	update(`{"senders":`)
+	if err := policy.reload(); err == nil {
+		t.Fatalf("broken policy reloaded")
+	}
+	if err := policy.admit(tx, alice); !errors.Is(err, ErrAdmissionDenied) {
+		t.Fatalf("denied sender admitted: %v", err)
+	}
+	if err := policy.admit(tx, bob); err != nil {
+		t.Fatalf("allowed sender denied: %v", err)
+	}
+}
+
+// Tests that accounts flagged in the on-chain registry are denied, both as the
+// sender and as the recipient of transactions.
+func TestPolicyRegistry(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
+
+	var (
+		registry = common.HexToAddress("0x0000000000000000000000000000000005a9c710")
+		alice    = common.HexToAddress("0x000000000000000000000000000000000000a11c")
+		bob      = common.HexToAddress("0x0000000000000000000000000000000000000b0b")
+		policy   = newRegistryPolicy(blockchain, registry)
+		send     = types.NewTx(&types.LegacyTx{To: &alice, Gas: 21000, GasPrice: big.NewInt(1)})
+		create   = types.NewTx(&types.LegacyTx{Gas: 53000, GasPrice: big.NewInt(1)})
+	)
+	if err := policy.admit(send, bob); err != nil {
+		t.Fatalf("unflagged transfer denied: %v", err)
+	}
+	slot := crypto.Keccak256Hash(common.LeftPadBytes(alice[:], 32), common.Hash{}.Bytes())
+	statedb.SetState(registry, slot, common.BigToHash(common.Big1))
+
+	if err := policy.admit(send, bob); !errors.Is(err, ErrAdmissionDenied) {
+		t.Fatalf("transfer to flagged account admitted: %v", err)
+	}
+	if err := policy.admit(create, alice); !errors.Is(err, ErrAdmissionDenied) {
+		t.Fatalf("creation from flagged account admitted: %v", err)
+	}
+	if err := policy.admit(create, bob); err != nil {
+		t.Fatalf("unflagged creation denied: %v", err)
+	}
+}
+
+// Tests that the pool rejects transactions denied by its admission policy,
+// surfacing the reason of the rejection.
+func TestPolicyPoolAdmission(t *testing.T) {
+	t.Parallel()
+
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(path, []byte(`{"senders":{"deny":["`+from.Hex()+`"]}}`), 0600); err != nil {
+		t.Fatalf("failed to write policy file: %v", err)
+	}
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.Policy = path
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	testAddBalance(pool, from, big.NewInt(1000000000))
+
+	if err := pool.AddLocal(transaction(0, 100000, key)); !errors.Is(err, ErrAdmissionDenied) {
+		t.Fatalf("local transaction error mismatch: have %v, want %v", err, ErrAdmissionDenied)
+	}
+	if err := pool.addRemoteSync(transaction(0, 100000, key)); !errors.Is(err, ErrAdmissionDenied) {
+		t.Fatalf("remote transaction error mismatch: have %v, want %v", err, ErrAdmissionDenied)
+	}
+	if err := pool.AddPrivate(transaction(0, 100000, key)); !errors.Is(err, ErrAdmissionDenied) {
+		t.Fatalf("private transaction error mismatch: have %v, want %v", err, ErrAdmissionDenied)
+	}
+	if pending, queued := pool.Stats(); pending != 0 || queued != 0 {
+		t.Fatalf("pool contents mismatch: have %d/%d, want 0/0", pending, queued)
+	}
+	// Transactions from other accounts should still go through
+	other, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(other.PublicKey), big.NewInt(1000000000))
+
+	if err := pool.addRemoteSync(transaction(0, 100000, other)); err != nil {
+		t.Fatalf("failed to add allowed transaction: %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testSubPool is a minimal subpool accepting all access list transactions,
+// tracking them without any validation.
+type testSubPool struct {
+	signer  types.Signer
+	reserve AddressReserver
+	txs     map[common.Hash]*types.Transaction
+	feed    event.Feed
+	lock    sync.Mutex
+}
+
+func (p *testSubPool) Filter(tx *types.Transaction) bool {
+	return tx.Type() == types.AccessListTxType
+}
+
+func (p *testSubPool) Init(gasTip *big.Int, head *types.Header, reserve AddressReserver) error {
+	p.reserve = reserve
+	p.txs = make(map[common.Hash]*types.Transaction)
+	return nil
+}
+
+func (p *testSubPool) Close() error                         { return nil }
+func (p *testSubPool) Reset(oldHead, newHead *types.Header) {}
+func (p *testSubPool) SetGasTip(tip *big.Int)               {}
+func (p *testSubPool) Nonce(addr common.Address) uint64     { return 0 }
+func (p *testSubPool) Stats() (int, int)                    { return len(p.txs), 0 }
+func (p *testSubPool) Status(hash common.Hash) TxStatus     { return TxStatusUnknown }
+func (p *testSubPool) Has(hash common.Hash) bool            { return p.Get(hash) != nil }
+func (p *testSubPool) ContentFrom(common.Address) (types.Transactions, types.Transactions) {
+	return nil, nil
+}
+
+func (p *testSubPool) Get(hash common.Hash) *types.Transaction {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.txs[hash]
+}
+
+func (p *testSubPool) Add(txs []*types.Transaction, local bool, sync bool) []error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	errs := make([]error, len(txs))
+	for i, tx := range txs {
+		from, _ := types.Sender(p.signer, tx)
+		if errs[i] = p.reserve(from, true); errs[i] == nil {
+			p.txs[tx.Hash()] = tx
+		}
+	}
+	return errs
+}
+
+func (p *testSubPool) Pending(enforceTips bool) map[common.Address]types.Transactions {
+	return p.content()
+}
+
+func (p *testSubPool) Content() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
+	return p.content(), nil
+}
+
+func (p *testSubPool) content() map[common.Address]types.Transactions {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	content := make(map[common.Address]types.Transactions)
+	for _, tx := range p.txs {
+		from, _ := types.Sender(p.signer, tx)
+		content[from] = append(content[from], tx)
+	}
+	return content
+}
+
+func (p *testSubPool) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
+	return p.feed.Subscribe(ch)
+}
+
+// Tests that transactions are routed into the subpools filtering for them, and
+// that accounts can only have transactions in a single pool at once.
+func TestSubPoolRouting(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
+
+	sub := &testSubPool{signer: types.LatestSigner(params.TestChainConfig)}
+	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain, sub)
+	defer pool.Stop()
+
+	key1, _ := crypto.GenerateKey()
+	key2, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key1.PublicKey), big.NewInt(1000000000))
+	testAddBalance(pool, crypto.PubkeyToAddress(key2.PublicKey), big.NewInt(1000000000))
+
+	// Add a transaction from both accounts into different pools
+	special := types.MustSignNewTx(key1, sub.signer, &types.AccessListTx{
+		ChainID:  params.TestChainConfig.ChainID,
+		Gas:      100000,
+		GasPrice: big.NewInt(1),
+	})
+	errs := pool.AddRemotesSync([]*types.Transaction{transaction(0, 100000, key2), special})
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("transaction %d: failed to add: %v", i, err)
+		}
+	}
+	if !sub.Has(special.Hash()) || !pool.Has(special.Hash()) {
+		t.Fatalf("special transaction not routed into the subpool")
+	}
+	if pending, _ := pool.Stats(); pending != 2 {
+		t.Fatalf("pending transaction count mismatch: have %d, want %d", pending, 2)
+	}
+	if pending := pool.Pending(false); len(pending) != 2 {
+		t.Fatalf("pending account count mismatch: have %d, want %d", len(pending), 2)
+	}
+	// Accounts owned by the other pool should be rejected
+	if err := pool.addRemoteSync(transaction(1, 100000, key1)); !errors.Is(err, ErrAlreadyReserved) {
+		t.Fatalf("legacy transaction of subpool account error mismatch: have %v, want %v", err, ErrAlreadyReserved)
+	}
+	rival := types.MustSignNewTx(key2, sub.signer, &types.AccessListTx{
+		ChainID:  params.TestChainConfig.ChainID,
+		Nonce:    1,
+		Gas:      100000,
+		GasPrice: big.NewInt(1),
+	})
+	if err := pool.AddRemotesSync([]*types.Transaction{rival})[0]; !errors.Is(err, ErrAlreadyReserved) {
+		t.Fatalf("subpool transaction of legacy account error mismatch: have %v, want %v", err, ErrAlreadyReserved)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
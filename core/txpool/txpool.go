@@ -121,6 +121,9 @@ var (
 	queuedNofundsMeter   = metrics.NewRegisteredMeter("txpool/queued/nofunds", nil)   // Dropped due to out-of-funds
 	queuedEvictionMeter  = metrics.NewRegisteredMeter("txpool/queued/eviction", nil)  // Dropped due to lifetime
 
+	// Metrics for the private transactions
+	privateEvictionMeter = metrics.NewRegisteredMeter("txpool/private/eviction", nil) // Dropped due to private lifetime
+
 	// General tx metrics
 	knownTxMeter       = metrics.NewRegisteredMeter("txpool/known", nil)
 	validTxMeter       = metrics.NewRegisteredMeter("txpool/valid", nil)
@@ -140,6 +143,7 @@ var (
 	pendingGauge = metrics.NewRegisteredGauge("txpool/pending", nil)
 	queuedGauge  = metrics.NewRegisteredGauge("txpool/queued", nil)
 	localGauge   = metrics.NewRegisteredGauge("txpool/local", nil)
+	privateGauge = metrics.NewRegisteredGauge("txpool/private", nil)
 	slotsGauge   = metrics.NewRegisteredGauge("txpool/slots", nil)
 
 	reheapTimer = metrics.NewRegisteredTimer("txpool/reheap", nil)
@@ -185,6 +189,11 @@ type Config struct {
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+
+	PrivateLifetime time.Duration // Maximum amount of time private transactions are kept without inclusion
+
+	Policy         string         // Admission policy file with allow- and deny-lists, reloaded on change
+	PolicyRegistry common.Address // Deny-list contract consulted on admission (zero = disabled)
 }
 
 // DefaultConfig contains the default configurations for the transaction
@@ -202,6 +211,8 @@ var DefaultConfig = Config{
 	GlobalQueue:  1024,
 
 	Lifetime: 3 * time.Hour,
+
+	PrivateLifetime: 30 * time.Minute,
 }
 
 // sanitize checks the provided user configurations and changes anything that's
@@ -240,6 +251,10 @@ func (config *Config) sanitize() Config {
 		log.Warn("Sanitizing invalid txpool lifetime", "provided", conf.Lifetime, "updated", DefaultConfig.Lifetime)
 		conf.Lifetime = DefaultConfig.Lifetime
 	}
+	if conf.PrivateLifetime < 1 {
+		log.Warn("Sanitizing invalid txpool private lifetime", "provided", conf.PrivateLifetime, "updated", DefaultConfig.PrivateLifetime)
+		conf.PrivateLifetime = DefaultConfig.PrivateLifetime
+	}
 	return conf
 }
 
@@ -291,6 +306,20 @@ type TxPool struct {
 	initDoneCh      chan struct{}  // is closed once the pool is initialized (for tests)
 
 	changesSinceReorg int // A counter for how many drops we've performed in-between reorg.
+
+	subpools     []SubPool                  // Specialized pools for transaction types the legacy pool rejects
+	subpoolSubs  []event.Subscription       // Subscriptions forwarding subpool events into txFeed
+	reservations map[common.Address]SubPool // Account owners, nil for the legacy pool itself
+	reserveLock  sync.Mutex                 // Lock protecting the account reservations
+	reserve      AddressReserver            // Reservation callback of the legacy pool
+
+	policies []admissionPolicy // Admission filters consulted before accepting any transaction
+
+	lifecycleFeed  event.Feed                    // Feed of the lifecycle changes of the transactions
+	lifecycleScope event.SubscriptionScope       // Subscriptions to the lifecycle changes
+	lifecycle      []core.TxPoolEvent            // Lifecycle changes gathered under the pool lock
+	lifecycleLock  sync.Mutex                    // Lock keeping the lifecycle batches ordered
+	included       map[common.Hash]*types.Header // Transactions included by the blocks of a running reset
 }
 
 type txpoolResetRequest struct {
@@ -298,8 +327,10 @@ type txpoolResetRequest struct {
 }
 
 // NewTxPool creates a new transaction pool to gather, sort and filter inbound
-// transactions from the network.
-func NewTxPool(config Config, chainconfig *params.ChainConfig, chain blockChain) *TxPool {
+// transactions from the network. Any subpools passed in take over the handling
+// of the transaction types they filter for, with the legacy pool acting as the
+// coordinator presenting a single unified view to the rest of the node.
+func NewTxPool(config Config, chainconfig *params.ChainConfig, chain blockChain, subpools ...SubPool) *TxPool {
 	// Sanitize the input to ensure no vulnerable gas prices are set
 	config = (&config).sanitize()
 
@@ -321,7 +352,9 @@ func NewTxPool(config Config, chainconfig *params.ChainConfig, chain blockChain)
 		reorgShutdownCh: make(chan struct{}),
 		initDoneCh:      make(chan struct{}),
 		gasPrice:        new(big.Int).SetUint64(config.PriceLimit),
+		reservations:    make(map[common.Address]SubPool),
 	}
+	pool.reserve = pool.reserver(nil)
 	pool.locals = newAccountSet(pool.signer)
 	for _, addr := range config.Locals {
 		log.Info("Setting new local account", "address", addr)
@@ -330,6 +363,15 @@ func NewTxPool(config Config, chainconfig *params.ChainConfig, chain blockChain)
 	pool.priced = newPricedList(pool.all)
 	pool.reset(nil, chain.CurrentBlock())
 
+	// Set up the admission policies before any transaction gets a chance to enter
+	if config.Policy != "" {
+		pool.policies = append(pool.policies, newFilePolicy(config.Policy))
+	}
+	if config.PolicyRegistry != (common.Address{}) {
+		log.Info("Enabled txpool admission registry", "address", config.PolicyRegistry)
+		pool.policies = append(pool.policies, newRegistryPolicy(chain, config.PolicyRegistry))
+	}
+
 	// Start the reorg loop early so it can handle requests generated during journal loading.
 	pool.wg.Add(1)
 	go pool.scheduleReorgLoop()
@@ -350,6 +392,23 @@ func NewTxPool(config Config, chainconfig *params.ChainConfig, chain blockChain)
 		}
 	}
 
+	// Initialize the specialized subpools and forward their events into the
+	// unified transaction feed.
+	head := chain.CurrentBlock()
+	for _, sp := range subpools {
+		if err := sp.Init(new(big.Int).Set(pool.gasPrice), head, pool.reserver(sp)); err != nil {
+			log.Error("Failed to initialize transaction subpool", "err", err)
+			continue
+		}
+		pool.subpools = append(pool.subpools, sp)
+
+		ch := make(chan core.NewTxsEvent, chainHeadChanSize)
+		sub := sp.SubscribeNewTxsEvent(ch)
+		pool.subpoolSubs = append(pool.subpoolSubs, sub)
+
+		pool.wg.Add(1)
+		go pool.forwardEvents(ch, sub)
+	}
 	// Subscribe events from blockchain and start the main event loop.
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
 	pool.wg.Add(1)
@@ -384,6 +443,9 @@ func (pool *TxPool) loop() {
 		// Handle ChainHeadEvent
 		case ev := <-pool.chainHeadCh:
 			if ev.Block != nil {
+				for _, sp := range pool.subpools {
+					sp.Reset(head, ev.Block.Header())
+				}
 				pool.requestReset(head, ev.Block.Header())
 				head = ev.Block.Header()
 			}
@@ -417,12 +479,21 @@ func (pool *TxPool) loop() {
 				if time.Since(pool.beats[addr]) > pool.config.Lifetime {
 					list := pool.queue[addr].Flatten()
 					for _, tx := range list {
+						pool.emitEviction(tx, core.TxEvictLifetime)
 						pool.removeTx(tx.Hash(), true)
 					}
 					queuedEvictionMeter.Mark(int64(len(list)))
 				}
 			}
+			// Private transactions not included in time are dropped altogether
+			if expired := pool.all.PrivatesExpired(time.Now()); len(expired) > 0 {
+				for _, hash := range expired {
+					pool.removeTx(hash, true)
+				}
+				privateEvictionMeter.Mark(int64(len(expired)))
+			}
 			pool.mu.Unlock()
+			pool.flushLifecycle()
 
 		// Handle local transaction journal rotation
 		case <-journal.C:
@@ -441,14 +512,26 @@ func (pool *TxPool) loop() {
 func (pool *TxPool) Stop() {
 	// Unsubscribe all subscriptions registered from txpool
 	pool.scope.Close()
+	pool.lifecycleScope.Close()
 
-	// Unsubscribe subscriptions registered from blockchain
+	// Unsubscribe subscriptions registered from blockchain and the subpools
 	pool.chainHeadSub.Unsubscribe()
+	for _, sub := range pool.subpoolSubs {
+		sub.Unsubscribe()
+	}
 	pool.wg.Wait()
 
 	if pool.journal != nil {
 		pool.journal.close()
 	}
+	for _, sp := range pool.subpools {
+		if err := sp.Close(); err != nil {
+			log.Error("Failed to close transaction subpool", "err", err)
+		}
+	}
+	for _, policy := range pool.policies {
+		policy.close()
+	}
 	log.Info("Transaction pool stopped")
 }
 
@@ -458,6 +541,178 @@ func (pool *TxPool) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subsc
 	return pool.scope.Track(pool.txFeed.Subscribe(ch))
 }
 
+// SubscribeTxPoolEvents registers a subscription for the lifecycle changes of
+// the transactions in the pool, delivered in batches in the order they happened.
+// Transactions handled by the subpools and privately submitted ones are not
+// reported.
+func (pool *TxPool) SubscribeTxPoolEvents(ch chan<- []core.TxPoolEvent) event.Subscription {
+	return pool.lifecycleScope.Track(pool.lifecycleFeed.Subscribe(ch))
+}
+
+// emitLifecycle records a lifecycle change of a transaction to be sent out when
+// the pool lock is released. It must be called while the transaction is still
+// tracked by the lookup, otherwise private transactions can't be filtered out.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) emitLifecycle(ev core.TxPoolEvent) {
+	if pool.lifecycleScope.Count() == 0 || pool.all.IsPrivate(ev.Tx.Hash()) {
+		return
+	}
+	// Public transactions may be replaced by private ones, don't leak the latter
+	if ev.Replacement != (common.Hash{}) && pool.all.IsPrivate(ev.Replacement) {
+		ev.Replacement = common.Hash{}
+	}
+	pool.lifecycle = append(pool.lifecycle, ev)
+}
+
+// emitEviction records the eviction of a transaction for the given reason.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) emitEviction(tx *types.Transaction, reason core.TxEvictReason) {
+	pool.emitLifecycle(core.TxPoolEvent{Type: core.TxEvicted, Tx: tx, Reason: reason})
+}
+
+// emitForwarded records the removal of a transaction whose nonce got used up on
+// chain, either by itself or by some other transaction unknown to the pool.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) emitForwarded(tx *types.Transaction) {
+	if header := pool.included[tx.Hash()]; header != nil {
+		pool.emitLifecycle(core.TxPoolEvent{Type: core.TxIncluded, Tx: tx, BlockHash: header.Hash(), BlockNumber: header.Number.Uint64()})
+		return
+	}
+	pool.emitEviction(tx, core.TxEvictStale)
+}
+
+// flushLifecycle sends out all the lifecycle changes gathered since the last
+// flush. It must be called after releasing the pool lock.
+func (pool *TxPool) flushLifecycle() {
+	pool.lifecycleLock.Lock()
+	defer pool.lifecycleLock.Unlock()
+
+	pool.mu.Lock()
+	events := pool.lifecycle
+	pool.lifecycle = nil
+	pool.mu.Unlock()
+
+	if len(events) > 0 {
+		pool.lifecycleFeed.Send(events)
+	}
+}
+
+// collectIncluded gathers the transactions included by the blocks between the
+// old and new heads, to tell included transactions apart from stale ones. Only
+// done if anyone listens for the lifecycle changes.
+func (pool *TxPool) collectIncluded(oldHead, newHead *types.Header) map[common.Hash]*types.Header {
+	if pool.lifecycleScope.Count() == 0 || newHead == nil {
+		return nil
+	}
+	var (
+		included = make(map[common.Hash]*types.Header)
+		hash     = newHead.Hash()
+		number   = newHead.Number.Uint64()
+	)
+	for i := 0; i < 64; i++ {
+		if oldHead != nil && number <= oldHead.Number.Uint64() {
+			break
+		}
+		block := pool.chain.GetBlock(hash, number)
+		if block == nil {
+			break
+		}
+		header := block.Header()
+		for _, tx := range block.Transactions() {
+			included[tx.Hash()] = header
+		}
+		if number == 0 {
+			break
+		}
+		hash, number = block.ParentHash(), number-1
+	}
+	return included
+}
+
+// forwardEvents relays the new transaction events of a subpool into the unified
+// transaction feed, until the subscription is torn down.
+func (pool *TxPool) forwardEvents(ch <-chan core.NewTxsEvent, sub event.Subscription) {
+	defer pool.wg.Done()
+
+	for {
+		select {
+		case ev := <-ch:
+			pool.txFeed.Send(ev)
+		case <-sub.Err():
+			return
+		}
+	}
+}
+
+// subpool returns the specialized subpool responsible for the given transaction,
+// or nil if it's handled by the legacy pool itself.
+func (pool *TxPool) subpool(tx *types.Transaction) SubPool {
+	for _, sp := range pool.subpools {
+		if sp.Filter(tx) {
+			return sp
+		}
+	}
+	return nil
+}
+
+// reserver returns an address reservation callback for the given pool (nil
+// meaning the legacy pool), ensuring that an account may only ever have
+// transactions tracked in a single pool at once.
+func (pool *TxPool) reserver(owner SubPool) AddressReserver {
+	return func(addr common.Address, reserve bool) error {
+		pool.reserveLock.Lock()
+		defer pool.reserveLock.Unlock()
+
+		holder, exists := pool.reservations[addr]
+		if reserve {
+			if exists {
+				if holder != owner {
+					return ErrAlreadyReserved
+				}
+				return nil
+			}
+			pool.reservations[addr] = owner
+			return nil
+		}
+		// Releasing an address someone else holds is a programming error, but
+		// there's no point in tearing down the node for it
+		if !exists || holder != owner {
+			log.Error("Attempted to release unreserved account", "address", addr)
+			return nil
+		}
+		delete(pool.reservations, addr)
+		return nil
+	}
+}
+
+// owner returns the subpool holding the reservation of the given address, or
+// nil if it's either unreserved or held by the legacy pool.
+func (pool *TxPool) owner(addr common.Address) SubPool {
+	pool.reserveLock.Lock()
+	defer pool.reserveLock.Unlock()
+
+	return pool.reservations[addr]
+}
+
+// releaseIdle drops the legacy pool's reservations of all the accounts that do
+// not have any transactions tracked anymore. The pool lock must be held.
+func (pool *TxPool) releaseIdle() {
+	pool.reserveLock.Lock()
+	defer pool.reserveLock.Unlock()
+
+	for addr, holder := range pool.reservations {
+		if holder != nil {
+			continue
+		}
+		if pool.pending[addr] == nil && pool.queue[addr] == nil {
+			delete(pool.reservations, addr)
+		}
+	}
+}
+
 // GasPrice returns the current gas price enforced by the transaction pool.
 func (pool *TxPool) GasPrice() *big.Int {
 	pool.mu.RLock()
@@ -469,16 +724,22 @@ func (pool *TxPool) GasPrice() *big.Int {
 // SetGasPrice updates the minimum price required by the transaction pool for a
 // new transaction, and drops all transactions below this threshold.
 func (pool *TxPool) SetGasPrice(price *big.Int) {
+	defer pool.flushLifecycle()
+
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
 	old := pool.gasPrice
 	pool.gasPrice = price
+	for _, sp := range pool.subpools {
+		sp.SetGasTip(new(big.Int).Set(price))
+	}
 	// if the min miner fee increased, remove transactions below the new threshold
 	if price.Cmp(old) > 0 {
 		// pool.priced is sorted by GasFeeCap, so we have to iterate through pool.all instead
 		drop := pool.all.RemotesBelowTip(price)
 		for _, tx := range drop {
+			pool.emitEviction(tx, core.TxEvictUnderpriced)
 			pool.removeTx(tx.Hash(), false)
 		}
 		pool.priced.Removed(len(drop))
@@ -490,6 +751,9 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 // Nonce returns the next nonce of an account, with all transactions executable
 // by the pool already applied on top.
 func (pool *TxPool) Nonce(addr common.Address) uint64 {
+	if sp := pool.owner(addr); sp != nil {
+		return sp.Nonce(addr)
+	}
 	pool.mu.RLock()
 	defer pool.mu.RUnlock()
 
@@ -497,12 +761,23 @@ func (pool *TxPool) Nonce(addr common.Address) uint64 {
 }
 
 // Stats retrieves the current pool stats, namely the number of pending and the
-// number of queued (non-executable) transactions.
+// number of queued (non-executable) transactions. Privately submitted transactions
+// are not counted.
 func (pool *TxPool) Stats() (int, int) {
 	pool.mu.RLock()
-	defer pool.mu.RUnlock()
+	pending, queued := pool.stats()
+	privPending, privQueued := pool.privateStats()
+	pool.mu.RUnlock()
 
-	return pool.stats()
+	pending -= privPending
+	queued -= privQueued
+
+	for _, sp := range pool.subpools {
+		p, q := sp.Stats()
+		pending += p
+		queued += q
+	}
+	return pending, queued
 }
 
 // stats retrieves the current pool stats, namely the number of pending and the
@@ -519,36 +794,77 @@ func (pool *TxPool) stats() (int, int) {
 	return pending, queued
 }
 
+// privateStats retrieves the number of privately submitted transactions among
+// the pending and among the queued ones.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) privateStats() (int, int) {
+	var pending, queued int
+	for _, hash := range pool.all.Privates() {
+		tx := pool.all.Get(hash)
+		if tx == nil {
+			continue
+		}
+		from, _ := types.Sender(pool.signer, tx) // already validated
+		if list := pool.pending[from]; list != nil && list.Contains(tx.Nonce()) {
+			pending++
+		} else {
+			queued++
+		}
+	}
+	return pending, queued
+}
+
 // Content retrieves the data content of the transaction pool, returning all the
 // pending as well as queued transactions, grouped by account and sorted by nonce.
+// Privately submitted transactions are not part of the content.
 func (pool *TxPool) Content() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
 	pending := make(map[common.Address]types.Transactions, len(pool.pending))
 	for addr, list := range pool.pending {
-		pending[addr] = list.Flatten()
+		if txs := pool.all.StripPrivates(list.Flatten()); len(txs) > 0 {
+			pending[addr] = txs
+		}
 	}
 	queued := make(map[common.Address]types.Transactions, len(pool.queue))
 	for addr, list := range pool.queue {
-		queued[addr] = list.Flatten()
+		if txs := pool.all.StripPrivates(list.Flatten()); len(txs) > 0 {
+			queued[addr] = txs
+		}
+	}
+	// Accounts are exclusive to a single pool, so the subpool contents can be
+	// merged in without any conflicts
+	for _, sp := range pool.subpools {
+		subPending, subQueued := sp.Content()
+		for addr, txs := range subPending {
+			pending[addr] = txs
+		}
+		for addr, txs := range subQueued {
+			queued[addr] = txs
+		}
 	}
 	return pending, queued
 }
 
 // ContentFrom retrieves the data content of the transaction pool, returning the
 // pending as well as queued transactions of this address, grouped by nonce.
+// Privately submitted transactions are not part of the content.
 func (pool *TxPool) ContentFrom(addr common.Address) (types.Transactions, types.Transactions) {
+	if sp := pool.owner(addr); sp != nil {
+		return sp.ContentFrom(addr)
+	}
 	pool.mu.RLock()
 	defer pool.mu.RUnlock()
 
 	var pending types.Transactions
 	if list, ok := pool.pending[addr]; ok {
-		pending = list.Flatten()
+		pending = pool.all.StripPrivates(list.Flatten())
 	}
 	var queued types.Transactions
 	if list, ok := pool.queue[addr]; ok {
-		queued = list.Flatten()
+		queued = pool.all.StripPrivates(list.Flatten())
 	}
 	return pending, queued
 }
@@ -559,7 +875,8 @@ func (pool *TxPool) ContentFrom(addr common.Address) (types.Transactions, types.
 //
 // The enforceTips parameter can be used to do an extra filtering on the pending
 // transactions and only return those whose **effective** tip is large enough in
-// the next pending execution environment.
+// the next pending execution environment. Privately submitted transactions are
+// only returned along with tip enforcement, i.e. to the block producer.
 func (pool *TxPool) Pending(enforceTips bool) map[common.Address]types.Transactions {
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
@@ -577,10 +894,19 @@ func (pool *TxPool) Pending(enforceTips bool) map[common.Address]types.Transacti
 				}
 			}
 		}
+		// Anyone else is not allowed to see the private transactions
+		if !enforceTips {
+			txs = pool.all.StripPrivates(txs)
+		}
 		if len(txs) > 0 {
 			pending[addr] = txs
 		}
 	}
+	for _, sp := range pool.subpools {
+		for addr, txs := range sp.Pending(enforceTips) {
+			pending[addr] = txs
+		}
+	}
 	return pending
 }
 
@@ -609,18 +935,28 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 }
 
 // toJournal retrieves all transactions that should be included in the journal,
-// grouped by origin account and sorted by nonce.
+// grouped by origin account and sorted by nonce. Private transactions are never
+// journaled, since reloading them would turn them into public ones.
 // The returned transaction set is a copy and can be freely modified by calling code.
 func (pool *TxPool) toJournal() map[common.Address]types.Transactions {
+	var txs map[common.Address]types.Transactions
 	if !pool.config.JournalRemote {
-		return pool.local()
-	}
-	txs := make(map[common.Address]types.Transactions)
-	for addr, pending := range pool.pending {
-		txs[addr] = append(txs[addr], pending.Flatten()...)
+		txs = pool.local()
+	} else {
+		txs = make(map[common.Address]types.Transactions)
+		for addr, pending := range pool.pending {
+			txs[addr] = append(txs[addr], pending.Flatten()...)
+		}
+		for addr, queued := range pool.queue {
+			txs[addr] = append(txs[addr], queued.Flatten()...)
+		}
 	}
-	for addr, queued := range pool.queue {
-		txs[addr] = append(txs[addr], queued.Flatten()...)
+	for addr, list := range txs {
+		if list = pool.all.StripPrivates(list); len(list) > 0 {
+			txs[addr] = list
+		} else {
+			delete(txs, addr)
+		}
 	}
 	return txs
 }
@@ -754,6 +1090,14 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 	// the sender is marked as local previously, treat it as the local transaction.
 	isLocal := local || pool.locals.containsTx(tx)
 
+	// Accounts with transactions in a specialized subpool can't mix in any from
+	// the legacy pool, since nonces are tracked separately. The reservation is
+	// released by the next reorg if the transaction ends up not being accepted.
+	from, _ := types.Sender(pool.signer, tx) // already validated by this point
+	if err := pool.reserve(from, true); err != nil {
+		log.Trace("Discarding transaction of reserved account", "hash", hash, "from", from)
+		return false, err
+	}
 	// If the transaction fails basic validation, discard it
 	if err := pool.validateTx(tx, isLocal); err != nil {
 		log.Trace("Discarding invalid transaction", "hash", hash, "err", err)
@@ -761,9 +1105,6 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 		return false, err
 	}
 
-	// already validated by this point
-	from, _ := types.Sender(pool.signer, tx)
-
 	// If the transaction pool is full, discard underpriced transactions
 	if uint64(pool.all.Slots()+numSlots(tx)) > pool.config.GlobalSlots+pool.config.GlobalQueue {
 		// If the new transaction is underpriced, don't accept it
@@ -818,6 +1159,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 		for _, tx := range drop {
 			log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "gasTipCap", tx.GasTipCap(), "gasFeeCap", tx.GasFeeCap())
 			underpricedTxMeter.Mark(1)
+			pool.emitEviction(tx, core.TxEvictUnderpriced)
 			dropped := pool.removeTx(tx.Hash(), false)
 			pool.changesSinceReorg += dropped
 		}
@@ -833,6 +1175,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 		}
 		// New transaction is better, replace old one
 		if old != nil {
+			pool.emitLifecycle(core.TxPoolEvent{Type: core.TxReplaced, Tx: old, Replacement: hash})
 			pool.all.Remove(old.Hash())
 			pool.priced.Removed(1)
 			pendingReplaceMeter.Mark(1)
@@ -841,6 +1184,9 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 		pool.priced.Put(tx, isLocal)
 		pool.journalTx(from, tx)
 		pool.queueTxEvent(tx)
+
+		pool.emitLifecycle(core.TxPoolEvent{Type: core.TxAdded, Tx: tx})
+		pool.emitLifecycle(core.TxPoolEvent{Type: core.TxPromoted, Tx: tx})
 		log.Trace("Pooled new executable transaction", "hash", hash, "from", from, "to", tx.To())
 
 		// Successful promotion, bump the heartbeat
@@ -906,6 +1252,7 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction, local boo
 	}
 	// Discard any previous transaction and mark this
 	if old != nil {
+		pool.emitLifecycle(core.TxPoolEvent{Type: core.TxReplaced, Tx: old, Replacement: hash})
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		queuedReplaceMeter.Mark(1)
@@ -921,6 +1268,9 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction, local boo
 	if addAll {
 		pool.all.Add(tx, local)
 		pool.priced.Put(tx, local)
+		pool.emitLifecycle(core.TxPoolEvent{Type: core.TxAdded, Tx: tx})
+	} else {
+		pool.emitLifecycle(core.TxPoolEvent{Type: core.TxDemoted, Tx: tx})
 	}
 	// If we never record the heartbeat, do it right now.
 	if _, exist := pool.beats[from]; !exist {
@@ -936,6 +1286,10 @@ func (pool *TxPool) journalTx(from common.Address, tx *types.Transaction) {
 	if pool.journal == nil || (!pool.config.JournalRemote && !pool.locals.contains(from)) {
 		return
 	}
+	// Private transactions must not survive a restart as public ones
+	if pool.all.IsPrivate(tx.Hash()) {
+		return
+	}
 	if err := pool.journal.insert(tx); err != nil {
 		log.Warn("Failed to journal local transaction", "err", err)
 	}
@@ -955,6 +1309,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 	inserted, old := list.Add(tx, pool.config.PriceBump)
 	if !inserted {
 		// An older transaction was better, discard this
+		pool.emitLifecycle(core.TxPoolEvent{Type: core.TxReplaced, Tx: tx, Replacement: list.txs.Get(tx.Nonce()).Hash()})
 		pool.all.Remove(hash)
 		pool.priced.Removed(1)
 		pendingDiscardMeter.Mark(1)
@@ -962,6 +1317,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 	}
 	// Otherwise discard any previous transaction and mark this
 	if old != nil {
+		pool.emitLifecycle(core.TxPoolEvent{Type: core.TxReplaced, Tx: old, Replacement: hash})
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		pendingReplaceMeter.Mark(1)
@@ -969,6 +1325,8 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		// Nothing was replaced, bump the pending counter
 		pendingGauge.Inc(1)
 	}
+	pool.emitLifecycle(core.TxPoolEvent{Type: core.TxPromoted, Tx: tx})
+
 	// Set the potentially new pending nonce and notify any subsystems of the new tx
 	pool.pendingNonces.set(addr, tx.Nonce()+1)
 
@@ -993,6 +1351,95 @@ func (pool *TxPool) AddLocal(tx *types.Transaction) error {
 	return errs[0]
 }
 
+// AddPrivates enqueues a batch of transactions into the pool if they are valid, marking
+// them as private. Private transactions are treated as locals and remain eligible for
+// block building, but they are never announced to the network, journaled, listed in
+// the pool content or counted in the pool stats. If not included within the configured
+// private lifetime, they are dropped from the pool.
+//
+// This method is used to add transactions from the private RPC API and performs
+// synchronous pool reorganization.
+func (pool *TxPool) AddPrivates(txs []*types.Transaction) []error {
+	var (
+		local    = !pool.config.NoLocals
+		deadline = time.Now().Add(pool.config.PrivateLifetime)
+		errs     = make([]error, len(txs))
+		dirty    = newAccountSet(pool.signer)
+	)
+	for i, tx := range txs {
+		if err := pool.validateTxBasics(tx, local); err != nil {
+			errs[i] = err
+			invalidTxMeter.Mark(1)
+			continue
+		}
+		errs[i] = pool.CheckPolicy(tx)
+	}
+	pool.mu.Lock()
+	for i, tx := range txs {
+		if errs[i] != nil {
+			continue
+		}
+		// Known transactions keep whatever visibility they already have
+		hash := tx.Hash()
+		if pool.all.Get(hash) != nil {
+			errs[i] = ErrAlreadyKnown
+			knownTxMeter.Mark(1)
+			continue
+		}
+		// Mark the transaction private before inserting it, so neither the journal
+		// nor the event feed get a chance to see it.
+		pool.all.MarkPrivate(hash, deadline)
+		replaced, err := pool.add(tx, local)
+		if err != nil {
+			pool.all.UnmarkPrivate(hash)
+			errs[i] = err
+			continue
+		}
+		if !replaced {
+			dirty.addTx(tx)
+		}
+	}
+	validTxMeter.Mark(int64(len(dirty.accounts)))
+	pool.mu.Unlock()
+
+	pool.flushLifecycle()
+	<-pool.requestPromoteExecutables(dirty)
+	return errs
+}
+
+// AddPrivate enqueues a single private transaction into the pool if it is valid.
+// This is a convenience wrapper around AddPrivates.
+func (pool *TxPool) AddPrivate(tx *types.Transaction) error {
+	errs := pool.AddPrivates([]*types.Transaction{tx})
+	return errs[0]
+}
+
+// IsPrivate reports whether the transaction with the given hash was submitted
+// privately and must not be propagated to the network.
+func (pool *TxPool) IsPrivate(hash common.Hash) bool {
+	return pool.all.IsPrivate(hash)
+}
+
+// CheckPolicy checks whether the transaction would be let into the pool by the
+// configured admission policies, returning the reason of the rejection if not.
+// Transactions with invalid signatures pass, as they are rejected by validation.
+func (pool *TxPool) CheckPolicy(tx *types.Transaction) error {
+	if len(pool.policies) == 0 {
+		return nil
+	}
+	from, err := types.Sender(pool.signer, tx)
+	if err != nil {
+		return nil
+	}
+	for _, policy := range pool.policies {
+		if err := policy.admit(tx, from); err != nil {
+			log.Trace("Transaction denied by admission policy", "hash", tx.Hash(), "err", err)
+			return err
+		}
+	}
+	return nil
+}
+
 // AddRemotes enqueues a batch of transactions into the pool if they are valid. If the
 // senders are not among the locally tracked ones, full pricing constraints will apply.
 //
@@ -1028,8 +1475,17 @@ func (pool *TxPool) addTxs(txs []*types.Transaction, local, sync bool) []error {
 	var (
 		errs = make([]error, len(txs))
 		news = make([]*types.Transaction, 0, len(txs))
+		idxs = make([]int, 0, len(txs))
+		subs = make(map[SubPool][]int)
 	)
 	for i, tx := range txs {
+		// Specialized transactions are handled by their own subpools
+		if sp := pool.subpool(tx); sp != nil {
+			if errs[i] = pool.CheckPolicy(tx); errs[i] == nil {
+				subs[sp] = append(subs[sp], i)
+			}
+			continue
+		}
 		// If the transaction is known, pre-set the error slot
 		if pool.all.Get(tx.Hash()) != nil {
 			errs[i] = ErrAlreadyKnown
@@ -1045,8 +1501,23 @@ func (pool *TxPool) addTxs(txs []*types.Transaction, local, sync bool) []error {
 			invalidTxMeter.Mark(1)
 			continue
 		}
+		// Drop anything the admission policy does not allow in
+		if err := pool.CheckPolicy(tx); err != nil {
+			errs[i] = err
+			continue
+		}
 		// Accumulate all unknown transactions for deeper processing
 		news = append(news, tx)
+		idxs = append(idxs, i)
+	}
+	for sp, indices := range subs {
+		batch := make([]*types.Transaction, len(indices))
+		for i, idx := range indices {
+			batch[i] = txs[idx]
+		}
+		for i, err := range sp.Add(batch, local, sync) {
+			errs[indices[i]] = err
+		}
 	}
 	if len(news) == 0 {
 		return errs
@@ -1057,13 +1528,10 @@ func (pool *TxPool) addTxs(txs []*types.Transaction, local, sync bool) []error {
 	newErrs, dirtyAddrs := pool.addTxsLocked(news, local)
 	pool.mu.Unlock()
 
-	var nilSlot = 0
-	for _, err := range newErrs {
-		for errs[nilSlot] != nil {
-			nilSlot++
-		}
-		errs[nilSlot] = err
-		nilSlot++
+	pool.flushLifecycle()
+
+	for i, err := range newErrs {
+		errs[idxs[i]] = err
 	}
 	// Reorg the pool internals if needed and return
 	done := pool.requestPromoteExecutables(dirtyAddrs)
@@ -1090,12 +1558,21 @@ func (pool *TxPool) addTxsLocked(txs []*types.Transaction, local bool) ([]error,
 }
 
 // Status returns the status (unknown/pending/queued) of a batch of transactions
-// identified by their hashes.
+// identified by their hashes. Privately submitted transactions are reported as
+// unknown.
 func (pool *TxPool) Status(hashes []common.Hash) []TxStatus {
 	status := make([]TxStatus, len(hashes))
 	for i, hash := range hashes {
-		tx := pool.Get(hash)
+		if pool.all.IsPrivate(hash) {
+			continue
+		}
+		tx := pool.all.Get(hash)
 		if tx == nil {
+			for _, sp := range pool.subpools {
+				if status[i] = sp.Status(hash); status[i] != TxStatusUnknown {
+					break
+				}
+			}
 			continue
 		}
 		from, _ := types.Sender(pool.signer, tx) // already validated
@@ -1113,14 +1590,34 @@ func (pool *TxPool) Status(hashes []common.Hash) []TxStatus {
 }
 
 // Get returns a transaction if it is contained in the pool and nil otherwise.
+// Privately submitted transactions are not returned.
 func (pool *TxPool) Get(hash common.Hash) *types.Transaction {
-	return pool.all.Get(hash)
+	if tx := pool.all.Get(hash); tx != nil {
+		if pool.all.IsPrivate(hash) {
+			return nil
+		}
+		return tx
+	}
+	for _, sp := range pool.subpools {
+		if tx := sp.Get(hash); tx != nil {
+			return tx
+		}
+	}
+	return nil
 }
 
 // Has returns an indicator whether txpool has a transaction cached with the
-// given hash.
+// given hash. Privately submitted transactions are not reported.
 func (pool *TxPool) Has(hash common.Hash) bool {
-	return pool.all.Get(hash) != nil
+	if pool.all.Get(hash) != nil {
+		return !pool.all.IsPrivate(hash)
+	}
+	for _, sp := range pool.subpools {
+		if sp.Has(hash) {
+			return true
+		}
+	}
+	return false
 }
 
 // removeTx removes a single transaction from the queue, moving all subsequent
@@ -1291,9 +1788,14 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 		// the flatten operation can be avoided.
 		promoteAddrs = dirtyAccounts.flatten()
 	}
+	var included map[common.Hash]*types.Header
+	if reset != nil {
+		included = pool.collectIncluded(reset.oldHead, reset.newHead)
+	}
 	pool.mu.Lock()
 	if reset != nil {
 		// Reset from the old head to the new, rescheduling any reorged transactions
+		pool.included = included
 		pool.reset(reset.oldHead, reset.newHead)
 
 		// Nonces were reset, discard any events that became stale
@@ -1317,6 +1819,8 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 	// because of another transaction (e.g. higher gas price).
 	if reset != nil {
 		pool.demoteUnexecutables()
+		pool.included = nil
+
 		if reset.newHead != nil && pool.chainconfig.IsLondon(new(big.Int).Add(reset.newHead.Number, big.NewInt(1))) {
 			pendingBaseFee := misc.CalcBaseFee(pool.chainconfig, reset.newHead)
 			pool.priced.SetBaseFee(pendingBaseFee)
@@ -1333,10 +1837,15 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 	pool.truncatePending()
 	pool.truncateQueue()
 
+	// Hand accounts without legacy transactions back to the subpools
+	pool.releaseIdle()
+
 	dropBetweenReorgHistogram.Update(int64(pool.changesSinceReorg))
 	pool.changesSinceReorg = 0 // Reset change counter
 	pool.mu.Unlock()
 
+	pool.flushLifecycle()
+
 	// Notify subsystems for newly added transactions
 	for _, tx := range promoted {
 		addr, _ := types.Sender(pool.signer, tx)
@@ -1348,9 +1857,12 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 	if len(events) > 0 {
 		var txs []*types.Transaction
 		for _, set := range events {
-			txs = append(txs, set.Flatten()...)
+			// Private transactions are never announced, not even locally
+			txs = append(txs, pool.all.StripPrivates(set.Flatten())...)
+		}
+		if len(txs) > 0 {
+			pool.txFeed.Send(core.NewTxsEvent{Txs: txs})
 		}
-		pool.txFeed.Send(core.NewTxsEvent{Txs: txs})
 	}
 }
 
@@ -1482,6 +1994,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 		forwards := list.Forward(pool.currentState.GetNonce(addr))
 		for _, tx := range forwards {
 			hash := tx.Hash()
+			pool.emitForwarded(tx)
 			pool.all.Remove(hash)
 		}
 		log.Trace("Removed old queued transactions", "count", len(forwards))
@@ -1497,6 +2010,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 		drops, _ := list.Filter(balance, pool.currentMaxGas.Load())
 		for _, tx := range drops {
 			hash := tx.Hash()
+			pool.emitEviction(tx, core.TxEvictUnpayable)
 			pool.all.Remove(hash)
 		}
 		log.Trace("Removed unpayable queued transactions", "count", len(drops))
@@ -1519,6 +2033,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 			caps = list.Cap(int(pool.config.AccountQueue))
 			for _, tx := range caps {
 				hash := tx.Hash()
+				pool.emitEviction(tx, core.TxEvictCapacity)
 				pool.all.Remove(hash)
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
@@ -1581,6 +2096,7 @@ func (pool *TxPool) truncatePending() {
 					for _, tx := range caps {
 						// Drop the transaction from the global pools too
 						hash := tx.Hash()
+						pool.emitEviction(tx, core.TxEvictCapacity)
 						pool.all.Remove(hash)
 
 						// Update the account nonce to the dropped transaction
@@ -1608,6 +2124,7 @@ func (pool *TxPool) truncatePending() {
 				for _, tx := range caps {
 					// Drop the transaction from the global pools too
 					hash := tx.Hash()
+					pool.emitEviction(tx, core.TxEvictCapacity)
 					pool.all.Remove(hash)
 
 					// Update the account nonce to the dropped transaction
@@ -1655,6 +2172,7 @@ func (pool *TxPool) truncateQueue() {
 		// Drop all transactions if they are less than the overflow
 		if size := uint64(list.Len()); size <= drop {
 			for _, tx := range list.Flatten() {
+				pool.emitEviction(tx, core.TxEvictCapacity)
 				pool.removeTx(tx.Hash(), true)
 			}
 			drop -= size
@@ -1664,6 +2182,7 @@ func (pool *TxPool) truncateQueue() {
 		// Otherwise drop only last few transactions
 		txs := list.Flatten()
 		for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
+			pool.emitEviction(txs[i], core.TxEvictCapacity)
 			pool.removeTx(txs[i].Hash(), true)
 			drop--
 			queuedRateLimitMeter.Mark(1)
@@ -1687,6 +2206,7 @@ func (pool *TxPool) demoteUnexecutables() {
 		olds := list.Forward(nonce)
 		for _, tx := range olds {
 			hash := tx.Hash()
+			pool.emitForwarded(tx)
 			pool.all.Remove(hash)
 			log.Trace("Removed old pending transaction", "hash", hash)
 		}
@@ -1703,6 +2223,7 @@ func (pool *TxPool) demoteUnexecutables() {
 		for _, tx := range drops {
 			hash := tx.Hash()
 			log.Trace("Removed unpayable pending transaction", "hash", hash)
+			pool.emitEviction(tx, core.TxEvictUnpayable)
 			pool.all.Remove(hash)
 		}
 		pendingNofundsMeter.Mark(int64(len(drops)))
@@ -1830,19 +2351,22 @@ func (as *accountSet) merge(other *accountSet) {
 // TxPool.mu mutex.
 //
 // This lookup set combines the notion of "local transactions", which is useful
-// to build upper-level structure.
+// to build upper-level structure. It also tracks which transactions were submitted
+// privately, along with the deadline until which they may stay in the pool.
 type lookup struct {
-	slots   int
-	lock    sync.RWMutex
-	locals  map[common.Hash]*types.Transaction
-	remotes map[common.Hash]*types.Transaction
+	slots    int
+	lock     sync.RWMutex
+	locals   map[common.Hash]*types.Transaction
+	remotes  map[common.Hash]*types.Transaction
+	privates map[common.Hash]time.Time
 }
 
 // newLookup returns a new lookup structure.
 func newLookup() *lookup {
 	return &lookup{
-		locals:  make(map[common.Hash]*types.Transaction),
-		remotes: make(map[common.Hash]*types.Transaction),
+		locals:   make(map[common.Hash]*types.Transaction),
+		remotes:  make(map[common.Hash]*types.Transaction),
+		privates: make(map[common.Hash]time.Time),
 	}
 }
 
@@ -1961,6 +2485,84 @@ func (t *lookup) Remove(hash common.Hash) {
 
 	delete(t.locals, hash)
 	delete(t.remotes, hash)
+
+	if _, ok := t.privates[hash]; ok {
+		delete(t.privates, hash)
+		privateGauge.Update(int64(len(t.privates)))
+	}
+}
+
+// MarkPrivate flags a transaction as privately submitted, to be dropped if it's
+// still around after the given deadline.
+func (t *lookup) MarkPrivate(hash common.Hash, deadline time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.privates[hash] = deadline
+	privateGauge.Update(int64(len(t.privates)))
+}
+
+// UnmarkPrivate removes the private flag of a transaction.
+func (t *lookup) UnmarkPrivate(hash common.Hash) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	delete(t.privates, hash)
+	privateGauge.Update(int64(len(t.privates)))
+}
+
+// IsPrivate returns whether a transaction was submitted privately.
+func (t *lookup) IsPrivate(hash common.Hash) bool {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	_, ok := t.privates[hash]
+	return ok
+}
+
+// Privates returns the hashes of all private transactions.
+func (t *lookup) Privates() []common.Hash {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	hashes := make([]common.Hash, 0, len(t.privates))
+	for hash := range t.privates {
+		hashes = append(hashes, hash)
+	}
+	return hashes
+}
+
+// PrivatesExpired returns the hashes of all private transactions whose deadline
+// passed before the given time.
+func (t *lookup) PrivatesExpired(now time.Time) []common.Hash {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	var expired []common.Hash
+	for hash, deadline := range t.privates {
+		if now.After(deadline) {
+			expired = append(expired, hash)
+		}
+	}
+	return expired
+}
+
+// StripPrivates filters all private transactions out of the given list, reusing
+// its backing array.
+func (t *lookup) StripPrivates(txs types.Transactions) types.Transactions {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if len(t.privates) == 0 {
+		return txs
+	}
+	public := txs[:0]
+	for _, tx := range txs {
+		if _, ok := t.privates[tx.Hash()]; !ok {
+			public = append(public, tx)
+		}
+	}
+	return public
 }
 
 // RemoteToLocals migrates the transactions belongs to the given locals to locals
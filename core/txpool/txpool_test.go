@@ -2436,6 +2436,261 @@ func TestStatusCheck(t *testing.T) {
 	}
 }
 
+// Tests that privately submitted transactions are available for block building,
+// but are neither announced on the event feed nor listed in the pool content.
+func TestPrivateTransactions(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Stop()
+
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000))
+
+	events := make(chan core.NewTxsEvent, 32)
+	sub := pool.txFeed.Subscribe(events)
+	defer sub.Unsubscribe()
+
+	// Add a private and a public transaction and ensure only the latter is announced
+	private := transaction(0, 100000, key)
+	if err := pool.AddPrivate(private); err != nil {
+		t.Fatalf("failed to add private transaction: %v", err)
+	}
+	if err := pool.AddLocal(transaction(1, 100000, key)); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	if err := validateEvents(events, 1); err != nil {
+		t.Fatalf("event firing failed: %v", err)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	if !pool.IsPrivate(private.Hash()) {
+		t.Fatalf("private transaction not marked private")
+	}
+	// Both transactions should be executable, but only the public one visible
+	if pending := pool.Pending(true); len(pending[crypto.PubkeyToAddress(key.PublicKey)]) != 2 {
+		t.Fatalf("mineable transactions mismatched: have %d, want %d", len(pending[crypto.PubkeyToAddress(key.PublicKey)]), 2)
+	}
+	if pending := pool.Pending(false)[crypto.PubkeyToAddress(key.PublicKey)]; len(pending) != 1 || pending[0].Nonce() != 1 {
+		t.Fatalf("pending transactions mismatched: have %v, want nonce 1 only", pending)
+	}
+	if pending, queued := pool.Stats(); pending != 1 || queued != 0 {
+		t.Fatalf("pool stats mismatched: have %d/%d, want %d/%d", pending, queued, 1, 0)
+	}
+	if tx := pool.Get(private.Hash()); tx != nil {
+		t.Fatalf("private transaction retrievable")
+	}
+	if tx := pool.Get(transaction(1, 100000, key).Hash()); tx == nil {
+		t.Fatalf("public transaction not retrievable")
+	}
+	if pool.Has(private.Hash()) {
+		t.Fatalf("private transaction reported as known")
+	}
+	if !pool.Has(transaction(1, 100000, key).Hash()) {
+		t.Fatalf("public transaction not reported as known")
+	}
+	if status := pool.Status([]common.Hash{private.Hash(), transaction(1, 100000, key).Hash()}); status[0] != TxStatusUnknown || status[1] != TxStatusPending {
+		t.Fatalf("transaction status mismatch: have %v, want [%v %v]", status, TxStatusUnknown, TxStatusPending)
+	}
+	pending, queued := pool.Content()
+	if txs := pending[crypto.PubkeyToAddress(key.PublicKey)]; len(txs) != 1 || txs[0].Nonce() != 1 {
+		t.Fatalf("pending content mismatched: have %v, want nonce 1 only", txs)
+	}
+	if len(queued) != 0 {
+		t.Fatalf("queued content mismatched: have %d, want %d", len(queued), 0)
+	}
+	// Resubmitting a known transaction privately should not alter its visibility
+	if err := pool.AddPrivate(transaction(1, 100000, key)); !errors.Is(err, ErrAlreadyKnown) {
+		t.Fatalf("known transaction error mismatch: have %v, want %v", err, ErrAlreadyKnown)
+	}
+	if pool.IsPrivate(transaction(1, 100000, key).Hash()) {
+		t.Fatalf("public transaction turned private")
+	}
+}
+
+// Tests that private transactions are dropped from the pool after their lifetime
+// passes without inclusion.
+func TestPrivateTimeLimiting(t *testing.T) {
+	// Reduce the eviction interval to a testable amount
+	defer func(old time.Duration) { evictionInterval = old }(evictionInterval)
+	evictionInterval = time.Millisecond * 100
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(1000000, statedb, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.PrivateLifetime = time.Second
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	if err := pool.AddPrivate(transaction(0, 100000, key)); err != nil {
+		t.Fatalf("failed to add private transaction: %v", err)
+	}
+	if err := pool.AddLocal(transaction(1, 100000, key)); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	if pending, queued := pool.Stats(); pending != 1 || queued != 0 {
+		t.Fatalf("pool stats mismatched: have %d/%d, want %d/%d", pending, queued, 1, 0)
+	}
+	// Wait for the private lifetime to pass and ensure the dependent transaction
+	// got demoted as a consequence of the eviction
+	time.Sleep(2 * config.PrivateLifetime)
+
+	if pending, queued := pool.Stats(); pending != 0 || queued != 1 {
+		t.Fatalf("pool stats mismatched: have %d/%d, want %d/%d", pending, queued, 0, 1)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// lifecycleTestChain is a test blockchain serving some pre-built blocks.
+type lifecycleTestChain struct {
+	*testBlockChain
+	blocks map[common.Hash]*types.Block
+}
+
+func (bc *lifecycleTestChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	if block := bc.blocks[hash]; block != nil {
+		return block
+	}
+	return bc.testBlockChain.GetBlock(hash, number)
+}
+
+// Tests that the lifecycle changes of transactions are reported with the correct
+// reasons and in the order they happened.
+func TestTxPoolEvents(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &lifecycleTestChain{
+		testBlockChain: newTestBlockChain(1000000, statedb, new(event.Feed)),
+		blocks:         make(map[common.Hash]*types.Block),
+	}
+	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	events := make(chan []core.TxPoolEvent, 16)
+	sub := pool.SubscribeTxPoolEvents(events)
+	defer sub.Unsubscribe()
+
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, from, big.NewInt(1000000000))
+
+	check := func(step string, want []core.TxPoolEvent) {
+		t.Helper()
+
+		var have []core.TxPoolEvent
+		for len(have) < len(want) {
+			select {
+			case batch := <-events:
+				have = append(have, batch...)
+			case <-time.After(time.Second):
+				t.Fatalf("%s: event count mismatch: have %d, want %d", step, len(have), len(want))
+			}
+		}
+		if len(have) != len(want) {
+			t.Fatalf("%s: event count mismatch: have %d, want %d", step, len(have), len(want))
+		}
+		for i := range want {
+			if have[i].Type != want[i].Type || have[i].Tx.Hash() != want[i].Tx.Hash() ||
+				have[i].Replacement != want[i].Replacement || have[i].Reason != want[i].Reason ||
+				have[i].BlockHash != want[i].BlockHash || have[i].BlockNumber != want[i].BlockNumber {
+				t.Errorf("%s: event %d mismatch: have %+v, want %+v", step, i, have[i], want[i])
+			}
+		}
+	}
+	// Add a transaction and replace it
+	tx0 := transaction(0, 100000, key)
+	if err := pool.addRemoteSync(tx0); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	check("add", []core.TxPoolEvent{
+		{Type: core.TxAdded, Tx: tx0},
+		{Type: core.TxPromoted, Tx: tx0},
+	})
+	tx0b := pricedTransaction(0, 100000, big.NewInt(2), key)
+	if err := pool.addRemoteSync(tx0b); err != nil {
+		t.Fatalf("failed to add replacement transaction: %v", err)
+	}
+	check("replace", []core.TxPoolEvent{
+		{Type: core.TxReplaced, Tx: tx0, Replacement: tx0b.Hash()},
+		{Type: core.TxAdded, Tx: tx0b},
+		{Type: core.TxPromoted, Tx: tx0b},
+	})
+	// Add a few more executable transactions, an expensive one and a cheap one
+	tx1 := pricedTransaction(1, 100000, big.NewInt(10), key)
+	tx2 := transaction(2, 100000, key)
+	for i, err := range pool.AddRemotesSync([]*types.Transaction{tx1, tx2}) {
+		if err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+	}
+	check("extend", []core.TxPoolEvent{
+		{Type: core.TxAdded, Tx: tx1},
+		{Type: core.TxAdded, Tx: tx2},
+		{Type: core.TxPromoted, Tx: tx1},
+		{Type: core.TxPromoted, Tx: tx2},
+	})
+	// Include the first transaction in a block, while also draining the account
+	// so the expensive transaction gets dropped and the cheap one demoted
+	head := blockchain.CurrentBlock()
+	block := types.NewBlock(&types.Header{
+		ParentHash: head.Hash(),
+		Number:     big.NewInt(1),
+		GasLimit:   head.GasLimit,
+		BaseFee:    big.NewInt(1),
+	}, []*types.Transaction{tx0b}, nil, nil, trie.NewStackTrie(nil))
+	blockchain.blocks[block.Hash()] = block
+
+	pool.mu.Lock()
+	statedb.SetNonce(from, 1)
+	statedb.SetBalance(from, big.NewInt(500000))
+	pool.mu.Unlock()
+
+	<-pool.requestReset(head, block.Header())
+	check("include", []core.TxPoolEvent{
+		{Type: core.TxIncluded, Tx: tx0b, BlockHash: block.Hash(), BlockNumber: 1},
+		{Type: core.TxEvicted, Tx: tx1, Reason: core.TxEvictUnpayable},
+		{Type: core.TxDemoted, Tx: tx2},
+	})
+	// Raise the price limit and ensure the cheap transaction is evicted
+	pool.SetGasPrice(big.NewInt(5))
+	check("reprice", []core.TxPoolEvent{
+		{Type: core.TxEvicted, Tx: tx2, Reason: core.TxEvictUnderpriced},
+	})
+	// Replace a transaction privately and ensure the replacement isn't leaked
+	testAddBalance(pool, from, big.NewInt(1000000000))
+
+	tx3 := pricedTransaction(1, 100000, big.NewInt(10), key)
+	if err := pool.addRemoteSync(tx3); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	check("readd", []core.TxPoolEvent{
+		{Type: core.TxAdded, Tx: tx3},
+		{Type: core.TxPromoted, Tx: tx3},
+	})
+	if err := pool.AddPrivate(pricedTransaction(1, 100000, big.NewInt(20), key)); err != nil {
+		t.Fatalf("failed to add private replacement: %v", err)
+	}
+	check("private replace", []core.TxPoolEvent{
+		{Type: core.TxReplaced, Tx: tx3},
+	})
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	select {
+	case batch := <-events:
+		t.Fatalf("unexpected events: %+v", batch)
+	default:
+	}
+}
+
 // Test the transaction slots consumption is computed correctly
 func TestSlotCount(t *testing.T) {
 	t.Parallel()
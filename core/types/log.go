@@ -86,3 +86,67 @@ func (l *Log) DecodeRLP(s *rlp.Stream) error {
 	}
 	return err
 }
+
+// rlpFullLog is the RLP encoding of a log including its derived fields.
+type rlpFullLog struct {
+	Address     common.Address
+	Topics      []common.Hash
+	Data        []byte
+	BlockNumber uint64
+	TxHash      common.Hash
+	TxIndex     uint
+	BlockHash   common.Hash
+	Index       uint
+	Removed     bool
+}
+
+func newRLPFullLogs(logs []*Log) []rlpFullLog {
+	enc := make([]rlpFullLog, len(logs))
+	for i, l := range logs {
+		enc[i] = rlpFullLog{
+			Address:     l.Address,
+			Topics:      l.Topics,
+			Data:        l.Data,
+			BlockNumber: l.BlockNumber,
+			TxHash:      l.TxHash,
+			TxIndex:     l.TxIndex,
+			BlockHash:   l.BlockHash,
+			Index:       l.Index,
+			Removed:     l.Removed,
+		}
+	}
+	return enc
+}
+
+func fromRLPFullLogs(dec []rlpFullLog) []*Log {
+	logs := make([]*Log, len(dec))
+	for i, l := range dec {
+		logs[i] = &Log{
+			Address:     l.Address,
+			Topics:      l.Topics,
+			Data:        l.Data,
+			BlockNumber: l.BlockNumber,
+			TxHash:      l.TxHash,
+			TxIndex:     l.TxIndex,
+			BlockHash:   l.BlockHash,
+			Index:       l.Index,
+			Removed:     l.Removed,
+		}
+	}
+	return logs
+}
+
+// EncodeFullLogs RLP-encodes a list of logs including their derived fields. This
+// is the format logs are transferred in by binary RPC transports.
+func EncodeFullLogs(logs []*Log) ([]byte, error) {
+	return rlp.EncodeToBytes(newRLPFullLogs(logs))
+}
+
+// DecodeFullLogs decodes a list of logs encoded by EncodeFullLogs.
+func DecodeFullLogs(input []byte) ([]*Log, error) {
+	var dec []rlpFullLog
+	if err := rlp.DecodeBytes(input, &dec); err != nil {
+		return nil, err
+	}
+	return fromRLPFullLogs(dec), nil
+}
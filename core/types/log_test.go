@@ -130,3 +130,35 @@ func checkError(t *testing.T, testname string, got, want error) bool {
 	}
 	return false
 }
+
+func TestFullLogsRLP(t *testing.T) {
+	logs := []*Log{
+		{
+			Address:     common.HexToAddress("0xecf8f87f810ecf450940c9f60066b4a7a501d6a7"),
+			Topics:      []common.Hash{common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")},
+			Data:        []byte{0x01, 0x02},
+			BlockNumber: 2019236,
+			TxHash:      common.HexToHash("0x3b198bfd5d2907285af009e9ae84a0ecd63677110d89d7e030251acb87f6487e"),
+			TxIndex:     3,
+			BlockHash:   common.HexToHash("0x656c34545f90a730a19008c0e7a7cd4fb3895064b48d6d69761bd5abad681056"),
+			Index:       2,
+			Removed:     true,
+		},
+		{
+			Address: common.HexToAddress("0x80b2c9d7cbbf30a1b0fc8983c647d754c6525615"),
+			Topics:  []common.Hash{},
+			Data:    []byte{},
+		},
+	}
+	enc, err := EncodeFullLogs(logs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec, err := DecodeFullLogs(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dec, logs) {
+		t.Fatalf("decoded logs mismatch:\nhave %s\nwant %s", spew.Sdump(dec), spew.Sdump(logs))
+	}
+}
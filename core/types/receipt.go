@@ -141,6 +141,114 @@ type LegacyOptimismStoredReceiptRLP struct {
 	FeeScalar         string
 }
 
+// rlpFullReceipt is the RLP encoding of a receipt including its derived fields.
+// Optional fields are wrapped into lists, since RLP can't tell nil from zero.
+type rlpFullReceipt struct {
+	Type              uint8
+	PostStateOrStatus []byte
+	CumulativeGasUsed uint64
+	Logs              []rlpFullLog
+	TxHash            common.Hash
+	ContractAddress   common.Address
+	GasUsed           uint64
+	EffectiveGasPrice []*big.Int
+	BlockHash         common.Hash
+	BlockNumber       uint64
+	TransactionIndex  uint
+	DepositNonce      []uint64
+	L1Fee             []rlpReceiptL1Fee
+}
+
+// rlpReceiptL1Fee is the RLP encoding of the L1 data fee fields of a receipt.
+type rlpReceiptL1Fee struct {
+	GasPrice  *big.Int
+	GasUsed   *big.Int
+	Fee       *big.Int
+	FeeScalar string
+}
+
+// EncodeFullReceipts RLP-encodes a list of receipts including their derived and
+// L1 data fee fields. This is the format receipts are transferred in by binary
+// RPC transports.
+func EncodeFullReceipts(receipts []*Receipt) ([]byte, error) {
+	enc := make([]rlpFullReceipt, len(receipts))
+	for i, r := range receipts {
+		enc[i] = rlpFullReceipt{
+			Type:              r.Type,
+			PostStateOrStatus: r.statusEncoding(),
+			CumulativeGasUsed: r.CumulativeGasUsed,
+			Logs:              newRLPFullLogs(r.Logs),
+			TxHash:            r.TxHash,
+			ContractAddress:   r.ContractAddress,
+			GasUsed:           r.GasUsed,
+			BlockHash:         r.BlockHash,
+			TransactionIndex:  r.TransactionIndex,
+		}
+		if r.EffectiveGasPrice != nil {
+			enc[i].EffectiveGasPrice = []*big.Int{r.EffectiveGasPrice}
+		}
+		if r.BlockNumber != nil {
+			enc[i].BlockNumber = r.BlockNumber.Uint64()
+		}
+		if r.DepositNonce != nil {
+			enc[i].DepositNonce = []uint64{*r.DepositNonce}
+		}
+		if r.L1Fee != nil {
+			fee := rlpReceiptL1Fee{GasPrice: r.L1GasPrice, GasUsed: r.L1GasUsed, Fee: r.L1Fee}
+			if r.FeeScalar != nil {
+				fee.FeeScalar = r.FeeScalar.String()
+			}
+			enc[i].L1Fee = []rlpReceiptL1Fee{fee}
+		}
+	}
+	return rlp.EncodeToBytes(enc)
+}
+
+// DecodeFullReceipts decodes a list of receipts encoded by EncodeFullReceipts.
+func DecodeFullReceipts(input []byte) ([]*Receipt, error) {
+	var dec []rlpFullReceipt
+	if err := rlp.DecodeBytes(input, &dec); err != nil {
+		return nil, err
+	}
+	receipts := make([]*Receipt, len(dec))
+	for i, d := range dec {
+		r := &Receipt{
+			Type:              d.Type,
+			CumulativeGasUsed: d.CumulativeGasUsed,
+			Logs:              fromRLPFullLogs(d.Logs),
+			TxHash:            d.TxHash,
+			ContractAddress:   d.ContractAddress,
+			GasUsed:           d.GasUsed,
+			BlockHash:         d.BlockHash,
+			BlockNumber:       new(big.Int).SetUint64(d.BlockNumber),
+			TransactionIndex:  d.TransactionIndex,
+		}
+		if err := r.setStatus(d.PostStateOrStatus); err != nil {
+			return nil, err
+		}
+		r.Bloom = CreateBloom(Receipts{r})
+		if len(d.EffectiveGasPrice) > 0 {
+			r.EffectiveGasPrice = d.EffectiveGasPrice[0]
+		}
+		if len(d.DepositNonce) > 0 {
+			r.DepositNonce = &d.DepositNonce[0]
+		}
+		if len(d.L1Fee) > 0 {
+			fee := d.L1Fee[0]
+			r.L1GasPrice, r.L1GasUsed, r.L1Fee = fee.GasPrice, fee.GasUsed, fee.Fee
+			if fee.FeeScalar != "" {
+				scalar, ok := new(big.Float).SetString(fee.FeeScalar)
+				if !ok {
+					return nil, errors.New("cannot parse fee scalar")
+				}
+				r.FeeScalar = scalar
+			}
+		}
+		receipts[i] = r
+	}
+	return receipts, nil
+}
+
 // LogForStorage is a wrapper around a Log that handles
 // backward compatibility with prior storage formats.
 type LogForStorage Log
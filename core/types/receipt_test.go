@@ -797,3 +797,69 @@ func TestRoundTripReceiptForStorage(t *testing.T) {
 		})
 	}
 }
+
+func TestFullReceiptsRLP(t *testing.T) {
+	nonce := uint64(0)
+	receipts := []*Receipt{
+		{
+			Type:              DynamicFeeTxType,
+			Status:            ReceiptStatusSuccessful,
+			CumulativeGasUsed: 21000,
+			Logs: []*Log{{
+				Address:     common.HexToAddress("0xecf8f87f810ecf450940c9f60066b4a7a501d6a7"),
+				Topics:      []common.Hash{common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")},
+				Data:        []byte{0x01, 0x02},
+				BlockNumber: 1,
+				TxHash:      common.Hash{0x11},
+				BlockHash:   common.Hash{0x22},
+			}},
+			TxHash:            common.Hash{0x11},
+			GasUsed:           21000,
+			EffectiveGasPrice: big.NewInt(0),
+			BlockHash:         common.Hash{0x22},
+			BlockNumber:       big.NewInt(1),
+			L1GasPrice:        big.NewInt(1000),
+			L1GasUsed:         big.NewInt(2100),
+			L1Fee:             big.NewInt(0),
+			FeeScalar:         big.NewFloat(0.684),
+		},
+		{
+			Type:              DepositTxType,
+			Status:            ReceiptStatusFailed,
+			CumulativeGasUsed: 50000,
+			Logs:              []*Log{},
+			TxHash:            common.Hash{0x33},
+			ContractAddress:   common.Address{0x44},
+			GasUsed:           29000,
+			DepositNonce:      &nonce,
+			BlockHash:         common.Hash{0x22},
+			BlockNumber:       big.NewInt(1),
+			TransactionIndex:  1,
+		},
+		{
+			Type:              LegacyTxType,
+			PostState:         common.Hash{0x55}.Bytes(),
+			CumulativeGasUsed: 71000,
+			Logs:              []*Log{},
+			EffectiveGasPrice: big.NewInt(params.GWei),
+			BlockNumber:       big.NewInt(1),
+			TransactionIndex:  2,
+		},
+	}
+	for _, r := range receipts {
+		r.Bloom = CreateBloom(Receipts{r})
+	}
+	enc, err := EncodeFullReceipts(receipts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec, err := DecodeFullReceipts(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	have, _ := json.Marshal(dec)
+	want, _ := json.Marshal(receipts)
+	if !bytes.Equal(have, want) {
+		t.Fatalf("decoded receipts mismatch:\nhave %s\nwant %s", have, want)
+	}
+}
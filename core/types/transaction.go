@@ -119,9 +119,13 @@ func (tx *Transaction) EncodeRLP(w io.Writer) error {
 	return rlp.Encode(w, buf.Bytes())
 }
 
-// encodeTyped writes the canonical encoding of a typed transaction to w.
+// encodeTyped writes the canonical encoding of a typed transaction to w. Blob
+// transactions carrying their sidecar are written in the network encoding.
 func (tx *Transaction) encodeTyped(w *bytes.Buffer) error {
 	w.WriteByte(tx.Type())
+	if blobtx, ok := tx.inner.(*BlobTx); ok {
+		return blobtx.encode(w)
+	}
 	return rlp.Encode(w, tx.inner)
 }
 
@@ -203,7 +207,7 @@ func (tx *Transaction) decodeTyped(b []byte) (TxData, error) {
 		return &inner, err
 	case BlobTxType:
 		var inner BlobTx
-		err := rlp.DecodeBytes(b[1:], &inner)
+		err := inner.decode(b[1:])
 		return &inner, err
 	case DepositTxType:
 		var inner DepositTx
@@ -307,6 +311,35 @@ func (tx *Transaction) BlobGasFeeCap() *big.Int { return tx.inner.blobGasFeeCap(
 // BlobHashes returns the hases of the blob commitments for blob transactions, nil otherwise.
 func (tx *Transaction) BlobHashes() []common.Hash { return tx.inner.blobHashes() }
 
+// BlobTxSidecar returns the sidecar of a blob transaction, nil otherwise.
+func (tx *Transaction) BlobTxSidecar() *BlobTxSidecar {
+	if blobtx, ok := tx.inner.(*BlobTx); ok {
+		return blobtx.Sidecar
+	}
+	return nil
+}
+
+// WithoutBlobTxSidecar returns a copy of tx with the blob sidecar removed. The
+// transaction is returned as is if it carries no sidecar.
+func (tx *Transaction) WithoutBlobTxSidecar() *Transaction {
+	blobtx, ok := tx.inner.(*BlobTx)
+	if !ok || blobtx.Sidecar == nil {
+		return tx
+	}
+	cpy := &Transaction{
+		inner: blobtx.withoutSidecar(),
+		time:  tx.time,
+	}
+	// The size cache is not copied over, since it includes the sidecar
+	if h := tx.hash.Load(); h != nil {
+		cpy.hash.Store(h)
+	}
+	if f := tx.from.Load(); f != nil {
+		cpy.from.Store(f)
+	}
+	return cpy
+}
+
 // Value returns the ether amount of the transaction.
 func (tx *Transaction) Value() *big.Int { return new(big.Int).Set(tx.inner.value()) }
 
@@ -474,6 +507,19 @@ func (tx *Transaction) BlobGasFeeCapIntCmp(other *big.Int) int {
 	return tx.inner.blobGasFeeCap().Cmp(other)
 }
 
+// Time returns the time when the transaction was first seen on the network. It
+// is a heuristic to prefer mining older txs vs new all other things equal.
+func (tx *Transaction) Time() time.Time {
+	return tx.time
+}
+
+// SetTime sets the decoding time of a transaction. This is used by tests to set
+// arbitrary times and by the transaction pool when restoring a dump of its
+// content, to retain the original arrival times.
+func (tx *Transaction) SetTime(t time.Time) {
+	tx.time = t
+}
+
 // Hash returns the transaction hash.
 func (tx *Transaction) Hash() common.Hash {
 	if hash := tx.hash.Load(); hash != nil {
@@ -500,6 +546,12 @@ func (tx *Transaction) Size() uint64 {
 	rlp.Encode(&c, &tx.inner)
 
 	size := uint64(c)
+
+	// Blob transactions carrying their sidecar are wrapped into an outer list
+	// together with the blobs, commitments and proofs
+	if sc := tx.BlobTxSidecar(); sc != nil {
+		size = rlp.ListSize(size + sc.encodedSize())
+	}
 	if tx.Type() != LegacyTxType {
 		size += 1 // type byte
 	}
@@ -0,0 +1,120 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/holiman/uint256"
+)
+
+// Tests that blob transactions carrying a sidecar use the network encoding,
+// while still hashing and sizing correctly, and that the sidecar can be dropped.
+func TestBlobTxSidecarEncoding(t *testing.T) {
+	var (
+		signer = NewCancunSigner(big.NewInt(1))
+		to     = common.HexToAddress("0x01")
+	)
+	key, _ := crypto.GenerateKey()
+
+	sidecar := &BlobTxSidecar{
+		Blobs:       []kzg4844.Blob{{0x01}},
+		Commitments: []kzg4844.Commitment{{0x02}},
+		Proofs:      []kzg4844.Proof{{0x03}},
+	}
+	tx, err := SignNewTx(key, signer, &BlobTx{
+		ChainID:    uint256.NewInt(1),
+		Nonce:      1,
+		GasTipCap:  uint256.NewInt(1),
+		GasFeeCap:  uint256.NewInt(10),
+		Gas:        21000,
+		To:         &to,
+		Value:      uint256.NewInt(0),
+		BlobFeeCap: uint256.NewInt(100),
+		BlobHashes: sidecar.BlobHashes(),
+		Sidecar:    sidecar,
+	})
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	// The network encoding should round trip, including the sidecar
+	bin, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to encode transaction: %v", err)
+	}
+	if have, want := int(tx.Size()), len(bin); have != want {
+		t.Errorf("size mismatch: have %d, want %d", have, want)
+	}
+	dec := new(Transaction)
+	if err := dec.UnmarshalBinary(bin); err != nil {
+		t.Fatalf("failed to decode transaction: %v", err)
+	}
+	if dec.Hash() != tx.Hash() {
+		t.Errorf("hash mismatch: have %x, want %x", dec.Hash(), tx.Hash())
+	}
+	if sc := dec.BlobTxSidecar(); sc == nil || sc.Blobs[0] != sidecar.Blobs[0] || sc.Commitments[0] != sidecar.Commitments[0] || sc.Proofs[0] != sidecar.Proofs[0] {
+		t.Fatalf("sidecar mismatch: have %v", sc)
+	}
+	if hashes := dec.BlobTxSidecar().BlobHashes(); hashes[0] != tx.BlobHashes()[0] || hashes[0][0] != kzg4844.VersionedHashVersionKZG {
+		t.Errorf("versioned hash mismatch: have %x, want %x", hashes[0], tx.BlobHashes()[0])
+	}
+	// Stripping the sidecar should yield the canonical encoding with the same hash
+	stripped := dec.WithoutBlobTxSidecar()
+	if stripped.BlobTxSidecar() != nil {
+		t.Fatalf("sidecar not stripped")
+	}
+	if dec.BlobTxSidecar() == nil {
+		t.Fatalf("stripping modified the original transaction")
+	}
+	if stripped.Hash() != tx.Hash() {
+		t.Errorf("stripped hash mismatch: have %x, want %x", stripped.Hash(), tx.Hash())
+	}
+	canon, err := stripped.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to encode stripped transaction: %v", err)
+	}
+	if have, want := int(stripped.Size()), len(canon); have != want {
+		t.Errorf("stripped size mismatch: have %d, want %d", have, want)
+	}
+	if len(canon) >= len(bin) {
+		t.Errorf("canonical encoding not smaller than network encoding: %d >= %d", len(canon), len(bin))
+	}
+	// The canonical encoding should also decode, without a sidecar
+	if err := dec.UnmarshalBinary(canon); err != nil {
+		t.Fatalf("failed to decode canonical transaction: %v", err)
+	}
+	if dec.BlobTxSidecar() != nil {
+		t.Errorf("sidecar conjured up from canonical encoding")
+	}
+	// Block bodies embed transactions via RLP, make sure that works with the
+	// sidecar stripped
+	enc, err := rlp.EncodeToBytes(stripped)
+	if err != nil {
+		t.Fatalf("failed to RLP encode transaction: %v", err)
+	}
+	if err := rlp.DecodeBytes(enc, dec); err != nil {
+		t.Fatalf("failed to RLP decode transaction: %v", err)
+	}
+	if dec.Hash() != tx.Hash() {
+		t.Errorf("RLP hash mismatch: have %x, want %x", dec.Hash(), tx.Hash())
+	}
+}
@@ -20,6 +20,7 @@ package kzg4844
 import (
 	"embed"
 	"errors"
+	"hash"
 	"sync/atomic"
 )
 
@@ -41,6 +42,23 @@ type Point [32]byte
 // Claim is a claimed evaluation value in a specific point.
 type Claim [32]byte
 
+// VersionedHashVersionKZG is the version byte prefixed to the hash of a KZG
+// commitment to derive the versioned hash referenced by blob transactions.
+const VersionedHashVersionKZG = 0x01
+
+// CalcBlobHashV1 calculates the 'versioned blob hash' of a commitment.
+// The given hasher must be a sha256 hash instance, otherwise the result will be invalid!
+func CalcBlobHashV1(hasher hash.Hash, commit *Commitment) (vh [32]byte) {
+	if hasher.Size() != 32 {
+		panic("wrong hash size")
+	}
+	hasher.Reset()
+	hasher.Write(commit[:])
+	hasher.Sum(vh[:0])
+	vh[0] = VersionedHashVersionKZG
+	return vh
+}
+
 // useCKZG controls whether the cryptography should use the Go or C backend.
 var useCKZG atomic.Bool
 
@@ -17,6 +17,7 @@
 package eth
 
 import (
+	"bytes"
 	"compress/gzip"
 	"context"
 	"errors"
@@ -32,7 +33,10 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -239,6 +243,83 @@ func (api *AdminAPI) ImportChain(file string) (bool, error) {
 	return true, nil
 }
 
+// txPoolDumpPageSize is the maximum number of bytes of transactions returned in
+// a single page of a transaction pool dump, keeping both the export responses and
+// the import requests of the hex encoded pages well within the RPC size limits.
+const txPoolDumpPageSize = 1024 * 1024
+
+// TxPoolDumpAPI offers the methods to migrate the content of the transaction
+// pool between nodes. The dumps carry the local flags of the transactions, which
+// exempt them from the pricing rules on import, so the txpool namespace should
+// not be exposed to untrusted clients.
+type TxPoolDumpAPI struct {
+	eth *Ethereum
+}
+
+// NewTxPoolDumpAPI creates a new TxPoolDumpAPI instance.
+func NewTxPoolDumpAPI(eth *Ethereum) *TxPoolDumpAPI {
+	return &TxPoolDumpAPI{eth: eth}
+}
+
+// TxPoolDumpCursor is the position to continue a paged transaction pool export
+// from, identifying the last exported transaction.
+type TxPoolDumpCursor struct {
+	Account common.Address `json:"account"`
+	Nonce   hexutil.Uint64 `json:"nonce"`
+}
+
+// TxPoolDump is a page of a transaction pool dump.
+type TxPoolDump struct {
+	Dump hexutil.Bytes     `json:"dump"` // RLP stream of transactions with metadata
+	Next *TxPoolDumpCursor `json:"next"` // Cursor of the next page, nil if complete
+}
+
+// Export returns a page of the pending and queued transactions of the pool as an
+// RLP stream, along with their arrival times and local flags. The transactions
+// are ordered by sender and nonce, starting after the given cursor or from the
+// start if none is given.
+func (api *TxPoolDumpAPI) Export(cursor *TxPoolDumpCursor) (*TxPoolDump, error) {
+	var after *txpool.DumpCursor
+	if cursor != nil {
+		after = &txpool.DumpCursor{Account: cursor.Account, Nonce: uint64(cursor.Nonce)}
+	}
+	var dump bytes.Buffer
+	_, next, err := api.eth.TxPool().ExportPage(&dump, after, txPoolDumpPageSize)
+	if err != nil {
+		return nil, err
+	}
+	page := &TxPoolDump{Dump: dump.Bytes()}
+	if next != nil {
+		page.Next = &TxPoolDumpCursor{Account: next.Account, Nonce: hexutil.Uint64(next.Nonce)}
+	}
+	return page, nil
+}
+
+// txPoolImportError is returned if a transaction pool dump could only be partly
+// imported. The numbers of imported and dropped transactions are reported as
+// the error data.
+type txPoolImportError struct {
+	err    error
+	counts map[string]hexutil.Uint
+}
+
+func (e *txPoolImportError) Error() string          { return e.err.Error() }
+func (e *txPoolImportError) ErrorData() interface{} { return e.counts }
+
+// Import injects the transactions of a dump page produced by Export into the
+// pool, returning the number of imported and dropped transactions.
+func (api *TxPoolDumpAPI) Import(dump hexutil.Bytes) (map[string]hexutil.Uint, error) {
+	imported, dropped, err := api.eth.TxPool().Import(bytes.NewReader(dump))
+	counts := map[string]hexutil.Uint{
+		"imported": hexutil.Uint(imported),
+		"dropped":  hexutil.Uint(dropped),
+	}
+	if err != nil {
+		return nil, &txPoolImportError{err: err, counts: counts}
+	}
+	return counts, nil
+}
+
 // DebugAPI is the collection of Ethereum full node APIs for debugging the
 // protocol.
 type DebugAPI struct {
@@ -335,6 +416,11 @@ func (api *DebugAPI) GetBadBlocks(ctx context.Context) ([]*BadBlockArgs, error)
 	return results, nil
 }
 
+// PruneStatus returns the progress of the background state pruning.
+func (api *DebugAPI) PruneStatus() (*pruner.OnlineStatus, error) {
+	return api.eth.blockchain.PruneStatus()
+}
+
 // AccountRangeMaxResults is the maximum number of results to be returned per call
 const AccountRangeMaxResults = 256
 
@@ -460,6 +546,130 @@ func storageRangeAt(st state.Trie, start []byte, maxResult int) (StorageRangeRes
 	return result, nil
 }
 
+// stateDiffReexec is the maximum number of blocks to re-execute for obtaining
+// the parent state of the block whose state diff is no longer cached.
+const stateDiffReexec = 128
+
+// GetStateDiff returns the pre and post values of the accounts and storage
+// slots changed by the given block. The changes of the recently processed
+// blocks are served from memory, others are obtained by re-executing the block.
+func (api *DebugAPI) GetStateDiff(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*ethapi.RPCStateDiff, error) {
+	if number, ok := blockNrOrHash.Number(); ok && number == rpc.PendingBlockNumber {
+		return nil, errors.New("state diff of pending block is not available")
+	}
+	block, err := api.eth.APIBackend.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, errors.New("block not found")
+	}
+	if diff := api.eth.blockchain.GetStateDiff(block.Hash()); diff != nil {
+		return ethapi.NewRPCStateDiff(block.Header(), diff), nil
+	}
+	if block.NumberU64() == 0 {
+		return nil, errors.New("genesis has no state diff")
+	}
+	parent := api.eth.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %#x not found", block.ParentHash())
+	}
+	statedb, release, err := api.eth.StateAtBlock(ctx, parent, stateDiffReexec, nil, true, false)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	statedb.TrackOrigins()
+	if _, _, _, err := api.eth.blockchain.Processor().Process(block, statedb, vm.Config{}); err != nil {
+		return nil, err
+	}
+	statedb.IntermediateRoot(api.eth.blockchain.Config().IsEIP158(block.Number()))
+	return ethapi.NewRPCStateDiff(block.Header(), statedb.StateDiff()), nil
+}
+
+// GetWitness re-executes the given block and returns all the trie nodes, codes
+// and ancestor headers accessed, which are sufficient to execute the block again
+// without the state database.
+func (api *DebugAPI) GetWitness(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*core.ExecutionWitness, error) {
+	block, err := api.eth.APIBackend.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, errors.New("block not found")
+	}
+	if block.NumberU64() == 0 {
+		return nil, errors.New("genesis is not executable")
+	}
+	parent := api.eth.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %#x not found", block.ParentHash())
+	}
+	base, release, err := api.eth.StateAtBlock(ctx, parent, stateDiffReexec, nil, true, false)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// Open the parent state afresh, all the accessed trie nodes are resolved
+	// from the database and captured by the tries.
+	statedb, err := state.New(parent.Root(), base.Database(), nil)
+	if err != nil {
+		return nil, err
+	}
+	statedb.StartWitness()
+
+	tracer := &blockHashTracer{numbers: make(map[uint64]struct{})}
+	if _, _, _, err := api.eth.blockchain.Processor().Process(block, statedb, vm.Config{Tracer: tracer}); err != nil {
+		return nil, err
+	}
+	statedb.IntermediateRoot(api.eth.blockchain.Config().IsEIP158(block.Number()))
+
+	// Include the contiguous ancestors down to the oldest one accessed, so that
+	// their hashes can be verified against the block.
+	oldest := parent.NumberU64()
+	for number := range tracer.numbers {
+		if number < oldest && number+256 >= block.NumberU64() {
+			oldest = number
+		}
+	}
+	headers := []*types.Header{parent.Header()}
+	for header := parent.Header(); header.Number.Uint64() > oldest; {
+		header = api.eth.blockchain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+		if header == nil {
+			return nil, errors.New("ancestor header not found")
+		}
+		headers = append(headers, header)
+	}
+	return core.NewExecutionWitness(headers, statedb.Witness()), nil
+}
+
+// blockHashTracer records the block numbers requested via the BLOCKHASH opcode.
+type blockHashTracer struct {
+	numbers map[uint64]struct{}
+}
+
+func (t *blockHashTracer) CaptureTxStart(gasLimit uint64) {}
+func (t *blockHashTracer) CaptureTxEnd(restGas uint64)    {}
+func (t *blockHashTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+}
+func (t *blockHashTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {}
+func (t *blockHashTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+}
+func (t *blockHashTracer) CaptureExit(output []byte, gasUsed uint64, err error) {}
+func (t *blockHashTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+func (t *blockHashTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if op != vm.BLOCKHASH || err != nil {
+		return
+	}
+	if number, overflow := scope.Stack.Back(0).Uint64WithOverflow(); !overflow {
+		t.numbers[number] = struct{}{}
+	}
+}
+
 // GetModifiedAccountsByNumber returns all accounts that have changed between the
 // two blocks specified. A change is defined as a difference in nonce, balance,
 // code hash, or storage hash.
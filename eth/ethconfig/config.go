@@ -28,7 +28,9 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/txpool/blobpool"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -61,6 +63,10 @@ var Defaults = Config{
 	SyncMode:                downloader.SnapSync,
 	NetworkId:               1,
 	TxLookupLimit:           2350000,
+	StateHistory:            params.FullImmutabilityThreshold,
+	OnlinePruningRetention:  pruner.DefaultOnlineConfig.Retention,
+	OnlinePruningBloomSize:  pruner.DefaultOnlineConfig.BloomSize,
+	OnlinePruningThrottle:   pruner.DefaultOnlineConfig.Throttle,
 	LightPeers:              100,
 	UltraLightFraction:      75,
 	DatabaseCache:           512,
@@ -73,6 +79,7 @@ var Defaults = Config{
 	FilterLogCacheSize:      32,
 	Miner:                   miner.DefaultConfig,
 	TxPool:                  txpool.DefaultConfig,
+	BlobPool:                blobpool.DefaultConfig,
 	RPCGasCap:               50000000,
 	RPCEVMTimeout:           5 * time.Second,
 	GPO:                     FullNodeGPO,
@@ -99,7 +106,25 @@ type Config struct {
 	NoPruning  bool // Whether to disable pruning and flush everything to disk
 	NoPrefetch bool // Whether to disable prefetching and only load state on demand
 
+	// State options
+	StateScheme  string `toml:",omitempty"` // State scheme used to store ethereum state and merkle trie nodes on top, empty means the stored one
+	StateHistory uint64 `toml:",omitempty"` // The maximum number of blocks from head whose state histories are reserved (path-based scheme only)
+
+	// Number of recent blocks whose historical states are reconstructable from
+	// the reverse state diffs, 0 disables the diff store
+	StateDiffHistory uint64 `toml:",omitempty"`
+
+	// Online pruning options (hash-based scheme only)
+	OnlinePruning          bool          `toml:",omitempty"` // Whether to delete stale state in the background
+	OnlinePruningRetention uint64        `toml:",omitempty"` // Number of recent blocks whose state is retained by the online pruner
+	OnlinePruningBloomSize uint64        `toml:",omitempty"` // Megabytes of memory allocated to the bloom filter of the online pruner
+	OnlinePruningThrottle  time.Duration `toml:",omitempty"` // Pause between two batches of trie nodes processed by the online pruner
+
 	TxLookupLimit uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
+	HistoryBlocks uint64 `toml:",omitempty"` // The number of blocks from head whose bodies and receipts are retained, 0 keeps all
+
+	LogIndex        bool   `toml:",omitempty"` // Whether to maintain the log index for fast log filtering
+	LogIndexHistory uint64 `toml:",omitempty"` // The number of blocks from head whose logs are indexed, 0 indexes all
 
 	// RequiredBlocks is a set of block number -> hash mappings which must be in the
 	// canonical chain of all remote peers. Setting the option makes geth verify the
@@ -140,7 +165,8 @@ type Config struct {
 	Miner miner.Config
 
 	// Transaction pool options
-	TxPool txpool.Config
+	TxPool   txpool.Config
+	BlobPool blobpool.Config
 
 	// Gas Price Oracle options
 	GPO gasprice.Config
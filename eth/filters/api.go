@@ -28,14 +28,16 @@ import (
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
 var (
-	errInvalidTopic   = errors.New("invalid topic(s)")
-	errFilterNotFound = errors.New("filter not found")
+	errInvalidTopic      = errors.New("invalid topic(s)")
+	errFilterNotFound    = errors.New("filter not found")
+	errInvalidBlockRange = errors.New("invalid block range")
 )
 
 // filter is a helper struct that holds meta information over the filter type
@@ -183,6 +185,109 @@ func (api *FilterAPI) NewPendingTransactions(ctx context.Context, fullTx *bool)
 	return rpcSub, nil
 }
 
+// txPoolEvent is the RPC representation of a lifecycle change of a transaction
+// in the transaction pool.
+type txPoolEvent struct {
+	Type        core.TxLifecycle       `json:"type"`
+	Hash        common.Hash            `json:"hash"`
+	From        common.Address         `json:"from"`
+	Nonce       hexutil.Uint64         `json:"nonce"`
+	Transaction *ethapi.RPCTransaction `json:"transaction,omitempty"`
+	ReplacedBy  *common.Hash           `json:"replacedBy,omitempty"`
+	Reason      core.TxEvictReason     `json:"reason,omitempty"`
+	BlockHash   *common.Hash           `json:"blockHash,omitempty"`
+	BlockNumber *hexutil.Uint64        `json:"blockNumber,omitempty"`
+}
+
+// TxPoolEvents creates a subscription that is triggered each time a transaction
+// changes state in the transaction pool: when it's added, promoted, replaced,
+// demoted, evicted or included in a block. If fullTx is true the full tx is sent
+// along with the change, otherwise only its hash.
+func (api *FilterAPI) TxPoolEvents(ctx context.Context, fullTx *bool) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan []core.TxPoolEvent, 128)
+		eventsSub := api.events.SubscribeTxPoolEvents(events)
+		chainConfig := api.sys.backend.ChainConfig()
+		signer := types.LatestSigner(chainConfig)
+
+		for {
+			select {
+			case events := <-events:
+				latest := api.sys.backend.CurrentHeader()
+				for _, ev := range events {
+					from, _ := types.Sender(signer, ev.Tx)
+					res := &txPoolEvent{
+						Type:   ev.Type,
+						Hash:   ev.Tx.Hash(),
+						From:   from,
+						Nonce:  hexutil.Uint64(ev.Tx.Nonce()),
+						Reason: ev.Reason,
+					}
+					if fullTx != nil && *fullTx {
+						res.Transaction = ethapi.NewRPCPendingTransaction(ev.Tx, latest, chainConfig)
+					}
+					if ev.Type == core.TxReplaced {
+						replacement := ev.Replacement
+						res.ReplacedBy = &replacement
+					}
+					if ev.Type == core.TxIncluded {
+						hash, number := ev.BlockHash, hexutil.Uint64(ev.BlockNumber)
+						res.BlockHash, res.BlockNumber = &hash, &number
+					}
+					notifier.Notify(rpcSub.ID, res)
+				}
+			case <-rpcSub.Err():
+				eventsSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				eventsSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// StateDiffs creates a subscription that is triggered each time a block becomes
+// canonical, sending the pre and post values of the accounts and storage slots
+// changed by the block.
+func (api *FilterAPI) StateDiffs(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		diffs := make(chan core.StateDiffEvent, stateDiffEvChanSize)
+		diffsSub := api.events.SubscribeStateDiffs(diffs)
+
+		for {
+			select {
+			case ev := <-diffs:
+				notifier.Notify(rpcSub.ID, ethapi.NewRPCStateDiff(ev.Block.Header(), ev.Diff))
+			case <-rpcSub.Err():
+				diffsSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				diffsSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // NewBlockFilter creates a filter that fetches blocks that are imported into the chain.
 // It is part of the filter package since polling goes with eth_getFilterChanges.
 func (api *FilterAPI) NewBlockFilter() rpc.ID {
@@ -247,11 +352,18 @@ func (api *FilterAPI) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
 }
 
 // Logs creates a subscription that fires for all new log that match the given filter criteria.
-func (api *FilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc.Subscription, error) {
+//
+// The optional options replay the matching logs of the canonical chain starting
+// at the given block before switching to the live logs, and request notifications
+// of chain reorganisations.
+func (api *FilterAPI) Logs(ctx context.Context, crit FilterCriteria, opts *LogsOptions) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
 	}
+	if opts != nil && (opts.FromBlock != nil || opts.Reorgs) {
+		return api.replayLogs(ctx, notifier, crit, opts)
+	}
 
 	var (
 		rpcSub      = notifier.CreateSubscription()
@@ -332,7 +444,7 @@ func (api *FilterAPI) NewFilter(crit FilterCriteria) (rpc.ID, error) {
 }
 
 // GetLogs returns logs matching the given argument that are stored within the state.
-func (api *FilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([]*types.Log, error) {
+func (api *FilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) (rpcLogs, error) {
 	var filter *Filter
 	if crit.BlockHash != nil {
 		// Block filter requested, construct a single-shot filter
@@ -358,6 +470,84 @@ func (api *FilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([]*type
 	return returnLogs(logs), err
 }
 
+// GetLogsPaged returns a page of the logs matching the given argument, holding at
+// most limit logs. If the page doesn't complete the query, it includes a cursor
+// to pass to the next call with the same criteria to continue the query. Pages
+// are also finished early if they grow too large or take too long to collect.
+// Cursors are invalidated by reorgs of their block, requiring a restart.
+func (api *FilterAPI) GetLogsPaged(ctx context.Context, crit FilterCriteria, cursor *string, limit *hexutil.Uint64) (*LogPage, error) {
+	query, err := newLogPageQuery(crit, cursor)
+	if err != nil {
+		return nil, err
+	}
+	pager := &logPager{
+		sys:      api.sys,
+		crit:     crit,
+		query:    query,
+		limit:    api.sys.cfg.LogPageLimit,
+		size:     api.sys.cfg.LogPageSize,
+		deadline: time.Now().Add(api.sys.cfg.LogPageTimeout),
+	}
+	if limit != nil && *limit > 0 && uint64(*limit) < uint64(pager.limit) {
+		pager.limit = int(*limit)
+	}
+	if crit.BlockHash != nil {
+		return pager.blockPage(ctx)
+	}
+	if cursor == nil {
+		if query.Block, err = api.resolveBlockNumber(ctx, crit.FromBlock); err != nil {
+			return nil, err
+		}
+		if query.End, err = api.resolveBlockNumber(ctx, crit.ToBlock); err != nil {
+			return nil, err
+		}
+		if query.Block > query.End {
+			return nil, errInvalidBlockRange
+		}
+		// Don't page beyond the head, the query would never complete otherwise
+		head, err := api.resolveBlockNumber(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		if query.End > head {
+			query.End = head
+		}
+	} else {
+		// Make sure the chain wasn't reorged below the cursor since the last page
+		header, err := api.sys.backend.HeaderByNumber(ctx, rpc.BlockNumber(query.Block))
+		if err != nil {
+			return nil, err
+		}
+		if header == nil || header.Hash() != query.Hash {
+			return nil, errCursorReorged
+		}
+	}
+	return pager.rangePage(ctx)
+}
+
+// resolveBlockNumber resolves a block number of the filter criteria into an
+// absolute one, defaulting to the latest block.
+func (api *FilterAPI) resolveBlockNumber(ctx context.Context, number *big.Int) (uint64, error) {
+	n := rpc.LatestBlockNumber
+	if number != nil {
+		n = rpc.BlockNumber(number.Int64())
+	}
+	switch {
+	case n >= 0:
+		return uint64(n), nil
+	case n == rpc.PendingBlockNumber:
+		return 0, errPagedPending
+	}
+	header, err := api.sys.backend.HeaderByNumber(ctx, n)
+	if err != nil {
+		return 0, err
+	}
+	if header == nil {
+		return 0, fmt.Errorf("%s header not found", n)
+	}
+	return header.Number.Uint64(), nil
+}
+
 // UninstallFilter removes the filter with the given filter id.
 func (api *FilterAPI) UninstallFilter(id rpc.ID) bool {
 	api.filtersMu.Lock()
@@ -375,7 +565,7 @@ func (api *FilterAPI) UninstallFilter(id rpc.ID) bool {
 
 // GetFilterLogs returns the logs for the filter with the given id.
 // If the filter could not be found an empty array of logs is returned.
-func (api *FilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) ([]*types.Log, error) {
+func (api *FilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) (rpcLogs, error) {
 	api.filtersMu.Lock()
 	f, found := api.filters[id]
 	api.filtersMu.Unlock()
@@ -469,6 +659,15 @@ func returnHashes(hashes []common.Hash) []common.Hash {
 	return hashes
 }
 
+// rpcLogs is the result of the log retrieval methods. Binary RPC transports send
+// it in the encoding of types.EncodeFullLogs instead of JSON.
+type rpcLogs []*types.Log
+
+// MarshalRPCBinary implements rpc.BinaryMarshaler.
+func (logs rpcLogs) MarshalRPCBinary() ([]byte, error) {
+	return types.EncodeFullLogs(logs)
+}
+
 // returnLogs is a helper that will return an empty log array in case the given logs array is nil,
 // otherwise the given logs array is returned.
 func returnLogs(logs []*types.Log) []*types.Log {
@@ -22,6 +22,7 @@ import (
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -109,7 +110,7 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	// Short-cut if all we care about is pending logs
 	if f.begin == rpc.PendingBlockNumber.Int64() {
 		if f.end != rpc.PendingBlockNumber.Int64() {
-			return nil, errors.New("invalid block range")
+			return nil, errInvalidBlockRange
 		}
 		return f.pendingLogs()
 	}
@@ -153,10 +154,50 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	if f.end, err = resolveSpecial(f.end); err != nil {
 		return nil, err
 	}
-	// Gather all indexed logs, and finish with non indexed ones
+	// Refuse ranges reaching into the expired history, the logs are gone
+	if uint64(f.begin) < f.sys.backend.HistoryPruningCutoff() {
+		return nil, rpc.ErrPrunedHistory
+	}
+	// Serve the range covered by the log index from it, and the rest from the
+	// bloom bits and raw blocks
+	var (
+		logs []*types.Log
+		end  = uint64(f.end)
+	)
+	if size, tail, head := f.sys.backend.LogIndexStatus(); f.useLogIndex() && tail < head && uint64(f.begin) < head && end >= tail {
+		if uint64(f.begin) < tail {
+			if logs, err = f.rangeLogs(ctx, tail-1); err != nil {
+				return logs, err
+			}
+		}
+		last := head - 1
+		if end < last {
+			last = end
+		}
+		found, err := f.logIndexLogs(ctx, size, last)
+		logs = append(logs, found...)
+		if err != nil {
+			return logs, err
+		}
+	}
+	rest, err := f.rangeLogs(ctx, end)
+	logs = append(logs, rest...)
+	if pending {
+		pendingLogs, err := f.pendingLogs()
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, pendingLogs...)
+	}
+	return logs, err
+}
+
+// rangeLogs returns the logs matching the filter criteria up to the given block,
+// using the bloom bits where available and raw block iteration beyond.
+func (f *Filter) rangeLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
 	var (
 		logs           []*types.Log
-		end            = uint64(f.end)
+		err            error
 		size, sections = f.sys.backend.BloomStatus()
 	)
 	if indexed := sections * size; indexed > uint64(f.begin) {
@@ -170,15 +211,57 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 		}
 	}
 	rest, err := f.unindexedLogs(ctx, end)
-	logs = append(logs, rest...)
-	if pending {
-		pendingLogs, err := f.pendingLogs()
-		if err != nil {
-			return nil, err
+	return append(logs, rest...), err
+}
+
+// useLogIndex reports whether the log index can serve the filter, which is the
+// case unless all criteria are wildcards.
+func (f *Filter) useLogIndex() bool {
+	if len(f.addresses) > 0 {
+		return true
+	}
+	for _, sub := range f.topics {
+		if len(sub) > 0 {
+			return true
 		}
-		logs = append(logs, pendingLogs...)
 	}
-	return logs, err
+	return false
+}
+
+// logIndexLogs returns the logs matching the filter criteria up to the given
+// block based on the log index, which must cover the range.
+func (f *Filter) logIndexLogs(ctx context.Context, size uint64, end uint64) ([]*types.Log, error) {
+	var logs []*types.Log
+
+	for section := uint64(f.begin) / size; section <= end/size; section++ {
+		if err := ctx.Err(); err != nil {
+			return logs, err
+		}
+		matches := core.MatchLogIndex(f.sys.backend.ChainDb(), size, section, f.addresses, f.topics)
+		for i := 0; i < len(matches); {
+			// Collect the positions of the matching logs within the block
+			number := matches[i].Number
+			var positions []uint
+			for ; i < len(matches) && matches[i].Number == number; i++ {
+				positions = append(positions, matches[i].Index)
+			}
+			if number < uint64(f.begin) || number > end {
+				continue
+			}
+			header, err := f.sys.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+			if header == nil || err != nil {
+				return logs, err
+			}
+			found, err := f.checkPositions(ctx, header, positions)
+			if err != nil {
+				return logs, err
+			}
+			logs = append(logs, found...)
+		}
+		f.begin = int64((section + 1) * size)
+	}
+	f.begin = int64(end) + 1
+	return logs, nil
 }
 
 // indexedLogs returns the logs matching the filter criteria based on the bloom
@@ -262,6 +345,12 @@ func (f *Filter) blockLogs(ctx context.Context, header *types.Header) ([]*types.
 // match the filter criteria. This function is called when the bloom filter signals a potential match.
 // skipFilter signals all logs of the given block are requested.
 func (f *Filter) checkMatches(ctx context.Context, header *types.Header) ([]*types.Log, error) {
+	return f.checkPositions(ctx, header, nil)
+}
+
+// checkPositions is like checkMatches, but only checks the logs at the given
+// positions within the block if positions is non-nil.
+func (f *Filter) checkPositions(ctx context.Context, header *types.Header, positions []uint) ([]*types.Log, error) {
 	hash := header.Hash()
 	// Logs in cache are partially filled with context data
 	// such as tx index, block hash, etc.
@@ -271,7 +360,16 @@ func (f *Filter) checkMatches(ctx context.Context, header *types.Header) ([]*typ
 	if err != nil {
 		return nil, err
 	}
-	logs := filterLogs(cached.logs, nil, nil, f.addresses, f.topics)
+	candidates := cached.logs
+	if positions != nil {
+		candidates = make([]*types.Log, 0, len(positions))
+		for _, pos := range positions {
+			if pos < uint(len(cached.logs)) {
+				candidates = append(candidates, cached.logs[pos])
+			}
+		}
+	}
+	logs := filterLogs(candidates, nil, nil, f.addresses, f.topics)
 	if len(logs) == 0 {
 		return nil, nil
 	}
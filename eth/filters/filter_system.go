@@ -42,8 +42,11 @@ import (
 
 // Config represents the configuration of the filter system.
 type Config struct {
-	LogCacheSize int           // maximum number of cached blocks (default: 32)
-	Timeout      time.Duration // how long filters stay active (default: 5min)
+	LogCacheSize   int           // maximum number of cached blocks (default: 32)
+	Timeout        time.Duration // how long filters stay active (default: 5min)
+	LogPageLimit   int           // maximum number of logs in a page of eth_getLogsPaged (default: 10000)
+	LogPageSize    int           // maximum approximate size of a page of eth_getLogsPaged in bytes (default: 10MB)
+	LogPageTimeout time.Duration // wall time after which a page of eth_getLogsPaged is finished (default: 5s)
 }
 
 func (cfg Config) withDefaults() Config {
@@ -53,6 +56,15 @@ func (cfg Config) withDefaults() Config {
 	if cfg.LogCacheSize == 0 {
 		cfg.LogCacheSize = 32
 	}
+	if cfg.LogPageLimit == 0 {
+		cfg.LogPageLimit = 10000
+	}
+	if cfg.LogPageSize == 0 {
+		cfg.LogPageSize = 10 * 1024 * 1024
+	}
+	if cfg.LogPageTimeout == 0 {
+		cfg.LogPageTimeout = 5 * time.Second
+	}
 	return cfg
 }
 
@@ -64,16 +76,22 @@ type Backend interface {
 	GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error)
 	GetLogs(ctx context.Context, blockHash common.Hash, number uint64) ([][]*types.Log, error)
 	PendingBlockAndReceipts() (*types.Block, types.Receipts)
+	HistoryPruningCutoff() uint64
 
 	CurrentHeader() *types.Header
 	ChainConfig() *params.ChainConfig
 	SubscribeNewTxsEvent(chan<- core.NewTxsEvent) event.Subscription
+	SubscribeTxPoolEvents(chan<- []core.TxPoolEvent) event.Subscription
 	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
+	SubscribeStateDiffEvent(ch chan<- core.StateDiffEvent) event.Subscription
 	SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription
 	SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription
 	SubscribePendingLogsEvent(ch chan<- []*types.Log) event.Subscription
 
 	BloomStatus() (uint64, uint64)
+	// LogIndexStatus returns the section size of the log index and the range of
+	// blocks [tail, head) it covers, which is empty if the index is unavailable.
+	LogIndexStatus() (size, tail, head uint64)
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
 }
 
@@ -162,6 +180,11 @@ const (
 	PendingTransactionsSubscription
 	// BlocksSubscription queries hashes for blocks that are imported
 	BlocksSubscription
+	// TxPoolEventsSubscription queries for lifecycle changes of transactions in
+	// the transaction pool
+	TxPoolEventsSubscription
+	// StateDiffsSubscription queries for the state changes of canonical blocks
+	StateDiffsSubscription
 	// LastIndexSubscription keeps track of the last index
 	LastIndexSubscription
 )
@@ -176,6 +199,11 @@ const (
 	logsChanSize = 10
 	// chainEvChanSize is the size of channel listening to ChainEvent.
 	chainEvChanSize = 10
+	// txPoolEvChanSize is the size of channel listening to transaction lifecycle
+	// changes.
+	txPoolEvChanSize = 128
+	// stateDiffEvChanSize is the size of channel listening to StateDiffEvent.
+	stateDiffEvChanSize = 10
 )
 
 type subscription struct {
@@ -185,6 +213,8 @@ type subscription struct {
 	logsCrit  ethereum.FilterQuery
 	logs      chan []*types.Log
 	txs       chan []*types.Transaction
+	txEvents  chan []core.TxPoolEvent
+	diffs     chan core.StateDiffEvent
 	headers   chan *types.Header
 	installed chan struct{} // closed when the filter is installed
 	err       chan error    // closed when the filter is uninstalled
@@ -200,6 +230,8 @@ type EventSystem struct {
 
 	// Subscriptions
 	txsSub         event.Subscription // Subscription for new transaction event
+	txPoolSub      event.Subscription // Subscription for transaction lifecycle events
+	stateDiffSub   event.Subscription // Subscription for state diff event, nil unless requested
 	logsSub        event.Subscription // Subscription for new log event
 	rmLogsSub      event.Subscription // Subscription for removed log event
 	pendingLogsSub event.Subscription // Subscription for pending log event
@@ -209,6 +241,8 @@ type EventSystem struct {
 	install       chan *subscription         // install filter for event notification
 	uninstall     chan *subscription         // remove filter for event notification
 	txsCh         chan core.NewTxsEvent      // Channel to receive new transactions event
+	txPoolCh      chan []core.TxPoolEvent    // Channel to receive transaction lifecycle events
+	stateDiffCh   chan core.StateDiffEvent   // Channel to receive state diff event
 	logsCh        chan []*types.Log          // Channel to receive new log event
 	pendingLogsCh chan []*types.Log          // Channel to receive new log event
 	rmLogsCh      chan core.RemovedLogsEvent // Channel to receive removed log event
@@ -229,6 +263,8 @@ func NewEventSystem(sys *FilterSystem, lightMode bool) *EventSystem {
 		install:       make(chan *subscription),
 		uninstall:     make(chan *subscription),
 		txsCh:         make(chan core.NewTxsEvent, txChanSize),
+		txPoolCh:      make(chan []core.TxPoolEvent, txPoolEvChanSize),
+		stateDiffCh:   make(chan core.StateDiffEvent, stateDiffEvChanSize),
 		logsCh:        make(chan []*types.Log, logsChanSize),
 		rmLogsCh:      make(chan core.RemovedLogsEvent, rmLogsChanSize),
 		pendingLogsCh: make(chan []*types.Log, logsChanSize),
@@ -237,13 +273,14 @@ func NewEventSystem(sys *FilterSystem, lightMode bool) *EventSystem {
 
 	// Subscribe events
 	m.txsSub = m.backend.SubscribeNewTxsEvent(m.txsCh)
+	m.txPoolSub = m.backend.SubscribeTxPoolEvents(m.txPoolCh)
 	m.logsSub = m.backend.SubscribeLogsEvent(m.logsCh)
 	m.rmLogsSub = m.backend.SubscribeRemovedLogsEvent(m.rmLogsCh)
 	m.chainSub = m.backend.SubscribeChainEvent(m.chainCh)
 	m.pendingLogsSub = m.backend.SubscribePendingLogsEvent(m.pendingLogsCh)
 
 	// Make sure none of the subscriptions are empty
-	if m.txsSub == nil || m.logsSub == nil || m.rmLogsSub == nil || m.chainSub == nil || m.pendingLogsSub == nil {
+	if m.txsSub == nil || m.txPoolSub == nil || m.logsSub == nil || m.rmLogsSub == nil || m.chainSub == nil || m.pendingLogsSub == nil {
 		log.Crit("Subscribe for event system failed")
 	}
 
@@ -278,6 +315,8 @@ func (sub *Subscription) Unsubscribe() {
 				break uninstallLoop
 			case <-sub.f.logs:
 			case <-sub.f.txs:
+			case <-sub.f.txEvents:
+			case <-sub.f.diffs:
 			case <-sub.f.headers:
 			}
 		}
@@ -418,6 +457,40 @@ func (es *EventSystem) SubscribePendingTxs(txs chan []*types.Transaction) *Subsc
 	return es.subscribe(sub)
 }
 
+// SubscribeTxPoolEvents creates a subscription that writes the lifecycle changes
+// of the transactions in the transaction pool.
+func (es *EventSystem) SubscribeTxPoolEvents(events chan []core.TxPoolEvent) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       TxPoolEventsSubscription,
+		created:   time.Now(),
+		logs:      make(chan []*types.Log),
+		txs:       make(chan []*types.Transaction),
+		txEvents:  events,
+		headers:   make(chan *types.Header),
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	return es.subscribe(sub)
+}
+
+// SubscribeStateDiffs creates a subscription that writes the state changes of
+// the blocks that become canonical.
+func (es *EventSystem) SubscribeStateDiffs(diffs chan core.StateDiffEvent) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       StateDiffsSubscription,
+		created:   time.Now(),
+		logs:      make(chan []*types.Log),
+		txs:       make(chan []*types.Transaction),
+		diffs:     diffs,
+		headers:   make(chan *types.Header),
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	return es.subscribe(sub)
+}
+
 type filterIndex map[Type]map[rpc.ID]*subscription
 
 func (es *EventSystem) handleLogs(filters filterIndex, ev []*types.Log) {
@@ -459,6 +532,18 @@ func (es *EventSystem) handleTxsEvent(filters filterIndex, ev core.NewTxsEvent)
 	}
 }
 
+func (es *EventSystem) handleTxPoolEvents(filters filterIndex, ev []core.TxPoolEvent) {
+	for _, f := range filters[TxPoolEventsSubscription] {
+		f.txEvents <- ev
+	}
+}
+
+func (es *EventSystem) handleStateDiffEvent(filters filterIndex, ev core.StateDiffEvent) {
+	for _, f := range filters[StateDiffsSubscription] {
+		f.diffs <- ev
+	}
+}
+
 func (es *EventSystem) handleChainEvent(filters filterIndex, ev core.ChainEvent) {
 	for _, f := range filters[BlocksSubscription] {
 		f.headers <- ev.Block.Header()
@@ -555,6 +640,10 @@ func (es *EventSystem) eventLoop() {
 	// Ensure all subscriptions get cleaned up
 	defer func() {
 		es.txsSub.Unsubscribe()
+		es.txPoolSub.Unsubscribe()
+		if es.stateDiffSub != nil {
+			es.stateDiffSub.Unsubscribe()
+		}
 		es.logsSub.Unsubscribe()
 		es.rmLogsSub.Unsubscribe()
 		es.pendingLogsSub.Unsubscribe()
@@ -565,11 +654,18 @@ func (es *EventSystem) eventLoop() {
 	for i := UnknownSubscription; i < LastIndexSubscription; i++ {
 		index[i] = make(map[rpc.ID]*subscription)
 	}
+	// The state diffs are only subscribed to while requested, as the chain only
+	// tracks the state changes if they are needed.
+	var stateDiffErr <-chan error
 
 	for {
 		select {
 		case ev := <-es.txsCh:
 			es.handleTxsEvent(index, ev)
+		case ev := <-es.txPoolCh:
+			es.handleTxPoolEvents(index, ev)
+		case ev := <-es.stateDiffCh:
+			es.handleStateDiffEvent(index, ev)
 		case ev := <-es.logsCh:
 			es.handleLogs(index, ev)
 		case ev := <-es.rmLogsCh:
@@ -587,6 +683,10 @@ func (es *EventSystem) eventLoop() {
 			} else {
 				index[f.typ][f.id] = f
 			}
+			if f.typ == StateDiffsSubscription && es.stateDiffSub == nil {
+				es.stateDiffSub = es.backend.SubscribeStateDiffEvent(es.stateDiffCh)
+				stateDiffErr = es.stateDiffSub.Err()
+			}
 			close(f.installed)
 
 		case f := <-es.uninstall:
@@ -597,11 +697,19 @@ func (es *EventSystem) eventLoop() {
 			} else {
 				delete(index[f.typ], f.id)
 			}
+			if f.typ == StateDiffsSubscription && len(index[StateDiffsSubscription]) == 0 && es.stateDiffSub != nil {
+				es.stateDiffSub.Unsubscribe()
+				es.stateDiffSub, stateDiffErr = nil, nil
+			}
 			close(f.err)
 
 		// System stopped
 		case <-es.txsSub.Err():
 			return
+		case <-es.txPoolSub.Err():
+			return
+		case <-stateDiffErr:
+			return
 		case <-es.logsSub.Err():
 			return
 		case <-es.rmLogsSub.Err():
@@ -29,6 +29,7 @@ import (
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
@@ -45,7 +46,11 @@ import (
 type testBackend struct {
 	db              ethdb.Database
 	sections        uint64
+	logIndexSize    uint64 // Section size of the log index
+	logIndexHead    uint64 // Number of blocks covered by the log index
 	txFeed          event.Feed
+	txPoolFeed      event.Feed
+	stateDiffFeed   event.Feed
 	logsFeed        event.Feed
 	rmLogsFeed      event.Feed
 	pendingLogsFeed event.Feed
@@ -131,6 +136,14 @@ func (b *testBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Sub
 	return b.txFeed.Subscribe(ch)
 }
 
+func (b *testBackend) SubscribeTxPoolEvents(ch chan<- []core.TxPoolEvent) event.Subscription {
+	return b.txPoolFeed.Subscribe(ch)
+}
+
+func (b *testBackend) SubscribeStateDiffEvent(ch chan<- core.StateDiffEvent) event.Subscription {
+	return b.stateDiffFeed.Subscribe(ch)
+}
+
 func (b *testBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription {
 	return b.rmLogsFeed.Subscribe(ch)
 }
@@ -147,10 +160,23 @@ func (b *testBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subsc
 	return b.chainFeed.Subscribe(ch)
 }
 
+func (b *testBackend) HistoryPruningCutoff() uint64 {
+	tail, _ := b.db.Tail()
+	return tail
+}
+
 func (b *testBackend) BloomStatus() (uint64, uint64) {
 	return params.BloomBitsBlocks, b.sections
 }
 
+func (b *testBackend) LogIndexStatus() (uint64, uint64, uint64) {
+	tail := rawdb.ReadLogIndexTail(b.db)
+	if tail == nil {
+		return b.logIndexSize, 0, 0
+	}
+	return b.logIndexSize, *tail, b.logIndexHead
+}
+
 func (b *testBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
 	requests := make(chan chan *bloombits.Retrieval)
 
@@ -356,6 +382,50 @@ func TestPendingTxFilterFullTx(t *testing.T) {
 	}
 }
 
+// TestTxPoolEventsSubscription tests whether transaction lifecycle changes are
+// delivered to the subscribers of the event system in order.
+func TestTxPoolEventsSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db           = rawdb.NewMemoryDatabase()
+		backend, sys = newTestFilterSystem(t, db, Config{})
+		api          = NewFilterAPI(sys, false)
+
+		tx0 = types.NewTransaction(0, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil)
+		tx1 = types.NewTransaction(0, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), big.NewInt(1), 0, new(big.Int), nil)
+
+		events = []core.TxPoolEvent{
+			{Type: core.TxAdded, Tx: tx0},
+			{Type: core.TxPromoted, Tx: tx0},
+			{Type: core.TxReplaced, Tx: tx0, Replacement: tx1.Hash()},
+			{Type: core.TxIncluded, Tx: tx1, BlockHash: common.Hash{0x01}, BlockNumber: 1},
+		}
+	)
+	ch := make(chan []core.TxPoolEvent)
+	sub := api.events.SubscribeTxPoolEvents(ch)
+	defer sub.Unsubscribe()
+
+	go func() {
+		backend.txPoolFeed.Send(events[:2])
+		backend.txPoolFeed.Send(events[2:])
+	}()
+	var received []core.TxPoolEvent
+	for len(received) < len(events) {
+		select {
+		case batch := <-ch:
+			received = append(received, batch...)
+		case <-time.After(time.Second):
+			t.Fatalf("timeout, received %d events, want %d", len(received), len(events))
+		}
+	}
+	for i := range events {
+		if received[i].Type != events[i].Type || received[i].Tx.Hash() != events[i].Tx.Hash() {
+			t.Errorf("event %d mismatch: have %s %x, want %s %x", i, received[i].Type, received[i].Tx.Hash(), events[i].Type, events[i].Tx.Hash())
+		}
+	}
+}
+
 // TestLogFilterCreation test whether a given filter criteria makes sense.
 // If not it must return an error.
 func TestLogFilterCreation(t *testing.T) {
@@ -955,3 +1025,163 @@ func flattenLogs(pl [][]*types.Log) []*types.Log {
 	}
 	return logs
 }
+
+// TestLogsReplay tests that log subscriptions replaying historical logs switch
+// over to the live logs without duplicates, and notify reorgs.
+func TestLogsReplay(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db           = rawdb.NewMemoryDatabase()
+		backend, sys = newTestFilterSystem(t, db, Config{})
+		api          = NewFilterAPI(sys, false)
+		addr         = common.HexToAddress("0x2")
+		gspec        = &core.Genesis{
+			Config:  params.TestChainConfig,
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+	)
+	_, chain, receipts := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 10, func(i int, gen *core.BlockGen) {
+		receipt := types.NewReceipt(nil, false, 0)
+		receipt.Logs = []*types.Log{{Address: addr}}
+		gen.AddUncheckedReceipt(receipt)
+		gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.HexToAddress("0x1"), big.NewInt(1), 1, gen.BaseFee(), nil))
+	})
+	gspec.MustCommit(db)
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteHeadHeaderHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("eth", api); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	var (
+		notifications = make(chan map[string]interface{})
+		from          = rpc.BlockNumber(4)
+	)
+	sub, err := client.EthSubscribe(context.Background(), notifications, "logs", FilterCriteria{Addresses: []common.Address{addr}}, &LogsOptions{FromBlock: &from, Reorgs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	next := func() map[string]interface{} {
+		select {
+		case n := <-notifications:
+			return n
+		case err := <-sub.Err():
+			t.Fatalf("subscription failed: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for notification")
+		}
+		return nil
+	}
+	// The head's log is already replayed, only the new one is delivered
+	head := chain[len(chain)-1]
+	headLog := &types.Log{Address: addr, BlockNumber: head.NumberU64(), BlockHash: head.Hash()}
+	backend.logsFeed.Send([]*types.Log{headLog})
+	backend.logsFeed.Send([]*types.Log{{Address: addr, BlockNumber: 11, BlockHash: common.Hash{0x11}}})
+
+	for number := uint64(4); number <= 11; number++ {
+		n := next()
+		if have := hexutil.MustDecodeUint64(n["blockNumber"].(string)); have != number {
+			t.Fatalf("log block number mismatch: have %d, want %d", have, number)
+		}
+	}
+	// Reorgs deliver the removed logs and a reorg notification
+	removed := *headLog
+	removed.Removed = true
+	backend.rmLogsFeed.Send(core.RemovedLogsEvent{Logs: []*types.Log{&removed}})
+	if n := next(); n["removed"] != true || n["blockHash"] != head.Hash().Hex() {
+		t.Fatalf("removed log mismatch: %v", n)
+	}
+	fork := types.NewBlockWithHeader(&types.Header{ParentHash: chain[len(chain)-2].Hash(), Number: head.Number(), Extra: []byte("fork")})
+	backend.chainFeed.Send(core.ChainEvent{Block: fork, Hash: fork.Hash()})
+
+	n := next()
+	if n["type"] != "reorg" {
+		t.Fatalf("expected reorg notification, have %v", n)
+	}
+	for field, want := range map[string]common.Hash{"oldHead": head.Hash(), "newHead": fork.Hash(), "commonAncestor": chain[len(chain)-2].Hash()} {
+		if have := n[field].(map[string]interface{})["hash"]; have != want.Hex() {
+			t.Errorf("%s mismatch: have %v, want %v", field, have, want.Hex())
+		}
+	}
+}
+
+// TestLogsReplayFailure tests that log subscriptions whose replay fails notify
+// the client and are ended by the server.
+func TestLogsReplayFailure(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db           = rawdb.NewMemoryDatabase()
+		backend, sys = newTestFilterSystem(t, db, Config{})
+		api          = NewFilterAPI(sys, false)
+		addr         = common.HexToAddress("0x2")
+		gspec        = &core.Genesis{
+			Config:  params.TestChainConfig,
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+	)
+	_, chain, receipts := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 10, func(i int, gen *core.BlockGen) {
+		receipt := types.NewReceipt(nil, false, 0)
+		receipt.Logs = []*types.Log{{Address: addr}}
+		gen.AddUncheckedReceipt(receipt)
+		gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.HexToAddress("0x1"), big.NewInt(1), 1, gen.BaseFee(), nil))
+	})
+	gspec.MustCommit(db)
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteHeadHeaderHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	// Lose a body in the replayed range, failing the replay
+	rawdb.DeleteBody(db, chain[5].Hash(), chain[5].NumberU64())
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("eth", api); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	var (
+		notifications = make(chan map[string]interface{})
+		from          = rpc.BlockNumber(4)
+	)
+	sub, err := client.EthSubscribe(context.Background(), notifications, "logs", FilterCriteria{Addresses: []common.Address{addr}}, &LogsOptions{FromBlock: &from})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	select {
+	case n := <-notifications:
+		if n["type"] != "error" || n["error"] == "" {
+			t.Fatalf("expected error notification, have %v", n)
+		}
+	case err := <-sub.Err():
+		t.Fatalf("subscription failed: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for notification")
+	}
+	// The subscription is ended, live logs are no longer delivered
+	backend.logsFeed.Send([]*types.Log{{Address: addr, BlockNumber: 11, BlockHash: common.Hash{0x11}}})
+	select {
+	case n := <-notifications:
+		t.Fatalf("unexpected notification after failure: %v", n)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
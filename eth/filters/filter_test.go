@@ -23,6 +23,7 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
@@ -242,3 +243,226 @@ func TestFilters(t *testing.T) {
 		}
 	}
 }
+
+func TestFiltersLogIndex(t *testing.T) {
+	var (
+		db, _   = rawdb.NewLevelDBDatabase(t.TempDir(), 0, 0, "", false)
+		backend = &testBackend{db: db, logIndexSize: 16}
+		sys     = NewFilterSystem(backend, Config{})
+		key1, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr1   = crypto.PubkeyToAddress(key1.PublicKey)
+		addr2   = common.HexToAddress("0x2")
+
+		hash1 = common.BytesToHash([]byte("topic1"))
+		hash2 = common.BytesToHash([]byte("topic2"))
+		hash3 = common.BytesToHash([]byte("topic3"))
+
+		latest = int64(rpc.LatestBlockNumber)
+		gspec  = &core.Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   core.GenesisAlloc{addr1: {Balance: big.NewInt(1000000)}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+	)
+	defer db.Close()
+
+	logs := map[int][]*types.Log{
+		1:  {{Address: addr1, Topics: []common.Hash{hash1}}},
+		20: {{Address: addr2, Topics: []common.Hash{hash1, hash2}}, {Address: addr1, Topics: []common.Hash{hash2, hash1}}},
+		50: {{Address: addr1, Topics: []common.Hash{hash3}}, {Address: addr2, Topics: []common.Hash{hash1, hash3}}},
+		70: {{Address: addr2, Topics: []common.Hash{hash2}}},
+		98: {{Address: addr1, Topics: []common.Hash{hash1, hash2}}},
+	}
+	_, chain, receipts := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 100, func(i int, gen *core.BlockGen) {
+		if logs[i] != nil {
+			receipt := types.NewReceipt(nil, false, 0)
+			receipt.Logs = logs[i]
+			gen.AddUncheckedReceipt(receipt)
+			gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.HexToAddress("0x1"), big.NewInt(1), 1, gen.BaseFee(), nil))
+		}
+	})
+	gspec.MustCommit(db)
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteHeadHeaderHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	criteria := []struct {
+		begin, end int64
+		addresses  []common.Address
+		topics     [][]common.Hash
+		want       int
+	}{
+		{0, latest, []common.Address{addr1}, nil, 4},
+		{0, latest, []common.Address{addr1, addr2}, [][]common.Hash{{hash1}}, 4},
+		{0, latest, nil, [][]common.Hash{nil, {hash2, hash3}}, 3},
+		{0, latest, []common.Address{addr2}, [][]common.Hash{{hash1}, {hash3}}, 1},
+		{10, 60, nil, [][]common.Hash{{hash2}}, 1},
+		{21, 97, nil, [][]common.Hash{{hash1}}, 2},
+		{0, latest, []common.Address{common.HexToAddress("0x3")}, nil, 0},
+		{0, latest, nil, [][]common.Hash{nil, nil, nil, nil, {hash1}}, 0},
+	}
+	// Collect the results without the log index
+	results := make([][]*types.Log, len(criteria))
+	for i, c := range criteria {
+		have, err := sys.NewRangeFilter(c.begin, c.end, c.addresses, c.topics).Logs(context.Background())
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %v", i, err)
+		}
+		if len(have) != c.want {
+			t.Fatalf("test %d: have %d logs, want %d", i, len(have), c.want)
+		}
+		results[i] = have
+	}
+	// Check the log index delivers the same logs, both covering the entire chain
+	// and only the recent blocks
+	for _, history := range []uint64{0, 40} {
+		rawdb.DeleteLogIndex(db)
+		indexer := core.NewLogIndexer(db, backend.logIndexSize, 0, history)
+		if err := indexer.ProcessSections(chain[len(chain)-1].NumberU64()); err != nil {
+			t.Fatalf("failed to build log index: %v", err)
+		}
+		sections, _, _ := indexer.Sections()
+		indexer.Close()
+		backend.logIndexHead = sections * backend.logIndexSize
+
+		if size, tail, head := backend.LogIndexStatus(); (history == 0 && tail != 0) || (history > 0 && tail == 0) || head != 96 {
+			t.Fatalf("history %d: unexpected log index range [%d, %d), section size %d", history, tail, head, size)
+		}
+		for i, c := range criteria {
+			have, err := sys.NewRangeFilter(c.begin, c.end, c.addresses, c.topics).Logs(context.Background())
+			if err != nil {
+				t.Fatalf("history %d, test %d: unexpected error: %v", history, i, err)
+			}
+			if !reflect.DeepEqual(have, results[i]) {
+				t.Fatalf("history %d, test %d: log mismatch: have %v, want %v", history, i, have, results[i])
+			}
+		}
+	}
+}
+
+func TestGetLogsPaged(t *testing.T) {
+	var (
+		db, _   = rawdb.NewLevelDBDatabase(t.TempDir(), 0, 0, "", false)
+		backend = &testBackend{db: db}
+		sys     = NewFilterSystem(backend, Config{})
+		api     = NewFilterAPI(sys, false)
+		key1, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr1   = crypto.PubkeyToAddress(key1.PublicKey)
+		addr2   = common.HexToAddress("0x2")
+		topic   = common.BytesToHash([]byte("topic"))
+
+		gspec = &core.Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   core.GenesisAlloc{addr1: {Balance: big.NewInt(1000000)}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+	)
+	defer db.Close()
+
+	_, chain, receipts := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 100, func(i int, gen *core.BlockGen) {
+		if i%7 == 0 {
+			receipt := types.NewReceipt(nil, false, 0)
+			for j := 0; j < i%3+1; j++ {
+				receipt.Logs = append(receipt.Logs, &types.Log{Address: addr2, Topics: []common.Hash{topic}})
+			}
+			gen.AddUncheckedReceipt(receipt)
+			gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.HexToAddress("0x1"), big.NewInt(1), 1, gen.BaseFee(), nil))
+		}
+	})
+	gspec.MustCommit(db)
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteHeadHeaderHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	blockHash := chain[14].Hash()
+	for i, crit := range []FilterCriteria{
+		{BlockHash: &blockHash},
+		{FromBlock: big.NewInt(0), Addresses: []common.Address{addr2}},
+		{FromBlock: big.NewInt(30), ToBlock: big.NewInt(80), Topics: [][]common.Hash{{topic}}},
+	} {
+		want, err := api.GetLogs(context.Background(), crit)
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %v", i, err)
+		}
+		// Walk the pages, checking they deliver the same logs as an unpaged query
+		var (
+			have   []*types.Log
+			cursor *string
+			limit  = hexutil.Uint64(2)
+		)
+		for pages := 0; ; pages++ {
+			if pages > len(want) {
+				t.Fatalf("test %d: paging doesn't terminate", i)
+			}
+			page, err := api.GetLogsPaged(context.Background(), crit, cursor, &limit)
+			if err != nil {
+				t.Fatalf("test %d: unexpected error: %v", i, err)
+			}
+			if len(page.Logs) > int(limit) {
+				t.Fatalf("test %d: page exceeds limit: %d logs", i, len(page.Logs))
+			}
+			have = append(have, page.Logs...)
+			if cursor = page.Cursor; cursor == nil {
+				break
+			}
+		}
+		if len(have) != len(want) || len(have) == 0 {
+			t.Fatalf("test %d: have %d logs, want %d", i, len(have), len(want))
+		}
+		for j := range have {
+			if have[j].BlockNumber != want[j].BlockNumber || have[j].Index != want[j].Index {
+				t.Fatalf("test %d: log %d mismatch: have %d/%d, want %d/%d", i, j, have[j].BlockNumber, have[j].Index, want[j].BlockNumber, want[j].Index)
+			}
+		}
+	}
+	// Cursors are only valid for the criteria they were created for
+	limit := hexutil.Uint64(1)
+	page, err := api.GetLogsPaged(context.Background(), FilterCriteria{Addresses: []common.Address{addr2}, FromBlock: big.NewInt(0)}, nil, &limit)
+	if err != nil || page.Cursor == nil {
+		t.Fatalf("expected incomplete page, have %v, %v", page, err)
+	}
+	if _, err := api.GetLogsPaged(context.Background(), FilterCriteria{Addresses: []common.Address{addr1}}, page.Cursor, &limit); err != errCursorQuery {
+		t.Fatalf("cursor of different criteria: have %v, want %v", err, errCursorQuery)
+	}
+	invalid := "0x1234"
+	if _, err := api.GetLogsPaged(context.Background(), FilterCriteria{}, &invalid, &limit); err != errInvalidCursor {
+		t.Fatalf("invalid cursor: have %v, want %v", err, errInvalidCursor)
+	}
+	pending := big.NewInt(int64(rpc.PendingBlockNumber))
+	if _, err := api.GetLogsPaged(context.Background(), FilterCriteria{ToBlock: pending}, nil, nil); err != errPagedPending {
+		t.Fatalf("pending query: have %v, want %v", err, errPagedPending)
+	}
+	// Inverted ranges are rejected, ranges beyond the head are capped by it
+	if _, err := api.GetLogsPaged(context.Background(), FilterCriteria{FromBlock: big.NewInt(50), ToBlock: big.NewInt(40)}, nil, nil); err != errInvalidBlockRange {
+		t.Fatalf("inverted range: have %v, want %v", err, errInvalidBlockRange)
+	}
+	page, err = api.GetLogsPaged(context.Background(), FilterCriteria{FromBlock: big.NewInt(90), ToBlock: big.NewInt(1 << 40)}, nil, nil)
+	if err != nil || page.Cursor != nil || len(page.Logs) != 5 {
+		t.Fatalf("range beyond head: have %v, %v, want 5 logs and no cursor", page, err)
+	}
+	page, err = api.GetLogsPaged(context.Background(), FilterCriteria{FromBlock: big.NewInt(1 << 40), ToBlock: big.NewInt(1 << 41)}, nil, nil)
+	if err != nil || page.Cursor != nil || len(page.Logs) != 0 {
+		t.Fatalf("range above head: have %v, %v, want empty page", page, err)
+	}
+	// Cursors are invalidated by reorgs of their block, the second log being in block 8
+	crit := FilterCriteria{FromBlock: big.NewInt(0), Addresses: []common.Address{addr2}}
+	page, err = api.GetLogsPaged(context.Background(), crit, nil, &limit)
+	if err != nil || page.Cursor == nil {
+		t.Fatalf("expected incomplete page, have %v, %v", page, err)
+	}
+	if _, err := api.GetLogsPaged(context.Background(), crit, page.Cursor, &limit); err != nil {
+		t.Fatalf("cursor continuation failed: %v", err)
+	}
+	fork := types.NewBlockWithHeader(&types.Header{ParentHash: chain[6].Hash(), Number: chain[7].Number(), Extra: []byte("fork")})
+	rawdb.WriteBlock(db, fork)
+	rawdb.WriteCanonicalHash(db, fork.Hash(), fork.NumberU64())
+	if _, err := api.GetLogsPaged(context.Background(), crit, page.Cursor, &limit); err != errCursorReorged {
+		t.Fatalf("reorged cursor: have %v, want %v", err, errCursorReorged)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	logPageMinChunk = 16    // Minimum number of blocks filtered at once while paging
+	logPageMaxChunk = 65536 // Maximum number of blocks filtered at once while paging
+)
+
+var (
+	errPagedPending  = errors.New("pending logs are not supported by paged queries")
+	errInvalidCursor = errors.New("invalid cursor")
+	errCursorQuery   = errors.New("cursor belongs to different filter criteria")
+	errCursorReorged = errors.New("cursor block was reorged, restart the query")
+)
+
+// LogPage is a page of the logs matching a paged log query.
+type LogPage struct {
+	Logs   []*types.Log `json:"logs"`
+	Cursor *string      `json:"cursor"` // Cursor continuing the query, nil if complete
+}
+
+// logPageQuery is the state of a paged log query, which is encoded into the
+// opaque cursors handed out to the caller.
+type logPageQuery struct {
+	Criteria common.Hash // Hash of the filter criteria the cursor belongs to
+	Block    uint64      // Block to continue the query at
+	Hash     common.Hash // Hash of Block, to detect reorgs between pages
+	Index    uint64      // Index of the first log to return within Block
+	End      uint64      // Last block of the query
+}
+
+// newLogPageQuery creates the state of a paged query, continuing at the cursor
+// if given.
+func newLogPageQuery(crit FilterCriteria, cursor *string) (*logPageQuery, error) {
+	enc, err := json.Marshal(crit)
+	if err != nil {
+		return nil, err
+	}
+	hash := crypto.Keccak256Hash(enc)
+	if cursor == nil {
+		return &logPageQuery{Criteria: hash}, nil
+	}
+	blob, err := hexutil.Decode(*cursor)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	query := new(logPageQuery)
+	if err := rlp.DecodeBytes(blob, query); err != nil {
+		return nil, errInvalidCursor
+	}
+	if query.Criteria != hash {
+		return nil, errCursorQuery
+	}
+	return query, nil
+}
+
+// cursor encodes a cursor continuing the query at the given log.
+func (q *logPageQuery) cursor(block uint64, hash common.Hash, index uint64) *string {
+	next := *q
+	next.Block, next.Hash, next.Index = block, hash, index
+	blob, _ := rlp.EncodeToBytes(&next)
+	cursor := hexutil.Encode(blob)
+	return &cursor
+}
+
+// logPager collects a page of logs.
+type logPager struct {
+	sys      *FilterSystem
+	crit     FilterCriteria
+	query    *logPageQuery
+	limit    int       // Maximum number of logs in the page
+	size     int       // Maximum approximate size of the page
+	deadline time.Time // Time after which the page is finished
+	page     LogPage
+	bytes    int // Approximate size of the page
+}
+
+// add appends the logs to the page, skipping the ones before the query position.
+// It returns false if the page is full, setting the cursor at the first log not
+// added.
+func (p *logPager) add(logs []*types.Log) bool {
+	for _, log := range logs {
+		if log.BlockNumber == p.query.Block && uint64(log.Index) < p.query.Index {
+			continue
+		}
+		size := logJSONSize(log)
+		if len(p.page.Logs) >= p.limit || (len(p.page.Logs) > 0 && p.bytes+size > p.size) {
+			p.page.Cursor = p.query.cursor(log.BlockNumber, log.BlockHash, uint64(log.Index))
+			return false
+		}
+		p.page.Logs = append(p.page.Logs, log)
+		p.bytes += size
+	}
+	return true
+}
+
+// blockPage collects a page of the logs of a single block query.
+func (p *logPager) blockPage(ctx context.Context) (*LogPage, error) {
+	logs, err := p.sys.NewBlockFilter(*p.crit.BlockHash, p.crit.Addresses, p.crit.Topics).Logs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(logs) > 0 {
+		p.query.Block = logs[0].BlockNumber
+	}
+	p.page.Logs = []*types.Log{}
+	p.add(logs)
+	return &p.page, nil
+}
+
+// rangePage collects a page of the logs of a block range query. The range is
+// filtered in chunks, adapting their size to finish the page in time.
+func (p *logPager) rangePage(ctx context.Context) (*LogPage, error) {
+	var (
+		chunk  = uint64(logPageMinChunk)
+		budget = time.Until(p.deadline)
+	)
+	p.page.Logs = []*types.Log{}
+	for from := p.query.Block; from <= p.query.End; {
+		to := from + chunk - 1
+		if to > p.query.End || to < from {
+			to = p.query.End
+		}
+		start := time.Now()
+		logs, err := p.sys.NewRangeFilter(int64(from), int64(to), p.crit.Addresses, p.crit.Topics).Logs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !p.add(logs) {
+			return &p.page, nil
+		}
+		if to == p.query.End {
+			break
+		}
+		from = to + 1
+		if time.Now().After(p.deadline) {
+			header, err := p.sys.backend.HeaderByNumber(ctx, rpc.BlockNumber(from))
+			if err != nil {
+				return nil, err
+			}
+			if header == nil {
+				return nil, errCursorReorged
+			}
+			p.page.Cursor = p.query.cursor(from, header.Hash(), 0)
+			return &p.page, nil
+		}
+		// Grow fast chunks and shrink slow ones
+		switch elapsed := time.Since(start); {
+		case elapsed < budget/16 && chunk < logPageMaxChunk:
+			chunk *= 2
+		case elapsed > budget/4 && chunk > logPageMinChunk:
+			chunk /= 2
+		}
+	}
+	return &p.page, nil
+}
+
+// logJSONSize approximates the size of the JSON encoding of a log.
+func logJSONSize(log *types.Log) int {
+	return 400 + 2*len(log.Data) + 69*len(log.Topics)
+}
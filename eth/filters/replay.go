@@ -0,0 +1,331 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// logReplayChunk is the number of blocks filtered at once while replaying.
+	logReplayChunk = 1024
+
+	// logReplayReorgDepth is the number of blocks below the replayed head for
+	// which delivered logs are tracked to avoid duplicates around the switch to
+	// live logs. Reorgs deeper than this may deliver duplicate logs.
+	logReplayReorgDepth = 1024
+
+	// logReplayBuffer is the maximum number of live events buffered while
+	// replaying. The subscription fails if the replay falls further behind.
+	logReplayBuffer = 4096
+)
+
+var (
+	errReplayPending  = errors.New("pending logs can't be replayed")
+	errReplayOverflow = errors.New("too many live events during log replay")
+)
+
+// LogsOptions are the options of a logs subscription.
+type LogsOptions struct {
+	FromBlock *rpc.BlockNumber `json:"fromBlock"` // Block to replay the logs of the canonical chain from
+	Reorgs    bool             `json:"reorgs"`    // Whether to notify chain reorganisations
+}
+
+// BlockRef identifies a block in notifications.
+type BlockRef struct {
+	Hash   common.Hash    `json:"hash"`
+	Number hexutil.Uint64 `json:"number"`
+}
+
+func newBlockRef(header *types.Header) *BlockRef {
+	if header == nil {
+		return nil
+	}
+	return &BlockRef{Hash: header.Hash(), Number: hexutil.Uint64(header.Number.Uint64())}
+}
+
+// LogsReorg is sent to logs subscriptions requesting reorg notifications when the
+// chain head switches to a block not descending from the previous head. The logs
+// of the abandoned blocks are delivered as removed logs as usual.
+type LogsReorg struct {
+	Type     string    `json:"type"` // Always "reorg"
+	OldHead  *BlockRef `json:"oldHead"`
+	NewHead  *BlockRef `json:"newHead"`
+	Ancestor *BlockRef `json:"commonAncestor"` // Nil if beyond the tracked depth
+}
+
+// LogsError is sent to logs subscriptions whose replay failed, before the
+// subscription is ended by the server.
+type LogsError struct {
+	Type  string `json:"type"` // Always "error"
+	Error string `json:"error"`
+}
+
+// logKey identifies a log delivered to a subscription.
+type logKey struct {
+	block common.Hash
+	index uint
+}
+
+// logStream delivers the notifications of a logs subscription replaying logs
+// and/or notifying reorgs. Live events arriving during the replay are buffered
+// and delivered afterwards, skipping the logs already replayed. If the replay
+// fails, the client is notified and the subscription is ended.
+type logStream struct {
+	api      *FilterAPI
+	notifier *rpc.Notifier
+	sub      *rpc.Subscription
+
+	replayed  uint64              // Last block covered by the replay
+	delivered map[logKey]struct{} // Logs held by the client near the replayed head
+	head      *types.Header       // Last chain head, nil if reorgs aren't notified
+}
+
+// replayLogs creates a logs subscription according to the options.
+func (api *FilterAPI) replayLogs(ctx context.Context, notifier *rpc.Notifier, crit FilterCriteria, opts *LogsOptions) (*rpc.Subscription, error) {
+	if crit.FromBlock != nil && crit.FromBlock.Int64() == rpc.PendingBlockNumber.Int64() {
+		return nil, errReplayPending
+	}
+	if crit.ToBlock != nil && crit.ToBlock.Int64() == rpc.PendingBlockNumber.Int64() {
+		return nil, errReplayPending
+	}
+	// Resolve the replayed range before installing the live subscription, it's
+	// bounded by the head seen afterwards
+	var from uint64
+	if opts.FromBlock != nil {
+		if *opts.FromBlock == rpc.PendingBlockNumber {
+			return nil, errReplayPending
+		}
+		number, err := api.resolveBlockNumber(ctx, big.NewInt(opts.FromBlock.Int64()))
+		if err != nil {
+			return nil, err
+		}
+		if number < api.sys.backend.HistoryPruningCutoff() {
+			return nil, rpc.ErrPrunedHistory
+		}
+		from = number
+	}
+	var (
+		matchedLogs = make(chan []*types.Log)
+		headers     chan *types.Header
+		headersSub  *Subscription
+	)
+	logsSub, err := api.events.SubscribeLogs(ethereum.FilterQuery(crit), matchedLogs)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Reorgs {
+		headers = make(chan *types.Header)
+		headersSub = api.events.SubscribeNewHeads(headers)
+	}
+	stream := &logStream{
+		api:       api,
+		notifier:  notifier,
+		sub:       notifier.CreateSubscription(),
+		delivered: make(map[logKey]struct{}),
+	}
+	head := api.sys.backend.CurrentHeader()
+	if opts.Reorgs {
+		stream.head = head
+	}
+	var (
+		replay            chan []*types.Log
+		failed            = make(chan error, 1)
+		replayCtx, cancel = context.WithCancel(context.Background())
+	)
+	if opts.FromBlock != nil {
+		end := head.Number.Uint64()
+		if crit.ToBlock != nil && crit.ToBlock.Sign() >= 0 && crit.ToBlock.Uint64() < end {
+			end = crit.ToBlock.Uint64()
+		}
+		if from <= end {
+			stream.replayed = end
+			replay = make(chan []*types.Log)
+			go stream.replay(replayCtx, crit, from, end, replay, failed)
+		}
+	}
+	go func() {
+		defer cancel()
+		defer logsSub.Unsubscribe()
+		if headersSub != nil {
+			defer headersSub.Unsubscribe()
+		}
+		stream.run(replay, failed, matchedLogs, headers)
+	}()
+	return stream.sub, nil
+}
+
+// replay filters the logs of the given block range, sending them in batches.
+// The error is sent on the failed channel if the replay fails.
+func (s *logStream) replay(ctx context.Context, crit FilterCriteria, from, to uint64, results chan<- []*types.Log, failed chan<- error) {
+	for begin := from; begin <= to; begin += logReplayChunk {
+		end := begin + logReplayChunk - 1
+		if end > to {
+			end = to
+		}
+		logs, err := s.api.sys.NewRangeFilter(int64(begin), int64(end), crit.Addresses, crit.Topics).Logs(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Warn("Failed to replay logs", "from", begin, "to", end, "err", err)
+			}
+			failed <- err
+			return
+		}
+		select {
+		case results <- logs:
+		case <-ctx.Done():
+			return
+		}
+	}
+	close(results)
+}
+
+// run delivers the notifications until the subscription ends. If the replay
+// fails, the subscription is ended as the client would miss logs.
+func (s *logStream) run(replay <-chan []*types.Log, failed <-chan error, logs <-chan []*types.Log, headers <-chan *types.Header) {
+	var buffered []interface{} // Live events received during the replay
+	for {
+		if len(buffered) > logReplayBuffer {
+			s.fail(errReplayOverflow)
+			return
+		}
+		select {
+		case batch, ok := <-replay:
+			if !ok {
+				// Replay done, catch up with the live events and switch over
+				for _, ev := range buffered {
+					s.deliver(ev)
+				}
+				buffered, replay = nil, nil
+				continue
+			}
+			for _, log := range batch {
+				s.track(log)
+				s.notifier.Notify(s.sub.ID, log)
+			}
+		case err := <-failed:
+			s.fail(err)
+			return
+		case batch := <-logs:
+			if replay != nil {
+				buffered = append(buffered, batch)
+			} else {
+				s.deliver(batch)
+			}
+		case header := <-headers:
+			if replay != nil {
+				buffered = append(buffered, header)
+			} else {
+				s.deliver(header)
+			}
+		case <-s.sub.Err(): // client send an unsubscribe request
+			return
+		case <-s.notifier.Closed(): // connection dropped
+			return
+		}
+	}
+}
+
+// fail notifies the client about the failed replay and ends the subscription.
+func (s *logStream) fail(err error) {
+	s.notifier.Notify(s.sub.ID, &LogsError{Type: "error", Error: err.Error()})
+	s.notifier.Unsubscribe()
+}
+
+// track records a log held by the client if it's near the replayed head.
+func (s *logStream) track(log *types.Log) {
+	if log.BlockNumber <= s.replayed && log.BlockNumber+logReplayReorgDepth > s.replayed {
+		s.delivered[logKey{log.BlockHash, log.Index}] = struct{}{}
+	}
+}
+
+// deliver sends the notifications of a live event.
+func (s *logStream) deliver(ev interface{}) {
+	switch ev := ev.(type) {
+	case []*types.Log:
+		for _, log := range ev {
+			// Logs near the replayed head may have been replayed already. Only
+			// deliver those which change what the client holds.
+			if log.BlockNumber <= s.replayed && log.BlockNumber+logReplayReorgDepth > s.replayed {
+				key := logKey{log.BlockHash, log.Index}
+				if _, held := s.delivered[key]; held == !log.Removed {
+					continue
+				}
+				if log.Removed {
+					delete(s.delivered, key)
+				} else {
+					s.delivered[key] = struct{}{}
+				}
+			}
+			s.notifier.Notify(s.sub.ID, log)
+		}
+	case *types.Header:
+		s.newHead(ev)
+	}
+}
+
+// newHead sends a reorg notification if the new head doesn't extend the last one.
+func (s *logStream) newHead(header *types.Header) {
+	old := s.head
+	if old.Hash() == header.Hash() {
+		return
+	}
+	s.head = header
+	if header.ParentHash == old.Hash() {
+		return
+	}
+	ancestor := s.commonAncestor(old, header)
+	if ancestor != nil && ancestor.Hash() == header.Hash() {
+		return // stale announcement of an ancestor, not a reorg
+	}
+	s.notifier.Notify(s.sub.ID, &LogsReorg{
+		Type:     "reorg",
+		OldHead:  newBlockRef(old),
+		NewHead:  newBlockRef(header),
+		Ancestor: newBlockRef(ancestor),
+	})
+}
+
+// commonAncestor returns the last common block of the chains of the given heads,
+// or nil if it's deeper than the tracked reorg depth.
+func (s *logStream) commonAncestor(a, b *types.Header) *types.Header {
+	backend := s.api.sys.backend
+	for i := 0; a.Hash() != b.Hash(); i++ {
+		if i >= 2*logReplayReorgDepth {
+			return nil
+		}
+		var err error
+		if a.Number.Uint64() >= b.Number.Uint64() {
+			a, err = backend.HeaderByHash(context.Background(), a.ParentHash)
+		} else {
+			b, err = backend.HeaderByHash(context.Background(), b.ParentHash)
+		}
+		if a == nil || b == nil || err != nil {
+			return nil
+		}
+	}
+	return a
+}
@@ -564,8 +564,13 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 	// Broadcast transactions to a batch of peers not knowing about it
 	for _, tx := range txs {
 		peers := h.peers.peersWithoutTransaction(tx.Hash())
-		// Send the tx unconditionally to a subset of our peers
-		numDirect := int(math.Sqrt(float64(len(peers))))
+		// Send the tx unconditionally to a subset of our peers, unless it's a
+		// blob transaction. Those are too large to push around blindly, so are
+		// only ever announced and retrieved on demand along with their sidecar.
+		var numDirect int
+		if tx.Type() != types.BlobTxType {
+			numDirect = int(math.Sqrt(float64(len(peers))))
+		}
 		for _, peer := range peers[:numDirect] {
 			txset[peer] = append(txset[peer], tx.Hash())
 		}
@@ -24,6 +24,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
@@ -36,6 +37,16 @@ import (
 // for releasing state.
 var noopReleaser = tracers.StateReleaseFunc(func() {})
 
+// pathState returns the state of the given block if it's available in the live
+// path-based database.
+func (eth *Ethereum) pathState(block *types.Block) (*state.StateDB, tracers.StateReleaseFunc, error) {
+	statedb, err := eth.blockchain.HistoricalState(block.Header())
+	if err == nil {
+		return statedb, noopReleaser, nil
+	}
+	return nil, nil, fmt.Errorf("historical state %x is not available in path scheme", block.Root())
+}
+
 // StateAtBlock retrieves the state database associated with a certain block.
 // If no state is locally available for the given block, a number of blocks
 // are attempted to be reexecuted to generate the desired state. The optional
@@ -58,6 +69,11 @@ var noopReleaser = tracers.StateReleaseFunc(func() {})
 //     provided, it would be preferable to start from a fresh state, if we have it
 //     on disk.
 func (eth *Ethereum) StateAtBlock(ctx context.Context, block *types.Block, reexec uint64, base *state.StateDB, readOnly bool, preferDisk bool) (statedb *state.StateDB, release tracers.StateReleaseFunc, err error) {
+	// The path-based database only retains the recent states, which can't be
+	// regenerated over an ephemeral database by reexecution.
+	if eth.blockchain.TrieDB().Scheme() == rawdb.PathScheme {
+		return eth.pathState(block)
+	}
 	var (
 		current  *types.Block
 		database state.Database
@@ -76,6 +92,11 @@ func (eth *Ethereum) StateAtBlock(ctx context.Context, block *types.Block, reexe
 				statedb.Database().TrieDB().Dereference(block.Root())
 			}, nil
 		}
+		// The state is unavailable, try to reconstruct it from the reverse
+		// state diffs if they are retained.
+		if statedb, err = eth.blockchain.HistoricalState(block.Header()); err == nil {
+			return statedb, noopReleaser, nil
+		}
 	}
 	// The state is both for reading and writing, or it's unavailable in disk,
 	// try to construct/recover the state over an ephemeral trie.Database for
@@ -40,6 +40,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/tracers/logger"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/internal/tracing"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -888,7 +889,10 @@ func (api *API) TraceTransaction(ctx context.Context, hash common.Hash, config *
 	if err != nil {
 		return nil, err
 	}
+	_, span := tracing.StartSpan(ctx, "tracers.stateAtTransaction", "block.number", blockNumber, "tx.index", index)
 	msg, vmctx, statedb, release, err := api.backend.StateAtTransaction(ctx, block, int(index), reexec)
+	span.SetError(err)
+	span.End()
 	if err != nil {
 		return nil, err
 	}
@@ -940,7 +944,10 @@ func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, bloc
 	if config != nil && config.Reexec != nil {
 		reexec = *config.Reexec
 	}
+	_, span := tracing.StartSpan(ctx, "tracers.stateAtBlock", "block.number", block.NumberU64())
 	statedb, release, err := api.backend.StateAtBlock(ctx, block, reexec, nil, true, false)
+	span.SetError(err)
+	span.End()
 	if err != nil {
 		return nil, err
 	}
@@ -1007,11 +1014,18 @@ func (api *API) traceTx(ctx context.Context, message *core.Message, txctx *Conte
 	}()
 	defer cancel()
 
+	_, span := tracing.StartSpan(ctx, "evm.trace", "tx.hash", txctx.TxHash.Hex())
+	defer span.End()
+
 	// Call Prepare to clear out the statedb access list
 	statedb.SetTxContext(txctx.TxHash, txctx.TxIndex)
-	if _, err = core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.GasLimit)); err != nil {
+	result, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.GasLimit))
+	span.SetAttributes("db.account_read_time", statedb.AccountReads, "db.storage_read_time", statedb.StorageReads)
+	if err != nil {
+		span.SetError(err)
 		return nil, fmt.Errorf("tracing failed: %w", err)
 	}
+	span.SetAttributes("gas.used", result.UsedGas, "failed", result.Failed())
 	return tracer.GetResult()
 }
 
@@ -28,6 +28,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -115,12 +116,33 @@ type rpcBlock struct {
 	Withdrawals  []*types.Withdrawal `json:"withdrawals,omitempty"`
 }
 
+// rpcBlockResult is the result of the block retrieval methods, holding either the
+// JSON response or the block itself if the server sent its RLP encoding.
+type rpcBlockResult struct {
+	raw   json.RawMessage
+	block *types.Block
+}
+
+func (r *rpcBlockResult) UnmarshalJSON(input []byte) error {
+	r.raw = append(r.raw[:0], input...)
+	return nil
+}
+
+func (r *rpcBlockResult) UnmarshalRPCBinary(input []byte) error {
+	r.block = new(types.Block)
+	return rlp.DecodeBytes(input, r.block)
+}
+
 func (ec *Client) getBlock(ctx context.Context, method string, args ...interface{}) (*types.Block, error) {
-	var raw json.RawMessage
-	err := ec.c.CallContext(ctx, &raw, method, args...)
+	var res rpcBlockResult
+	err := ec.c.CallContext(ctx, &res, method, args...)
 	if err != nil {
 		return nil, err
 	}
+	if res.block != nil {
+		return res.block, nil
+	}
+	raw := res.raw
 
 	// Decode header and transactions.
 	var head *types.Header
@@ -295,16 +317,53 @@ func (ec *Client) TransactionInBlock(ctx context.Context, blockHash common.Hash,
 // TransactionReceipt returns the receipt of a transaction by transaction hash.
 // Note that the receipt is not available for pending transactions.
 func (ec *Client) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
-	var r *types.Receipt
+	var r rpcReceipts
 	err := ec.c.CallContext(ctx, &r, "eth_getTransactionReceipt", txHash)
 	if err == nil {
-		if r == nil {
+		if len(r) == 0 || r[0] == nil {
 			return nil, ethereum.NotFound
 		}
+		return r[0], nil
+	}
+	return nil, err
+}
+
+// BlockReceipts returns the receipts of all transactions in the given block.
+func (ec *Client) BlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*types.Receipt, error) {
+	var r rpcReceipts
+	err := ec.c.CallContext(ctx, &r, "eth_getBlockReceipts", blockNrOrHash)
+	if err == nil && r == nil {
+		return nil, ethereum.NotFound
 	}
 	return r, err
 }
 
+// rpcReceipts is the result of the receipt retrieval methods, which binary
+// transports may send in RLP encoding. Single receipts are decoded as a list
+// holding just the receipt.
+type rpcReceipts []*types.Receipt
+
+func (r *rpcReceipts) UnmarshalJSON(input []byte) error {
+	if len(input) > 0 && input[0] == '{' {
+		var receipt *types.Receipt
+		if err := json.Unmarshal(input, &receipt); err != nil {
+			return err
+		}
+		*r = rpcReceipts{receipt}
+		return nil
+	}
+	return json.Unmarshal(input, (*[]*types.Receipt)(r))
+}
+
+func (r *rpcReceipts) UnmarshalRPCBinary(input []byte) error {
+	receipts, err := types.DecodeFullReceipts(input)
+	if err != nil {
+		return err
+	}
+	*r = receipts
+	return nil
+}
+
 // SyncProgress retrieves the current progress of the sync algorithm. If there's
 // no sync currently running, it returns nil.
 func (ec *Client) SyncProgress(ctx context.Context) (*ethereum.SyncProgress, error) {
@@ -388,7 +447,7 @@ func (ec *Client) NonceAt(ctx context.Context, account common.Address, blockNumb
 
 // FilterLogs executes a filter query.
 func (ec *Client) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
-	var result []types.Log
+	var result rpcLogs
 	arg, err := toFilterArg(q)
 	if err != nil {
 		return nil, err
@@ -397,6 +456,26 @@ func (ec *Client) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]typ
 	return result, err
 }
 
+// rpcLogs is the result of the log retrieval methods, which binary transports may
+// send in RLP encoding.
+type rpcLogs []types.Log
+
+func (l *rpcLogs) UnmarshalJSON(input []byte) error {
+	return json.Unmarshal(input, (*[]types.Log)(l))
+}
+
+func (l *rpcLogs) UnmarshalRPCBinary(input []byte) error {
+	logs, err := types.DecodeFullLogs(input)
+	if err != nil {
+		return err
+	}
+	*l = make(rpcLogs, len(logs))
+	for i, log := range logs {
+		(*l)[i] = *log
+	}
+	return nil
+}
+
 // SubscribeFilterLogs subscribes to the results of a streaming filter query.
 func (ec *Client) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
 	arg, err := toFilterArg(q)
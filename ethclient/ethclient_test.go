@@ -19,6 +19,7 @@ package ethclient
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
@@ -369,6 +370,9 @@ func TestEthClient(t *testing.T) {
 		"StatusFunctions": {
 			func(t *testing.T) { testStatusFunctions(t, client) },
 		},
+		"BlockReceipts": {
+			func(t *testing.T) { testBlockReceipts(t, chain, client) },
+		},
 		"CallContract": {
 			func(t *testing.T) { testCallContract(t, client) },
 		},
@@ -392,6 +396,90 @@ func TestEthClient(t *testing.T) {
 	}
 }
 
+func TestEthClientBinary(t *testing.T) {
+	backend, chain := newTestBackend(t, false)
+	defer backend.Close()
+
+	srv, err := backend.RPCHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go srv.ServeBinaryListener(listener)
+
+	client, err := rpc.Dial("bin://" + listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ec := NewClient(client)
+
+	// Blocks are transferred in RLP encoding
+	block, err := ec.BlockByNumber(context.Background(), big.NewInt(2))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if block.Hash() != chain[2].Hash() || len(block.Transactions()) != 2 {
+		t.Fatalf("block mismatch: have %x with %d txs, want %x", block.Hash(), len(block.Transactions()), chain[2].Hash())
+	}
+	if tx := block.Transactions()[0]; tx.Hash() != testTx1.Hash() {
+		t.Fatalf("transaction mismatch: have %x, want %x", tx.Hash(), testTx1.Hash())
+	}
+	if _, err := ec.BlockByNumber(context.Background(), big.NewInt(1000)); err != ethereum.NotFound {
+		t.Fatalf("wrong error for missing block: %v", err)
+	}
+	// Receipts are transferred in RLP encoding, matching the JSON ones
+	jsonClient, _ := backend.Attach()
+	defer jsonClient.Close()
+	jec := NewClient(jsonClient)
+
+	number := rpc.BlockNumberOrHashWithNumber(2)
+	receipts, err := ec.BlockReceipts(context.Background(), number)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want, err := jec.BlockReceipts(context.Background(), number)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(receipts) != 2 {
+		t.Fatalf("receipt count mismatch: have %d, want %d", len(receipts), 2)
+	}
+	if have, want := mustJSON(t, receipts), mustJSON(t, want); have != want {
+		t.Fatalf("block receipts mismatch:\nhave %s\nwant %s", have, want)
+	}
+	receipt, err := ec.TransactionReceipt(context.Background(), testTx2.Hash())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if have, want := mustJSON(t, receipt), mustJSON(t, receipts[1]); have != want {
+		t.Fatalf("receipt mismatch:\nhave %s\nwant %s", have, want)
+	}
+	if _, err := ec.TransactionReceipt(context.Background(), common.Hash{1}); err != ethereum.NotFound {
+		t.Fatalf("wrong error for missing receipt: %v", err)
+	}
+	if _, err := ec.BlockReceipts(context.Background(), rpc.BlockNumberOrHashWithNumber(1000)); err != ethereum.NotFound {
+		t.Fatalf("wrong error for missing block receipts: %v", err)
+	}
+	// Other results are still JSON encoded
+	testHeader(t, chain, client)
+	testChainID(t, client)
+}
+
+func mustJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+
+	enc, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(enc)
+}
+
 func testHeader(t *testing.T, chain []*types.Block, client *rpc.Client) {
 	tests := map[string]struct {
 		block   *big.Int
@@ -560,6 +648,41 @@ func testGetBlock(t *testing.T, client *rpc.Client) {
 	}
 }
 
+func testBlockReceipts(t *testing.T, chain []*types.Block, client *rpc.Client) {
+	ec := NewClient(client)
+
+	// Receipts by number and by hash
+	for _, block := range []rpc.BlockNumberOrHash{
+		rpc.BlockNumberOrHashWithNumber(2),
+		rpc.BlockNumberOrHashWithHash(chain[2].Hash(), false),
+	} {
+		receipts, err := ec.BlockReceipts(context.Background(), block)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(receipts) != 2 {
+			t.Fatalf("wrong number of receipts: %d", len(receipts))
+		}
+		for i, tx := range []*types.Transaction{testTx1, testTx2} {
+			receipt, err := ec.TransactionReceipt(context.Background(), tx.Hash())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(receipts[i], receipt) {
+				t.Errorf("receipt %d mismatch: have %+v, want %+v", i, receipts[i], receipt)
+			}
+		}
+	}
+	// Blocks without transactions have no receipts
+	receipts, err := ec.BlockReceipts(context.Background(), rpc.BlockNumberOrHashWithNumber(0))
+	if err != nil || len(receipts) != 0 {
+		t.Fatalf("wrong receipts of empty block: %v, %v", receipts, err)
+	}
+	if _, err := ec.BlockReceipts(context.Background(), rpc.BlockNumberOrHashWithNumber(1000)); err != ethereum.NotFound {
+		t.Fatalf("wrong error for missing block: %v", err)
+	}
+}
+
 func testStatusFunctions(t *testing.T, client *rpc.Client) {
 	ec := NewClient(client)
 
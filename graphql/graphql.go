@@ -90,11 +90,42 @@ func (a *Account) getState(ctx context.Context) (*state.StateDB, error) {
 	return state, err
 }
 
+// historical forwards a state query to the historical RPC service if the block is
+// from before the Bedrock upgrade, whose state isn't available locally. It
+// reports whether the query was forwarded.
+func (a *Account) historical(ctx context.Context, result interface{}, method string, args ...interface{}) (bool, error) {
+	return historicalState(ctx, a.r.backend, a.blockNrOrHash, result, method, append(args, a.blockNrOrHash)...)
+}
+
+// historicalState forwards a state query to the historical RPC service if the
+// block is from before the Bedrock upgrade. It reports whether the query was
+// forwarded.
+func historicalState(ctx context.Context, backend ethapi.Backend, blockNrOrHash rpc.BlockNumberOrHash, result interface{}, method string, args ...interface{}) (bool, error) {
+	if !backend.ChainConfig().IsOptimism() {
+		return false, nil
+	}
+	header, err := backend.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil || header == nil || !backend.ChainConfig().IsOptimismPreBedrock(header.Number) {
+		return false, nil
+	}
+	if backend.HistoricalRPCService() == nil {
+		return true, rpc.ErrNoHistoricalFallback
+	}
+	if err := backend.HistoricalRPCService().CallContext(ctx, result, method, args...); err != nil {
+		return true, fmt.Errorf("historical backend error: %w", err)
+	}
+	return true, nil
+}
+
 func (a *Account) Address(ctx context.Context) (common.Address, error) {
 	return a.address, nil
 }
 
 func (a *Account) Balance(ctx context.Context) (hexutil.Big, error) {
+	var res hexutil.Big
+	if ok, err := a.historical(ctx, &res, "eth_getBalance", a.address); ok {
+		return res, err
+	}
 	state, err := a.getState(ctx)
 	if err != nil {
 		return hexutil.Big{}, err
@@ -115,6 +146,10 @@ func (a *Account) TransactionCount(ctx context.Context) (hexutil.Uint64, error)
 		}
 		return hexutil.Uint64(nonce), nil
 	}
+	var res hexutil.Uint64
+	if ok, err := a.historical(ctx, &res, "eth_getTransactionCount", a.address); ok {
+		return res, err
+	}
 	state, err := a.getState(ctx)
 	if err != nil {
 		return 0, err
@@ -123,6 +158,10 @@ func (a *Account) TransactionCount(ctx context.Context) (hexutil.Uint64, error)
 }
 
 func (a *Account) Code(ctx context.Context) (hexutil.Bytes, error) {
+	var res hexutil.Bytes
+	if ok, err := a.historical(ctx, &res, "eth_getCode", a.address); ok {
+		return res, err
+	}
 	state, err := a.getState(ctx)
 	if err != nil {
 		return hexutil.Bytes{}, err
@@ -131,6 +170,10 @@ func (a *Account) Code(ctx context.Context) (hexutil.Bytes, error) {
 }
 
 func (a *Account) Storage(ctx context.Context, args struct{ Slot common.Hash }) (common.Hash, error) {
+	var res hexutil.Bytes
+	if ok, err := a.historical(ctx, &res, "eth_getStorageAt", a.address, args.Slot.Hex()); ok {
+		return common.BytesToHash(res), err
+	}
 	state, err := a.getState(ctx)
 	if err != nil {
 		return common.Hash{}, err
@@ -169,6 +212,10 @@ func (l *Log) Data(ctx context.Context) hexutil.Bytes {
 	return l.log.Data
 }
 
+func (l *Log) Removed(ctx context.Context) bool {
+	return l.log.Removed
+}
+
 // AccessTuple represents EIP-2930
 type AccessTuple struct {
 	address     common.Address
@@ -513,6 +560,87 @@ func (t *Transaction) Type(ctx context.Context) (*hexutil.Uint64, error) {
 	return &txType, nil
 }
 
+func (t *Transaction) SourceHash(ctx context.Context) (*common.Hash, error) {
+	tx, _, err := t.resolve(ctx)
+	if err != nil || tx == nil || !tx.IsDepositTx() {
+		return nil, err
+	}
+	hash := tx.SourceHash()
+	return &hash, nil
+}
+
+func (t *Transaction) Mint(ctx context.Context) (*hexutil.Big, error) {
+	tx, _, err := t.resolve(ctx)
+	if err != nil || tx == nil || !tx.IsDepositTx() {
+		return nil, err
+	}
+	return (*hexutil.Big)(tx.Mint()), nil
+}
+
+func (t *Transaction) IsSystemTx(ctx context.Context) (*bool, error) {
+	tx, _, err := t.resolve(ctx)
+	if err != nil || tx == nil || !tx.IsDepositTx() {
+		return nil, err
+	}
+	isSystemTx := tx.IsSystemTx()
+	return &isSystemTx, nil
+}
+
+func (t *Transaction) DepositNonce(ctx context.Context) (*hexutil.Uint64, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil || receipt.DepositNonce == nil {
+		return nil, err
+	}
+	nonce := hexutil.Uint64(*receipt.DepositNonce)
+	return &nonce, nil
+}
+
+// getL1Receipt returns the receipt of the transaction if it carries the L1 data
+// fee fields, i.e. it's a mined non-deposit transaction on an OP-stack chain.
+func (t *Transaction) getL1Receipt(ctx context.Context) (*types.Receipt, error) {
+	if t.r.backend.ChainConfig().Optimism == nil {
+		return nil, nil
+	}
+	tx, _, err := t.resolve(ctx)
+	if err != nil || tx == nil || tx.IsDepositTx() {
+		return nil, err
+	}
+	return t.getReceipt(ctx)
+}
+
+func (t *Transaction) L1GasPrice(ctx context.Context) (*hexutil.Big, error) {
+	receipt, err := t.getL1Receipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(receipt.L1GasPrice), nil
+}
+
+func (t *Transaction) L1GasUsed(ctx context.Context) (*hexutil.Big, error) {
+	receipt, err := t.getL1Receipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(receipt.L1GasUsed), nil
+}
+
+func (t *Transaction) L1Fee(ctx context.Context) (*hexutil.Big, error) {
+	receipt, err := t.getL1Receipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(receipt.L1Fee), nil
+}
+
+func (t *Transaction) L1FeeScalar(ctx context.Context) (*string, error) {
+	receipt, err := t.getL1Receipt(ctx)
+	if err != nil || receipt == nil || receipt.FeeScalar == nil {
+		return nil, err
+	}
+	scalar := receipt.FeeScalar.String()
+	return &scalar, nil
+}
+
 func (t *Transaction) AccessList(ctx context.Context) (*[]*AccessTuple, error) {
 	tx, _, err := t.resolve(ctx)
 	if err != nil || tx == nil {
@@ -572,6 +700,95 @@ func (t *Transaction) RawReceipt(ctx context.Context) (hexutil.Bytes, error) {
 	return receipt.MarshalBinary()
 }
 
+// Receipt represents the receipt of a mined transaction.
+type Receipt struct {
+	transaction *Transaction
+	receipt     *types.Receipt
+}
+
+func (r *Receipt) Transaction(ctx context.Context) *Transaction {
+	return r.transaction
+}
+
+func (r *Receipt) Status(ctx context.Context) *hexutil.Uint64 {
+	if len(r.receipt.PostState) != 0 {
+		return nil
+	}
+	ret := hexutil.Uint64(r.receipt.Status)
+	return &ret
+}
+
+func (r *Receipt) Root(ctx context.Context) *common.Hash {
+	if len(r.receipt.PostState) == 0 {
+		return nil
+	}
+	root := common.BytesToHash(r.receipt.PostState)
+	return &root
+}
+
+func (r *Receipt) GasUsed(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(r.receipt.GasUsed)
+}
+
+func (r *Receipt) CumulativeGasUsed(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(r.receipt.CumulativeGasUsed)
+}
+
+func (r *Receipt) EffectiveGasPrice(ctx context.Context) (*hexutil.Big, error) {
+	return r.transaction.EffectiveGasPrice(ctx)
+}
+
+func (r *Receipt) CreatedContract(ctx context.Context, args BlockNumberArgs) *Account {
+	if r.receipt.ContractAddress == (common.Address{}) {
+		return nil
+	}
+	return &Account{
+		r:             r.transaction.r,
+		address:       r.receipt.ContractAddress,
+		blockNrOrHash: args.NumberOrLatest(),
+	}
+}
+
+func (r *Receipt) Logs(ctx context.Context) []*Log {
+	ret := make([]*Log, 0, len(r.receipt.Logs))
+	for _, log := range r.receipt.Logs {
+		ret = append(ret, &Log{
+			r:           r.transaction.r,
+			transaction: r.transaction,
+			log:         log,
+		})
+	}
+	return ret
+}
+
+func (r *Receipt) LogsBloom(ctx context.Context) hexutil.Bytes {
+	return r.receipt.Bloom.Bytes()
+}
+
+func (r *Receipt) DepositNonce(ctx context.Context) (*hexutil.Uint64, error) {
+	return r.transaction.DepositNonce(ctx)
+}
+
+func (r *Receipt) L1GasPrice(ctx context.Context) (*hexutil.Big, error) {
+	return r.transaction.L1GasPrice(ctx)
+}
+
+func (r *Receipt) L1GasUsed(ctx context.Context) (*hexutil.Big, error) {
+	return r.transaction.L1GasUsed(ctx)
+}
+
+func (r *Receipt) L1Fee(ctx context.Context) (*hexutil.Big, error) {
+	return r.transaction.L1Fee(ctx)
+}
+
+func (r *Receipt) L1FeeScalar(ctx context.Context) (*string, error) {
+	return r.transaction.L1FeeScalar(ctx)
+}
+
+func (r *Receipt) Raw(ctx context.Context) (hexutil.Bytes, error) {
+	return r.receipt.MarshalBinary()
+}
+
 type BlockType int
 
 // Block represents an Ethereum block.
@@ -910,13 +1127,34 @@ func (b *Block) TransactionCount(ctx context.Context) (*hexutil.Uint64, error) {
 	return &count, err
 }
 
-func (b *Block) Transactions(ctx context.Context) (*[]*Transaction, error) {
+// TransactionsArgs are the arguments of the transaction list accessors.
+type TransactionsArgs struct {
+	Types *[]Long // restricts the list to transactions of these types
+}
+
+// includes reports whether the transaction passes the type filter.
+func (a TransactionsArgs) includes(tx *types.Transaction) bool {
+	if a.Types == nil {
+		return true
+	}
+	for _, typ := range *a.Types {
+		if Long(tx.Type()) == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Block) Transactions(ctx context.Context, args TransactionsArgs) (*[]*Transaction, error) {
 	block, err := b.resolve(ctx)
 	if err != nil || block == nil {
 		return nil, err
 	}
 	ret := make([]*Transaction, 0, len(block.Transactions()))
 	for i, tx := range block.Transactions() {
+		if !args.includes(tx) {
+			continue
+		}
 		ret = append(ret, &Transaction{
 			r:     b.r,
 			hash:  tx.Hash(),
@@ -928,6 +1166,35 @@ func (b *Block) Transactions(ctx context.Context) (*[]*Transaction, error) {
 	return &ret, nil
 }
 
+func (b *Block) Receipts(ctx context.Context) (*[]*Receipt, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	receipts, err := b.resolveReceipts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if len(receipts) != len(txs) {
+		return nil, fmt.Errorf("receipts length mismatch: %d vs %d", len(txs), len(receipts))
+	}
+	ret := make([]*Receipt, 0, len(receipts))
+	for i, receipt := range receipts {
+		ret = append(ret, &Receipt{
+			transaction: &Transaction{
+				r:     b.r,
+				hash:  txs[i].Hash(),
+				tx:    txs[i],
+				block: b,
+				index: uint64(i),
+			},
+			receipt: receipt,
+		})
+	}
+	return &ret, nil
+}
+
 func (b *Block) TransactionAt(ctx context.Context, args struct{ Index Long }) (*Transaction, error) {
 	block, err := b.resolve(ctx)
 	if err != nil || block == nil {
@@ -1048,26 +1315,35 @@ type CallData struct {
 
 // CallResult encapsulates the result of an invocation of the `call` accessor.
 type CallResult struct {
-	data    hexutil.Bytes  // The return data from the call
-	gasUsed hexutil.Uint64 // The amount of gas used
-	status  hexutil.Uint64 // The return status of the call - 0 for failure or 1 for success.
+	data    hexutil.Bytes   // The return data from the call
+	gasUsed *hexutil.Uint64 // The amount of gas used, nil if unknown
+	status  *hexutil.Uint64 // The return status of the call - 0 for failure or 1 for success, nil if unknown
 }
 
 func (c *CallResult) Data() hexutil.Bytes {
 	return c.data
 }
 
-func (c *CallResult) GasUsed() hexutil.Uint64 {
+func (c *CallResult) GasUsed() *hexutil.Uint64 {
 	return c.gasUsed
 }
 
-func (c *CallResult) Status() hexutil.Uint64 {
+func (c *CallResult) Status() *hexutil.Uint64 {
 	return c.status
 }
 
 func (b *Block) Call(ctx context.Context, args struct {
 	Data ethapi.TransactionArgs
 }) (*CallResult, error) {
+	// The historical RPC service only provides the return data of the call,
+	// the gas used and status are unknown
+	var res hexutil.Bytes
+	if ok, err := historicalState(ctx, b.r.backend, *b.numberOrHash, &res, "eth_call", args.Data, *b.numberOrHash); ok {
+		if err != nil {
+			return nil, err
+		}
+		return &CallResult{data: res}, nil
+	}
 	result, err := ethapi.DoCall(ctx, b.r.backend, args.Data, *b.numberOrHash, nil, nil, b.r.backend.RPCEVMTimeout(), b.r.backend.RPCGasCap())
 	if err != nil {
 		return nil, err
@@ -1076,17 +1352,22 @@ func (b *Block) Call(ctx context.Context, args struct {
 	if result.Failed() {
 		status = 0
 	}
+	gasUsed := hexutil.Uint64(result.UsedGas)
 
 	return &CallResult{
 		data:    result.ReturnData,
-		gasUsed: hexutil.Uint64(result.UsedGas),
-		status:  status,
+		gasUsed: &gasUsed,
+		status:  &status,
 	}, nil
 }
 
 func (b *Block) EstimateGas(ctx context.Context, args struct {
 	Data ethapi.TransactionArgs
 }) (hexutil.Uint64, error) {
+	var res hexutil.Uint64
+	if ok, err := historicalState(ctx, b.r.backend, *b.numberOrHash, &res, "eth_estimateGas", args.Data, *b.numberOrHash); ok {
+		return res, err
+	}
 	return ethapi.DoEstimateGas(ctx, b.r.backend, args.Data, *b.numberOrHash, b.r.backend.RPCGasCap())
 }
 
@@ -1099,13 +1380,16 @@ func (p *Pending) TransactionCount(ctx context.Context) (hexutil.Uint64, error)
 	return hexutil.Uint64(len(txs)), err
 }
 
-func (p *Pending) Transactions(ctx context.Context) (*[]*Transaction, error) {
+func (p *Pending) Transactions(ctx context.Context, args TransactionsArgs) (*[]*Transaction, error) {
 	txs, err := p.r.backend.GetPoolTransactions()
 	if err != nil {
 		return nil, err
 	}
 	ret := make([]*Transaction, 0, len(txs))
 	for i, tx := range txs {
+		if !args.includes(tx) {
+			continue
+		}
 		ret = append(ret, &Transaction{
 			r:     p.r,
 			hash:  tx.Hash(),
@@ -1139,11 +1423,12 @@ func (p *Pending) Call(ctx context.Context, args struct {
 	if result.Failed() {
 		status = 0
 	}
+	gasUsed := hexutil.Uint64(result.UsedGas)
 
 	return &CallResult{
 		data:    result.ReturnData,
-		gasUsed: hexutil.Uint64(result.UsedGas),
-		status:  status,
+		gasUsed: &gasUsed,
+		status:  &status,
 	}, nil
 }
 
@@ -1158,6 +1443,9 @@ func (p *Pending) EstimateGas(ctx context.Context, args struct {
 type Resolver struct {
 	backend      ethapi.Backend
 	filterSystem *filters.FilterSystem
+
+	events     *filters.EventSystem // backs the subscriptions, created on first use
+	eventsOnce sync.Once
 }
 
 func (r *Resolver) Block(ctx context.Context, args struct {
@@ -23,11 +23,13 @@ import (
 	"io"
 	"math/big"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -36,8 +38,10 @@ import (
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -230,6 +234,16 @@ func TestGraphQLBlockSerializationEIP2718(t *testing.T) {
 			want: `{"data":{"block":{"number":"0x1","transactions":[{"from":{"address":"0x71562b71999873db5b286df957af199ec94617f7"},"to":{"address":"0x0000000000000000000000000000000000000dad"},"value":"0x64","hash":"0xd864c9d7d37fade6b70164740540c06dd58bb9c3f6b46101908d6339db6a6a7b","type":"0x0","accessList":[],"index":"0x0"},{"from":{"address":"0x71562b71999873db5b286df957af199ec94617f7"},"to":{"address":"0x0000000000000000000000000000000000000dad"},"value":"0x32","hash":"0x19b35f8187b4e15fb59a9af469dca5dfa3cd363c11d372058c12f6482477b474","type":"0x1","accessList":[{"address":"0x0000000000000000000000000000000000000dad","storageKeys":["0x0000000000000000000000000000000000000000000000000000000000000000"]}],"index":"0x1"}]}}}`,
 			code: 200,
 		},
+		{
+			body: `{"query": "{block {transactions(types: [1]) { hash type index sourceHash mint isSystemTx depositNonce l1Fee l1FeeScalar }}}"}`,
+			want: `{"data":{"block":{"transactions":[{"hash":"0x19b35f8187b4e15fb59a9af469dca5dfa3cd363c11d372058c12f6482477b474","type":"0x1","index":"0x1","sourceHash":null,"mint":null,"isSystemTx":null,"depositNonce":null,"l1Fee":null,"l1FeeScalar":null}]}}}`,
+			code: 200,
+		},
+		{
+			body: `{"query": "{block {receipts { transaction { hash } status root gasUsed cumulativeGasUsed createdContract { address } logs { index } depositNonce l1Fee }}}"}`,
+			want: `{"data":{"block":{"receipts":[{"transaction":{"hash":"0xd864c9d7d37fade6b70164740540c06dd58bb9c3f6b46101908d6339db6a6a7b"},"status":"0x1","root":null,"gasUsed":"0x6274","cumulativeGasUsed":"0x6274","createdContract":null,"logs":[],"depositNonce":null,"l1Fee":null},{"transaction":{"hash":"0x19b35f8187b4e15fb59a9af469dca5dfa3cd363c11d372058c12f6482477b474"},"status":"0x1","root":null,"gasUsed":"0x6b70","cumulativeGasUsed":"0xcde4","createdContract":null,"logs":[],"depositNonce":null,"l1Fee":null}]}}}`,
+			code: 200,
+		},
 	} {
 		resp, err := http.Post(fmt.Sprintf("%s/graphql", stack.HTTPEndpoint()), "application/json", strings.NewReader(tt.body))
 		if err != nil {
@@ -360,6 +374,182 @@ func TestGraphQLConcurrentResolvers(t *testing.T) {
 	}
 }
 
+// historicalBackend is a mock of the legacy (pre-Bedrock) RPC node that serves
+// the state queries of historical blocks.
+type historicalBackend struct{}
+
+func (h *historicalBackend) GetBalance(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Big, error) {
+	return (*hexutil.Big)(big.NewInt(42)), nil
+}
+
+func (h *historicalBackend) Call(ctx context.Context, args ethapi.TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	return hexutil.Bytes{0x12, 0x34}, nil
+}
+
+func TestGraphQLOptimism(t *testing.T) {
+	var (
+		key, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address   = crypto.PubkeyToAddress(key.PublicKey)
+		depositor = common.HexToAddress("0xdeaddeaddeaddeaddeaddeaddeaddeaddead0001")
+		l1Block   = common.HexToAddress("0x4200000000000000000000000000000000000015")
+		dad       = common.HexToAddress("0x0000000000000000000000000000000000000dad")
+
+		l1BaseFee = big.NewInt(1000)
+		overhead  = big.NewInt(2100)
+		scalar    = big.NewInt(1_000_000)
+	)
+	config := *params.AllEthashProtocolChanges
+	config.BedrockBlock = big.NewInt(1)
+	config.RegolithTime = new(uint64)
+	config.Optimism = &params.OptimismConfig{EIP1559Elasticity: 6, EIP1559Denominator: 50}
+
+	genesis := &core.Genesis{
+		Config:     &config,
+		GasLimit:   11500000,
+		Difficulty: big.NewInt(1048576),
+		Alloc:      core.GenesisAlloc{address: {Balance: big.NewInt(params.Ether)}},
+		BaseFee:    big.NewInt(params.InitialBaseFee),
+	}
+	// Serve the pre-Bedrock state from a mock historical node
+	historical := rpc.NewServer()
+	if err := historical.RegisterName("eth", new(historicalBackend)); err != nil {
+		t.Fatalf("could not register historical backend: %v", err)
+	}
+	defer historical.Stop()
+	server := httptest.NewServer(historical)
+	defer server.Close()
+
+	stack := createNode(t)
+	defer stack.Close()
+	ethBackend, err := eth.New(stack, &ethconfig.Config{
+		Genesis:                    genesis,
+		NetworkId:                  1337,
+		TrieCleanCache:             5,
+		TrieDirtyCache:             5,
+		TrieTimeout:                60 * time.Minute,
+		SnapshotCache:              5,
+		RollupHistoricalRPC:        server.URL,
+		RollupHistoricalRPCTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("could not create eth backend: %v", err)
+	}
+	// The first transaction of a block carries the L1 attributes, with the base
+	// fee, overhead and scalar as the 3rd, 7th and 8th argument
+	l1Info := make([]byte, 4+32*8)
+	l1BaseFee.FillBytes(l1Info[4+32*2 : 4+32*3])
+	overhead.FillBytes(l1Info[4+32*6 : 4+32*7])
+	scalar.FillBytes(l1Info[4+32*7 : 4+32*8])
+
+	signer := types.LatestSigner(genesis.Config)
+	deposit := types.NewTx(&types.DepositTx{
+		SourceHash: common.Hash{1},
+		From:       depositor,
+		To:         &l1Block,
+		Mint:       big.NewInt(1000),
+		Value:      big.NewInt(0),
+		Gas:        1_000_000,
+		Data:       l1Info,
+	})
+	transfer, _ := types.SignNewTx(key, signer, &types.LegacyTx{
+		Nonce:    0,
+		To:       &dad,
+		Value:    big.NewInt(100),
+		Gas:      50000,
+		GasPrice: big.NewInt(params.InitialBaseFee),
+	})
+	chain, _ := core.GenerateChain(genesis.Config, ethBackend.BlockChain().Genesis(), ethash.NewFaker(), ethBackend.ChainDb(), 1, func(i int, gen *core.BlockGen) {
+		gen.AddTx(deposit)
+		gen.AddTx(transfer)
+	})
+	if _, err := ethBackend.BlockChain().InsertChain(chain); err != nil {
+		t.Fatalf("could not import blocks: %v", err)
+	}
+	// Fill the pool with a legacy and a dynamic fee transaction
+	pending := []*types.Transaction{
+		types.MustSignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    1,
+			To:       &dad,
+			Gas:      50000,
+			GasPrice: big.NewInt(params.InitialBaseFee),
+		}),
+		types.MustSignNewTx(key, signer, &types.DynamicFeeTx{
+			ChainID:   genesis.Config.ChainID,
+			Nonce:     2,
+			To:        &dad,
+			Gas:       50000,
+			GasFeeCap: big.NewInt(params.InitialBaseFee),
+			GasTipCap: big.NewInt(1),
+		}),
+	}
+	for _, tx := range pending {
+		if err := ethBackend.TxPool().AddLocal(tx); err != nil {
+			t.Fatalf("could not add pool transaction: %v", err)
+		}
+	}
+	filterSystem := filters.NewFilterSystem(ethBackend.APIBackend, filters.Config{})
+	handler, err := newHandler(stack, ethBackend.APIBackend, filterSystem, []string{}, []string{})
+	if err != nil {
+		t.Fatalf("could not create graphql service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	dataGas := transfer.RollupDataGas().DataGas(chain[0].Time(), genesis.Config)
+
+	for i, tt := range []struct {
+		body string
+		want string
+	}{
+		// Deposit fields are only set on deposit transactions
+		{
+			body: "{ block { transactions { hash type sourceHash mint isSystemTx depositNonce } } }",
+			want: fmt.Sprintf(`{"block":{"transactions":[{"hash":"%s","type":"0x7e","sourceHash":"%s","mint":"0x3e8","isSystemTx":false,"depositNonce":"0x0"},{"hash":"%s","type":"0x0","sourceHash":null,"mint":null,"isSystemTx":null,"depositNonce":null}]}}`, deposit.Hash(), common.Hash{1}, transfer.Hash()),
+		},
+		// L1 fee fields are only set on non-deposit transactions
+		{
+			body: "{ block { transactions { l1GasPrice l1GasUsed l1Fee l1FeeScalar } } }",
+			want: fmt.Sprintf(`{"block":{"transactions":[{"l1GasPrice":null,"l1GasUsed":null,"l1Fee":null,"l1FeeScalar":null},{"l1GasPrice":"%s","l1GasUsed":"%s","l1Fee":"%s","l1FeeScalar":"1"}]}}`,
+				(*hexutil.Big)(l1BaseFee), (*hexutil.Big)(new(big.Int).Add(new(big.Int).SetUint64(dataGas), overhead)), (*hexutil.Big)(types.L1Cost(dataGas, l1BaseFee, overhead, scalar))),
+		},
+		// Filter the block and pool transactions by type
+		{
+			body: "{ block { transactions(types: [126]) { hash } } }",
+			want: fmt.Sprintf(`{"block":{"transactions":[{"hash":"%s"}]}}`, deposit.Hash()),
+		},
+		{
+			body: "{ pending { transactions { hash } } }",
+			want: fmt.Sprintf(`{"pending":{"transactions":[{"hash":"%s"},{"hash":"%s"}]}}`, pending[0].Hash(), pending[1].Hash()),
+		},
+		{
+			body: "{ pending { transactions(types: [2]) { hash type } } }",
+			want: fmt.Sprintf(`{"pending":{"transactions":[{"hash":"%s","type":"0x2"}]}}`, pending[1].Hash()),
+		},
+		// Pre-Bedrock state is served by the historical backend, which reports
+		// neither the gas used nor the status of calls
+		{
+			body: fmt.Sprintf(`{ block(number: 0) { account(address: "%s") { balance } call(data: {to: "%s"}) { data gasUsed status } } }`, address, dad),
+			want: `{"block":{"account":{"balance":"0x2a"},"call":{"data":"0x1234","gasUsed":null,"status":null}}}`,
+		},
+		{
+			body: fmt.Sprintf(`{ block(number: 1) { account(address: "%s") { balance } call(data: {to: "%s"}) { data gasUsed status } } }`, dad, dad),
+			want: `{"block":{"account":{"balance":"0x64"},"call":{"data":"0x","gasUsed":"0x5208","status":"0x1"}}}`,
+		},
+	} {
+		res := handler.Schema.Exec(context.Background(), tt.body, "", map[string]interface{}{})
+		if res.Errors != nil {
+			t.Fatalf("failed to execute query for testcase #%d: %v", i, res.Errors)
+		}
+		have, err := json.Marshal(res.Data)
+		if err != nil {
+			t.Fatalf("failed to encode graphql response for testcase #%d: %s", i, err)
+		}
+		if string(have) != tt.want {
+			t.Errorf("response unmatch for testcase #%d.\nExpected:\n%s\nGot:\n%s\n", i, tt.want, have)
+		}
+	}
+}
+
 func createNode(t *testing.T) *node.Node {
 	stack, err := node.New(&node.Config{
 		HTTPHost:     "127.0.0.1",
@@ -375,6 +565,20 @@ func createNode(t *testing.T) *node.Node {
 }
 
 func newGQLService(t *testing.T, stack *node.Node, gspec *core.Genesis, genBlocks int, genfunc func(i int, gen *core.BlockGen)) (*handler, []*types.Block) {
+	ethBackend, chain := newGQLBackend(t, stack, gspec, genBlocks, genfunc)
+
+	// Set up handler
+	filterSystem := filters.NewFilterSystem(ethBackend.APIBackend, filters.Config{})
+	handler, err := newHandler(stack, ethBackend.APIBackend, filterSystem, []string{}, []string{})
+	if err != nil {
+		t.Fatalf("could not create graphql service: %v", err)
+	}
+	return handler, chain
+}
+
+// newGQLBackend creates the eth backend of a GraphQL service, importing the
+// generated blocks.
+func newGQLBackend(t *testing.T, stack *node.Node, gspec *core.Genesis, genBlocks int, genfunc func(i int, gen *core.BlockGen)) (*eth.Ethereum, []*types.Block) {
 	ethConf := &ethconfig.Config{
 		Genesis:                 gspec,
 		NetworkId:               1337,
@@ -396,11 +600,5 @@ func newGQLService(t *testing.T, stack *node.Node, gspec *core.Genesis, genBlock
 	if err != nil {
 		t.Fatalf("could not create import blocks: %v", err)
 	}
-	// Set up handler
-	filterSystem := filters.NewFilterSystem(ethBackend.APIBackend, filters.Config{})
-	handler, err := newHandler(stack, ethBackend.APIBackend, filterSystem, []string{}, []string{})
-	if err != nil {
-		t.Fatalf("could not create graphql service: %v", err)
-	}
-	return handler, chain
+	return ethBackend, chain
 }
@@ -36,6 +36,7 @@ const schema string = `
     schema {
         query: Query
         mutation: Mutation
+        subscription: Subscription
     }
 
     # Account is an Ethereum account at a particular block.
@@ -69,6 +70,9 @@ const schema string = `
         data: Bytes!
         # Transaction is the transaction that generated this log entry.
         transaction: Transaction!
+        # Removed is true if the log was reverted due to a chain reorganisation.
+        # It is only set in notifications of the logs subscription.
+        removed: Boolean!
     }
 
     #EIP-2718
@@ -149,6 +153,74 @@ const schema string = `
         # RawReceipt is the canonical encoding of the receipt. For post EIP-2718 typed transactions
         # this is equivalent to TxType || ReceiptEncoding.
         rawReceipt: Bytes!
+
+        # Rollup specific fields, null on chains other than OP-stack ones.
+        # SourceHash uniquely identifies the origin of a deposit transaction.
+        # Null for other transaction types.
+        sourceHash: Bytes32
+        # Mint is the value, in wei, minted on L2 by a deposit transaction.
+        # Null for other transaction types.
+        mint: BigInt
+        # IsSystemTx is true for the system deposit transactions before Regolith.
+        # Null for other transaction types.
+        isSystemTx: Boolean
+        # DepositNonce is the nonce the sender of a deposit transaction used. It
+        # is null for other transaction types, deposits before Regolith, or if the
+        # transaction has not yet been mined.
+        depositNonce: Long
+        # L1GasPrice is the L1 base fee the L1 data fee was computed with. Null
+        # for deposit transactions or if the transaction has not yet been mined.
+        l1GasPrice: BigInt
+        # L1GasUsed is the amount of L1 gas charged for posting the transaction
+        # data. Null for deposit transactions or if the transaction has not yet
+        # been mined.
+        l1GasUsed: BigInt
+        # L1Fee is the fee, in wei, charged for posting the transaction data to
+        # L1. Null for deposit transactions or if the transaction has not yet
+        # been mined.
+        l1Fee: BigInt
+        # L1FeeScalar is the scalar the L1 data fee was computed with, as a
+        # decimal string. Null for deposit transactions or if the transaction has
+        # not yet been mined.
+        l1FeeScalar: String
+    }
+
+    # Receipt is the receipt of a mined transaction.
+    type Receipt {
+        # Transaction is the transaction this receipt belongs to.
+        transaction: Transaction!
+        # Status is the return status of the transaction, 1 if it succeeded or
+        # 0 if it failed. Null for receipts of pre-Byzantium blocks.
+        status: Long
+        # Root is the post-transaction state root of receipts of pre-Byzantium
+        # blocks. Null for later receipts.
+        root: Bytes32
+        # GasUsed is the amount of gas that was used processing the transaction.
+        gasUsed: Long!
+        # CumulativeGasUsed is the total gas used in the block up to and including
+        # the transaction.
+        cumulativeGasUsed: Long!
+        # EffectiveGasPrice is actual value per gas deducted from the sender's
+        # account.
+        effectiveGasPrice: BigInt
+        # CreatedContract is the account that was created by a contract creation
+        # transaction, null for other transactions.
+        createdContract(block: Long): Account
+        # Logs is the list of log entries emitted by the transaction.
+        logs: [Log!]!
+        # LogsBloom is the bloom filter of the logs emitted by the transaction.
+        logsBloom: Bytes!
+
+        # Rollup specific fields, see the same fields of Transaction.
+        depositNonce: Long
+        l1GasPrice: BigInt
+        l1GasUsed: BigInt
+        l1Fee: BigInt
+        l1FeeScalar: String
+
+        # Raw is the canonical encoding of the receipt. For post EIP-2718 typed
+        # transactions this is equivalent to TxType || ReceiptEncoding.
+        raw: Bytes!
     }
 
     # BlockFilterCriteria encapsulates log filter criteria for a filter applied
@@ -230,7 +302,12 @@ const schema string = `
         ommerHash: Bytes32!
         # Transactions is a list of transactions associated with this block. If
         # transactions are unavailable for this block, this field will be null.
-        transactions: [Transaction!]
+        # If types is given, only the transactions of these types are returned.
+        transactions(types: [Long!]): [Transaction!]
+        # Receipts is the list of receipts of the transactions of this block,
+        # retrieved at once. If receipts are unavailable for this block, this
+        # field will be null.
+        receipts: [Receipt!]
         # TransactionAt returns the transaction at the specified index. If
         # transactions are unavailable for this block, or if the index is out of
         # bounds, this field will be null.
@@ -275,10 +352,12 @@ const schema string = `
     type CallResult {
         # Data is the return data of the called contract.
         data: Bytes!
-        # GasUsed is the amount of gas used by the call, after any refunds.
-        gasUsed: Long!
-        # Status is the result of the call - 1 for success or 0 for failure.
-        status: Long!
+        # GasUsed is the amount of gas used by the call, after any refunds. It's
+        # null if unknown, i.e. for calls served by the historical backend.
+        gasUsed: Long
+        # Status is the result of the call - 1 for success or 0 for failure. It's
+        # null if unknown, i.e. for calls served by the historical backend.
+        status: Long
     }
 
     # FilterCriteria encapsulates log filter criteria for searching log entries.
@@ -321,7 +400,8 @@ const schema string = `
       # TransactionCount is the number of transactions in the pending state.
       transactionCount: Long!
       # Transactions is a list of transactions in the current pending state.
-      transactions: [Transaction!]
+      # If types is given, only the transactions of these types are returned.
+      transactions(types: [Long!]): [Transaction!]
       # Account fetches an Ethereum account for the pending state.
       account(address: Address!): Account!
       # Call executes a local call operation for the pending state.
@@ -360,4 +440,18 @@ const schema string = `
         # SendRawTransaction sends an RLP-encoded transaction to the network.
         sendRawTransaction(data: Bytes!): Bytes32!
     }
+
+    # Subscriptions are served over WebSocket connections to the GraphQL
+    # endpoint, using the graphql-ws protocol.
+    type Subscription {
+        # NewBlocks emits every block added to the canonical chain.
+        newBlocks: Block!
+        # NewPendingTransactions emits the transactions added to the transaction
+        # pool.
+        newPendingTransactions: Transaction!
+        # NewLogs emits the logs matching the filter of blocks added to the
+        # canonical chain, and the logs of blocks removed from it by
+        # reorganisations with removed set to true.
+        newLogs(filter: FilterCriteria!): Log!
+    }
 `
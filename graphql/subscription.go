@@ -0,0 +1,152 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// eventSystem returns the event system backing the subscriptions.
+func (r *Resolver) eventSystem() *filters.EventSystem {
+	r.eventsOnce.Do(func() {
+		r.events = filters.NewEventSystem(r.filterSystem, false)
+	})
+	return r.events
+}
+
+// NewBlocks emits the blocks added to the canonical chain until the subscription
+// ends.
+func (r *Resolver) NewBlocks(ctx context.Context) <-chan *Block {
+	var (
+		headers = make(chan *types.Header)
+		sub     = r.eventSystem().SubscribeNewHeads(headers)
+		blocks  = make(chan *Block)
+	)
+	go func() {
+		defer close(blocks)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case header := <-headers:
+				numberOrHash := rpc.BlockNumberOrHashWithHash(header.Hash(), false)
+				block := &Block{
+					r:            r,
+					numberOrHash: &numberOrHash,
+					header:       header,
+					hash:         header.Hash(),
+				}
+				select {
+				case blocks <- block:
+				case <-ctx.Done():
+					return
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return blocks
+}
+
+// NewPendingTransactions emits the transactions added to the transaction pool
+// until the subscription ends.
+func (r *Resolver) NewPendingTransactions(ctx context.Context) <-chan *Transaction {
+	var (
+		pending = make(chan []*types.Transaction)
+		sub     = r.eventSystem().SubscribePendingTxs(pending)
+		txs     = make(chan *Transaction)
+	)
+	go func() {
+		defer close(txs)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case batch := <-pending:
+				for _, tx := range batch {
+					select {
+					case txs <- &Transaction{r: r, hash: tx.Hash(), tx: tx}:
+					case <-ctx.Done():
+						return
+					}
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return txs
+}
+
+// NewLogs emits the logs matching the filter until the subscription ends. Logs
+// of blocks removed from the canonical chain are emitted with removed set.
+func (r *Resolver) NewLogs(ctx context.Context, args struct{ Filter FilterCriteria }) (<-chan *Log, error) {
+	crit := ethereum.FilterQuery{}
+	if args.Filter.FromBlock != nil {
+		crit.FromBlock = big.NewInt(int64(*args.Filter.FromBlock))
+	}
+	if args.Filter.ToBlock != nil {
+		crit.ToBlock = big.NewInt(int64(*args.Filter.ToBlock))
+	}
+	if args.Filter.Addresses != nil {
+		crit.Addresses = *args.Filter.Addresses
+	}
+	if args.Filter.Topics != nil {
+		crit.Topics = *args.Filter.Topics
+	}
+	var (
+		matched = make(chan []*types.Log)
+		logs    = make(chan *Log)
+	)
+	sub, err := r.eventSystem().SubscribeLogs(crit, matched)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		defer close(logs)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case batch := <-matched:
+				for _, log := range batch {
+					select {
+					case logs <- &Log{r: r, transaction: &Transaction{r: r, hash: log.TxHash}, log: log}:
+					case <-ctx.Done():
+						return
+					}
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return logs, nil
+}
@@ -0,0 +1,373 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+)
+
+// GraphQL operations, including subscriptions, are served over WebSocket using
+// the protocol of the graphql-ws library. The older protocol of the Apollo
+// subscriptions-transport-ws library is supported too, it's selected by the
+// "graphql-ws" subprotocol.
+const (
+	wsProtocol       = "graphql-transport-ws" // Subprotocol of graphql-ws
+	wsLegacyProtocol = "graphql-ws"           // Subprotocol of subscriptions-transport-ws
+
+	wsInitTimeout  = 10 * time.Second // Time allowed to the client to initialise the connection
+	wsWriteTimeout = 10 * time.Second // Time allowed to write a message
+	wsKeepAlive    = 20 * time.Second // Keep alive interval of the legacy protocol
+	wsReadLimit    = 1024 * 1024      // Maximum size of client messages
+	wsSendQueue    = 1024             // Maximum number of queued messages per connection
+)
+
+// Message types of the protocols.
+const (
+	wsConnectionInit      = "connection_init"
+	wsConnectionAck       = "connection_ack"
+	wsConnectionTerminate = "connection_terminate" // legacy only
+	wsKeepAliveMsg        = "ka"                   // legacy only
+	wsPing                = "ping"
+	wsPong                = "pong"
+	wsSubscribe           = "subscribe" // "start" in the legacy protocol
+	wsNext                = "next"      // "data" in the legacy protocol
+	wsError               = "error"
+	wsComplete            = "complete" // "stop" from clients in the legacy protocol
+)
+
+// Close codes of the graphql-ws protocol.
+const (
+	wsCloseInvalidMessage     = 4400
+	wsCloseUnauthorized       = 4401
+	wsCloseInitTimeout        = 4408
+	wsCloseDuplicateID        = 4409
+	wsCloseTooManyInitialises = 4429
+)
+
+// wsMessage is a message of the protocols.
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsOperation is the payload of subscribe messages.
+type wsOperation struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// wsHandler serves GraphQL operations to WebSocket connections.
+type wsHandler struct {
+	schema   *graphql.Schema
+	upgrader websocket.Upgrader
+}
+
+func newWSHandler(schema *graphql.Schema, cors []string) *wsHandler {
+	return &wsHandler{
+		schema: schema,
+		upgrader: websocket.Upgrader{
+			Subprotocols: []string{wsProtocol, wsLegacyProtocol},
+			CheckOrigin:  wsOriginValidator(cors),
+		},
+	}
+}
+
+// wsOriginValidator returns a handshake validator accepting requests from the
+// allowed origins only. Requests without origin are accepted, as those aren't
+// made by browsers the check protects against.
+func wsOriginValidator(allowed []string) func(*http.Request) bool {
+	return func(r *http.Request) bool {
+		if _, ok := r.Header["Origin"]; !ok {
+			return true
+		}
+		origin := r.Header.Get("Origin")
+		for _, allow := range allowed {
+			if allow == "*" || strings.EqualFold(allow, origin) {
+				return true
+			}
+		}
+		log.Warn("Rejected GraphQL WebSocket connection", "origin", origin)
+		return false
+	}
+}
+
+// isWebsocket checks whether the request asks for a WebSocket upgrade.
+func isWebsocket(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") &&
+		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
+
+func (h *wsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Debug("GraphQL WebSocket upgrade failed", "err", err)
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &wsConn{
+		schema: h.schema,
+		conn:   conn,
+		legacy: conn.Subprotocol() == wsLegacyProtocol,
+		ctx:    ctx,
+		cancel: cancel,
+		queue:  make(chan *wsMessage, wsSendQueue),
+		subs:   make(map[string]context.CancelFunc),
+	}
+	c.run()
+}
+
+// wsConn is a WebSocket connection serving GraphQL operations.
+type wsConn struct {
+	schema *graphql.Schema
+	conn   *websocket.Conn
+	legacy bool
+
+	ctx    context.Context // Cancelled when the connection is closed
+	cancel context.CancelFunc
+	queue  chan *wsMessage // Messages to be written
+	wg     sync.WaitGroup
+
+	mu   sync.Mutex
+	subs map[string]context.CancelFunc // Active operations by ID
+}
+
+// run serves the connection until it's closed.
+func (c *wsConn) run() {
+	c.wg.Add(1)
+	go c.writeLoop()
+
+	defer func() {
+		c.cancel()
+		c.conn.Close()
+		c.wg.Wait()
+	}()
+	c.conn.SetReadLimit(wsReadLimit)
+	c.conn.SetReadDeadline(time.Now().Add(wsInitTimeout))
+
+	var initialised bool
+	for {
+		var msg wsMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			if !initialised && isTimeout(err) {
+				c.close(wsCloseInitTimeout, "Connection initialisation timeout")
+			} else if _, ok := err.(*websocket.CloseError); !ok {
+				c.close(wsCloseInvalidMessage, "Invalid message")
+			}
+			return
+		}
+		if !initialised && msg.Type != wsConnectionInit {
+			c.close(wsCloseUnauthorized, "Unauthorized")
+			return
+		}
+		switch msg.Type {
+		case wsConnectionInit:
+			if initialised {
+				c.close(wsCloseTooManyInitialises, "Too many initialisation requests")
+				return
+			}
+			initialised = true
+			c.conn.SetReadDeadline(time.Time{})
+			c.send(&wsMessage{Type: wsConnectionAck})
+			if c.legacy {
+				c.send(&wsMessage{Type: wsKeepAliveMsg})
+			}
+		case wsPing:
+			c.send(&wsMessage{Type: wsPong})
+		case wsPong:
+		case wsSubscribe, "start":
+			var op wsOperation
+			if msg.ID == "" || json.Unmarshal(msg.Payload, &op) != nil {
+				c.close(wsCloseInvalidMessage, "Invalid subscribe message")
+				return
+			}
+			if !c.start(msg.ID, op) {
+				if !c.legacy {
+					c.close(wsCloseDuplicateID, "Subscriber for "+msg.ID+" already exists")
+					return
+				}
+				c.sendErrors(msg.ID, "duplicate operation ID")
+			}
+		case wsComplete, "stop":
+			c.stop(msg.ID)
+		case wsConnectionTerminate:
+			return
+		default:
+			c.close(wsCloseInvalidMessage, "Invalid message type "+msg.Type)
+			return
+		}
+	}
+}
+
+// start runs a GraphQL operation, sending its results to the client. It reports
+// false if an operation with the same ID is already active.
+func (c *wsConn) start(id string, op wsOperation) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.subs[id]; ok {
+		return false
+	}
+	ctx, cancel := context.WithCancel(c.ctx)
+	c.subs[id] = cancel
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer cancel()
+
+		responses, err := c.schema.Subscribe(ctx, op.Query, op.OperationName, op.Variables)
+		if err != nil {
+			c.finish(id)
+			c.sendErrors(id, err.Error())
+			return
+		}
+		var failed bool
+		for res := range responses {
+			// Drain the responses of operations stopped by the client
+			if ctx.Err() != nil {
+				continue
+			}
+			resp := res.(*graphql.Response)
+			payload, err := json.Marshal(resp)
+			if err != nil {
+				log.Warn("Failed to encode GraphQL response", "err", err)
+				continue
+			}
+			// Operations failing before execution report their errors as such
+			if resp.Data == nil && len(resp.Errors) > 0 {
+				payload, _ = json.Marshal(resp.Errors)
+				if c.legacy && len(resp.Errors) == 1 {
+					payload, _ = json.Marshal(resp.Errors[0])
+				}
+				c.send(&wsMessage{ID: id, Type: wsError, Payload: payload})
+				failed = true
+				continue
+			}
+			typ := wsNext
+			if c.legacy {
+				typ = "data"
+			}
+			c.send(&wsMessage{ID: id, Type: typ, Payload: payload})
+		}
+		if c.finish(id) && !failed {
+			c.send(&wsMessage{ID: id, Type: wsComplete})
+		}
+	}()
+	return true
+}
+
+// stop cancels an operation on the client's request.
+func (c *wsConn) stop(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cancel, ok := c.subs[id]; ok {
+		cancel()
+		delete(c.subs, id)
+	}
+}
+
+// finish removes an operation, reporting whether it was still active.
+func (c *wsConn) finish(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ok := c.subs[id]
+	delete(c.subs, id)
+	return ok
+}
+
+// sendErrors sends an error message for an operation.
+func (c *wsConn) sendErrors(id string, message string) {
+	type wsQueryError struct {
+		Message string `json:"message"`
+	}
+	var payload []byte
+	if c.legacy {
+		payload, _ = json.Marshal(wsQueryError{message})
+	} else {
+		payload, _ = json.Marshal([]wsQueryError{{message}})
+	}
+	c.send(&wsMessage{ID: id, Type: wsError, Payload: payload})
+}
+
+// send queues a message for writing. Connections of clients not keeping up with
+// their messages are dropped.
+func (c *wsConn) send(msg *wsMessage) {
+	select {
+	case c.queue <- msg:
+	case <-c.ctx.Done():
+	default:
+		log.Debug("Dropping slow GraphQL WebSocket client", "addr", c.conn.RemoteAddr())
+		c.close(websocket.CloseTryAgainLater, "Too many pending messages")
+	}
+}
+
+// writeLoop writes the queued messages until the connection is closed.
+func (c *wsConn) writeLoop() {
+	defer c.wg.Done()
+
+	keepAlive := time.NewTicker(wsKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		var msg *wsMessage
+		select {
+		case msg = <-c.queue:
+		case <-keepAlive.C:
+			if !c.legacy {
+				continue
+			}
+			msg = &wsMessage{Type: wsKeepAliveMsg}
+		case <-c.ctx.Done():
+			return
+		}
+		c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+		if err := c.conn.WriteJSON(msg); err != nil {
+			log.Debug("GraphQL WebSocket write failed", "err", err)
+			c.cancel()
+			c.conn.Close()
+			return
+		}
+	}
+}
+
+// close closes the connection with the given close code and reason.
+func (c *wsConn) close(code int, reason string) {
+	deadline := time.Now().Add(wsWriteTimeout)
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), deadline)
+	c.cancel()
+	c.conn.Close()
+}
+
+// isTimeout reports whether the error is a network timeout.
+func isTimeout(err error) bool {
+	type timeout interface{ Timeout() bool }
+	t, ok := err.(timeout)
+	return ok && t.Timeout()
+}
@@ -0,0 +1,201 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/gorilla/websocket"
+)
+
+// dialGraphQLWS connects to the GraphQL endpoint of the node over WebSocket.
+func dialGraphQLWS(t *testing.T, endpoint string, protocol string) *websocket.Conn {
+	dialer := websocket.Dialer{Subprotocols: []string{protocol}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(endpoint, "http")+"/graphql", nil)
+	if err != nil {
+		t.Fatalf("could not dial: %v", err)
+	}
+	if conn.Subprotocol() != protocol {
+		t.Fatalf("subprotocol mismatch: have %q, want %q", conn.Subprotocol(), protocol)
+	}
+	return conn
+}
+
+func readWSMessage(t *testing.T, conn *websocket.Conn) *wsMessage {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("could not read message: %v", err)
+		}
+		if msg.Type != wsKeepAliveMsg {
+			return &msg
+		}
+	}
+}
+
+func TestGraphQLWebSocket(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		genesis = &core.Genesis{
+			Config:     params.AllEthashProtocolChanges,
+			GasLimit:   11500000,
+			Difficulty: big.NewInt(1048576),
+			Alloc:      core.GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}},
+			BaseFee:    big.NewInt(params.InitialBaseFee),
+		}
+		stack = createNode(t)
+	)
+	defer stack.Close()
+
+	ethBackend, _ := newGQLBackend(t, stack, genesis, 1, func(i int, gen *core.BlockGen) {})
+	filterSystem := filters.NewFilterSystem(ethBackend.APIBackend, filters.Config{})
+	if _, err := newHandler(stack, ethBackend.APIBackend, filterSystem, []string{}, []string{}); err != nil {
+		t.Fatalf("could not create graphql service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	for _, protocol := range []string{wsProtocol, wsLegacyProtocol} {
+		conn := dialGraphQLWS(t, stack.HTTPEndpoint(), protocol)
+		defer conn.Close()
+
+		start, next := wsSubscribe, wsNext
+		if protocol == wsLegacyProtocol {
+			start, next = "start", "data"
+		}
+		conn.WriteJSON(&wsMessage{Type: wsConnectionInit})
+		if msg := readWSMessage(t, conn); msg.Type != wsConnectionAck {
+			t.Fatalf("%s: expected ack, have %+v", protocol, msg)
+		}
+		// Queries are answered once and completed
+		conn.WriteJSON(&wsMessage{ID: "1", Type: start, Payload: json.RawMessage(`{"query": "{ block { number } }"}`)})
+		if msg := readWSMessage(t, conn); msg.Type != next || msg.ID != "1" || string(msg.Payload) != `{"data":{"block":{"number":"0x1"}}}` {
+			t.Fatalf("%s: query result mismatch: %+v %s", protocol, msg, msg.Payload)
+		}
+		if msg := readWSMessage(t, conn); msg.Type != wsComplete || msg.ID != "1" {
+			t.Fatalf("%s: expected completion, have %+v", protocol, msg)
+		}
+		// Invalid operations fail
+		conn.WriteJSON(&wsMessage{ID: "2", Type: start, Payload: json.RawMessage(`{"query": "subscription { unknown }"}`)})
+		if msg := readWSMessage(t, conn); msg.Type != wsError || msg.ID != "2" {
+			t.Fatalf("%s: expected error, have %+v", protocol, msg)
+		}
+	}
+	// Subscribe to new blocks and pending transactions, and produce both
+	conn := dialGraphQLWS(t, stack.HTTPEndpoint(), wsProtocol)
+	defer conn.Close()
+
+	conn.WriteJSON(&wsMessage{Type: wsConnectionInit})
+	readWSMessage(t, conn)
+	conn.WriteJSON(&wsMessage{ID: "blocks", Type: wsSubscribe, Payload: json.RawMessage(`{"query": "subscription { newBlocks { number } }"}`)})
+	conn.WriteJSON(&wsMessage{ID: "txs", Type: wsSubscribe, Payload: json.RawMessage(`{"query": "subscription { newPendingTransactions { hash nonce } }"}`)})
+	conn.WriteJSON(&wsMessage{Type: wsPing})
+	if msg := readWSMessage(t, conn); msg.Type != wsPong {
+		t.Fatalf("expected pong, have %+v", msg)
+	}
+	time.Sleep(100 * time.Millisecond) // subscriptions are installed asynchronously
+
+	chain := ethBackend.BlockChain()
+	blocks, _ := core.GenerateChain(genesis.Config, chain.GetBlockByHash(chain.CurrentBlock().Hash()), ethash.NewFaker(), ethBackend.ChainDb(), 1, func(i int, gen *core.BlockGen) {})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("could not import block: %v", err)
+	}
+	if msg := readWSMessage(t, conn); msg.Type != wsNext || msg.ID != "blocks" || string(msg.Payload) != `{"data":{"newBlocks":{"number":"0x2"}}}` {
+		t.Fatalf("block notification mismatch: %+v %s", msg, msg.Payload)
+	}
+	tx, _ := types.SignNewTx(key, types.LatestSigner(genesis.Config), &types.LegacyTx{
+		Nonce:    0,
+		To:       &addr,
+		Gas:      21000,
+		GasPrice: big.NewInt(2 * params.InitialBaseFee),
+	})
+	if err := ethBackend.TxPool().AddLocal(tx); err != nil {
+		t.Fatalf("could not add transaction: %v", err)
+	}
+	want := fmt.Sprintf(`{"data":{"newPendingTransactions":{"hash":"%s","nonce":"0x0"}}}`, tx.Hash().Hex())
+	if msg := readWSMessage(t, conn); msg.Type != wsNext || msg.ID != "txs" || string(msg.Payload) != want {
+		t.Fatalf("transaction notification mismatch: %+v %s", msg, msg.Payload)
+	}
+	// Stopped subscriptions aren't completed by the server, and the ID is free
+	// to be reused
+	conn.WriteJSON(&wsMessage{ID: "txs", Type: wsComplete})
+	conn.WriteJSON(&wsMessage{ID: "txs", Type: wsSubscribe, Payload: json.RawMessage(`{"query": "{ chainID }"}`)})
+	if msg := readWSMessage(t, conn); msg.Type != wsNext || msg.ID != "txs" {
+		t.Fatalf("expected result of reused ID, have %+v", msg)
+	}
+}
+
+// Tests that WebSocket connections are subject to the virtual host and origin
+// restrictions of the endpoint.
+func TestGraphQLWebSocketAccess(t *testing.T) {
+	stack := createNode(t)
+	defer stack.Close()
+
+	ethBackend, _ := newGQLBackend(t, stack, &core.Genesis{Config: params.AllEthashProtocolChanges}, 0, nil)
+	filterSystem := filters.NewFilterSystem(ethBackend.APIBackend, filters.Config{})
+	if _, err := newHandler(stack, ethBackend.APIBackend, filterSystem, []string{"http://allowed.example"}, []string{"localhost"}); err != nil {
+		t.Fatalf("could not create graphql service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	url := "ws" + strings.TrimPrefix(stack.HTTPEndpoint(), "http") + "/graphql"
+	for i, tt := range []struct {
+		host   string
+		origin string
+		ok     bool
+	}{
+		{"", "", true},
+		{"localhost", "http://allowed.example", true},
+		{"", "http://evil.example", false},
+		{"", "http://" + strings.TrimPrefix(stack.HTTPEndpoint(), "http://"), false}, // same host origin
+		{"rebound.example", "", false},
+	} {
+		header := make(http.Header)
+		if tt.host != "" {
+			header.Set("Host", tt.host)
+		}
+		if tt.origin != "" {
+			header.Set("Origin", tt.origin)
+		}
+		dialer := websocket.Dialer{Subprotocols: []string{wsProtocol}}
+		conn, resp, err := dialer.Dial(url, header)
+		if conn != nil {
+			conn.Close()
+		}
+		if tt.ok && err != nil {
+			t.Errorf("test %d: connection rejected: %v", i, err)
+		}
+		if !tt.ok && (err == nil || resp == nil || resp.StatusCode != http.StatusForbidden) {
+			t.Errorf("test %d: connection not rejected: %v", i, err)
+		}
+	}
+}
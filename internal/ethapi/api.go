@@ -19,6 +19,7 @@ package ethapi
 import (
 	"context"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
@@ -46,6 +47,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+	"github.com/ethereum/go-ethereum/internal/tracing"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/params"
@@ -795,36 +797,210 @@ func (s *BlockChainAPI) GetHeaderByHash(ctx context.Context, hash common.Hash) m
 	return nil
 }
 
+// blockResult is the result of the block retrieval methods. It is JSON encoded as
+// the marshalled block fields, but binary RPC transports send the RLP encoding of
+// the block instead if it includes the full transactions. The block fields are
+// only marshalled if the JSON encoding is requested.
+type blockResult struct {
+	fields  map[string]interface{} // Fields of a block served by the historical backend
+	ctx     context.Context
+	api     *BlockChainAPI
+	block   *types.Block
+	fullTx  bool
+	pending bool // Whether to nil out the fields unknown for pending blocks
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r *blockResult) MarshalJSON() ([]byte, error) {
+	if r.block == nil {
+		return json.Marshal(r.fields)
+	}
+	fields, err := r.api.rpcMarshalBlock(r.ctx, r.block, true, r.fullTx)
+	if err != nil {
+		return nil, err
+	}
+	if r.pending {
+		for _, field := range []string{"hash", "nonce", "miner"} {
+			fields[field] = nil
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// MarshalRPCBinary implements rpc.BinaryMarshaler.
+func (r *blockResult) MarshalRPCBinary() ([]byte, error) {
+	if r == nil || r.block == nil || !r.fullTx || r.pending {
+		return nil, nil
+	}
+	return rlp.EncodeToBytes(r.block)
+}
+
 // GetBlockByNumber returns the requested canonical block.
 //   - When blockNr is -1 the chain head is returned.
 //   - When blockNr is -2 the pending chain head is returned.
 //   - When fullTx is true all transactions in the block are returned, otherwise
 //     only the transaction hash is returned.
-func (s *BlockChainAPI) GetBlockByNumber(ctx context.Context, number rpc.BlockNumber, fullTx bool) (map[string]interface{}, error) {
+func (s *BlockChainAPI) GetBlockByNumber(ctx context.Context, number rpc.BlockNumber, fullTx bool) (*blockResult, error) {
 	block, err := s.b.BlockByNumber(ctx, number)
-	if block != nil && err == nil {
-		response, err := s.rpcMarshalBlock(ctx, block, true, fullTx)
-		if err == nil && number == rpc.PendingBlockNumber && s.b.ChainConfig().Optimism == nil { // don't remove info if optimism
-			// Pending blocks need to nil out a few fields
-			for _, field := range []string{"hash", "nonce", "miner"} {
-				response[field] = nil
-			}
+	if errors.Is(err, rpc.ErrPrunedHistory) {
+		var res map[string]interface{}
+		if err := historicalFallback(ctx, s.b, &res, "eth_getBlockByNumber", number, fullTx); err != nil {
+			return nil, err
 		}
-		return response, err
+		return &blockResult{fields: res}, nil
+	}
+	if block != nil && err == nil {
+		return &blockResult{
+			ctx:     ctx,
+			api:     s,
+			block:   block,
+			fullTx:  fullTx,
+			pending: number == rpc.PendingBlockNumber && s.b.ChainConfig().Optimism == nil, // don't remove info if optimism
+		}, nil
 	}
 	return nil, err
 }
 
 // GetBlockByHash returns the requested block. When fullTx is true all transactions in the block are returned in full
 // detail, otherwise only the transaction hash is returned.
-func (s *BlockChainAPI) GetBlockByHash(ctx context.Context, hash common.Hash, fullTx bool) (map[string]interface{}, error) {
+func (s *BlockChainAPI) GetBlockByHash(ctx context.Context, hash common.Hash, fullTx bool) (*blockResult, error) {
 	block, err := s.b.BlockByHash(ctx, hash)
+	if errors.Is(err, rpc.ErrPrunedHistory) {
+		var res map[string]interface{}
+		if err := historicalFallback(ctx, s.b, &res, "eth_getBlockByHash", hash, fullTx); err != nil {
+			return nil, err
+		}
+		return &blockResult{fields: res}, nil
+	}
 	if block != nil {
-		return s.rpcMarshalBlock(ctx, block, true, fullTx)
+		return &blockResult{ctx: ctx, api: s, block: block, fullTx: fullTx}, nil
 	}
 	return nil, err
 }
 
+// historicalFallback forwards a request for block data expired from the local
+// database to the historical RPC service, if one is configured.
+func historicalFallback(ctx context.Context, b Backend, result interface{}, method string, args ...interface{}) error {
+	if b.HistoricalRPCService() == nil {
+		return rpc.ErrPrunedHistory
+	}
+	if err := b.HistoricalRPCService().CallContext(ctx, result, method, args...); err != nil {
+		return fmt.Errorf("historical backend error: %w", err)
+	}
+	return nil
+}
+
+// receiptResult is the result of the receipt retrieval methods. It is JSON encoded
+// as the marshalled receipt fields, but binary RPC transports send the encoding of
+// types.EncodeFullReceipts instead, holding the single receipt.
+type receiptResult struct {
+	fields      map[string]interface{} // Fields of a receipt served by the historical backend
+	receipt     *types.Receipt
+	blockHash   common.Hash
+	blockNumber uint64
+	signer      types.Signer
+	tx          *types.Transaction
+	txIndex     int
+	config      *params.ChainConfig
+}
+
+// marshal returns the marshalled receipt fields.
+func (r *receiptResult) marshal() map[string]interface{} {
+	if r.receipt == nil {
+		return r.fields
+	}
+	return marshalReceipt(r.receipt, r.blockHash, r.blockNumber, r.signer, r.tx, r.txIndex, r.config)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r *receiptResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.marshal())
+}
+
+// MarshalRPCBinary implements rpc.BinaryMarshaler.
+func (r *receiptResult) MarshalRPCBinary() ([]byte, error) {
+	if r == nil || r.receipt == nil {
+		return nil, nil
+	}
+	return types.EncodeFullReceipts([]*types.Receipt{r.receipt})
+}
+
+// receiptsResult is the result of the block receipts retrieval. Binary RPC
+// transports send it in the encoding of types.EncodeFullReceipts.
+type receiptsResult []*receiptResult
+
+// MarshalRPCBinary implements rpc.BinaryMarshaler.
+func (rs receiptsResult) MarshalRPCBinary() ([]byte, error) {
+	if rs == nil {
+		return nil, nil
+	}
+	receipts := make([]*types.Receipt, len(rs))
+	for i, r := range rs {
+		if r.receipt == nil {
+			return nil, nil // served by the historical backend, only known as JSON
+		}
+		receipts[i] = r.receipt
+	}
+	return types.EncodeFullReceipts(receipts)
+}
+
+// historicalReceipts retrieves the receipts of a block from the historical backend.
+func historicalReceipts(ctx context.Context, b Backend, blockNrOrHash rpc.BlockNumberOrHash) (receiptsResult, error) {
+	var res []map[string]interface{}
+	if err := historicalFallback(ctx, b, &res, "eth_getBlockReceipts", blockNrOrHash); err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, nil
+	}
+	result := make(receiptsResult, len(res))
+	for i, fields := range res {
+		result[i] = &receiptResult{fields: fields}
+	}
+	return result, nil
+}
+
+// GetBlockReceipts returns the receipts of all transactions in the given block,
+// in the format of eth_getTransactionReceipt.
+func (s *BlockChainAPI) GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (receiptsResult, error) {
+	block, err := s.b.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if errors.Is(err, rpc.ErrPrunedHistory) {
+		return historicalReceipts(ctx, s.b, blockNrOrHash)
+	}
+	if block == nil || err != nil {
+		// When the block doesn't exist, the RPC method should return JSON null
+		// as per specification.
+		return nil, nil
+	}
+	receipts, err := s.b.GetReceipts(ctx, block.Hash())
+	if errors.Is(err, rpc.ErrPrunedHistory) {
+		return historicalReceipts(ctx, s.b, blockNrOrHash)
+	}
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if len(txs) != len(receipts) {
+		return nil, fmt.Errorf("receipts length mismatch: %d vs %d", len(txs), len(receipts))
+	}
+	var (
+		signer = types.MakeSigner(s.b.ChainConfig(), block.Number(), block.Time())
+		result = make(receiptsResult, len(receipts))
+	)
+	for i, receipt := range receipts {
+		result[i] = &receiptResult{
+			receipt:     receipt,
+			blockHash:   block.Hash(),
+			blockNumber: block.NumberU64(),
+			signer:      signer,
+			tx:          txs[i],
+			txIndex:     i,
+			config:      s.b.ChainConfig(),
+		}
+	}
+	return result, nil
+}
+
 // GetUncleByBlockNumberAndIndex returns the uncle block for the given block hash and index.
 func (s *BlockChainAPI) GetUncleByBlockNumberAndIndex(ctx context.Context, blockNr rpc.BlockNumber, index hexutil.Uint) (map[string]interface{}, error) {
 	block, err := s.b.BlockByNumber(ctx, blockNr)
@@ -1079,8 +1255,15 @@ func (context *ChainContext) GetHeader(hash common.Hash, number uint64) *types.H
 func DoCall(ctx context.Context, b Backend, args TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, blockOverrides *BlockOverrides, timeout time.Duration, globalGasCap uint64) (*core.ExecutionResult, error) {
 	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
 
+	ctx, span := tracing.StartSpan(ctx, "ethapi.DoCall", "gas.cap", globalGasCap)
+	defer span.End()
+
+	_, stateSpan := tracing.StartSpan(ctx, "ethapi.stateAndHeader", "block", blockNrOrHash.String())
 	state, header, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	stateSpan.SetError(err)
+	stateSpan.End()
 	if state == nil || err != nil {
+		span.SetError(err)
 		return nil, err
 	}
 	if err := overrides.Apply(state); err != nil {
@@ -1117,9 +1300,18 @@ func DoCall(ctx context.Context, b Backend, args TransactionArgs, blockNrOrHash
 	}()
 
 	// Execute the message.
+	_, execSpan := tracing.StartSpan(ctx, "evm.execute", "block.number", header.Number.Uint64())
 	gp := new(core.GasPool).AddGas(math.MaxUint64)
 	result, err := core.ApplyMessage(evm, msg, gp)
+	if result != nil {
+		execSpan.SetAttributes("gas.used", result.UsedGas, "failed", result.Failed())
+	}
+	execSpan.SetAttributes("db.account_read_time", state.AccountReads, "db.storage_read_time", state.StorageReads)
+	execSpan.SetError(err)
+	execSpan.End()
+
 	if err := vmError(); err != nil {
+		span.SetError(err)
 		return nil, err
 	}
 
@@ -1550,6 +1742,23 @@ func NewRPCPendingTransaction(tx *types.Transaction, current *types.Header, conf
 	return newRPCTransaction(tx, common.Hash{}, blockNumber, blockTime, 0, baseFee, config, nil)
 }
 
+// RPCStateDiff represents the state changes made by a block.
+type RPCStateDiff struct {
+	BlockHash   common.Hash     `json:"blockHash"`
+	BlockNumber hexutil.Uint64  `json:"blockNumber"`
+	Accounts    state.StateDiff `json:"accounts"`
+}
+
+// NewRPCStateDiff returns the state changes of the given block that will
+// serialize to the RPC representation.
+func NewRPCStateDiff(header *types.Header, diff state.StateDiff) *RPCStateDiff {
+	return &RPCStateDiff{
+		BlockHash:   header.Hash(),
+		BlockNumber: hexutil.Uint64(header.Number.Uint64()),
+		Accounts:    diff,
+	}
+}
+
 // newRPCTransactionFromBlockIndex returns a transaction that will serialize to the RPC representation.
 func newRPCTransactionFromBlockIndex(ctx context.Context, b *types.Block, index uint64, config *params.ChainConfig, backend Backend) *RPCTransaction {
 	txs := b.Transactions()
@@ -1797,6 +2006,14 @@ func (s *TransactionAPI) GetTransactionCount(ctx context.Context, address common
 func (s *TransactionAPI) GetTransactionByHash(ctx context.Context, hash common.Hash) (*RPCTransaction, error) {
 	// Try to return an already finalized transaction
 	tx, blockHash, blockNumber, index, err := s.b.GetTransaction(ctx, hash)
+	if errors.Is(err, rpc.ErrPrunedHistory) {
+		// The transaction is located in the expired history
+		var res *RPCTransaction
+		if err := historicalFallback(ctx, s.b, &res, "eth_getTransactionByHash", hash); err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -1812,7 +2029,6 @@ func (s *TransactionAPI) GetTransactionByHash(ctx context.Context, hash common.H
 	if tx := s.b.GetPoolTransaction(hash); tx != nil {
 		return NewRPCPendingTransaction(tx, s.b.CurrentHeader(), s.b.ChainConfig()), nil
 	}
-
 	// Transaction unknown, return as such
 	return nil, nil
 }
@@ -1821,6 +2037,13 @@ func (s *TransactionAPI) GetTransactionByHash(ctx context.Context, hash common.H
 func (s *TransactionAPI) GetRawTransactionByHash(ctx context.Context, hash common.Hash) (hexutil.Bytes, error) {
 	// Retrieve a finalized transaction, or a pooled otherwise
 	tx, _, _, _, err := s.b.GetTransaction(ctx, hash)
+	if errors.Is(err, rpc.ErrPrunedHistory) {
+		var res hexutil.Bytes
+		if err := historicalFallback(ctx, s.b, &res, "eth_getRawTransactionByHash", hash); err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -1835,18 +2058,28 @@ func (s *TransactionAPI) GetRawTransactionByHash(ctx context.Context, hash commo
 }
 
 // GetTransactionReceipt returns the transaction receipt for the given transaction hash.
-func (s *TransactionAPI) GetTransactionReceipt(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
+func (s *TransactionAPI) GetTransactionReceipt(ctx context.Context, hash common.Hash) (*receiptResult, error) {
 	tx, blockHash, blockNumber, index, err := s.b.GetTransaction(ctx, hash)
+	if errors.Is(err, rpc.ErrPrunedHistory) {
+		// The transaction is located in the expired history
+		return s.historicalReceipt(ctx, hash)
+	}
 	if err != nil {
 		// When the transaction doesn't exist, the RPC method should return JSON null
 		// as per specification.
 		return nil, nil
 	}
+	if tx == nil {
+		return nil, nil
+	}
 	header, err := s.b.HeaderByHash(ctx, blockHash)
 	if err != nil {
 		return nil, err
 	}
 	receipts, err := s.b.GetReceipts(ctx, blockHash)
+	if errors.Is(err, rpc.ErrPrunedHistory) {
+		return s.historicalReceipt(ctx, hash)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -1857,13 +2090,38 @@ func (s *TransactionAPI) GetTransactionReceipt(ctx context.Context, hash common.
 
 	// Derive the sender.
 	signer := types.MakeSigner(s.b.ChainConfig(), header.Number, header.Time)
+	return &receiptResult{
+		receipt:     receipt,
+		blockHash:   blockHash,
+		blockNumber: blockNumber,
+		signer:      signer,
+		tx:          tx,
+		txIndex:     int(index),
+		config:      s.b.ChainConfig(),
+	}, nil
+}
+
+// historicalReceipt retrieves a transaction receipt from the historical backend.
+func (s *TransactionAPI) historicalReceipt(ctx context.Context, hash common.Hash) (*receiptResult, error) {
+	var res map[string]interface{}
+	if err := historicalFallback(ctx, s.b, &res, "eth_getTransactionReceipt", hash); err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, nil
+	}
+	return &receiptResult{fields: res}, nil
+}
+
+// marshalReceipt marshals a transaction receipt into a JSON object.
+func marshalReceipt(receipt *types.Receipt, blockHash common.Hash, blockNumber uint64, signer types.Signer, tx *types.Transaction, txIndex int, config *params.ChainConfig) map[string]interface{} {
 	from, _ := types.Sender(signer, tx)
 
 	fields := map[string]interface{}{
 		"blockHash":         blockHash,
 		"blockNumber":       hexutil.Uint64(blockNumber),
-		"transactionHash":   hash,
-		"transactionIndex":  hexutil.Uint64(index),
+		"transactionHash":   tx.Hash(),
+		"transactionIndex":  hexutil.Uint64(txIndex),
 		"from":              from,
 		"to":                tx.To(),
 		"gasUsed":           hexutil.Uint64(receipt.GasUsed),
@@ -1875,13 +2133,13 @@ func (s *TransactionAPI) GetTransactionReceipt(ctx context.Context, hash common.
 		"effectiveGasPrice": (*hexutil.Big)(receipt.EffectiveGasPrice),
 	}
 
-	if s.b.ChainConfig().Optimism != nil && !tx.IsDepositTx() {
+	if config.Optimism != nil && !tx.IsDepositTx() {
 		fields["l1GasPrice"] = (*hexutil.Big)(receipt.L1GasPrice)
 		fields["l1GasUsed"] = (*hexutil.Big)(receipt.L1GasUsed)
 		fields["l1Fee"] = (*hexutil.Big)(receipt.L1Fee)
 		fields["l1FeeScalar"] = receipt.FeeScalar.String()
 	}
-	if s.b.ChainConfig().Optimism != nil && tx.IsDepositTx() && receipt.DepositNonce != nil {
+	if config.Optimism != nil && tx.IsDepositTx() && receipt.DepositNonce != nil {
 		fields["depositNonce"] = hexutil.Uint64(*receipt.DepositNonce)
 	}
 
@@ -1899,7 +2157,7 @@ func (s *TransactionAPI) GetTransactionReceipt(ctx context.Context, hash common.
 	if receipt.ContractAddress != (common.Address{}) {
 		fields["contractAddress"] = receipt.ContractAddress
 	}
-	return fields, nil
+	return fields
 }
 
 // sign is a helper function that signs a transaction with the private key of the given address.
@@ -1917,6 +2175,17 @@ func (s *TransactionAPI) sign(addr common.Address, tx *types.Transaction) (*type
 
 // SubmitTransaction is a helper function that submits tx to txPool and logs a message.
 func SubmitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (common.Hash, error) {
+	return submitTransaction(ctx, b, tx, b.SendTx)
+}
+
+// SubmitPrivateTransaction is a helper function that submits tx to the txPool as a
+// private transaction, which is never propagated to the network, and logs a message.
+func SubmitPrivateTransaction(ctx context.Context, b Backend, tx *types.Transaction) (common.Hash, error) {
+	return submitTransaction(ctx, b, tx, b.SendPrivateTx)
+}
+
+// submitTransaction sanity checks tx and hands it to the given send method.
+func submitTransaction(ctx context.Context, b Backend, tx *types.Transaction, send func(context.Context, *types.Transaction) error) (common.Hash, error) {
 	// If the transaction fee cap is already specified, ensure the
 	// fee of the given transaction is _reasonable_.
 	if err := checkTxFee(tx.GasPrice(), tx.Gas(), b.RPCTxFeeCap()); err != nil {
@@ -1926,7 +2195,11 @@ func SubmitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (c
 		// Ensure only eip155 signed transactions are submitted if EIP155Required is set.
 		return common.Hash{}, errors.New("only replay-protected (EIP-155) transactions allowed over RPC")
 	}
-	if err := b.SendTx(ctx, tx); err != nil {
+	// Refuse anything the local admission policy denies, even if forwarded
+	if err := b.CheckTxPolicy(tx); err != nil {
+		return common.Hash{}, err
+	}
+	if err := send(ctx, tx); err != nil {
 		return common.Hash{}, err
 	}
 	// Print a log with full tx details for manual investigations and interventions
@@ -2016,6 +2289,11 @@ func (s *TransactionAPI) SendRawTransaction(ctx context.Context, input hexutil.B
 	if err := tx.UnmarshalBinary(input); err != nil {
 		return common.Hash{}, err
 	}
+	// Refuse anything the local admission policy denies before forwarding, so
+	// the caller learns the reason of the rejection
+	if err := s.b.CheckTxPolicy(tx); err != nil {
+		return common.Hash{}, err
+	}
 
 	log.Info("收到一笔交易，转发给sequencer", "tx", tx.Hash().Hex())
 	var result string
@@ -2030,6 +2308,17 @@ func (s *TransactionAPI) SendRawTransaction(ctx context.Context, input hexutil.B
 	//return SubmitTransaction(ctx, s.b, tx)
 }
 
+// SendPrivateRawTransaction will add the signed transaction to the transaction pool
+// as a private transaction. It remains eligible for inclusion by the local block
+// builder, but is never propagated to the network nor listed in the pool content.
+func (s *TransactionAPI) SendPrivateRawTransaction(ctx context.Context, input hexutil.Bytes) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return common.Hash{}, err
+	}
+	return SubmitPrivateTransaction(ctx, s.b, tx)
+}
+
 // Sign calculates an ECDSA signature for:
 // keccak256("\x19Ethereum Signed Message:\n" + len(message) + message).
 //
@@ -2180,6 +2469,107 @@ func NewDebugAPI(b Backend) *DebugAPI {
 	return &DebugAPI{b: b}
 }
 
+// MultiProofQuery specifies an account and its storage slots to be proven.
+type MultiProofQuery struct {
+	Address     common.Address `json:"address"`
+	StorageKeys []string       `json:"storageKeys"`
+}
+
+// MultiProofAccount is the content of a proven account and its storage slots.
+type MultiProofAccount struct {
+	Address     common.Address      `json:"address"`
+	Balance     *hexutil.Big        `json:"balance"`
+	CodeHash    common.Hash         `json:"codeHash"`
+	Nonce       hexutil.Uint64      `json:"nonce"`
+	StorageHash common.Hash         `json:"storageHash"`
+	Storage     []MultiProofStorage `json:"storage"`
+}
+
+// MultiProofStorage is the value of a proven storage slot.
+type MultiProofStorage struct {
+	Key   string       `json:"key"`
+	Value *hexutil.Big `json:"value"`
+}
+
+// MultiProofResult is a batch of accounts and storage slots proven against the
+// state root of a block. The trie nodes of all the proofs are deduplicated into
+// a single list, against which every account and slot can be verified.
+type MultiProofResult struct {
+	StateRoot common.Hash         `json:"stateRoot"`
+	Accounts  []MultiProofAccount `json:"accounts"`
+	Proof     []string            `json:"proof"`
+}
+
+// GetMultiProof returns the deduplicated Merkle-proof of the given accounts and
+// their storage slots at the given block.
+func (api *DebugAPI) GetMultiProof(ctx context.Context, queries []MultiProofQuery, blockNrOrHash rpc.BlockNumberOrHash) (*MultiProofResult, error) {
+	state, header, err := api.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	var (
+		seen   = make(map[string]struct{})
+		result = &MultiProofResult{
+			StateRoot: header.Root,
+			Accounts:  make([]MultiProofAccount, 0, len(queries)),
+			Proof:     []string{},
+		}
+	)
+	collect := func(proof [][]byte) {
+		for _, node := range proof {
+			if _, ok := seen[string(node)]; ok {
+				continue
+			}
+			seen[string(node)] = struct{}{}
+			result.Proof = append(result.Proof, hexutil.Encode(node))
+		}
+	}
+	for _, query := range queries {
+		accountProof, err := state.GetProof(query.Address)
+		if err != nil {
+			return nil, err
+		}
+		collect(accountProof)
+
+		storageTrie, err := state.StorageTrie(query.Address)
+		if err != nil {
+			return nil, err
+		}
+		account := MultiProofAccount{
+			Address:     query.Address,
+			Balance:     (*hexutil.Big)(state.GetBalance(query.Address)),
+			CodeHash:    state.GetCodeHash(query.Address),
+			Nonce:       hexutil.Uint64(state.GetNonce(query.Address)),
+			StorageHash: types.EmptyRootHash,
+			Storage:     make([]MultiProofStorage, len(query.StorageKeys)),
+		}
+		if storageTrie != nil {
+			account.StorageHash = storageTrie.Hash()
+		} else {
+			// The account doesn't exist, its code hash is the hash of empty code.
+			account.CodeHash = types.EmptyCodeHash
+		}
+		for i, hexKey := range query.StorageKeys {
+			key, err := decodeHash(hexKey)
+			if err != nil {
+				return nil, err
+			}
+			account.Storage[i] = MultiProofStorage{Key: hexKey, Value: &hexutil.Big{}}
+			if storageTrie == nil {
+				continue
+			}
+			storageProof, err := state.GetStorageProof(query.Address, key)
+			if err != nil {
+				return nil, err
+			}
+			collect(storageProof)
+			account.Storage[i].Value = (*hexutil.Big)(state.GetState(query.Address, key).Big())
+		}
+		result.Accounts = append(result.Accounts, account)
+	}
+	return result, state.Error()
+}
+
 // GetRawHeader retrieves the RLP encoding for a single header.
 func (api *DebugAPI) GetRawHeader(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
 	var hash common.Hash
@@ -45,7 +45,9 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/sha3"
 )
@@ -375,6 +377,9 @@ func (b testBackend) BlockByNumberOrHash(ctx context.Context, blockNrOrHash rpc.
 	if blockNr, ok := blockNrOrHash.Number(); ok {
 		return b.BlockByNumber(ctx, blockNr)
 	}
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		return b.chain.GetBlockByHash(hash), nil
+	}
 	panic("implement me")
 }
 func (b testBackend) GetBody(ctx context.Context, hash common.Hash, number rpc.BlockNumber) (*types.Body, error) {
@@ -402,7 +407,7 @@ func (b testBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOr
 }
 func (b testBackend) PendingBlockAndReceipts() (*types.Block, types.Receipts) { panic("implement me") }
 func (b testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
-	panic("implement me")
+	return b.chain.GetReceiptsByHash(hash), nil
 }
 func (b testBackend) GetTd(ctx context.Context, hash common.Hash) *big.Int { panic("implement me") }
 func (b testBackend) GetEVM(ctx context.Context, msg *core.Message, state *state.StateDB, header *types.Header, vmConfig *vm.Config, blockContext *vm.BlockContext) (*vm.EVM, func() error) {
@@ -420,6 +425,9 @@ func (b testBackend) GetEVM(ctx context.Context, msg *core.Message, state *state
 func (b testBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
 	panic("implement me")
 }
+func (b testBackend) SubscribeStateDiffEvent(ch chan<- core.StateDiffEvent) event.Subscription {
+	panic("implement me")
+}
 func (b testBackend) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
 	panic("implement me")
 }
@@ -429,6 +437,10 @@ func (b testBackend) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) even
 func (b testBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
 	panic("implement me")
 }
+func (b testBackend) SendPrivateTx(ctx context.Context, signedTx *types.Transaction) error {
+	panic("implement me")
+}
+func (b testBackend) CheckTxPolicy(signedTx *types.Transaction) error { panic("implement me") }
 func (b testBackend) GetTransaction(ctx context.Context, txHash common.Hash) (*types.Transaction, common.Hash, uint64, uint64, error) {
 	panic("implement me")
 }
@@ -447,6 +459,9 @@ func (b testBackend) TxPoolContentFrom(addr common.Address) (types.Transactions,
 func (b testBackend) SubscribeNewTxsEvent(events chan<- core.NewTxsEvent) event.Subscription {
 	panic("implement me")
 }
+func (b testBackend) SubscribeTxPoolEvents(events chan<- []core.TxPoolEvent) event.Subscription {
+	panic("implement me")
+}
 func (b testBackend) ChainConfig() *params.ChainConfig { return b.chain.Config() }
 func (b testBackend) Engine() consensus.Engine         { return b.chain.Engine() }
 func (b testBackend) GetLogs(ctx context.Context, blockHash common.Hash, number uint64) ([][]*types.Log, error) {
@@ -461,13 +476,15 @@ func (b testBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscripti
 func (b testBackend) SubscribePendingLogsEvent(ch chan<- []*types.Log) event.Subscription {
 	panic("implement me")
 }
-func (b testBackend) BloomStatus() (uint64, uint64) { panic("implement me") }
+func (b testBackend) BloomStatus() (uint64, uint64)            { panic("implement me") }
+func (b testBackend) LogIndexStatus() (uint64, uint64, uint64) { panic("implement me") }
 func (b testBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
 	panic("implement me")
 }
 func (b testBackend) HistoricalRPCService() *rpc.Client {
 	panic("implement me")
 }
+func (b testBackend) HistoryPruningCutoff() uint64 { return b.chain.HistoryPruningCutoff() }
 func (b testBackend) Genesis() *types.Block {
 	panic("implement me")
 }
@@ -851,3 +868,168 @@ func TestRPCMarshalBlock(t *testing.T) {
 		}
 	}
 }
+
+func TestGetMultiProof(t *testing.T) {
+	t.Parallel()
+
+	var (
+		accounts = newAccounts(2)
+		contract = common.HexToAddress("0xc0de")
+		missing  = common.HexToAddress("0xdead")
+		genesis  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+				accounts[1].addr: {Balance: big.NewInt(params.Ether)},
+				contract: {
+					Balance: big.NewInt(1),
+					Code:    []byte{byte(vm.STOP)},
+					Storage: map[common.Hash]common.Hash{{0x01}: {0x11}, {0x02}: {0x22}},
+				},
+			},
+		}
+		api = NewDebugAPI(newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {}))
+	)
+	queries := []MultiProofQuery{
+		{Address: accounts[0].addr},
+		{Address: accounts[1].addr},
+		{Address: contract, StorageKeys: []string{"0x01", "0x02", "0x03"}},
+		{Address: missing, StorageKeys: []string{"0x01"}},
+	}
+	result, err := api.GetMultiProof(context.Background(), queries, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber))
+	if err != nil {
+		t.Fatalf("failed to get multiproof: %v", err)
+	}
+	// Load the deduplicated proof and verify every account and slot against it
+	proofDb := rawdb.NewMemoryDatabase()
+	for _, node := range result.Proof {
+		blob := hexutil.MustDecode(node)
+		proofDb.Put(crypto.Keccak256(blob), blob)
+	}
+	if len(result.Accounts) != len(queries) {
+		t.Fatalf("account count mismatch: have %d, want %d", len(result.Accounts), len(queries))
+	}
+	for i, account := range result.Accounts {
+		blob, err := trie.VerifyProof(result.StateRoot, crypto.Keccak256(account.Address.Bytes()), proofDb)
+		if err != nil {
+			t.Fatalf("account %x: invalid proof: %v", account.Address, err)
+		}
+		if account.Address == missing {
+			if blob != nil {
+				t.Fatalf("missing account proven to exist")
+			}
+			continue
+		}
+		var data types.StateAccount
+		if err := rlp.DecodeBytes(blob, &data); err != nil {
+			t.Fatalf("account %x: invalid value: %v", account.Address, err)
+		}
+		if data.Balance.Cmp(account.Balance.ToInt()) != 0 || data.Root != account.StorageHash {
+			t.Fatalf("account %x: content mismatch", account.Address)
+		}
+		for _, slot := range account.Storage {
+			key := common.HexToHash(slot.Key)
+			blob, err := trie.VerifyProof(account.StorageHash, crypto.Keccak256(key.Bytes()), proofDb)
+			if err != nil {
+				t.Fatalf("slot %x of %x: invalid proof: %v", key, account.Address, err)
+			}
+			var value []byte
+			if blob != nil {
+				if _, value, _, err = rlp.Split(blob); err != nil {
+					t.Fatalf("slot %x of %x: invalid value: %v", key, account.Address, err)
+				}
+			}
+			if new(big.Int).SetBytes(value).Cmp(slot.Value.ToInt()) != 0 {
+				t.Fatalf("slot %x of %x: value mismatch", key, account.Address)
+			}
+			if want := genesis.Alloc[queries[i].Address].Storage[key]; want.Big().Cmp(slot.Value.ToInt()) != 0 {
+				t.Fatalf("slot %x of %x: have %v, want %x", key, account.Address, slot.Value, want)
+			}
+		}
+	}
+	// The shared upper nodes must be deduplicated
+	seen := make(map[string]bool)
+	for _, node := range result.Proof {
+		if seen[node] {
+			t.Fatalf("duplicated proof node %s", node)
+		}
+		seen[node] = true
+	}
+}
+
+func TestRPCGetBlockReceipts(t *testing.T) {
+	t.Parallel()
+
+	var (
+		accounts = newAccounts(2)
+		genesis  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+			},
+		}
+		genBlocks = 3
+		signer    = types.LatestSignerForChainID(params.TestChainConfig.ChainID)
+	)
+	backend := newTestBackend(t, genBlocks, genesis, func(i int, b *core.BlockGen) {
+		// Block 1 is empty, the others hold a transfer and a contract creation
+		if i == 0 {
+			return
+		}
+		nonce := b.TxNonce(accounts[0].addr)
+		tx, _ := types.SignTx(types.NewTx(&types.LegacyTx{Nonce: nonce, To: &accounts[1].addr, Value: big.NewInt(1000), Gas: params.TxGas, GasPrice: b.BaseFee()}), signer, accounts[0].key)
+		b.AddTx(tx)
+		tx, _ = types.SignTx(types.NewTx(&types.DynamicFeeTx{Nonce: nonce + 1, Gas: 100000, GasFeeCap: b.BaseFee(), Data: common.FromHex("0x60006000f3")}), signer, accounts[0].key)
+		b.AddTx(tx)
+	})
+	api := NewBlockChainAPI(backend)
+
+	block2 := backend.chain.GetBlockByNumber(2)
+	for i, tt := range []struct {
+		block rpc.BlockNumberOrHash
+		want  *types.Block
+	}{
+		{rpc.BlockNumberOrHashWithNumber(0), backend.chain.GetBlockByNumber(0)},
+		{rpc.BlockNumberOrHashWithNumber(1), backend.chain.GetBlockByNumber(1)},
+		{rpc.BlockNumberOrHashWithHash(block2.Hash(), false), block2},
+		{rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), backend.chain.GetBlockByNumber(3)},
+		{rpc.BlockNumberOrHashWithNumber(10), nil},
+		{rpc.BlockNumberOrHashWithHash(common.Hash{0x1}, false), nil},
+	} {
+		result, err := api.GetBlockReceipts(context.Background(), tt.block)
+		if err != nil {
+			t.Fatalf("test %d: error %v", i, err)
+		}
+		if tt.want == nil {
+			if result != nil {
+				t.Errorf("test %d: receipts of missing block: %v", i, result)
+			}
+			continue
+		}
+		receipts := backend.chain.GetReceiptsByHash(tt.want.Hash())
+		if len(result) != len(receipts) {
+			t.Fatalf("test %d: receipt count mismatch: have %d, want %d", i, len(result), len(receipts))
+		}
+		for j, receipt := range receipts {
+			have := result[j].marshal()
+			if have["transactionHash"] != tt.want.Transactions()[j].Hash() {
+				t.Errorf("test %d, receipt %d: transaction hash mismatch: %v", i, j, have["transactionHash"])
+			}
+			if have["transactionIndex"] != hexutil.Uint64(j) || have["blockHash"] != tt.want.Hash() {
+				t.Errorf("test %d, receipt %d: location mismatch: %v, %v", i, j, have["transactionIndex"], have["blockHash"])
+			}
+			if have["from"] != accounts[0].addr {
+				t.Errorf("test %d, receipt %d: sender mismatch: %v", i, j, have["from"])
+			}
+			if have["cumulativeGasUsed"] != hexutil.Uint64(receipt.CumulativeGasUsed) || have["status"] != hexutil.Uint(types.ReceiptStatusSuccessful) {
+				t.Errorf("test %d, receipt %d: result mismatch: %v", i, j, have)
+			}
+			if have["contractAddress"] != nil && have["contractAddress"] != receipt.ContractAddress {
+				t.Errorf("test %d, receipt %d: contract address mismatch: %v", i, j, have["contractAddress"])
+			}
+			if created := j == 1; created != (have["contractAddress"] != nil) {
+				t.Errorf("test %d, receipt %d: contract creation mismatch: %v", i, j, have["contractAddress"])
+			}
+		}
+	}
+}
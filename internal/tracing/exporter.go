@@ -0,0 +1,257 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// exporter delivers finished spans to their destination.
+type exporter interface {
+	Export(spans []*Span) error
+	Close() error
+}
+
+// The types below are the JSON encoding of an OTLP ExportTraceServiceRequest, as
+// accepted by collectors on the /v1/traces endpoint and written by the file
+// exporter of the OpenTelemetry collector.
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              SpanKind        `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            *otlpStatus     `json:"status,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"` // 2 = error
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+// newOTLPValue converts an attribute value into its OTLP representation.
+func newOTLPValue(v interface{}) otlpValue {
+	var (
+		str string
+		num int64
+	)
+	switch v := v.(type) {
+	case string:
+		str = v
+	case bool:
+		return otlpValue{BoolValue: &v}
+	case int:
+		num = int64(v)
+	case int64:
+		num = v
+	case uint64:
+		if v > 1<<63-1 {
+			str = strconv.FormatUint(v, 10)
+			break
+		}
+		num = int64(v)
+	case float64:
+		return otlpValue{DoubleValue: &v}
+	case time.Duration:
+		num = v.Nanoseconds()
+	case fmt.Stringer:
+		str = v.String()
+	case error:
+		str = v.Error()
+	default:
+		str = fmt.Sprint(v)
+	}
+	if str != "" {
+		return otlpValue{StringValue: &str}
+	}
+	enc := strconv.FormatInt(num, 10)
+	return otlpValue{IntValue: &enc}
+}
+
+// newOTLPAttributes converts alternating keys and values into OTLP attributes.
+func newOTLPAttributes(kv []interface{}) []otlpAttribute {
+	attrs := make([]otlpAttribute, 0, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprint(kv[i])
+		}
+		attrs = append(attrs, otlpAttribute{Key: key, Value: newOTLPValue(kv[i+1])})
+	}
+	return attrs
+}
+
+// encodeOTLP encodes the given spans as an OTLP export request.
+func encodeOTLP(service string, spans []*Span) ([]byte, error) {
+	encoded := make([]otlpSpan, len(spans))
+	for i, span := range spans {
+		span.lock.Lock()
+		encoded[i] = otlpSpan{
+			TraceID:           hex.EncodeToString(span.context.TraceID[:]),
+			SpanID:            hex.EncodeToString(span.context.SpanID[:]),
+			Name:              span.name,
+			Kind:              span.kind,
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+			Attributes:        newOTLPAttributes(span.attrs),
+		}
+		if span.parent != (SpanID{}) {
+			encoded[i].ParentSpanID = hex.EncodeToString(span.parent[:])
+		}
+		if span.err != "" {
+			encoded[i].Status = &otlpStatus{Code: 2, Message: span.err}
+		}
+		span.lock.Unlock()
+	}
+	return json.Marshal(&otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: newOTLPAttributes([]interface{}{"service.name", service, "service.version", params.VersionWithMeta}),
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "github.com/ethereum/go-ethereum"},
+				Spans: encoded,
+			}},
+		}},
+	})
+}
+
+// httpExporter sends spans to an OTLP/HTTP collector.
+type httpExporter struct {
+	endpoint string
+	service  string
+	client   *http.Client
+}
+
+func newHTTPExporter(endpoint, service string) (*httpExporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid trace collector endpoint %q", endpoint)
+	}
+	return &httpExporter{
+		endpoint: endpoint,
+		service:  service,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Export implements exporter.
+func (e *httpExporter) Export(spans []*Span) error {
+	blob, err := encodeOTLP(e.service, spans)
+	if err != nil {
+		return err
+	}
+	res, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(blob))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("collector responded %s: %s", res.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// Close implements exporter.
+func (e *httpExporter) Close() error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// fileExporter appends spans to a file, one export request per line.
+type fileExporter struct {
+	file    *os.File
+	service string
+}
+
+func newFileExporter(path, service string) (*fileExporter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &fileExporter{file: file, service: service}, nil
+}
+
+// Export implements exporter.
+func (e *fileExporter) Export(spans []*Span) error {
+	blob, err := encodeOTLP(e.service, spans)
+	if err != nil {
+		return err
+	}
+	_, err = e.file.Write(append(blob, '\n'))
+	return err
+}
+
+// Close implements exporter.
+func (e *fileExporter) Close() error {
+	return e.file.Close()
+}
@@ -0,0 +1,377 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package tracing implements span based request tracing. Trace contexts are
+// propagated using the W3C Trace Context format and finished spans are exported
+// in the OpenTelemetry protocol (OTLP) JSON encoding.
+//
+// Tracing is disabled by default, in which case starting a span returns a nil
+// span. All span methods are safe to call on nil spans, so instrumented code
+// doesn't need to check whether tracing is enabled.
+package tracing
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// TraceID identifies a trace, i.e. a tree of spans.
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+// SpanContext is the propagated part of a span.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid reports whether both the trace and span IDs are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != (TraceID{}) && sc.SpanID != (SpanID{})
+}
+
+// Traceparent encodes the span context as a W3C traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%x-%x-%s", sc.TraceID[:], sc.SpanID[:], flags)
+}
+
+// ParseTraceparent decodes a W3C traceparent header value.
+func ParseTraceparent(header string) (SpanContext, error) {
+	var sc SpanContext
+
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, errors.New("malformed traceparent")
+	}
+	// Version 00 has exactly four fields, future versions may append more
+	if parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return sc, errors.New("invalid traceparent version")
+	}
+	var flags [1]byte
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, fmt.Errorf("invalid trace id: %v", err)
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, fmt.Errorf("invalid span id: %v", err)
+	}
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return sc, fmt.Errorf("invalid trace flags: %v", err)
+	}
+	if !sc.IsValid() {
+		return sc, errors.New("zero trace or span id")
+	}
+	sc.Sampled = flags[0]&0x01 != 0
+	return sc, nil
+}
+
+// SpanKind describes the relationship of a span to its surroundings, using the
+// values of the OTLP specification.
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+// Span is a timed operation within a trace.
+type Span struct {
+	tracer  *tracer
+	context SpanContext
+	parent  SpanID
+	name    string
+	kind    SpanKind
+	start   time.Time
+
+	lock  sync.Mutex
+	end   time.Time
+	attrs []interface{} // Alternating keys and values
+	err   string
+	ended bool
+}
+
+// Context returns the span context, which is the zero value for nil spans.
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.context
+}
+
+// SetAttributes adds the given key-value pairs to the span.
+func (s *Span) SetAttributes(kv ...interface{}) {
+	if s == nil || !s.context.Sampled {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.attrs = append(s.attrs, kv...)
+}
+
+// SetError marks the span as failed if err is non-nil.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil || !s.context.Sampled {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.err = err.Error()
+}
+
+// End finishes the span and queues it for export. Calls after the first one are
+// ignored.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	if s.ended {
+		s.lock.Unlock()
+		return
+	}
+	s.ended, s.end = true, time.Now()
+	s.lock.Unlock()
+
+	if s.context.Sampled {
+		s.tracer.enqueue(s)
+	}
+}
+
+type spanContextKey struct{}
+
+type remoteParentKey struct{}
+
+// ContextWithRemoteParent returns a context carrying a span context received
+// from a remote caller. Spans started with the returned context become children
+// of the remote span.
+func ContextWithRemoteParent(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteParentKey{}, sc)
+}
+
+// SpanFromContext returns the current span of the context, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// StartSpan starts an internal span as a child of the context's current span.
+// The returned context carries the new span. If tracing is disabled, the context
+// is returned unchanged together with a nil span.
+func StartSpan(ctx context.Context, name string, kv ...interface{}) (context.Context, *Span) {
+	return startSpan(ctx, name, SpanKindInternal, kv)
+}
+
+// StartServerSpan starts a span handling a request of a remote caller.
+func StartServerSpan(ctx context.Context, name string, kv ...interface{}) (context.Context, *Span) {
+	return startSpan(ctx, name, SpanKindServer, kv)
+}
+
+func startSpan(ctx context.Context, name string, kind SpanKind, kv []interface{}) (context.Context, *Span) {
+	t := active.Load()
+	if t == nil {
+		return ctx, nil
+	}
+	span := &Span{
+		tracer: t,
+		name:   name,
+		kind:   kind,
+		start:  time.Now(),
+	}
+	// Inherit the trace from the local or remote parent, or start a new one
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.context.TraceID = parent.context.TraceID
+		span.context.Sampled = parent.context.Sampled
+		span.parent = parent.context.SpanID
+	} else if remote, ok := ctx.Value(remoteParentKey{}).(SpanContext); ok && remote.IsValid() {
+		span.context.TraceID = remote.TraceID
+		span.context.Sampled = remote.Sampled
+		span.parent = remote.SpanID
+	} else {
+		crand.Read(span.context.TraceID[:])
+		span.context.Sampled = t.sample(span.context.TraceID)
+	}
+	crand.Read(span.context.SpanID[:])
+	if span.context.Sampled {
+		span.attrs = kv
+	}
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// Config contains the settings of the tracer.
+type Config struct {
+	Endpoint    string  // OTLP/HTTP traces endpoint of a collector
+	File        string  // File to write the traces to, instead of a collector
+	SampleRatio float64 // Fraction of the new traces to record
+	ServiceName string  // Service name reported in the traces
+}
+
+// DefaultConfig is the default tracer configuration.
+var DefaultConfig = Config{
+	Endpoint:    "http://localhost:4318/v1/traces",
+	SampleRatio: 1,
+	ServiceName: "geth",
+}
+
+const (
+	exportBatchSize = 512             // Maximum number of spans exported at once
+	exportInterval  = 5 * time.Second // Interval of exporting partial batches
+	exportQueueSize = 4096            // Number of spans queued before dropping
+)
+
+// active is the running tracer, nil if tracing is disabled.
+var active atomic.Pointer[tracer]
+
+// tracer samples spans and exports the finished ones in batches.
+type tracer struct {
+	threshold uint64 // Trace ID values below which traces are sampled
+	exporter  exporter
+	queue     chan *Span
+	dropped   atomic.Uint64
+	quit      chan chan struct{}
+}
+
+// Start enables tracing with the given configuration.
+func Start(config Config) error {
+	var (
+		exp exporter
+		err error
+	)
+	if config.ServiceName == "" {
+		config.ServiceName = DefaultConfig.ServiceName
+	}
+	if config.File != "" {
+		exp, err = newFileExporter(config.File, config.ServiceName)
+	} else {
+		exp, err = newHTTPExporter(config.Endpoint, config.ServiceName)
+	}
+	if err != nil {
+		return err
+	}
+	t := &tracer{
+		exporter: exp,
+		queue:    make(chan *Span, exportQueueSize),
+		quit:     make(chan chan struct{}),
+	}
+	switch {
+	case config.SampleRatio >= 1:
+		t.threshold = ^uint64(0)
+	case config.SampleRatio > 0:
+		t.threshold = uint64(config.SampleRatio * (1 << 63) * 2)
+	}
+	if !active.CompareAndSwap(nil, t) {
+		exp.Close()
+		return errors.New("tracing already started")
+	}
+	go t.loop()
+	return nil
+}
+
+// Stop disables tracing, exporting all spans finished so far.
+func Stop() {
+	t := active.Swap(nil)
+	if t == nil {
+		return
+	}
+	done := make(chan struct{})
+	t.quit <- done
+	<-done
+}
+
+// Enabled reports whether tracing is enabled.
+func Enabled() bool {
+	return active.Load() != nil
+}
+
+// sample decides whether a new trace is recorded, based on the random trace ID
+// so that the decision is consistent for the whole trace.
+func (t *tracer) sample(id TraceID) bool {
+	if t.threshold == ^uint64(0) {
+		return true
+	}
+	return binary.BigEndian.Uint64(id[8:]) < t.threshold
+}
+
+// enqueue adds a finished span to the export queue, dropping it if the queue
+// is full.
+func (t *tracer) enqueue(span *Span) {
+	select {
+	case t.queue <- span:
+	default:
+		t.dropped.Add(1)
+	}
+}
+
+// loop exports the queued spans in batches.
+func (t *tracer) loop() {
+	var (
+		batch  = make([]*Span, 0, exportBatchSize)
+		ticker = time.NewTicker(exportInterval)
+	)
+	defer ticker.Stop()
+
+	export := func() {
+		if dropped := t.dropped.Swap(0); dropped > 0 {
+			log.Warn("Dropped trace spans, export queue full", "count", dropped)
+		}
+		if len(batch) == 0 {
+			return
+		}
+		if err := t.exporter.Export(batch); err != nil {
+			log.Warn("Failed to export trace spans", "count", len(batch), "err", err)
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case span := <-t.queue:
+			if batch = append(batch, span); len(batch) >= exportBatchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case done := <-t.quit:
+			// Export the remaining queued spans before closing
+			for len(t.queue) > 0 {
+				if batch = append(batch, <-t.queue); len(batch) >= exportBatchSize {
+					export()
+				}
+			}
+			export()
+			t.exporter.Close()
+			close(done)
+			return
+		}
+	}
+}
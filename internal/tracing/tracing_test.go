@@ -0,0 +1,195 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTraceparent(t *testing.T) {
+	header := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := ParseTraceparent(header)
+	if err != nil {
+		t.Fatalf("failed to parse traceparent: %v", err)
+	}
+	if !sc.Sampled || sc.TraceID[0] != 0x4b || sc.SpanID[7] != 0xb7 {
+		t.Fatalf("parsed span context mismatch: %+v", sc)
+	}
+	if have := sc.Traceparent(); have != header {
+		t.Fatalf("traceparent mismatch: have %s, want %s", have, header)
+	}
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-xbf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz",
+	} {
+		if _, err := ParseTraceparent(invalid); err == nil {
+			t.Errorf("invalid traceparent %q accepted", invalid)
+		}
+	}
+	// Future versions may carry additional fields
+	if _, err := ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"); err != nil {
+		t.Errorf("future traceparent version rejected: %v", err)
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	ctx, span := StartSpan(context.Background(), "test")
+	if span != nil || SpanFromContext(ctx) != nil {
+		t.Fatal("span started with tracing disabled")
+	}
+	// Methods of nil spans are no-ops
+	span.SetAttributes("key", "value")
+	span.SetError(errors.New("failure"))
+	span.End()
+}
+
+// readTrace reads the spans written by the file exporter.
+func readTrace(t *testing.T, path string) []otlpSpan {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var spans []otlpSpan
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var req otlpRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			t.Fatalf("invalid export request: %v", err)
+		}
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+	}
+	return spans
+}
+
+func TestFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.json")
+	if err := Start(Config{File: path, SampleRatio: 1}); err != nil {
+		t.Fatal(err)
+	}
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	ctx, root := StartServerSpan(ContextWithRemoteParent(context.Background(), remote), "root", "method", "eth_call")
+	_, child := StartSpan(ctx, "child")
+	child.SetAttributes("count", 3, "ok", true)
+	child.SetError(errors.New("failure"))
+	child.End()
+	root.End()
+	root.End()
+	Stop()
+
+	spans := readTrace(t, path)
+	if len(spans) != 2 {
+		t.Fatalf("span count mismatch: have %d, want 2", len(spans))
+	}
+	have, want := spans[0], spans[1]
+	if have.Name != "child" || want.Name != "root" {
+		t.Fatalf("span order mismatch: %s, %s", have.Name, want.Name)
+	}
+	if want.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || want.ParentSpanID != "00f067aa0ba902b7" || want.Kind != SpanKindServer {
+		t.Errorf("root span mismatch: %+v", want)
+	}
+	if have.TraceID != want.TraceID || have.ParentSpanID != want.SpanID || have.Kind != SpanKindInternal {
+		t.Errorf("child span mismatch: %+v", have)
+	}
+	if have.Status == nil || have.Status.Message != "failure" {
+		t.Errorf("child status mismatch: %+v", have.Status)
+	}
+	if len(have.Attributes) != 2 || *have.Attributes[0].Value.IntValue != "3" || !*have.Attributes[1].Value.BoolValue {
+		t.Errorf("child attributes mismatch: %+v", have.Attributes)
+	}
+	if len(want.Attributes) != 1 || *want.Attributes[0].Value.StringValue != "eth_call" {
+		t.Errorf("root attributes mismatch: %+v", want.Attributes)
+	}
+}
+
+func TestSampling(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.json")
+	if err := Start(Config{File: path, SampleRatio: 0}); err != nil {
+		t.Fatal(err)
+	}
+	// New traces are dropped, but sampled remote parents are honoured
+	_, dropped := StartSpan(context.Background(), "dropped")
+	dropped.End()
+
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	_, kept := StartServerSpan(ContextWithRemoteParent(context.Background(), remote), "kept")
+	kept.End()
+	Stop()
+
+	spans := readTrace(t, path)
+	if len(spans) != 1 || spans[0].Name != "kept" {
+		t.Fatalf("sampled spans mismatch: %+v", spans)
+	}
+}
+
+func TestHTTPExporter(t *testing.T) {
+	requests := make(chan otlpRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req otlpRequest
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("content-type") != "application/json" {
+			http.Error(w, "bad content type", http.StatusUnsupportedMediaType)
+			return
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		requests <- req
+	}))
+	defer srv.Close()
+
+	if err := Start(Config{Endpoint: srv.URL, SampleRatio: 1, ServiceName: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	_, span := StartSpan(context.Background(), "test")
+	span.End()
+	Stop()
+
+	req := <-requests
+	if len(req.ResourceSpans) != 1 {
+		t.Fatalf("resource count mismatch: have %d, want 1", len(req.ResourceSpans))
+	}
+	attrs := req.ResourceSpans[0].Resource.Attributes
+	if attrs[0].Key != "service.name" || *attrs[0].Value.StringValue != "test" {
+		t.Errorf("service name mismatch: %+v", attrs[0])
+	}
+	if spans := req.ResourceSpans[0].ScopeSpans[0].Spans; len(spans) != 1 || spans[0].Name != "test" {
+		t.Errorf("exported spans mismatch: %+v", spans)
+	}
+}
@@ -172,6 +172,13 @@ func (t *odrTrie) Commit(collectLeaf bool) (common.Hash, *trienode.NodeSet) {
 	return t.trie.Commit(collectLeaf)
 }
 
+func (t *odrTrie) Witness() map[string]struct{} {
+	if t.trie == nil {
+		return nil
+	}
+	return t.trie.Witness()
+}
+
 func (t *odrTrie) Hash() common.Hash {
 	if t.trie == nil {
 		return t.id.Root
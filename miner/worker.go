@@ -95,6 +95,7 @@ type environment struct {
 	family    mapset.Set[common.Hash] // family set (used for checking uncle invalidity)
 	tcount    int                     // tx count in cycle
 	gasPool   *core.GasPool           // available gas used to pack transactions
+	blobs     int                     // number of blobs included, capped by the data gas limit
 	coinbase  common.Address
 
 	header   *types.Header
@@ -112,6 +113,7 @@ func (env *environment) copy() *environment {
 		family:    env.family.Clone(),
 		tcount:    env.tcount,
 		coinbase:  env.coinbase,
+		blobs:     env.blobs,
 		header:    types.CopyHeader(env.header),
 		receipts:  copyReceipts(env.receipts),
 	}
@@ -836,6 +838,7 @@ func (w *worker) makeEnv(parent *types.Header, header *types.Header, coinbase co
 	if err != nil {
 		return nil, err
 	}
+	w.chain.TrackStateOrigins(state)
 	state.StartPrefetcher("miner")
 
 	// Note the passed coinbase may be different with header.Coinbase.
@@ -910,8 +913,10 @@ func (w *worker) commitTransaction(env *environment, tx *types.Transaction) ([]*
 		env.gasPool.SetGas(gp)
 		return nil, err
 	}
-	env.txs = append(env.txs, tx)
+	// Blob sidecars are never part of the block, only the versioned hashes are
+	env.txs = append(env.txs, tx.WithoutBlobTxSidecar())
 	env.receipts = append(env.receipts, receipt)
+	env.blobs += len(tx.BlobHashes())
 
 	return receipt.Logs, nil
 }
@@ -952,6 +957,20 @@ func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByP
 			txs.Pop()
 			continue
 		}
+		// Blob transactions are only allowed post-Cancun and are capped by the
+		// block-level data gas limit, independently of the execution gas.
+		if tx.Type() == types.BlobTxType {
+			if !w.chainConfig.IsCancun(env.header.Number, env.header.Time) {
+				log.Trace("Ignoring blob transaction before Cancun", "hash", tx.Hash())
+				txs.Pop()
+				continue
+			}
+			if left := uint64(params.MaxDataGasPerBlock - env.blobs*params.BlobTxDataGasPerBlob); left < tx.BlobGas() {
+				log.Trace("Not enough data gas left for transaction", "hash", tx.Hash(), "left", left, "needed", tx.BlobGas())
+				txs.Pop()
+				continue
+			}
+		}
 		// Start executing the transaction
 		env.state.SetTxContext(tx.Hash(), env.tcount)
 
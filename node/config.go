@@ -129,6 +129,10 @@ type Config struct {
 	// exposed.
 	HTTPModules []string
 
+	// HTTPMethods restricts the methods callable via the HTTP RPC interface within
+	// the exposed modules.
+	HTTPMethods rpc.MethodPolicy `toml:",omitempty"`
+
 	// HTTPTimeouts allows for customization of the timeout values used by the HTTP RPC
 	// interface.
 	HTTPTimeouts rpc.HTTPTimeouts
@@ -146,6 +150,19 @@ type Config struct {
 	// for the authenticated api. This is by default {'localhost'}.
 	AuthVirtualHosts []string `toml:",omitempty"`
 
+	// AuthModules is the list of API modules exposed on the authenticated api. This
+	// is by default {'eth', 'engine'}.
+	AuthModules []string `toml:",omitempty"`
+
+	// AuthMethods restricts the methods callable on the authenticated api within
+	// the exposed modules.
+	AuthMethods rpc.MethodPolicy `toml:",omitempty"`
+
+	// AuthIdentities further restricts the methods callable with tokens carrying
+	// an id claim, keyed by the claim value. The policy under the key "*" applies
+	// to all tokens without a policy of their own.
+	AuthIdentities map[string]rpc.MethodPolicy `toml:",omitempty"`
+
 	// WSHost is the host interface on which to start the websocket RPC server. If
 	// this field is empty, no websocket API endpoint will be started.
 	WSHost string
@@ -168,6 +185,10 @@ type Config struct {
 	// exposed.
 	WSModules []string
 
+	// WSMethods restricts the methods callable via the websocket RPC interface
+	// within the exposed modules.
+	WSMethods rpc.MethodPolicy `toml:",omitempty"`
+
 	// WSExposeAll exposes all API modules via the WebSocket RPC interface rather
 	// than just the public ones.
 	//
@@ -175,6 +196,22 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// BinaryHost is the host interface on which to start the binary RPC server. If
+	// this field is empty, no binary API endpoint will be started.
+	BinaryHost string `toml:",omitempty"`
+
+	// BinaryPort is the TCP port number on which to start the binary RPC server.
+	BinaryPort int `toml:",omitempty"`
+
+	// BinaryModules is a list of API modules to expose via the binary RPC interface.
+	// If the module list is empty, all RPC API endpoints designated public will be
+	// exposed.
+	BinaryModules []string `toml:",omitempty"`
+
+	// BinaryMethods restricts the methods callable via the binary RPC interface
+	// within the exposed modules.
+	BinaryMethods rpc.MethodPolicy `toml:",omitempty"`
+
 	// GraphQLCors is the Cross-Origin Resource Sharing header to send to requesting
 	// clients. Please be aware that CORS is a browser enforced security, it's fully
 	// useless for custom HTTP clients.
@@ -200,10 +237,31 @@ type Config struct {
 	// JWTSecret is the path to the hex-encoded jwt secret.
 	JWTSecret string `toml:",omitempty"`
 
+	// BatchRequestLimit is the maximum number of requests in a batch.
+	BatchRequestLimit int `toml:",omitempty"`
+
+	// BatchResponseMaxSize is the maximum number of bytes returned from a batched rpc call.
+	BatchResponseMaxSize int `toml:",omitempty"`
+
+	// RPCCostLimit configures the per-connection and per-IP call budgets of the
+	// public RPC endpoints (HTTP, WebSocket and IPC).
+	RPCCostLimit rpc.CostLimitConfig `toml:",omitempty"`
+
 	// EnablePersonal enables the deprecated personal namespace.
 	EnablePersonal bool `toml:"-"`
 
 	DBEngine string `toml:",omitempty"`
+
+	// AncientColdStore is the location of the cold store sealed ancient segments
+	// are moved into, either a directory or an s3:// URL. Empty disables it.
+	AncientColdStore string `toml:",omitempty"`
+
+	// AncientColdThreshold is the number of recent ancient blocks whose segments
+	// are kept locally.
+	AncientColdThreshold uint64 `toml:",omitempty"`
+
+	// AncientColdCache is the number of cold segments cached locally for reading.
+	AncientColdCache int `toml:",omitempty"`
 }
 
 // IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
@@ -275,6 +333,15 @@ func (c *Config) WSEndpoint() string {
 	return fmt.Sprintf("%s:%d", c.WSHost, c.WSPort)
 }
 
+// BinaryEndpoint resolves a binary RPC endpoint based on the configured host
+// interface and port parameters.
+func (c *Config) BinaryEndpoint() string {
+	if c.BinaryHost == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", c.BinaryHost, c.BinaryPort)
+}
+
 // DefaultWSEndpoint returns the websocket endpoint used by default.
 func DefaultWSEndpoint() string {
 	config := &Config{WSHost: DefaultWSHost, WSPort: DefaultWSPort}
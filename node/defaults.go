@@ -28,12 +28,14 @@ import (
 )
 
 const (
-	DefaultHTTPHost = "localhost" // Default host interface for the HTTP RPC server
-	DefaultHTTPPort = 8545        // Default TCP port for the HTTP RPC server
-	DefaultWSHost   = "localhost" // Default host interface for the websocket RPC server
-	DefaultWSPort   = 8546        // Default TCP port for the websocket RPC server
-	DefaultAuthHost = "localhost" // Default host interface for the authenticated apis
-	DefaultAuthPort = 8551        // Default port for the authenticated apis
+	DefaultHTTPHost   = "localhost" // Default host interface for the HTTP RPC server
+	DefaultHTTPPort   = 8545        // Default TCP port for the HTTP RPC server
+	DefaultWSHost     = "localhost" // Default host interface for the websocket RPC server
+	DefaultWSPort     = 8546        // Default TCP port for the websocket RPC server
+	DefaultAuthHost   = "localhost" // Default host interface for the authenticated apis
+	DefaultAuthPort   = 8551        // Default port for the authenticated apis
+	DefaultBinaryHost = "localhost" // Default host interface for the binary RPC server
+	DefaultBinaryPort = 8549        // Default TCP port for the binary RPC server
 )
 
 var (
@@ -46,23 +48,30 @@ var (
 
 // DefaultConfig contains reasonable default settings.
 var DefaultConfig = Config{
-	DataDir:             DefaultDataDir(),
-	HTTPPort:            DefaultHTTPPort,
-	AuthAddr:            DefaultAuthHost,
-	AuthPort:            DefaultAuthPort,
-	AuthVirtualHosts:    DefaultAuthVhosts,
-	HTTPModules:         []string{"net", "web3"},
-	HTTPVirtualHosts:    []string{"localhost"},
-	HTTPTimeouts:        rpc.DefaultHTTPTimeouts,
-	WSPort:              DefaultWSPort,
-	WSModules:           []string{"net", "web3"},
-	GraphQLVirtualHosts: []string{"localhost"},
+	DataDir:              DefaultDataDir(),
+	HTTPPort:             DefaultHTTPPort,
+	AuthAddr:             DefaultAuthHost,
+	AuthPort:             DefaultAuthPort,
+	AuthVirtualHosts:     DefaultAuthVhosts,
+	AuthModules:          DefaultAuthModules,
+	HTTPModules:          []string{"net", "web3"},
+	HTTPVirtualHosts:     []string{"localhost"},
+	HTTPTimeouts:         rpc.DefaultHTTPTimeouts,
+	WSPort:               DefaultWSPort,
+	WSModules:            []string{"net", "web3"},
+	BinaryPort:           DefaultBinaryPort,
+	BinaryModules:        []string{"net", "web3"},
+	GraphQLVirtualHosts:  []string{"localhost"},
+	BatchRequestLimit:    1000,
+	BatchResponseMaxSize: 25 * 1000 * 1000,
 	P2P: p2p.Config{
 		ListenAddr: ":30303",
 		MaxPeers:   50,
 		NAT:        nat.Any(),
 	},
-	DBEngine: "", // Use whatever exists, will default to Pebble if non-existent and supported
+	DBEngine:             "", // Use whatever exists, will default to Pebble if non-existent and supported
+	AncientColdThreshold: 1000000,
+	AncientColdCache:     2,
 }
 
 // DefaultDataDir is the default data directory to use for the databases and other
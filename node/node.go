@@ -55,14 +55,15 @@ type Node struct {
 	state         int           // Tracks state of node lifecycle
 
 	lock          sync.Mutex
-	lifecycles    []Lifecycle // All registered backends, services, and auxiliary services that have a lifecycle
-	rpcAPIs       []rpc.API   // List of APIs currently provided by the node
-	http          *httpServer //
-	ws            *httpServer //
-	httpAuth      *httpServer //
-	wsAuth        *httpServer //
-	ipc           *ipcServer  // Stores information about the ipc http server
-	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
+	lifecycles    []Lifecycle   // All registered backends, services, and auxiliary services that have a lifecycle
+	rpcAPIs       []rpc.API     // List of APIs currently provided by the node
+	http          *httpServer   //
+	ws            *httpServer   //
+	httpAuth      *httpServer   //
+	wsAuth        *httpServer   //
+	ipc           *ipcServer    // Stores information about the ipc http server
+	binary        *binaryServer // Stores information about the binary RPC server
+	inprocHandler *rpc.Server   // In-process RPC request handler to process the API requests
 
 	databases map[*closeTrackingDB]struct{} // All open databases
 }
@@ -152,6 +153,7 @@ func New(conf *Config) (*Node, error) {
 	node.ws = newHTTPServer(node.log, rpc.DefaultHTTPTimeouts)
 	node.wsAuth = newHTTPServer(node.log, rpc.DefaultHTTPTimeouts)
 	node.ipc = newIPCServer(node.log, conf.IPCEndpoint())
+	node.binary = newBinaryServer(node.log, conf.BinaryEndpoint())
 
 	return node, nil
 }
@@ -391,10 +393,42 @@ func (n *Node) startRPC() error {
 	if err := n.startInProc(apis); err != nil {
 		return err
 	}
+	// The call budgets are shared by all public endpoints, the authenticated
+	// endpoints are only subject to the batch limits.
+	var (
+		authRPCConfig = rpcEndpointConfig{
+			batchItemLimit:         n.config.BatchRequestLimit,
+			batchResponseSizeLimit: n.config.BatchResponseMaxSize,
+		}
+		rpcConfig = authRPCConfig
+	)
+	if n.config.RPCCostLimit.Enabled() {
+		rpcConfig.limiter = rpc.NewCostLimiter(n.config.RPCCostLimit)
+	}
+	// Each listener has its own method policy, tokens of the authenticated
+	// endpoints may be restricted further based on their identity.
+	var (
+		httpRPCConfig   = rpcConfig
+		wsRPCConfig     = rpcConfig
+		binaryRPCConfig = rpcConfig
+	)
+	httpRPCConfig.access = rpc.AccessPolicy{Methods: n.config.HTTPMethods}
+	wsRPCConfig.access = rpc.AccessPolicy{Methods: n.config.WSMethods}
+	binaryRPCConfig.access = rpc.AccessPolicy{Methods: n.config.BinaryMethods}
+	authRPCConfig.access = rpc.AccessPolicy{Methods: n.config.AuthMethods, Identities: n.config.AuthIdentities}
+	for _, config := range []rpcEndpointConfig{httpRPCConfig, wsRPCConfig, binaryRPCConfig, authRPCConfig} {
+		if err := config.access.Validate(); err != nil {
+			return err
+		}
+	}
+	authModules := n.config.AuthModules
+	if len(authModules) == 0 {
+		authModules = DefaultAuthModules
+	}
 
 	// Configure IPC.
 	if n.ipc.endpoint != "" {
-		if err := n.ipc.start(apis); err != nil {
+		if err := n.ipc.start(apis, rpcConfig); err != nil {
 			return err
 		}
 	}
@@ -412,6 +446,7 @@ func (n *Node) startRPC() error {
 			Vhosts:             n.config.HTTPVirtualHosts,
 			Modules:            n.config.HTTPModules,
 			prefix:             n.config.HTTPPathPrefix,
+			rpcEndpointConfig:  httpRPCConfig,
 		}); err != nil {
 			return err
 		}
@@ -425,9 +460,10 @@ func (n *Node) startRPC() error {
 			return err
 		}
 		if err := server.enableWS(openAPIs, wsConfig{
-			Modules: n.config.WSModules,
-			Origins: n.config.WSOrigins,
-			prefix:  n.config.WSPathPrefix,
+			Modules:           n.config.WSModules,
+			Origins:           n.config.WSOrigins,
+			prefix:            n.config.WSPathPrefix,
+			rpcEndpointConfig: wsRPCConfig,
 		}); err != nil {
 			return err
 		}
@@ -444,9 +480,10 @@ func (n *Node) startRPC() error {
 		if err := server.enableRPC(allAPIs, httpConfig{
 			CorsAllowedOrigins: DefaultAuthCors,
 			Vhosts:             n.config.AuthVirtualHosts,
-			Modules:            DefaultAuthModules,
+			Modules:            authModules,
 			prefix:             DefaultAuthPrefix,
 			jwtSecret:          secret,
+			rpcEndpointConfig:  authRPCConfig,
 		}); err != nil {
 			return err
 		}
@@ -457,10 +494,11 @@ func (n *Node) startRPC() error {
 			return err
 		}
 		if err := server.enableWS(allAPIs, wsConfig{
-			Modules:   DefaultAuthModules,
-			Origins:   DefaultAuthOrigins,
-			prefix:    DefaultAuthPrefix,
-			jwtSecret: secret,
+			Modules:           authModules,
+			Origins:           DefaultAuthOrigins,
+			prefix:            DefaultAuthPrefix,
+			jwtSecret:         secret,
+			rpcEndpointConfig: authRPCConfig,
 		}); err != nil {
 			return err
 		}
@@ -482,6 +520,12 @@ func (n *Node) startRPC() error {
 			return err
 		}
 	}
+	// Configure the binary transport.
+	if n.binary.endpoint != "" {
+		if err := n.binary.start(openAPIs, n.config.BinaryModules, binaryRPCConfig); err != nil {
+			return err
+		}
+	}
 	// Configure authenticated API
 	if len(openAPIs) != len(allAPIs) {
 		jwtSecret, err := n.obtainJWTSecret(n.config.JWTSecret)
@@ -518,6 +562,7 @@ func (n *Node) stopRPC() {
 	n.httpAuth.stop()
 	n.wsAuth.stop()
 	n.ipc.stop()
+	n.binary.stop()
 	n.stopInProc()
 }
 
@@ -661,6 +706,16 @@ func (n *Node) IPCEndpoint() string {
 	return n.ipc.endpoint
 }
 
+// BinaryEndpoint returns the URL of the binary RPC server, or the empty string
+// if the server is not running.
+func (n *Node) BinaryEndpoint() string {
+	addr := n.binary.listenAddr()
+	if addr == "" {
+		return ""
+	}
+	return "bin://" + addr
+}
+
 // HTTPEndpoint returns the URL of the HTTP server. Note that this URL does not
 // contain the JSON-RPC path prefix set by HTTPPathPrefix.
 func (n *Node) HTTPEndpoint() string {
@@ -741,6 +796,10 @@ func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, ancient
 	if n.config.DataDir == "" {
 		db = rawdb.NewMemoryDatabase()
 	} else {
+		var cold *rawdb.ColdConfig
+		if cold, err = n.AncientColdConfig(); err != nil {
+			return nil, err
+		}
 		db, err = rawdb.Open(rawdb.OpenOptions{
 			Type:              n.config.DBEngine,
 			Directory:         n.ResolvePath(name),
@@ -749,6 +808,7 @@ func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, ancient
 			Cache:             cache,
 			Handles:           handles,
 			ReadOnly:          readonly,
+			Cold:              cold,
 		})
 		log.Warn("OpenDatabaseWithFreezer", db, reflect.TypeOf(db))
 	}
@@ -775,6 +835,23 @@ func (n *Node) ResolveAncient(name string, ancient string) string {
 	return ancient
 }
 
+// AncientColdConfig returns the configuration of the cold tier of the chain
+// freezer, or nil if no cold store is configured.
+func (n *Node) AncientColdConfig() (*rawdb.ColdConfig, error) {
+	if n.config.AncientColdStore == "" {
+		return nil, nil
+	}
+	store, err := rawdb.OpenColdStore(n.config.AncientColdStore)
+	if err != nil {
+		return nil, err
+	}
+	return &rawdb.ColdConfig{
+		Store:     store,
+		Threshold: n.config.AncientColdThreshold,
+		Cache:     n.config.AncientColdCache,
+	}, nil
+}
+
 // closeTrackingDB wraps the Close method of a database. When the database is closed by the
 // service, the wrapper removes it from the node's database map. This ensures that Node
 // won't auto-close the database if it is closed by the service that opened it.
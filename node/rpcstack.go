@@ -42,6 +42,7 @@ type httpConfig struct {
 	Vhosts             []string
 	prefix             string // path prefix on which to mount http handler
 	jwtSecret          []byte // optional JWT secret
+	rpcEndpointConfig
 }
 
 // wsConfig is the JSON-RPC/Websocket configuration
@@ -50,6 +51,26 @@ type wsConfig struct {
 	Modules   []string
 	prefix    string // path prefix on which to mount ws handler
 	jwtSecret []byte // optional JWT secret
+	rpcEndpointConfig
+}
+
+// rpcEndpointConfig contains the request limits and method policy of an RPC endpoint.
+type rpcEndpointConfig struct {
+	batchItemLimit         int
+	batchResponseSizeLimit int
+	limiter                rpc.Limiter      // optional call cost accounting
+	access                 rpc.AccessPolicy // optional method restrictions
+}
+
+// apply configures the limits on the given server.
+func (c rpcEndpointConfig) apply(srv *rpc.Server) {
+	srv.SetBatchLimits(c.batchItemLimit, c.batchResponseSizeLimit)
+	if c.limiter != nil {
+		srv.SetLimiter(c.limiter)
+	}
+	if !c.access.IsEmpty() {
+		srv.SetAccessPolicy(c.access)
+	}
 }
 
 type rpcHandler struct {
@@ -189,12 +210,11 @@ func (h *httpServer) start() error {
 }
 
 func (h *httpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// check if ws request and serve if ws enabled
+	// check if ws request and serve if ws enabled. Websocket requests to other
+	// paths may be served by the handlers registered in the mux.
 	ws := h.wsHandler.Load().(*rpcHandler)
-	if ws != nil && isWebsocket(r) {
-		if checkPath(r, h.wsConfig.prefix) {
-			ws.ServeHTTP(w, r)
-		}
+	if ws != nil && isWebsocket(r) && checkPath(r, h.wsConfig.prefix) {
+		ws.ServeHTTP(w, r)
 		return
 	}
 
@@ -297,6 +317,7 @@ func (h *httpServer) enableRPC(apis []rpc.API, config httpConfig) error {
 
 	// Create RPC server and handler.
 	srv := rpc.NewServer()
+	config.apply(srv)
 	if err := RegisterApis(apis, config.Modules, srv); err != nil {
 		return err
 	}
@@ -328,6 +349,7 @@ func (h *httpServer) enableWS(apis []rpc.API, config wsConfig) error {
 	}
 	// Create RPC server and handler.
 	srv := rpc.NewServer()
+	config.apply(srv)
 	if err := RegisterApis(apis, config.Modules, srv); err != nil {
 		return err
 	}
@@ -419,6 +441,12 @@ type virtualHostHandler struct {
 	next   http.Handler
 }
 
+// NewVHostHandler returns a handler passing on the requests addressed to one of
+// the given virtual hosts and rejecting the rest.
+func NewVHostHandler(vhosts []string, next http.Handler) http.Handler {
+	return newVHostHandler(vhosts, next)
+}
+
 func newVHostHandler(vhosts []string, next http.Handler) http.Handler {
 	vhostMap := make(map[string]struct{})
 	for _, allowedHost := range vhosts {
@@ -582,14 +610,16 @@ func newIPCServer(log log.Logger, endpoint string) *ipcServer {
 }
 
 // Start starts the httpServer's http.Server
-func (is *ipcServer) start(apis []rpc.API) error {
+func (is *ipcServer) start(apis []rpc.API, config rpcEndpointConfig) error {
 	is.mu.Lock()
 	defer is.mu.Unlock()
 
 	if is.listener != nil {
 		return nil // already running
 	}
-	listener, srv, err := rpc.StartIPCEndpoint(is.endpoint, apis)
+	srv := rpc.NewServer()
+	config.apply(srv)
+	listener, err := srv.StartIPCEndpoint(is.endpoint, apis)
 	if err != nil {
 		is.log.Warn("IPC opening failed", "url", is.endpoint, "error", err)
 		return err
@@ -613,6 +643,69 @@ func (is *ipcServer) stop() error {
 	return err
 }
 
+// binaryServer serves the RPC APIs over the binary transport of package rpc.
+type binaryServer struct {
+	log      log.Logger
+	endpoint string
+
+	mu       sync.Mutex
+	listener net.Listener
+	srv      *rpc.Server
+}
+
+func newBinaryServer(log log.Logger, endpoint string) *binaryServer {
+	return &binaryServer{log: log, endpoint: endpoint}
+}
+
+// start opens the listener and serves the given modules on it.
+func (bs *binaryServer) start(apis []rpc.API, modules []string, config rpcEndpointConfig) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	if bs.listener != nil {
+		return nil // already running
+	}
+	srv := rpc.NewServer()
+	config.apply(srv)
+	if err := RegisterApis(apis, modules, srv); err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", bs.endpoint)
+	if err != nil {
+		bs.log.Warn("Binary RPC opening failed", "endpoint", bs.endpoint, "error", err)
+		return err
+	}
+	go srv.ServeBinaryListener(listener)
+	bs.log.Info("Binary RPC endpoint opened", "url", "bin://"+listener.Addr().String())
+	bs.listener, bs.srv = listener, srv
+	return nil
+}
+
+// listenAddr returns the listening address of the server.
+func (bs *binaryServer) listenAddr() string {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	if bs.listener == nil {
+		return ""
+	}
+	return bs.listener.Addr().String()
+}
+
+func (bs *binaryServer) stop() error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	if bs.listener == nil {
+		return nil // not running
+	}
+	err := bs.listener.Close()
+	bs.srv.Stop()
+	bs.listener, bs.srv = nil, nil
+	bs.log.Info("Binary RPC endpoint closed", "endpoint", bs.endpoint)
+	return err
+}
+
 // RegisterApis checks the given modules' availability, generates an allowlist based on the allowed modules,
 // and then registers all of the APIs exposed by the services.
 func RegisterApis(apis []rpc.API, modules []string, srv *rpc.Server) error {
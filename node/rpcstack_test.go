@@ -18,6 +18,7 @@ package node
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -447,6 +448,57 @@ func TestJWT(t *testing.T) {
 	srv.stop()
 }
 
+func TestJWTIdentityPolicy(t *testing.T) {
+	secret := []byte("secret")
+	srv := createAndStartServer(t, &httpConfig{
+		Modules:   []string{"test"},
+		jwtSecret: secret,
+		rpcEndpointConfig: rpcEndpointConfig{
+			access: rpc.AccessPolicy{
+				Methods:    rpc.MethodPolicy{Deny: []string{"test_sleep"}},
+				Identities: map[string]rpc.MethodPolicy{"indexer": {Allow: []string{"test_*"}}},
+			},
+		},
+	}, false, nil, nil)
+	defer srv.stop()
+	url := fmt.Sprintf("http://%v", srv.listenAddr())
+
+	call := func(method string, claims testClaim) *rpcResponse {
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+		resp := rpcRequest(t, url, method, "Authorization", "Bearer "+token)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: unexpected status %v", method, resp.StatusCode)
+		}
+		var res rpcResponse
+		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		return &res
+	}
+	now := time.Now().Unix()
+	if res := call(testMethod, testClaim{"iat": now}); res.Error != nil {
+		t.Errorf("token without identity: %s rejected: %s", testMethod, res.Error.Message)
+	}
+	if res := call(testMethod, testClaim{"iat": now, "id": "indexer"}); res.Error == nil || res.Error.Code != -32601 {
+		t.Errorf("indexer token: %s not rejected", testMethod)
+	}
+	if res := call("test_greet", testClaim{"iat": now, "id": "indexer"}); res.Error != nil {
+		t.Errorf("indexer token: test_greet rejected: %s", res.Error.Message)
+	}
+	// The endpoint policy applies to all identities
+	if res := call("test_sleep", testClaim{"iat": now, "id": "indexer"}); res.Error == nil {
+		t.Errorf("indexer token: test_sleep not rejected")
+	}
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
 func TestGzipHandler(t *testing.T) {
 	type gzipTest struct {
 		name    string
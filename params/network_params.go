@@ -32,6 +32,13 @@ const (
 	// considered probably final and its rotated bits are calculated.
 	BloomConfirms = 256
 
+	// LogIndexBlocks is the number of blocks a single log index section covers.
+	LogIndexBlocks uint64 = 1024
+
+	// LogIndexConfirms is the number of confirmation blocks before a log index
+	// section is considered final and indexed.
+	LogIndexConfirms = 64
+
 	// CHTFrequency is the block frequency for creating CHTs
 	CHTFrequency = 32768
 
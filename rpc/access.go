@@ -0,0 +1,133 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"path"
+)
+
+// MethodPolicy restricts the methods callable on a server. Entries are method
+// names or patterns as understood by path.Match, e.g. "debug_trace*".
+type MethodPolicy struct {
+	Allow []string `toml:",omitempty"` // Callable methods, all if empty
+	Deny  []string `toml:",omitempty"` // Methods rejected even if allowed
+}
+
+// Validate checks that all entries of the policy are well-formed patterns.
+func (p MethodPolicy) Validate() error {
+	for _, list := range [][]string{p.Allow, p.Deny} {
+		for _, pattern := range list {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid method pattern %q: %v", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// IsEmpty reports whether the policy permits all methods.
+func (p MethodPolicy) IsEmpty() bool {
+	return len(p.Allow) == 0 && len(p.Deny) == 0
+}
+
+// Permits reports whether the policy allows calling the given method.
+func (p MethodPolicy) Permits(method string) bool {
+	if matchMethod(p.Deny, method) {
+		return false
+	}
+	return len(p.Allow) == 0 || matchMethod(p.Allow, method)
+}
+
+// matchMethod reports whether the method matches any of the patterns.
+func matchMethod(patterns []string, method string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, method); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// AnyIdentity is the key of the identity policy applied to authenticated callers
+// without a policy of their own.
+const AnyIdentity = "*"
+
+// AccessPolicy is the method access policy of a server. The policy of the server
+// applies to all callers, authenticated callers are additionally restricted by
+// the policy of their identity.
+type AccessPolicy struct {
+	Methods    MethodPolicy            // Policy applying to all callers
+	Identities map[string]MethodPolicy // Policies of authenticated callers by identity
+}
+
+// Validate checks that all method patterns of the policy are well-formed.
+func (p AccessPolicy) Validate() error {
+	if err := p.Methods.Validate(); err != nil {
+		return err
+	}
+	for id, policy := range p.Identities {
+		if err := policy.Validate(); err != nil {
+			return fmt.Errorf("identity %q: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// IsEmpty reports whether the policy permits all methods to all callers.
+func (p AccessPolicy) IsEmpty() bool {
+	if !p.Methods.IsEmpty() {
+		return false
+	}
+	for _, policy := range p.Identities {
+		if !policy.IsEmpty() {
+			return false
+		}
+	}
+	return true
+}
+
+// forPeer returns the policies applying to the calls of the given caller.
+func (p *AccessPolicy) forPeer(peer PeerInfo) []MethodPolicy {
+	policies := []MethodPolicy{p.Methods}
+	if !peer.Authenticated {
+		return policies
+	}
+	if policy, ok := p.Identities[peer.Identity]; ok {
+		return append(policies, policy)
+	}
+	if policy, ok := p.Identities[AnyIdentity]; ok {
+		return append(policies, policy)
+	}
+	return policies
+}
+
+type identityContextKey struct{}
+
+// ContextWithIdentity returns a context marking the caller as authenticated with
+// the given identity. Transports serving HTTP requests with such a context expose
+// the identity in PeerInfo, where it selects the identity policy of the server.
+func ContextWithIdentity(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, identityContextKey{}, identity)
+}
+
+// identityFromContext returns the authenticated identity of the caller.
+func identityFromContext(ctx context.Context) (string, bool) {
+	identity, ok := ctx.Value(identityContextKey{}).(string)
+	return identity, ok
+}
@@ -0,0 +1,121 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMethodPolicy(t *testing.T) {
+	policy := MethodPolicy{
+		Allow: []string{"eth_*", "debug_trace*"},
+		Deny:  []string{"eth_sendRawTransaction"},
+	}
+	if err := policy.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for method, want := range map[string]bool{
+		"eth_call":               true,
+		"debug_traceTransaction": true,
+		"debug_setHead":          false,
+		"admin_addPeer":          false,
+		"eth_sendRawTransaction": false,
+	} {
+		if have := policy.Permits(method); have != want {
+			t.Errorf("%s: have %v, want %v", method, have, want)
+		}
+	}
+	if !(MethodPolicy{}).Permits("admin_addPeer") {
+		t.Error("empty policy rejects method")
+	}
+	if err := (MethodPolicy{Deny: []string{"eth_[call"}}).Validate(); err == nil {
+		t.Error("malformed pattern accepted")
+	}
+}
+
+func TestAccessPolicyIdentities(t *testing.T) {
+	policy := &AccessPolicy{
+		Methods: MethodPolicy{Deny: []string{"admin_*"}},
+		Identities: map[string]MethodPolicy{
+			"indexer":   {Allow: []string{"debug_trace*"}},
+			AnyIdentity: {Deny: []string{"debug_*"}},
+		},
+	}
+	for _, tt := range []struct {
+		peer   PeerInfo
+		method string
+		want   bool
+	}{
+		{PeerInfo{}, "debug_traceTransaction", true},
+		{PeerInfo{}, "admin_addPeer", false},
+		{PeerInfo{Authenticated: true, Identity: "indexer"}, "debug_traceTransaction", true},
+		{PeerInfo{Authenticated: true, Identity: "indexer"}, "engine_forkchoiceUpdatedV2", false},
+		{PeerInfo{Authenticated: true, Identity: "node"}, "engine_forkchoiceUpdatedV2", true},
+		{PeerInfo{Authenticated: true, Identity: "node"}, "debug_traceTransaction", false},
+		{PeerInfo{Authenticated: true}, "admin_addPeer", false},
+	} {
+		h := &handler{access: policy.forPeer(tt.peer)}
+		if have := h.permits(tt.method); have != tt.want {
+			t.Errorf("%+v %s: have %v, want %v", tt.peer, tt.method, have, tt.want)
+		}
+	}
+}
+
+func TestServerAccessPolicy(t *testing.T) {
+	server := newTestServer()
+	server.SetAccessPolicy(AccessPolicy{
+		Methods:    MethodPolicy{Deny: []string{"test_sleep"}},
+		Identities: map[string]MethodPolicy{"reader": {Allow: []string{"test_echo"}}},
+	})
+	defer server.Stop()
+
+	// Authenticate all HTTP requests as reader
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.ServeHTTP(w, r.WithContext(ContextWithIdentity(r.Context(), "reader")))
+	}))
+	defer ts.Close()
+
+	inproc := DialInProc(server)
+	defer inproc.Close()
+	client, err := DialHTTP(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var resp echoResult
+	if err := client.Call(&resp, "test_echo", "hello", 10, &echoArgs{"world"}); err != nil {
+		t.Fatalf("permitted call rejected: %v", err)
+	}
+	for _, c := range []*Client{inproc, client} {
+		err := c.Call(nil, "test_sleep", 0)
+		if err == nil {
+			t.Fatal("denied call accepted")
+		}
+		if code := err.(Error).ErrorCode(); code != -32601 {
+			t.Fatalf("wrong error code %d", code)
+		}
+	}
+	if err := client.Call(nil, "test_noArgsRets"); err == nil {
+		t.Fatal("call outside identity policy accepted")
+	}
+	if err := inproc.Call(nil, "test_noArgsRets"); err != nil {
+		t.Fatalf("unauthenticated call rejected: %v", err)
+	}
+}
@@ -0,0 +1,282 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// The binary transport serves the registered services over a stream connection,
+// exchanging length-prefixed frames of RLP encoded messages. Method parameters
+// and results are JSON encoded like on the other transports, except for results
+// with a binary encoding of their own: if the caller's result value implements
+// BinaryUnmarshaler and the method's result implements BinaryMarshaler, the
+// binary encoding is sent instead of JSON.
+
+// BinaryMarshaler is implemented by method results having a binary encoding.
+// Returning a nil encoding without error makes the server fall back to JSON.
+type BinaryMarshaler interface {
+	MarshalRPCBinary() ([]byte, error)
+}
+
+// BinaryUnmarshaler is implemented by call results able to decode the binary
+// encoding of a method result. Such results must also support JSON decoding, as
+// servers send JSON if the transport or the result doesn't support binary.
+type BinaryUnmarshaler interface {
+	UnmarshalRPCBinary(input []byte) error
+}
+
+const (
+	binaryFrameHeaderSize = 4                 // Size of the big endian frame length prefix
+	binaryClientReadLimit = 256 * 1024 * 1024 // Maximum frame size accepted by clients
+)
+
+// Flags of a binary message.
+const (
+	binaryFlagResult = 1 << iota // Message carries a result
+	binaryFlagBinary             // Call accepts or response carries a binary result
+)
+
+// binaryFrame is the RLP encoding of a frame, holding a message or a batch.
+type binaryFrame struct {
+	Batch    bool
+	Messages []binaryMessage
+}
+
+// binaryMessage is the RLP encoding of a message. The ID, parameters and error
+// are JSON encoded, the result is either JSON or binary encoded.
+type binaryMessage struct {
+	ID     []byte
+	Method string
+	Params []byte
+	Flags  uint
+	Result []byte
+	Error  []byte
+}
+
+func encodeBinaryMessage(msg *jsonrpcMessage) (binaryMessage, error) {
+	enc := binaryMessage{ID: msg.ID, Method: msg.Method, Params: msg.Params}
+	if msg.Result != nil {
+		enc.Flags |= binaryFlagResult
+		enc.Result = msg.Result
+	}
+	if msg.binary {
+		enc.Flags |= binaryFlagBinary
+	}
+	if msg.Error != nil {
+		var err error
+		if enc.Error, err = json.Marshal(msg.Error); err != nil {
+			return enc, err
+		}
+	}
+	return enc, nil
+}
+
+func decodeBinaryMessage(enc *binaryMessage) (*jsonrpcMessage, error) {
+	msg := &jsonrpcMessage{Version: vsn, Method: enc.Method, binary: enc.Flags&binaryFlagBinary != 0}
+	if len(enc.ID) > 0 {
+		msg.ID = enc.ID
+	}
+	if len(enc.Params) > 0 {
+		msg.Params = enc.Params
+	}
+	if enc.Flags&binaryFlagResult != 0 {
+		msg.Result = enc.Result
+		if msg.Result == nil {
+			msg.Result = []byte{}
+		}
+	}
+	if len(enc.Error) > 0 {
+		msg.Error = new(jsonError)
+		if err := json.Unmarshal(enc.Error, msg.Error); err != nil {
+			return nil, fmt.Errorf("invalid error object: %v", err)
+		}
+	}
+	return msg, nil
+}
+
+// binaryCodec reads and writes binary frames on a stream connection.
+type binaryCodec struct {
+	conn      Conn
+	info      PeerInfo
+	reader    *bufio.Reader
+	readLimit uint32
+
+	encMu   sync.Mutex // guards writes to conn
+	closer  sync.Once
+	closeCh chan interface{}
+}
+
+// NewBinaryCodec creates a codec speaking the binary transport protocol on the
+// given connection.
+func NewBinaryCodec(conn Conn) ServerCodec {
+	return newBinaryCodec(conn, maxRequestContentLength)
+}
+
+func newBinaryCodec(conn Conn, readLimit uint32) *binaryCodec {
+	c := &binaryCodec{
+		conn:      conn,
+		info:      PeerInfo{Transport: "binary"},
+		reader:    bufio.NewReader(conn),
+		readLimit: readLimit,
+		closeCh:   make(chan interface{}),
+	}
+	if ra, ok := conn.(ConnRemoteAddr); ok {
+		c.info.RemoteAddr = ra.RemoteAddr()
+	} else if nc, ok := conn.(net.Conn); ok {
+		c.info.RemoteAddr = nc.RemoteAddr().String()
+	}
+	return c
+}
+
+func (c *binaryCodec) peerInfo() PeerInfo {
+	return c.info
+}
+
+func (c *binaryCodec) remoteAddr() string {
+	return c.info.RemoteAddr
+}
+
+func (c *binaryCodec) readBatch() ([]*jsonrpcMessage, bool, error) {
+	var header [binaryFrameHeaderSize]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return nil, false, err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if size > c.readLimit {
+		return nil, false, fmt.Errorf("frame too large: %d > %d", size, c.readLimit)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return nil, false, err
+	}
+	var frame binaryFrame
+	if err := rlp.DecodeBytes(payload, &frame); err != nil {
+		return nil, false, fmt.Errorf("invalid frame: %v", err)
+	}
+	msgs := make([]*jsonrpcMessage, len(frame.Messages))
+	for i := range frame.Messages {
+		msg, err := decodeBinaryMessage(&frame.Messages[i])
+		if err != nil {
+			return nil, false, err
+		}
+		msgs[i] = msg
+	}
+	return msgs, frame.Batch, nil
+}
+
+func (c *binaryCodec) writeJSON(ctx context.Context, v interface{}, isError bool) error {
+	var frame binaryFrame
+	switch v := v.(type) {
+	case *jsonrpcMessage:
+		frame.Messages = make([]binaryMessage, 1)
+		enc, err := encodeBinaryMessage(v)
+		if err != nil {
+			return err
+		}
+		frame.Messages[0] = enc
+	case []*jsonrpcMessage:
+		frame.Batch = true
+		frame.Messages = make([]binaryMessage, len(v))
+		for i, msg := range v {
+			enc, err := encodeBinaryMessage(msg)
+			if err != nil {
+				return err
+			}
+			frame.Messages[i] = enc
+		}
+	default:
+		return fmt.Errorf("can't encode %T on binary transport", v)
+	}
+	payload, err := rlp.EncodeToBytes(&frame)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, binaryFrameHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(buf, uint32(len(payload)))
+	copy(buf[binaryFrameHeaderSize:], payload)
+
+	c.encMu.Lock()
+	defer c.encMu.Unlock()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultWriteTimeout)
+	}
+	c.conn.SetWriteDeadline(deadline)
+	_, err = c.conn.Write(buf)
+	return err
+}
+
+func (c *binaryCodec) close() {
+	c.closer.Do(func() {
+		close(c.closeCh)
+		c.conn.Close()
+	})
+}
+
+func (c *binaryCodec) closed() <-chan interface{} {
+	return c.closeCh
+}
+
+// ServeBinaryListener accepts connections on l, serving the binary transport
+// protocol on them.
+func (s *Server) ServeBinaryListener(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if netutil.IsTemporaryError(err) {
+			log.Warn("RPC accept error", "err", err)
+			continue
+		} else if err != nil {
+			return err
+		}
+		log.Trace("Accepted binary RPC connection", "conn", conn.RemoteAddr())
+		go s.ServeCodec(NewBinaryCodec(conn), 0)
+	}
+}
+
+// DialBinary creates a new client speaking the binary transport protocol to the
+// given TCP endpoint, which is of the form "host:port".
+//
+// The context is used for the initial connection establishment. It does not
+// affect subsequent interactions with the client.
+func DialBinary(ctx context.Context, endpoint string) (*Client, error) {
+	return newClient(ctx, newClientTransportBinary(endpoint))
+}
+
+func newClientTransportBinary(endpoint string) reconnectFunc {
+	return func(ctx context.Context) (ServerCodec, error) {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", endpoint)
+		if err != nil {
+			return nil, err
+		}
+		return newBinaryCodec(conn, binaryClientReadLimit), nil
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// binaryValue has both a JSON and a binary encoding.
+type binaryValue struct {
+	Value  string
+	Binary bool // set if decoded from the binary encoding
+}
+
+func (v *binaryValue) MarshalJSON() ([]byte, error) { return json.Marshal(v.Value) }
+
+func (v *binaryValue) UnmarshalJSON(input []byte) error { return json.Unmarshal(input, &v.Value) }
+
+func (v *binaryValue) MarshalRPCBinary() ([]byte, error) {
+	if v.Value == "" {
+		return nil, nil // fall back to JSON
+	}
+	return []byte(v.Value), nil
+}
+
+func (v *binaryValue) UnmarshalRPCBinary(input []byte) error {
+	v.Value, v.Binary = string(input), true
+	return nil
+}
+
+type binaryTestService struct{}
+
+func (s *binaryTestService) Value(value string) *binaryValue {
+	return &binaryValue{Value: value}
+}
+
+func (s *binaryTestService) Fail() (*binaryValue, error) {
+	return nil, errors.New("failure")
+}
+
+func newTestBinaryServer(t *testing.T) (*Server, string) {
+	server := newTestServer()
+	if err := server.RegisterName("bin", new(binaryTestService)); err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.ServeBinaryListener(listener)
+	t.Cleanup(func() {
+		listener.Close()
+		server.Stop()
+	})
+	return server, listener.Addr().String()
+}
+
+func TestBinaryTransport(t *testing.T) {
+	_, endpoint := newTestBinaryServer(t)
+	client, err := DialContext(context.Background(), "bin://"+endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// Plain JSON results
+	var resp echoResult
+	if err := client.Call(&resp, "test_echo", "hello", 10, &echoArgs{"world"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resp, echoResult{"hello", 10, &echoArgs{"world"}}) {
+		t.Errorf("incorrect result %#v", resp)
+	}
+	// Binary results, falling back to JSON if the result has no binary encoding
+	for _, tt := range []struct {
+		value  string
+		binary bool
+	}{{"hello", true}, {"", false}} {
+		var res binaryValue
+		if err := client.Call(&res, "bin_value", tt.value); err != nil {
+			t.Fatal(err)
+		}
+		if res.Value != tt.value || res.Binary != tt.binary {
+			t.Errorf("result mismatch: have %+v, want %q, binary %v", res, tt.value, tt.binary)
+		}
+	}
+	// Results not supporting the binary encoding receive JSON
+	var str string
+	if err := client.Call(&str, "bin_value", "hello"); err != nil || str != "hello" {
+		t.Fatalf("JSON result mismatch: %q, %v", str, err)
+	}
+	// Errors are transferred as usual
+	err = client.Call(new(binaryValue), "bin_fail")
+	if err == nil || err.Error() != "failure" {
+		t.Fatalf("wrong error: %v", err)
+	}
+	if err := client.Call(nil, "test_unknown"); err == nil || err.(Error).ErrorCode() != -32601 {
+		t.Fatalf("wrong error for unknown method: %v", err)
+	}
+}
+
+func TestBinaryTransportBatch(t *testing.T) {
+	_, endpoint := newTestBinaryServer(t)
+	client, err := DialBinary(context.Background(), endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	batch := []BatchElem{
+		{Method: "bin_value", Args: []interface{}{"first"}, Result: new(binaryValue)},
+		{Method: "bin_value", Args: []interface{}{"second"}, Result: new(string)},
+		{Method: "test_echo", Args: []interface{}{"hello", 10, &echoArgs{"world"}}, Result: new(echoResult)},
+		{Method: "bin_fail", Result: new(binaryValue)},
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	if res := batch[0].Result.(*binaryValue); batch[0].Error != nil || res.Value != "first" || !res.Binary {
+		t.Errorf("binary batch result mismatch: %+v, %v", res, batch[0].Error)
+	}
+	if res := *batch[1].Result.(*string); batch[1].Error != nil || res != "second" {
+		t.Errorf("JSON batch result mismatch: %q, %v", res, batch[1].Error)
+	}
+	if res := batch[2].Result.(*echoResult); batch[2].Error != nil || res.Int != 10 {
+		t.Errorf("echo batch result mismatch: %+v, %v", res, batch[2].Error)
+	}
+	if batch[3].Error == nil {
+		t.Errorf("failing batch call succeeded")
+	}
+}
+
+func TestBinaryTransportSubscription(t *testing.T) {
+	_, endpoint := newTestBinaryServer(t)
+	client, err := DialBinary(context.Background(), endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var (
+		count = 5
+		ch    = make(chan int)
+	)
+	sub, err := client.Subscribe(context.Background(), "nftest", ch, "someSubscription", count, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	for i := 0; i < count; i++ {
+		select {
+		case v := <-ch:
+			if v != i {
+				t.Fatalf("notification %d mismatch: have %d", i, v)
+			}
+		case err := <-sub.Err():
+			t.Fatalf("subscription failed: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for notification")
+		}
+	}
+}
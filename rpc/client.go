@@ -78,6 +78,7 @@ type Client struct {
 	idgen    func() ID // for subscriptions
 	isHTTP   bool      // connection type: http, ws or ipc
 	services *serviceRegistry
+	limits   *callLimits // limits applied to served calls, nil unless owned by a server
 
 	idCounter atomic.Uint32
 
@@ -114,7 +115,7 @@ func (c *Client) newClientConn(conn ServerCodec) *clientConn {
 	ctx := context.Background()
 	ctx = context.WithValue(ctx, clientContextKey{}, c)
 	ctx = context.WithValue(ctx, peerInfoContextKey{}, conn.peerInfo())
-	handler := newHandler(ctx, conn, c.idgen, c.services)
+	handler := newHandler(ctx, conn, c.idgen, c.services, c.limits)
 	return &clientConn{conn, handler}
 }
 
@@ -153,9 +154,10 @@ func (op *requestOp) wait(ctx context.Context, c *Client) (*jsonrpcMessage, erro
 
 // Dial creates a new client for the given URL.
 //
-// The currently supported URL schemes are "http", "https", "ws" and "wss". If rawurl is a
-// file name with no URL scheme, a local socket connection is established using UNIX
-// domain sockets on supported platforms and named pipes on Windows.
+// The currently supported URL schemes are "http", "https", "ws", "wss" and "bin", the
+// latter connecting to a binary transport endpoint, e.g. "bin://localhost:8549". If
+// rawurl is a file name with no URL scheme, a local socket connection is established
+// using UNIX domain sockets on supported platforms and named pipes on Windows.
 //
 // If you want to further configure the transport, use DialOptions instead of this
 // function.
@@ -203,6 +205,8 @@ func DialOptions(ctx context.Context, rawurl string, options ...ClientOption) (*
 			return nil, err
 		}
 		reconnect = rc
+	case "bin":
+		reconnect = newClientTransportBinary(u.Host)
 	case "stdio":
 		reconnect = newClientTransportIO(os.Stdin, os.Stdout)
 	case "":
@@ -226,17 +230,18 @@ func newClient(initctx context.Context, connect reconnectFunc) (*Client, error)
 	if err != nil {
 		return nil, err
 	}
-	c := initClient(conn, randomIDGenerator(), new(serviceRegistry))
+	c := initClient(conn, randomIDGenerator(), new(serviceRegistry), nil)
 	c.reconnectFunc = connect
 	return c, nil
 }
 
-func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry) *Client {
+func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry, limits *callLimits) *Client {
 	_, isHTTP := conn.(*httpConn)
 	c := &Client{
 		isHTTP:      isHTTP,
 		idgen:       idgen,
 		services:    services,
+		limits:      limits,
 		writeConn:   conn,
 		close:       make(chan struct{}),
 		closing:     make(chan struct{}),
@@ -325,6 +330,7 @@ func (c *Client) CallContext(ctx context.Context, result interface{}, method str
 	if err != nil {
 		return err
 	}
+	_, msg.binary = result.(BinaryUnmarshaler)
 	op := &requestOp{ids: []json.RawMessage{msg.ID}, resp: make(chan *jsonrpcMessage, 1)}
 
 	if c.isHTTP {
@@ -348,10 +354,22 @@ func (c *Client) CallContext(ctx context.Context, result interface{}, method str
 		if result == nil {
 			return nil
 		}
-		return json.Unmarshal(resp.Result, result)
+		return decodeResult(resp, result)
 	}
 }
 
+// decodeResult decodes the result of a response into the given value.
+func decodeResult(resp *jsonrpcMessage, result interface{}) error {
+	if resp.binary {
+		dec, ok := result.(BinaryUnmarshaler)
+		if !ok {
+			return fmt.Errorf("unexpected binary result for %T", result)
+		}
+		return dec.UnmarshalRPCBinary(resp.Result)
+	}
+	return json.Unmarshal(resp.Result, result)
+}
+
 // BatchCall sends all given requests as a single batch and waits for the server
 // to return a response for all of them.
 //
@@ -387,6 +405,7 @@ func (c *Client) BatchCallContext(ctx context.Context, b []BatchElem) error {
 		if err != nil {
 			return err
 		}
+		_, msg.binary = elem.Result.(BinaryUnmarshaler)
 		msgs[i] = msg
 		op.ids[i] = msg.ID
 		byID[string(msg.ID)] = i
@@ -418,7 +437,7 @@ func (c *Client) BatchCallContext(ctx context.Context, b []BatchElem) error {
 			elem.Error = ErrNoResult
 			continue
 		}
-		elem.Error = json.Unmarshal(resp.Result, elem.Result)
+		elem.Error = decodeResult(resp, elem.Result)
 	}
 	return err
 }
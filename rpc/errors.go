@@ -16,7 +16,11 @@
 
 package rpc
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"time"
+)
 
 // HTTPError is returned by client operations when the HTTP status code of the
 // response is not a 2xx status.
@@ -49,24 +53,32 @@ type DataError interface {
 
 var (
 	_ Error = new(methodNotFoundError)
+	_ Error = new(methodNotAllowedError)
 	_ Error = new(subscriptionNotFoundError)
 	_ Error = new(parseError)
 	_ Error = new(invalidRequestError)
 	_ Error = new(invalidMessageError)
 	_ Error = new(invalidParamsError)
 	_ Error = new(internalServerError)
+	_ Error = new(limitExceededError)
+
+	_ DataError = new(limitExceededError)
 )
 
 const (
 	errcodeDefault                  = -32000
 	errcodeNotificationsUnsupported = -32001
 	errcodeTimeout                  = -32002
+	errcodeResponseTooLarge         = -32003
+	errcodeLimitExceeded            = -32005
 	errcodePanic                    = -32603
 	errcodeMarshalError             = -32603
 )
 
 const (
-	errMsgTimeout = "request timed out"
+	errMsgTimeout          = "request timed out"
+	errMsgBatchTooLarge    = "batch too large"
+	errMsgResponseTooLarge = "response too large"
 )
 
 var ErrNoHistoricalFallback = NoHistoricalFallbackError{}
@@ -79,6 +91,18 @@ func (e NoHistoricalFallbackError) Error() string {
 	return "no historical RPC is available for this historical (pre-bedrock) execution request"
 }
 
+var ErrPrunedHistory = PrunedHistoryError{}
+
+// PrunedHistoryError is returned when the requested block data has been expired
+// from the local database by history pruning.
+type PrunedHistoryError struct{}
+
+func (e PrunedHistoryError) ErrorCode() int { return 4444 }
+
+func (e PrunedHistoryError) Error() string {
+	return "pruned history unavailable"
+}
+
 type methodNotFoundError struct{ method string }
 
 func (e *methodNotFoundError) ErrorCode() int { return -32601 }
@@ -87,6 +111,16 @@ func (e *methodNotFoundError) Error() string {
 	return fmt.Sprintf("the method %s does not exist/is not available", e.method)
 }
 
+// methodNotAllowedError is returned for methods rejected by the access policy.
+// It uses the method not found code, as the method isn't available to the caller.
+type methodNotAllowedError struct{ method string }
+
+func (e *methodNotAllowedError) ErrorCode() int { return -32601 }
+
+func (e *methodNotAllowedError) Error() string {
+	return fmt.Sprintf("the method %s is not allowed", e.method)
+}
+
 type subscriptionNotFoundError struct{ namespace, subscription string }
 
 func (e *subscriptionNotFoundError) ErrorCode() int { return -32601 }
@@ -132,3 +166,20 @@ type internalServerError struct {
 func (e *internalServerError) ErrorCode() int { return e.code }
 
 func (e *internalServerError) Error() string { return e.message }
+
+// limitExceededError is returned when a call exceeds the caller's budget.
+type limitExceededError struct {
+	method     string
+	retryAfter time.Duration
+}
+
+func (e *limitExceededError) ErrorCode() int { return errcodeLimitExceeded }
+
+func (e *limitExceededError) Error() string {
+	return fmt.Sprintf("rate limit exceeded for %s", e.method)
+}
+
+// ErrorData returns the number of seconds after which the call can be retried.
+func (e *limitExceededError) ErrorData() interface{} {
+	return map[string]float64{"retryAfter": math.Ceil(e.retryAfter.Seconds()*1000) / 1000}
+}
@@ -25,6 +25,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ethereum/go-ethereum/internal/tracing"
 	"github.com/ethereum/go-ethereum/log"
 )
 
@@ -60,6 +61,9 @@ type handler struct {
 	conn           jsonWriter                     // where responses will be sent
 	log            log.Logger
 	allowSubscribe bool
+	limits         callLimits     // limits applied to served calls
+	budget         Budget         // call budget of the connection, nil if unlimited
+	access         []MethodPolicy // method policies applying to the connection
 
 	subLock    sync.Mutex
 	serverSubs map[ID]*Subscription
@@ -70,7 +74,7 @@ type callProc struct {
 	notifiers []*Notifier
 }
 
-func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry) *handler {
+func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry, limits *callLimits) *handler {
 	rootCtx, cancelRoot := context.WithCancel(connCtx)
 	h := &handler{
 		reg:            reg,
@@ -87,6 +91,15 @@ func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *
 	if conn.remoteAddr() != "" {
 		h.log = h.log.New("conn", conn.remoteAddr())
 	}
+	if limits != nil {
+		h.limits = *limits
+		if limits.limiter != nil {
+			h.budget = limits.limiter.Connect(PeerInfoFromContext(connCtx))
+		}
+		if limits.access != nil {
+			h.access = limits.access.forPeer(PeerInfoFromContext(connCtx))
+		}
+	}
 	h.unsubscribeCb = newCallback(reflect.Value{}, reflect.ValueOf(h.unsubscribe))
 	return h
 }
@@ -134,16 +147,15 @@ func (b *batchCallBuffer) write(ctx context.Context, conn jsonWriter) {
 	b.doWrite(ctx, conn, false)
 }
 
-// timeout sends the responses added so far. For the remaining unanswered call
-// messages, it sends a timeout error response.
-func (b *batchCallBuffer) timeout(ctx context.Context, conn jsonWriter) {
+// respondWithError sends the responses added so far. For the remaining unanswered call
+// messages, it sends the given error response.
+func (b *batchCallBuffer) respondWithError(ctx context.Context, conn jsonWriter, err error) {
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
 
 	for _, msg := range b.calls {
 		if !msg.isNotification() {
-			resp := msg.errorResponse(&internalServerError{errcodeTimeout, errMsgTimeout})
-			b.resp = append(b.resp, resp)
+			b.resp = append(b.resp, msg.errorResponse(err))
 		}
 	}
 	b.doWrite(ctx, conn, true)
@@ -171,6 +183,13 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 		})
 		return
 	}
+	// Apply limit on total number of requests.
+	if h.limits.batchItemLimit != 0 && len(msgs) > h.limits.batchItemLimit {
+		h.startCallProc(func(cp *callProc) {
+			h.respondWithBatchTooLarge(cp, msgs)
+		})
+		return
+	}
 
 	// Handle non-call messages first:
 	calls := make([]*jsonrpcMessage, 0, len(msgs))
@@ -187,8 +206,11 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 		var (
 			timer      *time.Timer
 			cancel     context.CancelFunc
+			span       *tracing.Span
 			callBuffer = &batchCallBuffer{calls: calls, resp: make([]*jsonrpcMessage, 0, len(calls))}
 		)
+		cp.ctx, span = tracing.StartServerSpan(cp.ctx, "rpc.batch", "rpc.system", "jsonrpc", "rpc.batch.size", len(calls))
+		defer span.End()
 
 		cp.ctx, cancel = context.WithCancel(cp.ctx)
 		defer cancel()
@@ -199,10 +221,11 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 		if timeout, ok := ContextRequestTimeout(cp.ctx); ok {
 			timer = time.AfterFunc(timeout, func() {
 				cancel()
-				callBuffer.timeout(cp.ctx, h.conn)
+				callBuffer.respondWithError(cp.ctx, h.conn, &internalServerError{errcodeTimeout, errMsgTimeout})
 			})
 		}
 
+		responseBytes := 0
 		for {
 			// No need to handle rest of calls if timed out.
 			if cp.ctx.Err() != nil {
@@ -214,6 +237,14 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 			}
 			resp := h.handleCallMsg(cp, msg)
 			callBuffer.pushResponse(resp)
+			if resp != nil && h.limits.batchResponseSizeLimit != 0 {
+				responseBytes += len(resp.Result)
+				if responseBytes > h.limits.batchResponseSizeLimit {
+					err := &internalServerError{errcodeResponseTooLarge, errMsgResponseTooLarge}
+					callBuffer.respondWithError(cp.ctx, h.conn, err)
+					break
+				}
+			}
 		}
 		if timer != nil {
 			timer.Stop()
@@ -226,6 +257,22 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 	})
 }
 
+// respondWithBatchTooLarge answers all calls of a batch exceeding the item limit
+// with an error, so that clients waiting for every response are not stalled.
+func (h *handler) respondWithBatchTooLarge(cp *callProc, batch []*jsonrpcMessage) {
+	err := &invalidRequestError{errMsgBatchTooLarge}
+	resp := make([]*jsonrpcMessage, 0, len(batch))
+	for _, msg := range batch {
+		if msg.isCall() {
+			resp = append(resp, msg.errorResponse(err))
+		}
+	}
+	if len(resp) == 0 {
+		resp = append(resp, errorMessage(err))
+	}
+	h.conn.writeJSON(cp.ctx, resp, true)
+}
+
 // handleMsg handles a single message.
 func (h *handler) handleMsg(msg *jsonrpcMessage) {
 	if ok := h.handleImmediate(msg); ok {
@@ -276,6 +323,9 @@ func (h *handler) close(err error, inflightReq *requestOp) {
 	h.callWG.Wait()
 	h.cancelRoot()
 	h.cancelServerSubscriptions(err)
+	if h.budget != nil {
+		h.budget.Release()
+	}
 }
 
 // addRequestOp registers a request operation.
@@ -437,8 +487,45 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMess
 	}
 }
 
-// handleCall processes method calls.
+// handleCall processes method calls, tracing each call in its own span.
 func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
+	ctx, span := tracing.StartServerSpan(cp.ctx, msg.Method,
+		"rpc.system", "jsonrpc",
+		"rpc.method", msg.Method,
+		"rpc.transport", PeerInfoFromContext(cp.ctx).Transport,
+	)
+	if span == nil {
+		return h.serveCall(cp, msg)
+	}
+	defer span.End()
+
+	if msg.isCall() {
+		span.SetAttributes("rpc.jsonrpc.request_id", string(msg.ID))
+	}
+	// The call procedure is shared by the calls of a batch and its context may be
+	// accessed concurrently on timeout, so run the call on a copy.
+	call := &callProc{ctx: ctx}
+	answer := h.serveCall(call, msg)
+	cp.notifiers = append(cp.notifiers, call.notifiers...)
+
+	if answer != nil && answer.Error != nil {
+		span.SetAttributes("rpc.jsonrpc.error_code", answer.Error.Code)
+		span.SetError(answer.Error)
+	}
+	return answer
+}
+
+// serveCall dispatches a method call to its handler.
+func (h *handler) serveCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
+	if !msg.isUnsubscribe() && !h.permits(msg.Method) {
+		return msg.errorResponse(&methodNotAllowedError{method: msg.Method})
+	}
+	if h.budget != nil && !msg.isUnsubscribe() {
+		if err := h.budget.Charge(msg.Method); err != nil {
+			limitedRequestMeter.Mark(1)
+			return msg.errorResponse(err)
+		}
+	}
 	if msg.isSubscribe() {
 		return h.handleSubscribe(cp, msg)
 	}
@@ -472,6 +559,17 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage
 	return answer
 }
 
+// permits reports whether the method policies of the connection allow calling
+// the given method.
+func (h *handler) permits(method string) bool {
+	for _, policy := range h.access {
+		if !policy.Permits(method) {
+			return false
+		}
+	}
+	return true
+}
+
 // handleSubscribe processes *_subscribe method calls.
 func (h *handler) handleSubscribe(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
 	if !h.allowSubscribe {
@@ -514,6 +612,11 @@ func (h *handler) runMethod(ctx context.Context, msg *jsonrpcMessage, callb *cal
 	if err != nil {
 		return msg.errorResponse(err)
 	}
+	if msg.binary {
+		if resp := msg.binaryResponse(result); resp != nil {
+			return resp
+		}
+	}
 	return msg.response(result)
 }
 
@@ -18,11 +18,16 @@ package rpc
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/internal/tracing"
 )
 
 func confirmStatusCode(t *testing.T, got, want int) {
@@ -241,3 +246,60 @@ func TestNewContextWithHeaders(t *testing.T) {
 		t.Error("call failed:", err)
 	}
 }
+
+func TestHTTPTraceparent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.json")
+	if err := tracing.Start(tracing.Config{File: path, SampleRatio: 1}); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestServer()
+	defer s.Stop()
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	c, err := DialHTTP(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// The client span should become the parent of the server side call span
+	ctx, span := tracing.StartSpan(context.Background(), "client")
+	var resp echoResult
+	if err := c.CallContext(ctx, &resp, "test_echo", "hello", 10, &echoArgs{"world"}); err != nil {
+		t.Fatal(err)
+	}
+	span.End()
+	tracing.Stop()
+
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID      string `json:"traceId"`
+					SpanID       string `json:"spanId"`
+					ParentSpanID string `json:"parentSpanId"`
+					Name         string `json:"name"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err := json.Unmarshal(blob, &req); err != nil {
+		t.Fatal(err)
+	}
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("span count mismatch: have %d, want 2", len(spans))
+	}
+	call, client := spans[0], spans[1]
+	if call.Name != "test_echo" || client.Name != "client" {
+		t.Fatalf("span names mismatch: %s, %s", call.Name, client.Name)
+	}
+	if call.TraceID != client.TraceID || call.ParentSpanID != client.SpanID {
+		t.Fatalf("call span not a child of the client span: call %+v, client %+v", call, client)
+	}
+}
@@ -55,6 +55,10 @@ type jsonrpcMessage struct {
 	Params  json.RawMessage `json:"params,omitempty"`
 	Error   *jsonError      `json:"error,omitempty"`
 	Result  json.RawMessage `json:"result,omitempty"`
+
+	// binary is only used by the binary transport. It is set on calls accepting a
+	// binary encoded result and on responses whose result is binary encoded.
+	binary bool
 }
 
 func (msg *jsonrpcMessage) isNotification() bool {
@@ -109,6 +113,23 @@ func (msg *jsonrpcMessage) response(result interface{}) *jsonrpcMessage {
 	return &jsonrpcMessage{Version: vsn, ID: msg.ID, Result: enc}
 }
 
+// binaryResponse creates a response holding the binary encoding of the result. It
+// returns nil if the result should be sent as JSON instead.
+func (msg *jsonrpcMessage) binaryResponse(result interface{}) *jsonrpcMessage {
+	enc, ok := result.(BinaryMarshaler)
+	if !ok {
+		return nil
+	}
+	blob, err := enc.MarshalRPCBinary()
+	if err != nil {
+		return msg.errorResponse(&internalServerError{errcodeMarshalError, err.Error()})
+	}
+	if blob == nil {
+		return nil
+	}
+	return &jsonrpcMessage{Version: vsn, ID: msg.ID, Result: blob, binary: true}
+}
+
 func errorMessage(err error) *jsonrpcMessage {
 	msg := &jsonrpcMessage{Version: vsn, ID: null, Error: &jsonError{
 		Code:    errcodeDefault,
@@ -0,0 +1,294 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+)
+
+// Limiter accounts for the cost of the calls served by a server. It is consulted
+// before every method call and can reject calls exceeding the caller's budget.
+type Limiter interface {
+	// Connect returns the budget of a new connection. For HTTP, it is invoked
+	// for every request.
+	Connect(peer PeerInfo) Budget
+}
+
+// Budget is the call budget of a single connection.
+type Budget interface {
+	// Charge accounts for a call of the given method. The returned error, if
+	// any, is sent to the caller instead of executing the call.
+	Charge(method string) error
+
+	// Release is invoked when the connection is closed.
+	Release()
+}
+
+// DefaultMethodCost is the cost of methods not listed in the cost table.
+const DefaultMethodCost = 1
+
+// DefaultMethodCosts is the default cost table of the cost limiter, listing the
+// methods known to be expensive to serve. Besides method names, entries may also
+// be namespace wildcards, e.g. "debug_*".
+var DefaultMethodCosts = map[string]float64{
+	"eth_call":                 5,
+	"eth_estimateGas":          5,
+	"eth_createAccessList":     10,
+	"eth_getLogs":              20,
+	"eth_getFilterLogs":        20,
+	"eth_getBlockReceipts":     10,
+	"eth_getProof":             10,
+	"eth_feeHistory":           5,
+	"debug_*":                  50,
+	"debug_traceTransaction":   100,
+	"debug_traceCall":          100,
+	"debug_traceBlockByNumber": 200,
+	"debug_traceBlockByHash":   200,
+	"debug_traceChain":         500,
+}
+
+// CostLimitConfig configures the budgets enforced by the cost limiter. Budgets
+// are expressed in cost units, refilled continuously at the configured rate up
+// to the burst size.
+type CostLimitConfig struct {
+	ConnRate  float64            // Budget refilled per second for each connection, 0 = unlimited
+	ConnBurst float64            // Maximum budget of a connection, defaults to ConnRate
+	IPRate    float64            // Budget refilled per second for each IP address, 0 = unlimited
+	IPBurst   float64            // Maximum budget of an IP address, defaults to IPRate
+	Costs     map[string]float64 `toml:",omitempty"` // Method costs overriding DefaultMethodCosts
+}
+
+// Enabled reports whether any budget is configured.
+func (c CostLimitConfig) Enabled() bool {
+	return c.ConnRate > 0 || c.IPRate > 0
+}
+
+// costLimiterGCInterval is the interval at which idle budgets are dropped.
+const costLimiterGCInterval = time.Minute
+
+// costLimiter is the built-in Limiter, charging every call a configurable cost
+// against a per-connection and a per-IP token bucket. HTTP requests sent over
+// the same keep-alive connection share their connection budget.
+type costLimiter struct {
+	costs     map[string]float64
+	connRate  float64
+	connBurst float64
+	ipRate    float64
+	ipBurst   float64
+	clock     mclock.Clock
+
+	lock   sync.Mutex
+	ips    map[string]*tokenBucket // Budgets of remote IP addresses
+	http   map[string]*tokenBucket // Budgets of HTTP connections by remote address
+	lastGC mclock.AbsTime
+}
+
+// NewCostLimiter creates a limiter enforcing the given budgets.
+func NewCostLimiter(config CostLimitConfig) Limiter {
+	return newCostLimiter(config, mclock.System{})
+}
+
+func newCostLimiter(config CostLimitConfig, clock mclock.Clock) *costLimiter {
+	l := &costLimiter{
+		costs:     make(map[string]float64),
+		connRate:  config.ConnRate,
+		connBurst: config.ConnBurst,
+		ipRate:    config.IPRate,
+		ipBurst:   config.IPBurst,
+		clock:     clock,
+		ips:       make(map[string]*tokenBucket),
+		http:      make(map[string]*tokenBucket),
+		lastGC:    clock.Now(),
+	}
+	if l.connBurst <= 0 {
+		l.connBurst = l.connRate
+	}
+	if l.ipBurst <= 0 {
+		l.ipBurst = l.ipRate
+	}
+	for method, cost := range DefaultMethodCosts {
+		l.costs[method] = cost
+	}
+	for method, cost := range config.Costs {
+		l.costs[method] = cost
+	}
+	return l
+}
+
+// cost returns the cost of calling the given method.
+func (l *costLimiter) cost(method string) float64 {
+	if cost, ok := l.costs[method]; ok {
+		return cost
+	}
+	if i := strings.IndexByte(method, serviceMethodSeparator[0]); i > 0 {
+		if cost, ok := l.costs[method[:i+1]+"*"]; ok {
+			return cost
+		}
+	}
+	return DefaultMethodCost
+}
+
+// Connect implements Limiter.
+func (l *costLimiter) Connect(peer PeerInfo) Budget {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.clock.Now()
+	if time.Duration(now-l.lastGC) > costLimiterGCInterval {
+		l.gc(now)
+	}
+	b := &costBudget{limiter: l}
+	if l.connRate > 0 {
+		if peer.Transport == "http" {
+			if b.conn = l.http[peer.RemoteAddr]; b.conn == nil {
+				b.conn = newTokenBucket(l.connRate, l.connBurst, now)
+				l.http[peer.RemoteAddr] = b.conn
+			}
+		} else {
+			b.conn = newTokenBucket(l.connRate, l.connBurst, now)
+		}
+	}
+	if l.ipRate > 0 {
+		if ip := remoteIP(peer.RemoteAddr); ip != "" {
+			if b.ip = l.ips[ip]; b.ip == nil {
+				b.ip = newTokenBucket(l.ipRate, l.ipBurst, now)
+				l.ips[ip] = b.ip
+			}
+			b.ip.refs++
+		}
+	}
+	if b.conn != nil {
+		b.conn.refs++
+	}
+	return b
+}
+
+// gc drops all unused budgets which are refilled completely, so no accounting
+// information is lost. It assumes the lock is held.
+func (l *costLimiter) gc(now mclock.AbsTime) {
+	for _, buckets := range []map[string]*tokenBucket{l.ips, l.http} {
+		for key, bucket := range buckets {
+			if bucket.refs == 0 && bucket.available(now) >= bucket.burst {
+				delete(buckets, key)
+			}
+		}
+	}
+	l.lastGC = now
+}
+
+// remoteIP extracts the IP address from a remote address, returning the empty
+// string for local transports.
+func remoteIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return ""
+	}
+	return host
+}
+
+// costBudget is the budget of a connection handed out by the cost limiter.
+type costBudget struct {
+	limiter *costLimiter
+	conn    *tokenBucket // nil if connections are not limited
+	ip      *tokenBucket // nil if IPs are not limited or unknown
+}
+
+// Charge implements Budget.
+func (b *costBudget) Charge(method string) error {
+	l := b.limiter
+	cost := l.cost(method)
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	// Only charge if both budgets suffice, otherwise report the longer wait
+	var (
+		now  = l.clock.Now()
+		wait time.Duration
+	)
+	for _, bucket := range []*tokenBucket{b.conn, b.ip} {
+		if bucket != nil {
+			if w := bucket.wait(cost, now); w > wait {
+				wait = w
+			}
+		}
+	}
+	if wait > 0 {
+		return &limitExceededError{method: method, retryAfter: wait}
+	}
+	for _, bucket := range []*tokenBucket{b.conn, b.ip} {
+		if bucket != nil {
+			bucket.charge(cost, now)
+		}
+	}
+	return nil
+}
+
+// Release implements Budget.
+func (b *costBudget) Release() {
+	l := b.limiter
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for _, bucket := range []*tokenBucket{b.conn, b.ip} {
+		if bucket != nil {
+			bucket.refs--
+		}
+	}
+}
+
+// tokenBucket is a budget refilled continuously at a constant rate.
+type tokenBucket struct {
+	rate   float64 // Tokens added per second
+	burst  float64 // Maximum number of tokens
+	tokens float64 // Tokens available at the last update
+	last   mclock.AbsTime
+	refs   int // Number of connections using the bucket
+}
+
+func newTokenBucket(rate, burst float64, now mclock.AbsTime) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: burst, tokens: burst, last: now}
+}
+
+// available returns the number of tokens available at the given time.
+func (b *tokenBucket) available(now mclock.AbsTime) float64 {
+	tokens := b.tokens + time.Duration(now-b.last).Seconds()*b.rate
+	return math.Min(tokens, b.burst)
+}
+
+// wait returns the time until the given cost can be charged. Costs exceeding the
+// burst size are capped, so that expensive calls can still be made.
+func (b *tokenBucket) wait(cost float64, now mclock.AbsTime) time.Duration {
+	cost = math.Min(cost, b.burst)
+	missing := cost - b.available(now)
+	if missing <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(missing / b.rate * float64(time.Second)))
+}
+
+// charge deducts the given cost.
+func (b *tokenBucket) charge(cost float64, now mclock.AbsTime) {
+	b.tokens = b.available(now) - math.Min(cost, b.burst)
+	b.last = now
+}
@@ -0,0 +1,175 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+)
+
+func TestCostLimiter(t *testing.T) {
+	var (
+		clock   = new(mclock.Simulated)
+		limiter = newCostLimiter(CostLimitConfig{
+			ConnRate:  10,
+			ConnBurst: 20,
+			IPRate:    10,
+			IPBurst:   30,
+			Costs:     map[string]float64{"test_expensive": 15, "debug_*": 8},
+		}, clock)
+		peer = PeerInfo{Transport: "ws", RemoteAddr: "10.0.0.1:1000"}
+	)
+	for method, want := range map[string]float64{
+		"test_expensive":         15,
+		"debug_traceTransaction": 100,
+		"debug_dumpBlock":        8,
+		"eth_getLogs":            20,
+		"eth_blockNumber":        DefaultMethodCost,
+	} {
+		if have := limiter.cost(method); have != want {
+			t.Errorf("cost of %s mismatch: have %v, want %v", method, have, want)
+		}
+	}
+	// Exhaust the budget of the first connection
+	conn1 := limiter.Connect(peer)
+	if err := conn1.Charge("test_expensive"); err != nil {
+		t.Fatalf("first call rejected: %v", err)
+	}
+	err := conn1.Charge("test_expensive")
+	var limitErr *limitExceededError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("second call not rejected: %v", err)
+	}
+	if limitErr.retryAfter != time.Second {
+		t.Fatalf("retry delay mismatch: have %v, want %v", limitErr.retryAfter, time.Second)
+	}
+	// A second connection from the same IP has its own connection budget, but
+	// shares the remaining IP budget
+	conn2 := limiter.Connect(peer)
+	if err := conn2.Charge("test_expensive"); err != nil {
+		t.Fatalf("call of second connection rejected: %v", err)
+	}
+	if err := conn2.Charge("eth_blockNumber"); err == nil {
+		t.Fatal("call exceeding the IP budget accepted")
+	}
+	// Calls from other IPs are unaffected
+	conn3 := limiter.Connect(PeerInfo{Transport: "ws", RemoteAddr: "10.0.0.2:1000"})
+	if err := conn3.Charge("test_expensive"); err != nil {
+		t.Fatalf("call from other IP rejected: %v", err)
+	}
+	// Costs exceeding the burst size can be charged once the budget is full
+	clock.Run(3 * time.Second)
+	if err := conn1.Charge("debug_traceTransaction"); err != nil {
+		t.Fatalf("expensive call rejected: %v", err)
+	}
+	// HTTP requests on the same connection share the budget
+	httpPeer := PeerInfo{Transport: "http", RemoteAddr: "10.0.0.3:2000"}
+	req1 := limiter.Connect(httpPeer)
+	if err := req1.Charge("test_expensive"); err != nil {
+		t.Fatalf("http call rejected: %v", err)
+	}
+	req1.Release()
+	req2 := limiter.Connect(httpPeer)
+	if err := req2.Charge("test_expensive"); err == nil {
+		t.Fatal("http call exceeding the connection budget accepted")
+	}
+	req2.Release()
+
+	// Released budgets are dropped once refilled
+	conn1.Release()
+	conn2.Release()
+	conn3.Release()
+	clock.Run(costLimiterGCInterval + time.Second)
+	limiter.Connect(PeerInfo{Transport: "ipc"}).Release()
+	if len(limiter.ips) != 0 || len(limiter.http) != 0 {
+		t.Fatalf("idle budgets not dropped: %d IPs, %d HTTP connections", len(limiter.ips), len(limiter.http))
+	}
+}
+
+func TestServerCostLimit(t *testing.T) {
+	server := newTestServer()
+	server.SetLimiter(NewCostLimiter(CostLimitConfig{
+		ConnRate:  0.001,
+		ConnBurst: 10,
+		Costs:     map[string]float64{"test_echo": 6},
+	}))
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	var resp echoResult
+	if err := client.Call(&resp, "test_echo", "hello", 10, &echoArgs{"world"}); err != nil {
+		t.Fatal(err)
+	}
+	err := client.Call(&resp, "test_echo", "hello", 10, &echoArgs{"world"})
+	if err == nil {
+		t.Fatal("call exceeding the budget accepted")
+	}
+	if code := err.(Error).ErrorCode(); code != errcodeLimitExceeded {
+		t.Fatalf("wrong error code %d", code)
+	}
+	data, ok := err.(DataError).ErrorData().(map[string]interface{})
+	if !ok || data["retryAfter"] == nil {
+		t.Fatalf("retry delay missing from error data: %v", err.(DataError).ErrorData())
+	}
+	// Cheap calls still fit in the remaining budget
+	if err := client.Call(nil, "test_noArgsRets"); err != nil {
+		t.Fatalf("cheap call rejected: %v", err)
+	}
+}
+
+func TestServerBatchLimits(t *testing.T) {
+	server := newTestServer()
+	server.SetBatchLimits(3, 30)
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	// Batches exceeding the item limit are rejected as a whole
+	batch := make([]BatchElem, 4)
+	for i := range batch {
+		batch[i] = BatchElem{Method: "test_echo", Args: []interface{}{"x", i, &echoArgs{"y"}}, Result: new(echoResult)}
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	for _, elem := range batch {
+		if elem.Error == nil || elem.Error.Error() != errMsgBatchTooLarge {
+			t.Fatalf("expected batch too large error, got %v", elem.Error)
+		}
+	}
+	// Responses exceeding the size limit fail the remaining calls
+	batch = batch[:3]
+	for i := range batch {
+		batch[i].Error = nil
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	if batch[0].Error != nil {
+		t.Fatalf("first call failed: %v", batch[0].Error)
+	}
+	for _, elem := range batch[1:] {
+		re, ok := elem.Error.(Error)
+		if !ok || re.ErrorCode() != errcodeResponseTooLarge {
+			t.Fatalf("expected response too large error, got %v", elem.Error)
+		}
+	}
+}
@@ -49,6 +49,15 @@ type Server struct {
 	mutex  sync.Mutex
 	codecs map[ServerCodec]struct{}
 	run    atomic.Bool
+	limits callLimits
+}
+
+// callLimits are the limits a server applies to the calls of its connections.
+type callLimits struct {
+	batchItemLimit         int           // Maximum number of calls in a batch, 0 = unlimited
+	batchResponseSizeLimit int           // Maximum response bytes of a batch, 0 = unlimited
+	limiter                Limiter       // Cost accounting of calls, nil if disabled
+	access                 *AccessPolicy // Methods callable, nil if unrestricted
 }
 
 // NewServer creates a new server instance with no registered handlers.
@@ -65,6 +74,35 @@ func NewServer() *Server {
 	return server
 }
 
+// SetBatchLimits sets limits applied to batch requests. There are two limits: 'itemLimit'
+// is the maximum number of items in a batch. 'maxResponseSize' is the maximum number of
+// response bytes across all requests in a batch. Zero disables the respective limit.
+//
+// This method should be called before processing any requests via ServeCodec, ServeHTTP,
+// ServeListener etc.
+func (s *Server) SetBatchLimits(itemLimit, maxResponseSize int) {
+	s.limits.batchItemLimit = itemLimit
+	s.limits.batchResponseSizeLimit = maxResponseSize
+}
+
+// SetLimiter sets the limiter accounting for the cost of all calls served. Calls
+// rejected by the limiter are answered with an error instead of being executed.
+//
+// This method should be called before processing any requests via ServeCodec, ServeHTTP,
+// ServeListener etc.
+func (s *Server) SetLimiter(limiter Limiter) {
+	s.limits.limiter = limiter
+}
+
+// SetAccessPolicy restricts the methods callable on the server. Calls of methods
+// not permitted by the policy are rejected without being executed.
+//
+// This method should be called before processing any requests via ServeCodec, ServeHTTP,
+// ServeListener etc.
+func (s *Server) SetAccessPolicy(policy AccessPolicy) {
+	s.limits.access = &policy
+}
+
 // RegisterName creates a service for the given receiver type under the given name. When no
 // methods on the given receiver match the criteria to be either a RPC method or a
 // subscription an error is returned. Otherwise a new service is created and added to the
@@ -86,7 +124,7 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	}
 	defer s.untrackCodec(codec)
 
-	c := initClient(codec, s.idgen, &s.services)
+	c := initClient(codec, s.idgen, &s.services, &s.limits)
 	<-codec.closed()
 	c.Close()
 }
@@ -118,7 +156,7 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec) {
 		return
 	}
 
-	h := newHandler(ctx, codec, s.idgen, &s.services)
+	h := newHandler(ctx, codec, s.idgen, &s.services, &s.limits)
 	h.allowSubscribe = false
 	defer h.close(io.EOF, nil)
 
@@ -177,12 +215,17 @@ func (s *RPCService) Modules() map[string]string {
 // the current method call.
 type PeerInfo struct {
 	// Transport is name of the protocol used by the client.
-	// This can be "http", "ws" or "ipc".
+	// This can be "http", "ws", "ipc" or "binary".
 	Transport string
 
 	// Address of client. This will usually contain the IP address and port.
 	RemoteAddr string
 
+	// Authenticated is set if the transport authenticated the client, Identity
+	// is the identity it authenticated as, e.g. the "id" claim of a JWT.
+	Authenticated bool
+	Identity      string
+
 	// Additional information for HTTP and WebSocket connections.
 	HTTP struct {
 		// Protocol version, i.e. "HTTP/1.1". This is not set for WebSocket.
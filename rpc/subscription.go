@@ -99,6 +99,7 @@ type Notifier struct {
 	buffer       []json.RawMessage
 	callReturned bool
 	activated    bool
+	unsubscribed bool
 }
 
 // CreateSubscription returns a new subscription that is coupled to the
@@ -141,6 +142,23 @@ func (n *Notifier) Notify(id ID, data interface{}) error {
 	return nil
 }
 
+// Unsubscribe ends the subscription on the server side, e.g. if it can't be served
+// anymore. Its error channel is closed as if the client had unsubscribed. The
+// client isn't informed, any reason has to be sent in a notification beforehand.
+func (n *Notifier) Unsubscribe() {
+	n.h.subLock.Lock()
+	defer n.h.subLock.Unlock()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.sub == nil || n.unsubscribed {
+		return
+	}
+	n.unsubscribed = true
+	delete(n.h.serverSubs, n.sub.ID)
+	close(n.sub.err)
+}
+
 // Closed returns a channel that is closed when the RPC connection is closed.
 // Deprecated: use subscription error channel
 func (n *Notifier) Closed() <-chan interface{} {
@@ -153,6 +171,9 @@ func (n *Notifier) takeSubscription() *Subscription {
 	n.mu.Lock()
 	defer n.mu.Unlock()
 	n.callReturned = true
+	if n.unsubscribed {
+		return nil
+	}
 	return n.sub
 }
 
@@ -17,6 +17,7 @@
 package rpc
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net"
@@ -171,6 +172,42 @@ func TestServerUnsubscribe(t *testing.T) {
 	}
 }
 
+// This test checks that subscriptions can be ended on the server side.
+func TestServerSideUnsubscribe(t *testing.T) {
+	server := newTestServer()
+	service := &notificationTestService{unsubscribed: make(chan string, 1)}
+	server.RegisterName("nftest2", service)
+	client := DialInProc(server)
+	defer client.Close()
+
+	// Subscribe, the notification is delivered before the server unsubscribes.
+	ch := make(chan int, 1)
+	sub, err := client.Subscribe(context.Background(), "nftest2", ch, "closingSubscription", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+	select {
+	case val := <-ch:
+		if val != 7 {
+			t.Errorf("wrong notification: have %d, want 7", val)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for notification")
+	}
+	var subid string
+	select {
+	case subid = <-service.unsubscribed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for server-side unsubscribe")
+	}
+	// The subscription is gone from the server.
+	var ok bool
+	if err := client.Call(&ok, "nftest2_unsubscribe", subid); err == nil || err.Error() != ErrSubscriptionNotFound.Error() {
+		t.Fatalf("wrong unsubscribe error: have %v, want %v", err, ErrSubscriptionNotFound)
+	}
+}
+
 type subConfirmation struct {
 	reqid int
 	subid ID
@@ -202,6 +202,23 @@ func (s *notificationTestService) SomeSubscription(ctx context.Context, n, val i
 	return subscription, nil
 }
 
+// ClosingSubscription sends a notification and ends the subscription on the
+// server side, signalling s.unsubscribed afterwards.
+func (s *notificationTestService) ClosingSubscription(ctx context.Context, val int) (*Subscription, error) {
+	notifier, supported := NotifierFromContext(ctx)
+	if !supported {
+		return nil, ErrNotificationsUnsupported
+	}
+	subscription := notifier.CreateSubscription()
+	go func() {
+		notifier.Notify(subscription.ID, val)
+		notifier.Unsubscribe()
+		<-subscription.Err()
+		s.unsubscribed <- string(subscription.ID)
+	}()
+	return subscription, nil
+}
+
 // HangSubscription blocks on s.unblockHangSubscription before sending anything.
 func (s *notificationTestService) HangSubscription(ctx context.Context, val int) (*Subscription, error) {
 	notifier, supported := NotifierFromContext(ctx)
@@ -61,6 +61,8 @@ func (s *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 			return
 		}
 		codec := newWebsocketCodec(conn, r.Host, r.Header)
+		info := &codec.(*websocketCodec).info
+		info.Identity, info.Authenticated = identityFromContext(r.Context())
 		s.ServeCodec(codec, 0)
 	})
 }
@@ -26,6 +26,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/trie/triedb/hashdb"
+	"github.com/ethereum/go-ethereum/trie/triedb/pathdb"
 	"github.com/ethereum/go-ethereum/trie/trienode"
 )
 
@@ -34,6 +35,10 @@ type Config struct {
 	Cache     int    // Memory allowance (MB) to use for caching trie nodes in memory
 	Journal   string // Journal of clean cache to survive node restarts
 	Preimages bool   // Flag whether the preimage of trie key is recorded
+
+	// Configs for the path-based scheme, the legacy hash-based scheme is used
+	// if it's not set.
+	PathDB *pathdb.Config
 }
 
 // backend defines the methods needed to access/update trie nodes in different
@@ -104,18 +109,32 @@ func NewDatabase(diskdb ethdb.Database) *Database {
 }
 
 // NewDatabaseWithConfig initializes the trie database with provided configs.
-// The path-based scheme is not activated yet, always initialized with legacy
-// hash-based scheme by default.
+// The path-based scheme is used if its configs are provided, otherwise the
+// legacy hash-based scheme is initialized.
 func NewDatabaseWithConfig(diskdb ethdb.Database, config *Config) *Database {
 	db := prepare(diskdb, config)
-	db.backend = hashdb.New(diskdb, db.cleans, mptResolver{})
+	if config != nil && config.PathDB != nil {
+		db.backend = pathdb.New(diskdb, db.cleans, config.PathDB)
+	} else {
+		db.backend = hashdb.New(diskdb, db.cleans, mptResolver{})
+	}
 	return db
 }
 
 // Reader returns a reader for accessing all trie nodes with provided state root.
 // Nil is returned in case the state is not available.
 func (db *Database) Reader(blockRoot common.Hash) Reader {
-	return db.backend.(*hashdb.Database).Reader(blockRoot)
+	switch b := db.backend.(type) {
+	case *hashdb.Database:
+		return b.Reader(blockRoot)
+	case *pathdb.Database:
+		reader, err := b.Reader(blockRoot)
+		if err != nil {
+			return nil
+		}
+		return reader
+	}
+	return nil
 }
 
 // Update performs a state transition by committing dirty nodes contained in the
@@ -255,6 +274,18 @@ func (db *Database) Dereference(root common.Hash) error {
 	return nil
 }
 
+// SetInsertHook installs a callback invoked for every trie node committed into
+// the database, nil removes it. It's only supported by hash-based database and
+// will return an error for others.
+func (db *Database) SetInsertHook(hook func(hash common.Hash)) error {
+	hdb, ok := db.backend.(*hashdb.Database)
+	if !ok {
+		return errors.New("not supported")
+	}
+	hdb.SetInsertHook(hook)
+	return nil
+}
+
 // Node retrieves the rlp-encoded node blob with provided node hash. It's
 // only supported by hash-based database and will return an error for others.
 // Note, this function should be deprecated once ETH66 is deprecated.
@@ -265,3 +296,39 @@ func (db *Database) Node(hash common.Hash) ([]byte, error) {
 	}
 	return hdb.Node(hash)
 }
+
+// Recover rollbacks the database to a specified historical point. The state is
+// supported as the rollback destination only if it's canonical state and the
+// corresponding reverse diffs are existent.
+//
+// It's only supported by path-based database and will return an error for others.
+func (db *Database) Recover(target common.Hash) error {
+	pdb, ok := db.backend.(*pathdb.Database)
+	if !ok {
+		return errors.New("not supported")
+	}
+	return pdb.Recover(target)
+}
+
+// Recoverable returns the indicator if the specified state is enabled to be
+// recovered. It's only supported by path-based database and will return false
+// for others.
+func (db *Database) Recoverable(root common.Hash) bool {
+	pdb, ok := db.backend.(*pathdb.Database)
+	if !ok {
+		return false
+	}
+	return pdb.Recoverable(root)
+}
+
+// Enable activates the database and resets the state tree with the provided
+// persistent state root once the state sync is finished.
+//
+// It's only supported by path-based database and will return an error for others.
+func (db *Database) Enable(root common.Hash) error {
+	pdb, ok := db.backend.(*pathdb.Database)
+	if !ok {
+		return errors.New("not supported")
+	}
+	return pdb.Enable(root)
+}
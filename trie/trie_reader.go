@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 )
 
 // Reader wraps the Node method of a backing trie store.
@@ -49,6 +50,11 @@ type trieReader struct {
 func newTrieReader(stateRoot, owner common.Hash, db NodeReader) (*trieReader, error) {
 	reader := db.Reader(stateRoot)
 	if reader == nil {
+		// An empty state has no nodes to be resolved, which the backing
+		// store may not track explicitly.
+		if stateRoot == types.EmptyRootHash {
+			return &trieReader{owner: owner}, nil
+		}
 		return nil, fmt.Errorf("state not found #%x", stateRoot)
 	}
 	return &trieReader{owner: owner, reader: reader}, nil
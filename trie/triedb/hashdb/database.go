@@ -91,6 +91,8 @@ type Database struct {
 	dirtiesSize  common.StorageSize // Storage size of the dirty node cache (exc. metadata)
 	childrenSize common.StorageSize // Storage size of the external children tracking
 
+	onInsert func(hash common.Hash) // Optional hook invoked for all committed trie nodes
+
 	lock sync.RWMutex
 }
 
@@ -581,6 +583,9 @@ func (db *Database) Update(root common.Hash, parent common.Hash, nodes *trienode
 				return // ignore deletion
 			}
 			db.insert(n.Hash, n.Blob)
+			if db.onInsert != nil {
+				db.onInsert(n.Hash)
+			}
 		})
 	}
 	// Link up the account trie and storage trie if the node points
@@ -599,6 +604,17 @@ func (db *Database) Update(root common.Hash, parent common.Hash, nodes *trienode
 	return nil
 }
 
+// SetInsertHook installs a callback which is invoked for every trie node
+// committed into the database, regardless of whether it's already known.
+// It's used to track the nodes which are reachable from the recent states.
+// Passing nil removes the installed hook.
+func (db *Database) SetInsertHook(hook func(hash common.Hash)) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.onInsert = hook
+}
+
 // Size returns the current storage size of the memory cache in front of the
 // persistent database layer.
 func (db *Database) Size() common.StorageSize {
@@ -0,0 +1,317 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>
+
+package pathdb
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/fastcache"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie/trienode"
+)
+
+// maxDiffLayers is the maximum diff layers allowed in the layer tree.
+const maxDiffLayers = 128
+
+// layer is the interface implemented by all state layers which includes some
+// public methods and some additional methods for internal usage.
+type layer interface {
+	// Node retrieves the trie node with the node info. An error will be returned
+	// if the read operation exits abnormally. For example, if the layer is already
+	// stale, or the node stored at the given path doesn't match the requested one.
+	Node(owner common.Hash, path []byte, hash common.Hash) ([]byte, error)
+
+	// rootHash returns the root hash for which this layer was made.
+	rootHash() common.Hash
+
+	// stateID returns the associated state id of layer.
+	stateID() uint64
+
+	// parentLayer returns the subsequent layer of it, or nil if the disk was reached.
+	parentLayer() layer
+
+	// update creates a new layer on top of the existing layer tree with
+	// the provided dirty trie nodes.
+	update(root common.Hash, id uint64, nodes map[common.Hash]map[string]*trienode.WithPrev) *diffLayer
+}
+
+// Config contains the settings for database.
+type Config struct {
+	StateHistory uint64 // Number of recent blocks to maintain state history for, 0 means unlimited
+}
+
+// Defaults contains default settings for Ethereum mainnet.
+var Defaults = &Config{
+	StateHistory: params.FullImmutabilityThreshold,
+}
+
+// Database is a multiple-layered structure for maintaining in-memory trie nodes.
+// It consists of one persistent base layer backed by a key-value store, on top
+// of which arbitrarily many in-memory diff layers are stacked. The memory diffs
+// can form a tree with branching, but the disk layer is singleton and common to
+// all. If a reorg goes deeper than the disk layer, the disk layer is rolled back
+// with the retained reverse diffs.
+//
+// Trie nodes are keyed by owner and path in the disk, so only a single version
+// of the state is stored, making pruning of stale state inherent.
+type Database struct {
+	config *Config          // Configuration for database
+	diskdb ethdb.Database   // Persistent storage for matured trie nodes
+	cleans *fastcache.Cache // GC friendly memory cache of clean node RLPs
+	tree   *layerTree       // The group for all known layers
+	lock   sync.RWMutex     // Lock to prevent mutations from happening at the same time
+}
+
+// New attempts to load an already existing layer from a persistent key-value
+// store. The in-memory diff layers don't survive restarts, the ones needed are
+// expected to be committed on shutdown.
+func New(diskdb ethdb.Database, cleans *fastcache.Cache, config *Config) *Database {
+	if config == nil {
+		config = Defaults
+	}
+	db := &Database{
+		config: config,
+		diskdb: diskdb,
+		cleans: cleans,
+	}
+	db.tree = newLayerTree(db.loadDiskLayer())
+	return db
+}
+
+// loadDiskLayer constructs the disk layer from the persistent state.
+func (db *Database) loadDiskLayer() *diskLayer {
+	_, root := rawdb.ReadAccountTrieNode(db.diskdb, nil)
+	root = trieRootHash(root)
+
+	// Ensure the persistent state can be looked up, e.g. for recovery to the
+	// very first state written into the database.
+	id := rawdb.ReadPersistentStateID(db.diskdb)
+	if stored := rawdb.ReadStateID(db.diskdb, root); stored == nil {
+		rawdb.WriteStateID(db.diskdb, root, id)
+	}
+	return newDiskLayer(root, id, db, db.cleans)
+}
+
+// Reader retrieves a layer belonging to the given state root.
+func (db *Database) Reader(root common.Hash) (layer, error) {
+	l := db.tree.get(root)
+	if l == nil {
+		return nil, fmt.Errorf("state %#x is not available", root)
+	}
+	return l, nil
+}
+
+// Update adds a new layer into the tree, if that can be linked to an existing
+// old parent. It is disallowed to insert a disk layer (the origin of all). Apart
+// from that this function will flatten the extra diff layers at bottom into disk
+// to only keep 128 diff layers in memory by default.
+func (db *Database) Update(root common.Hash, parentRoot common.Hash, nodes *trienode.MergedNodeSet) error {
+	// Hold the lock to prevent concurrent mutations.
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if err := db.tree.add(root, parentRoot, nodes); err != nil {
+		return err
+	}
+	// Keep 128 diff layers in the memory, persistent layer is 129th.
+	// - head layer is paired with HEAD state
+	// - head-1 layer is paired with HEAD-1 state
+	// - head-127 layer(bottom-most diff layer) is paired with HEAD-127 state
+	return db.tree.cap(root, maxDiffLayers)
+}
+
+// Commit traverses downwards the layer tree from a specified layer with the
+// provided state root and all the layers below are flattened downwards. It
+// can be used alone and mostly for test purposes.
+func (db *Database) Commit(root common.Hash, report bool) error {
+	// Hold the lock to prevent concurrent mutations.
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	start := time.Now()
+	if err := db.tree.cap(root, 0); err != nil {
+		return err
+	}
+	logger := log.Debug
+	if report {
+		logger = log.Info
+	}
+	logger("Persisted trie from memory database", "root", root, "id", db.tree.bottom().stateID(), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// Enable activates the database and resets the state tree with the provided
+// persistent state root once the state sync is finished. The nodes of the
+// target state are expected to be written into the disk already.
+func (db *Database) Enable(root common.Hash) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	root = trieRootHash(root)
+	_, stored := rawdb.ReadAccountTrieNode(db.diskdb, nil)
+	if stored = trieRootHash(stored); stored != root {
+		return fmt.Errorf("state root mismatch: stored %x, synced %x", stored, root)
+	}
+	// Drop the stale state histories and lookups, the state has been replaced
+	// by the synced one and they can't be applied anymore.
+	batch := db.diskdb.NewBatch()
+	for id := db.tree.bottom().stateID(); id > 0 && rawdb.HasTrieHistory(db.diskdb, id); id-- {
+		rawdb.DeleteTrieHistory(batch, id)
+	}
+	it := db.diskdb.NewIterator(rawdb.StateIDPrefix, nil)
+	for it.Next() {
+		if len(it.Key()) == len(rawdb.StateIDPrefix)+common.HashLength {
+			batch.Delete(it.Key())
+		}
+	}
+	it.Release()
+	rawdb.WritePersistentStateID(batch, 0)
+	rawdb.WriteStateID(batch, root, 0)
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	// Drop the clean cache as it might contain nodes of the replaced state.
+	if db.cleans != nil {
+		db.cleans.Reset()
+	}
+	db.tree.forEach(func(l layer) {
+		if dl, ok := l.(*diskLayer); ok && !dl.isStale() {
+			dl.markStale()
+		}
+	})
+	db.tree.reset(newDiskLayer(root, 0, db, db.cleans))
+	log.Info("Rebuilt trie database", "root", root)
+	return nil
+}
+
+// Recover rollbacks the database to a specified historical point. The state is
+// supported as the rollback destination only if it's canonical state and the
+// corresponding reverse diffs are existent.
+func (db *Database) Recover(root common.Hash) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if !db.recoverable(root) {
+		return errStateUnrecoverable
+	}
+	// Apply the reverse diffs with the given order, all the in-memory layers
+	// are discarded as they're descendants of the reverted states.
+	var (
+		start = time.Now()
+		dl    = db.tree.bottom()
+	)
+	root = trieRootHash(root)
+	for dl.rootHash() != root {
+		h, err := readHistory(db.diskdb, dl.stateID())
+		if err != nil {
+			return err
+		}
+		dl, err = dl.revert(h)
+		if err != nil {
+			return err
+		}
+		// reset layer with newly created disk layer. It must be
+		// done after each revert operation, otherwise the new
+		// disk layer won't be accessible from outside.
+		db.tree.reset(dl)
+	}
+	log.Debug("Recovered state", "root", root, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// Recoverable returns the indicator if the specified state is recoverable.
+func (db *Database) Recoverable(root common.Hash) bool {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	return db.recoverable(root)
+}
+
+// recoverable is the lock free version of Recoverable.
+func (db *Database) recoverable(root common.Hash) bool {
+	// Ensure the requested state is a known state.
+	id := rawdb.ReadStateID(db.diskdb, trieRootHash(root))
+	if id == nil {
+		return false
+	}
+	// Recoverable state must below the disk layer. The recoverable
+	// state only refers the state that is currently not available,
+	// but can be restored by applying state history.
+	dl := db.tree.bottom()
+	if *id >= dl.stateID() {
+		return false
+	}
+	// Ensure all the reverse diffs in between are retained. They are pruned
+	// from the tail, so it's enough to check the oldest one needed.
+	return rawdb.HasTrieHistory(db.diskdb, *id+1)
+}
+
+// Close closes the trie database. The in-memory layers are discarded, they
+// are expected to be committed by the caller beforehand if needed.
+func (db *Database) Close() error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if dl := db.tree.bottom(); !dl.isStale() {
+		dl.markStale()
+	}
+	return nil
+}
+
+// Size returns the current storage size of the memory cache in front of the
+// persistent database layer.
+func (db *Database) Size() (size common.StorageSize) {
+	db.tree.forEach(func(layer layer) {
+		if diff, ok := layer.(*diffLayer); ok {
+			size += common.StorageSize(diff.memory)
+		}
+	})
+	return size
+}
+
+// Initialized returns an indicator if the state data is already
+// initialized in path-based scheme.
+func (db *Database) Initialized(genesisRoot common.Hash) bool {
+	var inited bool
+	db.tree.forEach(func(layer layer) {
+		if layer.rootHash() != types.EmptyRootHash {
+			inited = true
+		}
+	})
+	return inited
+}
+
+// Scheme returns the node scheme used in the database.
+func (db *Database) Scheme() string {
+	return rawdb.PathScheme
+}
+
+// trieRootHash returns the given root hash, substituting the empty root hash
+// for the zero one as the former is what represents an empty state.
+func trieRootHash(root common.Hash) common.Hash {
+	if root == (common.Hash{}) {
+		return types.EmptyRootHash
+	}
+	return root
+}
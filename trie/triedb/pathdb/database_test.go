@@ -0,0 +1,276 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>
+
+package pathdb
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/trie/trienode"
+)
+
+// nodeSnapshot is the full set of trie nodes of a state, indexed by owner and path.
+type nodeSnapshot map[common.Hash]map[string][]byte
+
+func (s nodeSnapshot) copy() nodeSnapshot {
+	cpy := make(nodeSnapshot)
+	for owner, subset := range s {
+		cpy[owner] = make(map[string][]byte)
+		for path, blob := range subset {
+			cpy[owner][path] = blob
+		}
+	}
+	return cpy
+}
+
+// tester generates random state transitions on top of a path database and
+// tracks the expected content of every state.
+type tester struct {
+	diskdb    ethdb.Database
+	db        *Database
+	roots     []common.Hash
+	snapshots map[common.Hash]nodeSnapshot
+	owners    []common.Hash
+	rand      *rand.Rand
+}
+
+func newTester(limit uint64) *tester {
+	diskdb := rawdb.NewMemoryDatabase()
+	t := &tester{
+		diskdb:    diskdb,
+		db:        New(diskdb, nil, &Config{StateHistory: limit}),
+		snapshots: map[common.Hash]nodeSnapshot{types.EmptyRootHash: {}},
+		owners:    []common.Hash{{}, {0x1}, {0x2}, {0x3}},
+		rand:      rand.New(rand.NewSource(1)),
+	}
+	return t
+}
+
+func (t *tester) randBlob() []byte {
+	blob := make([]byte, 1+t.rand.Intn(64))
+	t.rand.Read(blob)
+	return blob
+}
+
+// generate creates a random state transition on top of the given parent.
+func (t *tester) generate(parent common.Hash) (common.Hash, *trienode.MergedNodeSet, nodeSnapshot) {
+	var (
+		state = t.snapshots[parent].copy()
+		sets  = trienode.NewMergedNodeSet()
+	)
+	for _, owner := range t.owners {
+		set := trienode.NewNodeSet(owner)
+		if state[owner] == nil {
+			state[owner] = make(map[string][]byte)
+		}
+		subset := state[owner]
+
+		// Delete or update a few existing nodes
+		for path, prev := range subset {
+			if t.rand.Intn(4) != 0 || path == "" {
+				continue
+			}
+			if t.rand.Intn(2) == 0 {
+				set.AddNode([]byte(path), trienode.NewWithPrev(common.Hash{}, nil, prev))
+				delete(subset, path)
+			} else {
+				blob := t.randBlob()
+				set.AddNode([]byte(path), trienode.NewWithPrev(crypto.Keccak256Hash(blob), blob, prev))
+				subset[path] = blob
+			}
+		}
+		// Create a few new nodes, along with the root node of the account trie
+		// which is always changed to derive the state root
+		paths := []string{string([]byte{byte(t.rand.Intn(16)), byte(t.rand.Intn(16))})}
+		if owner == (common.Hash{}) {
+			paths = append(paths, "")
+		}
+		for _, path := range paths {
+			blob := t.randBlob()
+			set.AddNode([]byte(path), trienode.NewWithPrev(crypto.Keccak256Hash(blob), blob, t.snapshots[parent][owner][path]))
+			subset[path] = blob
+		}
+		sets.Merge(set)
+	}
+	root := crypto.Keccak256Hash(state[common.Hash{}][""])
+	return root, sets, state
+}
+
+// extend adds a number of state transitions on top of the current head.
+func (t *tester) extend(test *testing.T, n int) {
+	for i := 0; i < n; i++ {
+		parent := types.EmptyRootHash
+		if len(t.roots) > 0 {
+			parent = t.roots[len(t.roots)-1]
+		}
+		root, nodes, state := t.generate(parent)
+		if err := t.db.Update(root, parent, nodes); err != nil {
+			test.Fatalf("failed to update state %d: %v", len(t.roots), err)
+		}
+		t.roots = append(t.roots, root)
+		t.snapshots[root] = state
+	}
+}
+
+// verify checks that all the nodes of the given state are accessible.
+func (t *tester) verify(root common.Hash) error {
+	reader, err := t.db.Reader(root)
+	if err != nil {
+		return err
+	}
+	for owner, subset := range t.snapshots[root] {
+		for path, blob := range subset {
+			have, err := reader.Node(owner, []byte(path), crypto.Keccak256Hash(blob))
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(have, blob) {
+				return errUnexpectedNode
+			}
+		}
+	}
+	return nil
+}
+
+// Tests that the recent states are retained in memory, while the older ones
+// are flattened into the disk along with their reverse diffs.
+func TestDatabaseLayers(t *testing.T) {
+	tester := newTester(0)
+	tester.extend(t, maxDiffLayers+32)
+
+	// The most recent 128 states live as diff layers, the 129th as the disk
+	if n := tester.db.tree.len(); n != maxDiffLayers+1 {
+		t.Fatalf("layer count mismatch: have %d, want %d", n, maxDiffLayers+1)
+	}
+	for i := len(tester.roots) - maxDiffLayers - 1; i < len(tester.roots); i++ {
+		if err := tester.verify(tester.roots[i]); err != nil {
+			t.Fatalf("state %d not accessible: %v", i, err)
+		}
+	}
+	// Older states are not accessible, but recoverable from the histories
+	for i := 0; i < len(tester.roots)-maxDiffLayers-1; i++ {
+		if _, err := tester.db.Reader(tester.roots[i]); err == nil {
+			t.Fatalf("stale state %d accessible", i)
+		}
+		if !tester.db.Recoverable(tester.roots[i]) {
+			t.Fatalf("stale state %d not recoverable", i)
+		}
+	}
+	if tester.db.Recoverable(tester.roots[len(tester.roots)-1]) {
+		t.Fatal("live state reported recoverable")
+	}
+	// Reopening the database only retains the persisted state
+	head := tester.roots[len(tester.roots)-1]
+	if err := tester.db.Commit(head, false); err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	tester.db = New(tester.diskdb, nil, nil)
+	if err := tester.verify(head); err != nil {
+		t.Fatalf("persisted state not accessible: %v", err)
+	}
+	if !tester.db.Initialized(types.EmptyRootHash) {
+		t.Fatal("database not initialized")
+	}
+	if scheme := rawdb.ReadStateScheme(tester.diskdb); scheme != rawdb.PathScheme {
+		t.Fatalf("state scheme mismatch: have %s, want %s", scheme, rawdb.PathScheme)
+	}
+}
+
+// Tests that the disk layer can be rolled back to historical states by applying
+// the reverse diffs, and that the diffs beyond the retention limit are pruned.
+func TestDatabaseRecover(t *testing.T) {
+	const limit = 64
+
+	tester := newTester(limit)
+	tester.extend(t, maxDiffLayers+limit+16)
+
+	var (
+		disk   = len(tester.roots) - maxDiffLayers - 1 // index of the disk layer state
+		oldest = disk - limit                          // index of the oldest recoverable state
+	)
+	for i := 0; i < len(tester.roots)-maxDiffLayers-1; i++ {
+		if have, want := tester.db.Recoverable(tester.roots[i]), i >= oldest; have != want {
+			t.Fatalf("state %d recoverability mismatch: have %v, want %v", i, have, want)
+		}
+	}
+	if err := tester.db.Recover(tester.roots[oldest-1]); err == nil {
+		t.Fatal("pruned state recovered")
+	}
+	// Roll back in two steps and ensure the states are intact
+	for _, target := range []int{disk - limit/2, oldest} {
+		if err := tester.db.Recover(tester.roots[target]); err != nil {
+			t.Fatalf("failed to recover state %d: %v", target, err)
+		}
+		if err := tester.verify(tester.roots[target]); err != nil {
+			t.Fatalf("recovered state %d not accessible: %v", target, err)
+		}
+		if n := tester.db.tree.len(); n != 1 {
+			t.Fatalf("layer count mismatch: have %d, want 1", n)
+		}
+		for i := target; i < len(tester.roots); i++ {
+			if tester.db.Recoverable(tester.roots[i]) {
+				t.Fatalf("state %d recoverable after rollback", i)
+			}
+		}
+	}
+	// Build a different chain on top of the recovered state
+	tester.roots = tester.roots[:oldest+1]
+	tester.extend(t, maxDiffLayers+8)
+	for i := len(tester.roots) - maxDiffLayers - 1; i < len(tester.roots); i++ {
+		if err := tester.verify(tester.roots[i]); err != nil {
+			t.Fatalf("state %d not accessible: %v", i, err)
+		}
+	}
+}
+
+// Tests that the database can be reset onto a state written directly to the
+// disk, like the one retrieved by state sync.
+func TestDatabaseEnable(t *testing.T) {
+	tester := newTester(0)
+	tester.extend(t, 16)
+
+	// Write a brand new state directly into the disk
+	var (
+		blob = []byte{0xde, 0xad}
+		root = crypto.Keccak256Hash(blob)
+	)
+	if err := tester.db.Enable(root); err == nil {
+		t.Fatal("mismatched state enabled")
+	}
+	rawdb.WriteAccountTrieNode(tester.diskdb, nil, blob)
+	if err := tester.db.Enable(root); err != nil {
+		t.Fatalf("failed to enable state: %v", err)
+	}
+	reader, err := tester.db.Reader(root)
+	if err != nil {
+		t.Fatalf("enabled state not accessible: %v", err)
+	}
+	if have, err := reader.Node(common.Hash{}, nil, root); err != nil || !bytes.Equal(have, blob) {
+		t.Fatalf("root node mismatch: have %x/%v, want %x", have, err, blob)
+	}
+	for _, root := range tester.roots {
+		if tester.db.Recoverable(root) {
+			t.Fatalf("state %x recoverable after reset", root)
+		}
+	}
+}
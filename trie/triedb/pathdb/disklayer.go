@@ -0,0 +1,270 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>
+
+package pathdb
+
+import (
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/fastcache"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/trie/trienode"
+)
+
+// diskLayer is a low level persistent layer built on top of a key-value store.
+// It holds exactly one version of every trie node, keyed by owner and path.
+type diskLayer struct {
+	root   common.Hash      // Immutable, root hash to which this layer was made for
+	id     uint64           // Immutable, corresponding state id
+	db     *Database        // Path-based trie database
+	cleans *fastcache.Cache // GC friendly memory cache of clean node RLPs
+	stale  bool             // Signals that the layer became stale (state progressed)
+	lock   sync.RWMutex     // Lock used to protect stale flag
+}
+
+// newDiskLayer creates a new disk layer based on the passing arguments.
+func newDiskLayer(root common.Hash, id uint64, db *Database, cleans *fastcache.Cache) *diskLayer {
+	return &diskLayer{
+		root:   root,
+		id:     id,
+		db:     db,
+		cleans: cleans,
+	}
+}
+
+// rootHash implements the layer interface, returning root hash of corresponding state.
+func (dl *diskLayer) rootHash() common.Hash {
+	return dl.root
+}
+
+// stateID implements the layer interface, returning the state id of disk layer.
+func (dl *diskLayer) stateID() uint64 {
+	return dl.id
+}
+
+// parentLayer implements the layer interface, returning nil as there's no layer
+// below the disk.
+func (dl *diskLayer) parentLayer() layer {
+	return nil
+}
+
+// isStale return whether this layer has become stale (was flattened across) or if
+// it's still live.
+func (dl *diskLayer) isStale() bool {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.stale
+}
+
+// markStale sets the stale flag as true.
+func (dl *diskLayer) markStale() {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	if dl.stale {
+		panic("triedb disk layer is stale") // we've committed into the same base from two children, boom
+	}
+	dl.stale = true
+}
+
+// Node implements the layer interface, retrieving the trie node with the
+// provided node info. An error will be returned if the node is not found,
+// or if the persisted one doesn't match the requested hash.
+func (dl *diskLayer) Node(owner common.Hash, path []byte, hash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	if dl.stale {
+		return nil, errSnapshotStale
+	}
+	// Try to retrieve the trie node from the clean memory cache
+	key := cacheKey(owner, path)
+	if dl.cleans != nil {
+		if blob := dl.cleans.Get(nil, key); len(blob) > 0 {
+			if crypto.Keccak256Hash(blob) == hash {
+				cleanHitMeter.Mark(1)
+				cleanReadMeter.Mark(int64(len(blob)))
+				return blob, nil
+			}
+		}
+		cleanMissMeter.Mark(1)
+	}
+	// Try to retrieve the trie node from the disk.
+	var (
+		nBlob []byte
+		nHash common.Hash
+	)
+	if owner == (common.Hash{}) {
+		nBlob, nHash = rawdb.ReadAccountTrieNode(dl.db.diskdb, path)
+	} else {
+		nBlob, nHash = rawdb.ReadStorageTrieNode(dl.db.diskdb, owner, path)
+	}
+	if nHash != hash {
+		diskFalseMeter.Mark(1)
+		log.Debug("Unexpected trie node in disk", "owner", owner, "path", path, "expect", hash, "got", nHash)
+		return nil, newUnexpectedNodeError("disk", hash, nHash, owner, path)
+	}
+	if dl.cleans != nil && len(nBlob) > 0 {
+		dl.cleans.Set(key, nBlob)
+		cleanWriteMeter.Mark(int64(len(nBlob)))
+	}
+	return nBlob, nil
+}
+
+// update implements the layer interface, returning a new diff layer on top
+// with the given state set.
+func (dl *diskLayer) update(root common.Hash, id uint64, nodes map[common.Hash]map[string]*trienode.WithPrev) *diffLayer {
+	return newDiffLayer(dl, root, id, nodes)
+}
+
+// commit merges the given bottom-most diff layer into the disk, storing the
+// reverse diff of the transition first, and returns the newly constructed disk
+// layer. The current one is marked as stale.
+func (dl *diskLayer) commit(bottom *diffLayer) (*diskLayer, error) {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	if dl.stale {
+		return nil, errSnapshotStale
+	}
+	var (
+		start = time.Now()
+		batch = dl.db.diskdb.NewBatch()
+	)
+	// Store the reverse diff of the transition, so that the disk layer can be
+	// rolled back in case of a deep reorg.
+	if err := writeHistory(dl.db.diskdb, batch, bottom.id, newHistory(bottom.root, dl.root, bottom.nodes), dl.db.config.StateHistory); err != nil {
+		return nil, err
+	}
+	nodes := writeNodes(batch, bottom.nodes, dl.cleans)
+	rawdb.WritePersistentStateID(batch, bottom.id)
+
+	size := batch.ValueSize()
+	if err := batch.Write(); err != nil {
+		return nil, err
+	}
+	commitTimeTimer.UpdateSince(start)
+	commitNodesMeter.Mark(int64(nodes))
+	commitBytesMeter.Mark(int64(size))
+
+	dl.stale = true
+	log.Debug("Persisted trie diff layer", "id", bottom.id, "root", bottom.root, "nodes", nodes, "size", common.StorageSize(size), "elapsed", common.PrettyDuration(time.Since(start)))
+	return newDiskLayer(bottom.root, bottom.id, dl.db, dl.cleans), nil
+}
+
+// revert applies the given reverse diff on the disk layer, returning the disk
+// layer representing the parent state. The current one is marked as stale.
+func (dl *diskLayer) revert(h *history) (*diskLayer, error) {
+	if h.Root != dl.rootHash() {
+		return nil, errUnexpectedHistory
+	}
+	if dl.id == 0 {
+		return nil, errStateUnrecoverable
+	}
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	if dl.stale {
+		return nil, errSnapshotStale
+	}
+	start := time.Now()
+
+	batch := dl.db.diskdb.NewBatch()
+	for _, trie := range h.Tries {
+		for _, n := range trie.Nodes {
+			key := cacheKey(trie.Owner, n.Path)
+			if len(n.Prev) == 0 {
+				if trie.Owner == (common.Hash{}) {
+					rawdb.DeleteAccountTrieNode(batch, n.Path)
+				} else {
+					rawdb.DeleteStorageTrieNode(batch, trie.Owner, n.Path)
+				}
+				if dl.cleans != nil {
+					dl.cleans.Del(key)
+				}
+				continue
+			}
+			if trie.Owner == (common.Hash{}) {
+				rawdb.WriteAccountTrieNode(batch, n.Path, n.Prev)
+			} else {
+				rawdb.WriteStorageTrieNode(batch, trie.Owner, n.Path, n.Prev)
+			}
+			if dl.cleans != nil {
+				dl.cleans.Set(key, n.Prev)
+			}
+		}
+	}
+	// The reverted state is not reachable anymore, drop its lookup and
+	// the applied history.
+	if stored := rawdb.ReadStateID(dl.db.diskdb, dl.root); stored != nil && *stored == dl.id {
+		rawdb.DeleteStateID(batch, dl.root)
+	}
+	rawdb.DeleteTrieHistory(batch, dl.id)
+	rawdb.WritePersistentStateID(batch, dl.id-1)
+	if err := batch.Write(); err != nil {
+		return nil, err
+	}
+	historyRevertTimeTimer.UpdateSince(start)
+
+	dl.stale = true
+	log.Debug("Reverted trie disk layer", "id", dl.id, "root", dl.root, "parent", h.Parent, "elapsed", common.PrettyDuration(time.Since(start)))
+	return newDiskLayer(h.Parent, dl.id-1, dl.db, dl.cleans), nil
+}
+
+// writeNodes writes the trie nodes into the provided database batch, keeping
+// the clean cache in sync. Note this function will also inject all the newly
+// written nodes into the clean cache.
+func writeNodes(batch ethdb.Batch, nodes map[common.Hash]map[string]*trienode.WithPrev, clean *fastcache.Cache) (total int) {
+	for owner, subset := range nodes {
+		for path, n := range subset {
+			if n.IsDeleted() {
+				if owner == (common.Hash{}) {
+					rawdb.DeleteAccountTrieNode(batch, []byte(path))
+				} else {
+					rawdb.DeleteStorageTrieNode(batch, owner, []byte(path))
+				}
+				if clean != nil {
+					clean.Del(cacheKey(owner, []byte(path)))
+				}
+			} else {
+				if owner == (common.Hash{}) {
+					rawdb.WriteAccountTrieNode(batch, []byte(path), n.Blob)
+				} else {
+					rawdb.WriteStorageTrieNode(batch, owner, []byte(path), n.Blob)
+				}
+				if clean != nil {
+					clean.Set(cacheKey(owner, []byte(path)), n.Blob)
+				}
+			}
+		}
+		total += len(subset)
+	}
+	return total
+}
+
+// cacheKey constructs the unique key of clean cache.
+func cacheKey(owner common.Hash, path []byte) []byte {
+	if owner == (common.Hash{}) {
+		return path
+	}
+	return append(owner.Bytes(), path...)
+}
@@ -0,0 +1,53 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>
+
+package pathdb
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	// errSnapshotStale is returned from data accessors if the underlying layer
+	// had been invalidated due to the chain progressing forward far enough
+	// to not maintain the layer's original state.
+	errSnapshotStale = errors.New("layer stale")
+
+	// errLayerCycle is returned if a state transition would make a layer the
+	// parent of itself.
+	errLayerCycle = errors.New("layer cycle")
+
+	// errStateUnrecoverable is returned if the state is requested to be reverted
+	// to a point for which no reverse diffs are retained.
+	errStateUnrecoverable = errors.New("state is unrecoverable")
+
+	// errUnexpectedHistory is returned if a reverse diff is not applicable to the
+	// state it's applied on.
+	errUnexpectedHistory = errors.New("unexpected state history")
+
+	// errUnexpectedNode is returned if the requested trie node is not the one
+	// stored at the given path.
+	errUnexpectedNode = errors.New("unexpected node")
+)
+
+// newUnexpectedNodeError constructs an error for a trie node whose hash doesn't
+// match the requested one, e.g. because it was overwritten by a newer state.
+func newUnexpectedNodeError(loc string, expHash common.Hash, gotHash common.Hash, owner common.Hash, path []byte) error {
+	return fmt.Errorf("%w, loc: %s, node: (%x %v), %x!=%x", errUnexpectedNode, loc, owner, path, expHash, gotHash)
+}
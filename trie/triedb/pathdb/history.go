@@ -0,0 +1,130 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>
+
+package pathdb
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie/trienode"
+)
+
+// State history is a set of reverse diffs, one for each state transition that
+// made it into the persistent disk layer. The reverse diff with id N contains
+// the original values of all trie nodes modified by the transition from state
+// N-1 to state N, which is sufficient to revert the disk layer to state N-1.
+//
+// The histories are stored in the key-value store, and only the most recent
+// ones are retained (configurable). In case of a deep reorg, the disk layer can
+// be rolled back along the retained histories as long as the target state is
+// still covered.
+
+// historyNode is the original value of a single trie node before a state
+// transition, nil meaning the node didn't exist.
+type historyNode struct {
+	Path []byte
+	Prev []byte
+}
+
+// historyTrie is the list of original trie node values belonging to a single
+// trie, ordered by path.
+type historyTrie struct {
+	Owner common.Hash
+	Nodes []historyNode
+}
+
+// history is the reverse diff of a state transition from parent to root.
+type history struct {
+	Parent common.Hash   // State root before the transition
+	Root   common.Hash   // State root after the transition
+	Tries  []historyTrie // Original trie node values, ordered by owner
+}
+
+// newHistory constructs the reverse diff of the state transition represented
+// by the given dirty nodes.
+func newHistory(root common.Hash, parent common.Hash, nodes map[common.Hash]map[string]*trienode.WithPrev) *history {
+	owners := make([]common.Hash, 0, len(nodes))
+	for owner := range nodes {
+		owners = append(owners, owner)
+	}
+	sort.Slice(owners, func(i, j int) bool { return bytes.Compare(owners[i][:], owners[j][:]) < 0 })
+
+	tries := make([]historyTrie, 0, len(owners))
+	for _, owner := range owners {
+		subset := nodes[owner]
+		paths := make([]string, 0, len(subset))
+		for path := range subset {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		trie := historyTrie{Owner: owner, Nodes: make([]historyNode, 0, len(paths))}
+		for _, path := range paths {
+			trie.Nodes = append(trie.Nodes, historyNode{Path: []byte(path), Prev: subset[path].Prev})
+		}
+		tries = append(tries, trie)
+	}
+	return &history{Parent: parent, Root: root, Tries: tries}
+}
+
+// writeHistory persists the reverse diff of the state transition into the batch
+// with the given id, and prunes the oldest retained one if the limit is exceeded.
+func writeHistory(db ethdb.KeyValueReader, batch ethdb.Batch, id uint64, h *history, limit uint64) error {
+	start := time.Now()
+
+	blob, err := rlp.EncodeToBytes(h)
+	if err != nil {
+		return err
+	}
+	rawdb.WriteTrieHistory(batch, id, blob)
+	rawdb.WriteStateID(batch, h.Root, id)
+
+	// Prune the history falling out of the retention window, along with the
+	// lookup of the state it would revert to as that's not reachable anymore.
+	if limit != 0 && id > limit {
+		tail := id - limit
+		if old, err := readHistory(db, tail); err == nil {
+			if stored := rawdb.ReadStateID(db, old.Parent); stored != nil && *stored == tail-1 {
+				rawdb.DeleteStateID(batch, old.Parent)
+			}
+			rawdb.DeleteTrieHistory(batch, tail)
+			historyPrunedMeter.Mark(1)
+		}
+	}
+	historyDataBytesMeter.Mark(int64(len(blob)))
+	historyBuildTimeMeter.UpdateSince(start)
+	return nil
+}
+
+// readHistory retrieves and decodes the reverse diff with the given id.
+func readHistory(db ethdb.KeyValueReader, id uint64) (*history, error) {
+	blob := rawdb.ReadTrieHistory(db, id)
+	if len(blob) == 0 {
+		return nil, fmt.Errorf("state history #%d not found", id)
+	}
+	h := new(history)
+	if err := rlp.DecodeBytes(blob, h); err != nil {
+		return nil, fmt.Errorf("invalid state history #%d: %v", id, err)
+	}
+	return h, nil
+}